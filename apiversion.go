@@ -0,0 +1,71 @@
+package httpc
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// API version placements for Config.APIVersion.In.
+const (
+	// APIVersionInHeader sends the version in a header (default name
+	// API-Version), e.g. Stripe-Version or X-GitHub-Api-Version.
+	APIVersionInHeader = "header"
+	// APIVersionInMediaType adds a parameter (default name version) to every
+	// Accept media type, e.g. application/json; version=2.
+	APIVersionInMediaType = "media_type"
+	// APIVersionInQuery sends a query parameter (default name api-version),
+	// as Azure does.
+	APIVersionInQuery = "query"
+)
+
+func validateAPIVersionPlacement(in string) error {
+	switch in {
+	case APIVersionInHeader, APIVersionInMediaType, APIVersionInQuery:
+		return nil
+	}
+	return fmt.Errorf("unsupported api_version.in %q", in)
+}
+
+// applyAPIVersion places version on req as cfg.APIVersion describes.
+func applyAPIVersion(req *http.Request, version string, cfg Config) {
+	name := cfg.APIVersion.Name
+	switch cfg.APIVersion.In {
+	case APIVersionInMediaType:
+		if name == "" {
+			name = "version"
+		}
+		accept := req.Header.Get("Accept")
+		if accept == "" {
+			accept = "application/json"
+		}
+		req.Header.Set("Accept", withMediaTypeParam(accept, name, version))
+	case APIVersionInQuery:
+		if name == "" {
+			name = "api-version"
+		}
+		q := req.URL.Query()
+		q.Set(name, version)
+		req.URL.RawQuery = q.Encode()
+	default:
+		if name == "" {
+			name = "API-Version"
+		}
+		req.Header.Set(name, version)
+	}
+}
+
+// withMediaTypeParam sets param on each media range of an Accept value.
+func withMediaTypeParam(accept, param, value string) string {
+	ranges := strings.Split(accept, ",")
+	for i, r := range ranges {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(r))
+		if err != nil {
+			continue
+		}
+		params[param] = value
+		ranges[i] = mime.FormatMediaType(mediaType, params)
+	}
+	return strings.Join(ranges, ", ")
+}
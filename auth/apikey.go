@@ -11,10 +11,15 @@ type APIKeyOptions struct {
 	Key  string
 	In   string
 	Name string
+	// SecondaryKey, when set, is sent in place of Key for requests rejected
+	// with 401 or 403, e.g. while Key is being rotated. The client resends
+	// such requests through NewKeyFallbackMiddleware.
+	SecondaryKey string
 }
 
 // NewAPIKey constructs an AuthProvider that injects an API key either via a
-// header or query parameter.
+// header or query parameter. With a SecondaryKey the provider also
+// implements SecondaryApplier.
 func NewAPIKey(opts APIKeyOptions) AuthProvider {
 	location := strings.ToLower(strings.TrimSpace(opts.In))
 	if location == "" {
@@ -24,11 +29,15 @@ func NewAPIKey(opts APIKeyOptions) AuthProvider {
 	if name == "" {
 		name = "X-API-Key"
 	}
-	return &apiKeyProvider{
+	p := &apiKeyProvider{
 		key:      opts.Key,
 		location: location,
 		name:     name,
 	}
+	if opts.SecondaryKey != "" {
+		return &dualKeyProvider{apiKeyProvider: p, secondary: opts.SecondaryKey}
+	}
+	return p
 }
 
 type apiKeyProvider struct {
@@ -38,7 +47,11 @@ type apiKeyProvider struct {
 }
 
 func (p *apiKeyProvider) Apply(req *http.Request) error {
-	if p.key == "" {
+	return p.apply(req, p.key)
+}
+
+func (p *apiKeyProvider) apply(req *http.Request, key string) error {
+	if key == "" {
 		return fmt.Errorf("api key is empty")
 	}
 
@@ -49,10 +62,10 @@ func (p *apiKeyProvider) Apply(req *http.Request) error {
 			return fmt.Errorf("request URL is nil")
 		}
 		query := u.Query()
-		query.Set(p.name, p.key)
+		query.Set(p.name, key)
 		u.RawQuery = query.Encode()
 	case "header", "":
-		req.Header.Set(p.name, p.key)
+		req.Header.Set(p.name, key)
 	default:
 		return fmt.Errorf("unsupported api key location: %s", p.location)
 	}
@@ -68,3 +81,13 @@ func (p *apiKeyProvider) Name() string {
 		return fmt.Sprintf("api-key-header:%s", p.name)
 	}
 }
+
+// dualKeyProvider is an apiKeyProvider with a secondary key for rotation.
+type dualKeyProvider struct {
+	*apiKeyProvider
+	secondary string
+}
+
+func (p *dualKeyProvider) ApplySecondary(req *http.Request) error {
+	return p.apply(req, p.secondary)
+}
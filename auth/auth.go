@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"net/http"
 )
 
@@ -15,3 +16,29 @@ type ProviderFunc func(req *http.Request) error
 
 func (f ProviderFunc) Apply(req *http.Request) error { return f(req) }
 func (f ProviderFunc) Name() string                  { return "provider-func" }
+
+// ContextProvider is implemented by providers that need the caller's context,
+// e.g. to fetch or refresh a token within the request deadline and carrying
+// its trace. The client calls ApplyContext instead of Apply on such
+// providers.
+type ContextProvider interface {
+	AuthProvider
+	ApplyContext(ctx context.Context, req *http.Request) error
+}
+
+// ContextProviderFunc adapts a context-aware function into a ContextProvider.
+type ContextProviderFunc func(ctx context.Context, req *http.Request) error
+
+func (f ContextProviderFunc) ApplyContext(ctx context.Context, req *http.Request) error {
+	return f(ctx, req)
+}
+func (f ContextProviderFunc) Apply(req *http.Request) error { return f(req.Context(), req) }
+func (f ContextProviderFunc) Name() string                  { return "context-provider-func" }
+
+// Apply authenticates req with p, passing ctx to a ContextProvider.
+func Apply(ctx context.Context, p AuthProvider, req *http.Request) error {
+	if cp, ok := p.(ContextProvider); ok {
+		return cp.ApplyContext(ctx, req)
+	}
+	return p.Apply(req)
+}
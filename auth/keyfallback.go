@@ -0,0 +1,50 @@
+package auth
+
+import "net/http"
+
+// SecondaryApplier is implemented by providers holding a second credential
+// that stays valid while the first is rotated, such as an API key provider
+// with a SecondaryKey.
+type SecondaryApplier interface {
+	// ApplySecondary authenticates req with the secondary credential in
+	// place of the primary one.
+	ApplySecondary(req *http.Request) error
+}
+
+// NewKeyFallbackMiddleware resends a request rejected with 401 Unauthorized
+// or 403 Forbidden once, authenticated with the secondary credential of the
+// provider recorded with WithProvider, so a key rotation window causes no
+// failed calls. onFallback, when set, is called with every resent request
+// and its response or error, e.g. to warn that the primary key is no longer
+// accepted. Each request tries the primary credential first. Requests whose
+// body cannot be replayed return the rejection unchanged.
+func NewKeyFallbackMiddleware(onFallback func(req *http.Request, resp *http.Response, err error)) func(http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if err != nil || (resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden) {
+				return resp, err
+			}
+			sa, ok := ProviderFromContext(req.Context()).(SecondaryApplier)
+			if !ok || !replayable(req) {
+				return resp, nil
+			}
+			retry, ok := cloneForReplay(req)
+			if !ok {
+				return resp, nil
+			}
+			if aerr := sa.ApplySecondary(retry); aerr != nil {
+				if retry.Body != nil {
+					_ = retry.Body.Close()
+				}
+				return resp, nil
+			}
+			discard(resp)
+			resp, err = next.RoundTrip(retry)
+			if onFallback != nil {
+				onFallback(retry, resp, err)
+			}
+			return resp, err
+		})
+	}
+}
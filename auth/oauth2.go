@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gostratum/httpc/clock"
+)
+
+// OAuth2Options configures the OAuth2 client credentials provider.
+type OAuth2Options struct {
+	// TokenURL is the authorization server's token endpoint.
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	// Scopes are requested space-separated in the scope parameter.
+	Scopes []string
+	// EndpointParams are extra form parameters for the token request, such
+	// as audience or resource.
+	EndpointParams url.Values
+	// CredentialsInBody sends the client credentials as form parameters
+	// instead of HTTP Basic auth, for servers that support only that
+	// (RFC 6749 section 2.3.1).
+	CredentialsInBody bool
+	// HTTPClient sends the token requests. Defaults to http.DefaultClient;
+	// it must not be a client that authenticates with this provider.
+	HTTPClient *http.Client
+	// RefreshBefore, RetryInterval and FetchTimeout are as in TokenOptions.
+	RefreshBefore time.Duration
+	RetryInterval time.Duration
+	FetchTimeout  time.Duration
+	// Clock drives expiry and the refresh schedule; defaults to the real clock.
+	Clock clock.Clock
+}
+
+// OAuth2Error is the error response of a token endpoint (RFC 6749 section
+// 5.2), or its status when the body is not one.
+type OAuth2Error struct {
+	StatusCode  int
+	Code        string
+	Description string
+}
+
+func (e *OAuth2Error) Error() string {
+	switch {
+	case e.Code != "" && e.Description != "":
+		return fmt.Sprintf("oauth2 token request failed: %s: %s", e.Code, e.Description)
+	case e.Code != "":
+		return "oauth2 token request failed: " + e.Code
+	}
+	return fmt.Sprintf("oauth2 token request failed: status %d", e.StatusCode)
+}
+
+// ErrOAuth2Config is returned by NewOAuth2ClientCredentials without a token
+// URL or client ID.
+var ErrOAuth2Config = errors.New("oauth2 client credentials require token url and client id")
+
+// NewOAuth2ClientCredentials constructs a bearer provider obtaining tokens
+// with the OAuth2 client credentials grant. Tokens are cached until shortly
+// before expires_in runs out, and concurrent requests share one token
+// request, as with NewToken; the provider also implements Refresher.
+func NewOAuth2ClientCredentials(opts OAuth2Options) (AuthProvider, error) {
+	if opts.TokenURL == "" || opts.ClientID == "" {
+		return nil, ErrOAuth2Config
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	src := &clientCredentials{opts: opts, clk: clock.OrReal(opts.Clock)}
+	p, err := NewToken(TokenOptions{
+		Source:        src,
+		RefreshBefore: opts.RefreshBefore,
+		RetryInterval: opts.RetryInterval,
+		FetchTimeout:  opts.FetchTimeout,
+		Clock:         opts.Clock,
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.(*tokenProvider).name = "oauth2"
+	return p, nil
+}
+
+type clientCredentials struct {
+	opts OAuth2Options
+	clk  clock.Clock
+}
+
+func (s *clientCredentials) Token(ctx context.Context) (Token, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(s.opts.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.opts.Scopes, " "))
+	}
+	for key, values := range s.opts.EndpointParams {
+		form[key] = values
+	}
+	if s.opts.CredentialsInBody {
+		form.Set("client_id", s.opts.ClientID)
+		form.Set("client_secret", s.opts.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.opts.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Token{}, fmt.Errorf("build oauth2 token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if !s.opts.CredentialsInBody {
+		// RFC 6749 section 2.3.1 form-encodes both before Basic encoding.
+		req.SetBasicAuth(url.QueryEscape(s.opts.ClientID), url.QueryEscape(s.opts.ClientSecret))
+	}
+
+	issued := s.clk.Now()
+	resp, err := s.opts.HTTPClient.Do(req)
+	if err != nil {
+		return Token{}, fmt.Errorf("oauth2 token request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Token{}, fmt.Errorf("read oauth2 token response: %w", err)
+	}
+
+	var payload struct {
+		AccessToken      string      `json:"access_token"`
+		TokenType        string      `json:"token_type"`
+		ExpiresIn        json.Number `json:"expires_in"`
+		Error            string      `json:"error"`
+		ErrorDescription string      `json:"error_description"`
+	}
+	decodeErr := json.Unmarshal(body, &payload)
+	if resp.StatusCode != http.StatusOK || payload.Error != "" {
+		return Token{}, &OAuth2Error{
+			StatusCode:  resp.StatusCode,
+			Code:        payload.Error,
+			Description: payload.ErrorDescription,
+		}
+	}
+	if decodeErr != nil {
+		return Token{}, fmt.Errorf("decode oauth2 token response: %w", decodeErr)
+	}
+
+	tok := Token{AccessToken: payload.AccessToken, TokenType: payload.TokenType}
+	if strings.EqualFold(tok.TokenType, "bearer") {
+		// Servers send "bearer" as often as "Bearer"; normalise the header.
+		tok.TokenType = "Bearer"
+	}
+	// expires_in is counted from when the request was sent, so network time
+	// shortens rather than extends the cached lifetime.
+	if secs, err := payload.ExpiresIn.Int64(); err == nil && secs > 0 {
+		tok.Expiry = issued.Add(time.Duration(secs) * time.Second)
+	}
+	return tok, nil
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// Invalidator is implemented by providers whose cached credentials can be
+// dropped once the server rejects them, so the next Apply fetches fresh ones.
+type Invalidator interface {
+	// Invalidate discards the credentials applied to req if they are still
+	// the cached ones; credentials already replaced by a concurrent refresh
+	// are kept.
+	Invalidate(req *http.Request)
+}
+
+type providerKey struct{}
+
+// WithProvider records the provider that authenticated the request carrying
+// ctx, for NewRefreshMiddleware.
+func WithProvider(ctx context.Context, p AuthProvider) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, providerKey{}, p)
+}
+
+// ProviderFromContext returns the provider set with WithProvider, if any.
+func ProviderFromContext(ctx context.Context) AuthProvider {
+	if ctx == nil {
+		return nil
+	}
+	p, _ := ctx.Value(providerKey{}).(AuthProvider)
+	return p
+}
+
+// NewRefreshMiddleware retries a request once after a 401 Unauthorized when
+// the provider recorded with WithProvider implements Invalidator: the
+// rejected credentials are invalidated, the request is authenticated again
+// (fetching a new token) and resent. Requests whose body cannot be replayed
+// return the 401 unchanged.
+func NewRefreshMiddleware() func(http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if err != nil || resp.StatusCode != http.StatusUnauthorized {
+				return resp, err
+			}
+			p := ProviderFromContext(req.Context())
+			inv, ok := p.(Invalidator)
+			if !ok || !replayable(req) {
+				return resp, nil
+			}
+
+			inv.Invalidate(req)
+			retry, ok := cloneForReplay(req)
+			if !ok {
+				return resp, nil
+			}
+			if aerr := Apply(req.Context(), p, retry); aerr != nil {
+				if retry.Body != nil {
+					_ = retry.Body.Close()
+				}
+				return resp, nil
+			}
+			discard(resp)
+			return next.RoundTrip(retry)
+		})
+	}
+}
+
+// replayable reports whether req can be sent again.
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// cloneForReplay copies req with a fresh body from GetBody.
+func cloneForReplay(req *http.Request) (*http.Request, bool) {
+	retry := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, false
+		}
+		retry.Body = body
+	}
+	return retry, true
+}
+
+// discard drains a little of a response that is being replaced, so its
+// connection can be reused, and closes it.
+func discard(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+	_ = resp.Body.Close()
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,261 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gostratum/httpc/clock"
+)
+
+// Token is an access token issued by a TokenSource.
+type Token struct {
+	AccessToken string
+	// TokenType prefixes the Authorization header value; defaults to Bearer.
+	TokenType string
+	// Expiry is when the token stops being valid; zero never expires.
+	Expiry time.Time
+}
+
+// validAt reports whether t can still be used at now, leaving leeway for
+// clock skew and transit time.
+func (t Token) validAt(now time.Time, leeway time.Duration) bool {
+	if t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || now.Add(leeway).Before(t.Expiry)
+}
+
+func (t Token) header() string {
+	typ := t.TokenType
+	if typ == "" {
+		typ = "Bearer"
+	}
+	return typ + " " + t.AccessToken
+}
+
+// TokenSource fetches access tokens, e.g. from an OAuth2 token endpoint.
+type TokenSource interface {
+	Token(ctx context.Context) (Token, error)
+}
+
+// TokenSourceFunc adapts a function into a TokenSource.
+type TokenSourceFunc func(ctx context.Context) (Token, error)
+
+func (f TokenSourceFunc) Token(ctx context.Context) (Token, error) { return f(ctx) }
+
+// Refresher is implemented by providers that can renew credentials in the
+// background ahead of expiry, so request latency never includes a token
+// round trip. Its methods match fx.Hook so they can be registered on an
+// application lifecycle.
+type Refresher interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// TokenOptions configures the token provider.
+type TokenOptions struct {
+	Source TokenSource
+	// RefreshBefore is how long before expiry the background refresher renews
+	// a token. Requests renew it inline once within half that margin, which
+	// only happens when the refresher is not running or keeps failing.
+	// Defaults to 30s.
+	RefreshBefore time.Duration
+	// RetryInterval spaces background refresh attempts after a failure.
+	// Defaults to 5s.
+	RetryInterval time.Duration
+	// FetchTimeout bounds background fetches. Defaults to 30s.
+	FetchTimeout time.Duration
+	// Clock drives expiry and the refresh schedule; defaults to the real clock.
+	Clock clock.Clock
+}
+
+// ErrNoTokenSource is returned by NewToken without a Source.
+var ErrNoTokenSource = errors.New("token provider requires a source")
+
+// NewToken constructs a bearer provider that caches the tokens of
+// opts.Source. Tokens are fetched on first use with the caller's context and
+// renewed inline shortly before expiry. The returned provider also implements
+// Refresher: once started it prefetches a token and renews it ahead of expiry
+// in the background.
+func NewToken(opts TokenOptions) (AuthProvider, error) {
+	if opts.Source == nil {
+		return nil, ErrNoTokenSource
+	}
+	if opts.RefreshBefore <= 0 {
+		opts.RefreshBefore = 30 * time.Second
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = 5 * time.Second
+	}
+	if opts.FetchTimeout <= 0 {
+		opts.FetchTimeout = 30 * time.Second
+	}
+	return &tokenProvider{name: "token", opts: opts, clk: clock.OrReal(opts.Clock)}, nil
+}
+
+type tokenProvider struct {
+	name string
+	opts TokenOptions
+	clk  clock.Clock
+
+	// fetchMu serialises fetches so concurrent requests share one round trip.
+	fetchMu sync.Mutex
+
+	mu    sync.Mutex
+	token Token
+	stop  context.CancelFunc
+	done  chan struct{}
+}
+
+func (p *tokenProvider) Name() string { return p.name }
+
+func (p *tokenProvider) Apply(req *http.Request) error {
+	return p.ApplyContext(req.Context(), req)
+}
+
+func (p *tokenProvider) ApplyContext(ctx context.Context, req *http.Request) error {
+	tok, err := p.get(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", tok.header())
+	return nil
+}
+
+// Invalidate drops the cached token when it is the one req carries.
+func (p *tokenProvider) Invalidate(req *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token.AccessToken != "" && req.Header.Get("Authorization") == p.token.header() {
+		p.token = Token{}
+	}
+}
+
+func (p *tokenProvider) cached() Token {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.token
+}
+
+// get returns the cached token, fetching a new one when it is missing or
+// about to expire.
+func (p *tokenProvider) get(ctx context.Context) (Token, error) {
+	leeway := p.opts.RefreshBefore / 2
+	if tok := p.cached(); tok.validAt(p.clk.Now(), leeway) {
+		return tok, nil
+	}
+	p.fetchMu.Lock()
+	defer p.fetchMu.Unlock()
+	if tok := p.cached(); tok.validAt(p.clk.Now(), leeway) {
+		return tok, nil
+	}
+	return p.fetch(ctx)
+}
+
+// fetch must be called with fetchMu held.
+func (p *tokenProvider) fetch(ctx context.Context) (Token, error) {
+	tok, err := p.opts.Source.Token(ctx)
+	if err != nil {
+		return Token{}, err
+	}
+	if tok.AccessToken == "" {
+		return Token{}, errors.New("token source returned an empty token")
+	}
+	p.mu.Lock()
+	p.token = tok
+	p.mu.Unlock()
+	return tok, nil
+}
+
+// Start launches the background refresher. Calling it again while running
+// is a no-op.
+func (p *tokenProvider) Start(context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop != nil {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p.stop = cancel
+	p.done = make(chan struct{})
+	go p.refreshLoop(ctx, p.done)
+	return nil
+}
+
+// Stop halts the background refresher and waits for it to exit or ctx to
+// end.
+func (p *tokenProvider) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	stop, done := p.stop, p.done
+	p.stop, p.done = nil, nil
+	p.mu.Unlock()
+	if stop == nil {
+		return nil
+	}
+	stop()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *tokenProvider) refreshLoop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	var minWait time.Duration
+	for {
+		// minWait stops tokens living shorter than RefreshBefore from being
+		// refetched in a tight loop.
+		wait := max(p.untilRefresh(), minWait)
+		if wait > 0 {
+			timer := p.clk.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C():
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		fetchCtx, cancel := context.WithTimeout(ctx, p.opts.FetchTimeout)
+		p.fetchMu.Lock()
+		_, err := p.fetch(fetchCtx)
+		p.fetchMu.Unlock()
+		cancel()
+		if err == nil {
+			minWait = p.opts.RetryInterval
+			continue
+		}
+
+		// Keep serving the current token; requests fall back to an inline
+		// fetch once it expires.
+		timer := p.clk.NewTimer(p.opts.RetryInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+	}
+}
+
+// untilRefresh is how long the refresher can sleep before renewing the
+// cached token. Tokens without expiry are not renewed.
+func (p *tokenProvider) untilRefresh() time.Duration {
+	tok := p.cached()
+	if tok.AccessToken == "" {
+		return 0
+	}
+	if tok.Expiry.IsZero() {
+		return math.MaxInt64
+	}
+	return tok.Expiry.Add(-p.opts.RefreshBefore).Sub(p.clk.Now())
+}
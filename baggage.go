@@ -0,0 +1,75 @@
+package httpc
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+type baggageKey struct{}
+
+// WithBaggage attaches request-scoped metadata such as a tenant ID, user ID
+// or feature flag to ctx, typically in inbound middleware. Nothing is sent
+// by default: only keys listed in Config.ForwardBaggage become headers on
+// outbound requests, so internal values cannot leak to third parties.
+func WithBaggage(ctx context.Context, key, value string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	bag := maps.Clone(baggageMap(ctx))
+	if bag == nil {
+		bag = make(map[string]string, 1)
+	}
+	bag[key] = value
+	return context.WithValue(ctx, baggageKey{}, bag)
+}
+
+// BaggageFromContext returns the value attached to ctx with WithBaggage.
+func BaggageFromContext(ctx context.Context, key string) (string, bool) {
+	v, ok := baggageMap(ctx)[key]
+	return v, ok
+}
+
+func baggageMap(ctx context.Context) map[string]string {
+	if ctx == nil {
+		return nil
+	}
+	bag, _ := ctx.Value(baggageKey{}).(map[string]string)
+	return bag
+}
+
+// BaggageLookup resolves a baggage key from ctx, for values that other
+// middleware (e.g. the service framework's request context) stores under
+// its own context keys.
+type BaggageLookup func(ctx context.Context, key string) (string, bool)
+
+// applyBaggage sets the header of every allowlisted baggage key found in
+// ctx, leaving headers the request already sets alone.
+func applyBaggage(ctx context.Context, req *http.Request, cfg Config) {
+	if len(cfg.ForwardBaggage) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(cfg.ForwardBaggage))
+	for key := range cfg.ForwardBaggage {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		header := cfg.ForwardBaggage[key]
+		if header == "" || req.Header.Get(header) != "" {
+			continue
+		}
+		value, ok := BaggageFromContext(ctx, key)
+		if !ok && cfg.BaggageLookup != nil {
+			value, ok = cfg.BaggageLookup(ctx, key)
+		}
+		// A value that would split the header is dropped rather than
+		// failing the request.
+		if !ok || value == "" || strings.ContainsAny(value, "\r\n") {
+			continue
+		}
+		req.Header.Set(header, value)
+	}
+}
@@ -6,25 +6,81 @@ import (
 	"sync"
 	"time"
 
+	"github.com/gostratum/httpc/clock"
 	"github.com/sony/gobreaker"
 )
 
-// Manager manages host-scoped circuit breakers.
+// Manager manages circuit breakers keyed by request host, or by the key set
+// with WithKey.
 type Manager interface {
 	Do(host string, fn func() (*http.Response, error)) (*http.Response, error)
 }
 
+// Strategy selects how a breaker decides to trip.
+type Strategy string
+
+const (
+	// StrategyConsecutive trips after a run of consecutive failures
+	// (gobreaker). This is the default.
+	StrategyConsecutive Strategy = "consecutive"
+	// StrategySlidingWindow trips when the failure rate or slow-call rate over
+	// a time-based window exceeds its threshold.
+	StrategySlidingWindow Strategy = "sliding_window"
+)
+
 // Config controls breaker behaviour.
 type Config struct {
 	Name          string
+	Strategy      Strategy
 	Interval      time.Duration
 	Timeout       time.Duration
 	ReadyToTrip   func(counts gobreaker.Counts) bool
 	OnStateChange func(name string, from gobreaker.State, to gobreaker.State)
+	// Clock drives the interval, open timeout and sliding window of both
+	// strategies; defaults to the real clock.
+	Clock clock.Clock
+
+	// Sliding-window strategy settings. Zero values fall back to a 60s window,
+	// 20 minimum calls, a 50% failure rate and 3 half-open probe calls; the
+	// slow-call check is disabled unless SlowCallDuration is set.
+	WindowSize            time.Duration
+	MinimumCalls          int
+	FailureRateThreshold  float64
+	SlowCallDuration      time.Duration
+	SlowCallRateThreshold float64
+	HalfOpenMaxCalls      int
+}
+
+// StateReporter is implemented by managers that can report the state of
+// each breaker they have created, keyed by breaker key (host or WithKey
+// label).
+type StateReporter interface {
+	States() map[string]gobreaker.State
+}
+
+type executor interface {
+	Execute(fn func() (any, error)) (any, error)
+	State() gobreaker.State
 }
 
 // NewManager returns a default breaker manager keyed by host.
 func NewManager(cfg Config) Manager {
+	cfg.Timeout = defaultDuration(cfg.Timeout, 30*time.Second)
+	if cfg.Strategy == StrategySlidingWindow {
+		cfg.WindowSize = defaultDuration(cfg.WindowSize, time.Minute)
+		if cfg.MinimumCalls <= 0 {
+			cfg.MinimumCalls = 20
+		}
+		if cfg.FailureRateThreshold <= 0 {
+			cfg.FailureRateThreshold = 0.5
+		}
+		if cfg.SlowCallDuration > 0 && cfg.SlowCallRateThreshold <= 0 {
+			cfg.SlowCallRateThreshold = 1
+		}
+		if cfg.HalfOpenMaxCalls <= 0 {
+			cfg.HalfOpenMaxCalls = 3
+		}
+	}
 	return &manager{
 		config:   cfg,
 		breakers: sync.Map{},
@@ -52,33 +108,29 @@ func (m *manager) Do(host string, fn func() (*http.Response, error)) (*http.Resp
 	return resp, nil
 }
 
-func (m *manager) get(host string) *gobreaker.CircuitBreaker {
+// States implements StateReporter.
+func (m *manager) States() map[string]gobreaker.State {
+	states := make(map[string]gobreaker.State)
+	m.breakers.Range(func(key, value any) bool {
+		states[key.(string)] = value.(executor).State()
+		return true
+	})
+	return states
+}
+
+func (m *manager) get(host string) executor {
 	if cb, ok := m.breakers.Load(host); ok {
-		return cb.(*gobreaker.CircuitBreaker)
+		return cb.(executor)
 	}
 
-	settings := gobreaker.Settings{
-		Name:        host,
-		MaxRequests: 1,
-		Interval:    m.config.Interval,
-		Timeout:     defaultDuration(m.config.Timeout, 30*time.Second),
-		ReadyToTrip: m.config.ReadyToTrip,
-		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
-			if m.config.OnStateChange != nil {
-				m.config.OnStateChange(name, from, to)
-			}
-		},
+	var cb executor
+	if m.config.Strategy == StrategySlidingWindow {
+		cb = newSlidingWindow(host, m.config)
+	} else {
+		cb = newConsecutive(host, m.config)
 	}
-	if settings.ReadyToTrip == nil {
-		settings.ReadyToTrip = func(counts gobreaker.Counts) bool {
-			// Trip after 5 consecutive failures.
-			return counts.ConsecutiveFailures >= 5
-		}
-	}
-
-	cb := gobreaker.NewCircuitBreaker(settings)
 	actual, _ := m.breakers.LoadOrStore(host, cb)
-	return actual.(*gobreaker.CircuitBreaker)
+	return actual.(executor)
 }
 
 func defaultDuration(value, fallback time.Duration) time.Duration {
@@ -106,18 +158,37 @@ func Enabled(ctx context.Context, defaultEnabled bool) bool {
 	return defaultEnabled
 }
 
-// NewMiddleware wraps a transport with breaker protection.
+type keyKey struct{}
+
+// WithKey isolates the request in the breaker named key instead of the one of
+// its host, so e.g. critical payment calls and bulk exports against the same
+// host trip independently, or several hosts behind one dependency share one.
+func WithKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyKey{}, key)
+}
+
+// KeyFromContext returns the key set with WithKey, or "".
+func KeyFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	key, _ := ctx.Value(keyKey{}).(string)
+	return key
+}
+
+// NewMiddleware wraps a transport with breaker protection. Requests use the
+// breaker of their WithKey key when set, otherwise that of their host.
 func NewMiddleware(m Manager) func(http.RoundTripper) http.RoundTripper {
 	return func(next http.RoundTripper) http.RoundTripper {
 		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
 			if !Enabled(req.Context(), true) {
 				return next.RoundTrip(req)
 			}
-			host := ""
-			if req.URL != nil {
-				host = req.URL.Host
+			key := KeyFromContext(req.Context())
+			if key == "" && req.URL != nil {
+				key = req.URL.Host
 			}
-			return m.Do(host, func() (*http.Response, error) {
+			return m.Do(key, func() (*http.Response, error) {
 				return next.RoundTrip(req)
 			})
 		})
@@ -0,0 +1,183 @@
+package breaker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gostratum/httpc/clock"
+	"github.com/sony/gobreaker"
+)
+
+// consecutive is gobreaker's consecutive-failure breaker driven by the
+// configured clock: closed counts reset every Interval, ReadyToTrip opens
+// the breaker, and after Timeout a single half-open probe decides whether it
+// closes again. Results of calls started in an earlier state are ignored.
+type consecutive struct {
+	name        string
+	cfg         Config
+	clk         clock.Clock
+	readyToTrip func(gobreaker.Counts) bool
+
+	mu         sync.Mutex
+	state      gobreaker.State
+	generation uint64
+	counts     gobreaker.Counts
+	expiry     time.Time
+
+	// changes queues state transitions for OnStateChange, which runs after
+	// mu is released so it may call back into the breaker.
+	changes []stateChange
+}
+
+// consecutiveMaxRequests is the number of half-open probe calls.
+const consecutiveMaxRequests = 1
+
+func newConsecutive(name string, cfg Config) *consecutive {
+	c := &consecutive{
+		name:        name,
+		cfg:         cfg,
+		clk:         clock.OrReal(cfg.Clock),
+		readyToTrip: cfg.ReadyToTrip,
+		state:       gobreaker.StateClosed,
+	}
+	if c.readyToTrip == nil {
+		c.readyToTrip = func(counts gobreaker.Counts) bool {
+			// Trip after 5 consecutive failures.
+			return counts.ConsecutiveFailures >= 5
+		}
+	}
+	c.newGeneration(c.clk.Now())
+	return c
+}
+
+// Execute mirrors gobreaker.CircuitBreaker.Execute.
+func (c *consecutive) Execute(fn func() (any, error)) (any, error) {
+	generation, err := c.before()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			c.after(generation, false)
+			panic(r)
+		}
+	}()
+
+	result, err := fn()
+	c.after(generation, err == nil)
+	return result, err
+}
+
+// State reports the current state, moving an expired open breaker to
+// half-open.
+func (c *consecutive) State() gobreaker.State {
+	c.mu.Lock()
+	defer c.unlock()
+	state, _ := c.current(c.clk.Now())
+	return state
+}
+
+func (c *consecutive) before() (uint64, error) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	state, generation := c.current(c.clk.Now())
+	switch {
+	case state == gobreaker.StateOpen:
+		return generation, gobreaker.ErrOpenState
+	case state == gobreaker.StateHalfOpen && c.counts.Requests >= consecutiveMaxRequests:
+		return generation, gobreaker.ErrTooManyRequests
+	}
+	c.counts.Requests++
+	return generation, nil
+}
+
+func (c *consecutive) after(before uint64, success bool) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	now := c.clk.Now()
+	state, generation := c.current(now)
+	if generation != before {
+		return
+	}
+
+	if success {
+		c.counts.TotalSuccesses++
+		c.counts.ConsecutiveSuccesses++
+		c.counts.ConsecutiveFailures = 0
+		if state == gobreaker.StateHalfOpen && c.counts.ConsecutiveSuccesses >= consecutiveMaxRequests {
+			c.setState(gobreaker.StateClosed, now)
+		}
+		return
+	}
+
+	c.counts.TotalFailures++
+	c.counts.ConsecutiveFailures++
+	c.counts.ConsecutiveSuccesses = 0
+	switch state {
+	case gobreaker.StateClosed:
+		if c.readyToTrip(c.counts) {
+			c.setState(gobreaker.StateOpen, now)
+		}
+	case gobreaker.StateHalfOpen:
+		c.setState(gobreaker.StateOpen, now)
+	}
+}
+
+// current must be called with c.mu held.
+func (c *consecutive) current(now time.Time) (gobreaker.State, uint64) {
+	switch c.state {
+	case gobreaker.StateClosed:
+		if !c.expiry.IsZero() && !now.Before(c.expiry) {
+			c.newGeneration(now)
+		}
+	case gobreaker.StateOpen:
+		if !now.Before(c.expiry) {
+			c.setState(gobreaker.StateHalfOpen, now)
+		}
+	}
+	return c.state, c.generation
+}
+
+// setState must be called with c.mu held.
+func (c *consecutive) setState(to gobreaker.State, now time.Time) {
+	from := c.state
+	if from == to {
+		return
+	}
+	c.state = to
+	c.newGeneration(now)
+	if c.cfg.OnStateChange != nil {
+		c.changes = append(c.changes, stateChange{from, to})
+	}
+}
+
+// newGeneration must be called with c.mu held.
+func (c *consecutive) newGeneration(now time.Time) {
+	c.generation++
+	c.counts = gobreaker.Counts{}
+	switch c.state {
+	case gobreaker.StateClosed:
+		c.expiry = time.Time{}
+		if c.cfg.Interval > 0 {
+			c.expiry = now.Add(c.cfg.Interval)
+		}
+	case gobreaker.StateOpen:
+		c.expiry = now.Add(c.cfg.Timeout)
+	default:
+		c.expiry = time.Time{}
+	}
+}
+
+// unlock releases c.mu, then reports the state changes made while it was
+// held.
+func (c *consecutive) unlock() {
+	changes := c.changes
+	c.changes = nil
+	c.mu.Unlock()
+	for _, ch := range changes {
+		c.cfg.OnStateChange(c.name, ch.from, ch.to)
+	}
+}
@@ -0,0 +1,214 @@
+package breaker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gostratum/httpc/clock"
+	"github.com/sony/gobreaker"
+)
+
+const windowBuckets = 10
+
+// slidingWindow is a resilience4j-style breaker that trips on the failure
+// rate or slow-call rate observed over a time-based window, rather than on a
+// run of consecutive failures.
+type slidingWindow struct {
+	name string
+	cfg  Config
+	clk  clock.Clock
+
+	mu         sync.Mutex
+	state      gobreaker.State
+	generation uint64
+	openUntil  time.Time
+	buckets    [windowBuckets]bucket
+	width      time.Duration
+
+	halfOpenInFlight  int
+	halfOpenSucceeded int
+
+	// changes queues state transitions for OnStateChange, which runs after
+	// w.mu is released so it may call back into the breaker.
+	changes []stateChange
+}
+
+type stateChange struct{ from, to gobreaker.State }
+
+type bucket struct {
+	start    time.Time
+	calls    int
+	failures int
+	slow     int
+}
+
+func newSlidingWindow(name string, cfg Config) *slidingWindow {
+	return &slidingWindow{
+		name:  name,
+		cfg:   cfg,
+		clk:   clock.OrReal(cfg.Clock),
+		state: gobreaker.StateClosed,
+		// At least 1ns, so bucketAt never divides by zero on tiny windows.
+		width: max(cfg.WindowSize/windowBuckets, time.Nanosecond),
+	}
+}
+
+// Execute mirrors gobreaker.CircuitBreaker.Execute.
+func (w *slidingWindow) Execute(fn func() (any, error)) (any, error) {
+	generation, err := w.before()
+	if err != nil {
+		return nil, err
+	}
+
+	start := w.clk.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			w.after(generation, true, w.clk.Now().Sub(start))
+			panic(r)
+		}
+	}()
+
+	result, err := fn()
+	w.after(generation, err != nil, w.clk.Now().Sub(start))
+	return result, err
+}
+
+// State reports the current state, moving an expired open breaker to
+// half-open like gobreaker does.
+func (w *slidingWindow) State() gobreaker.State {
+	w.mu.Lock()
+	defer w.unlock()
+	now := w.clk.Now()
+	if w.state == gobreaker.StateOpen && !now.Before(w.openUntil) {
+		w.setState(gobreaker.StateHalfOpen, now)
+	}
+	return w.state
+}
+
+// before admits a call and returns the generation it started in.
+func (w *slidingWindow) before() (uint64, error) {
+	w.mu.Lock()
+	defer w.unlock()
+
+	now := w.clk.Now()
+	if w.state == gobreaker.StateOpen {
+		if now.Before(w.openUntil) {
+			return w.generation, gobreaker.ErrOpenState
+		}
+		w.setState(gobreaker.StateHalfOpen, now)
+	}
+
+	if w.state == gobreaker.StateHalfOpen {
+		if w.halfOpenInFlight+w.halfOpenSucceeded >= w.cfg.HalfOpenMaxCalls {
+			return w.generation, gobreaker.ErrTooManyRequests
+		}
+		w.halfOpenInFlight++
+	}
+	return w.generation, nil
+}
+
+// after records a call's outcome; results of calls started before the
+// last state change are ignored.
+func (w *slidingWindow) after(before uint64, failed bool, elapsed time.Duration) {
+	w.mu.Lock()
+	defer w.unlock()
+
+	if before != w.generation {
+		return
+	}
+	now := w.clk.Now()
+	slow := w.cfg.SlowCallDuration > 0 && elapsed >= w.cfg.SlowCallDuration
+
+	if w.state == gobreaker.StateHalfOpen {
+		w.halfOpenInFlight--
+		if failed || slow {
+			w.setState(gobreaker.StateOpen, now)
+			return
+		}
+		w.halfOpenSucceeded++
+		if w.halfOpenSucceeded >= w.cfg.HalfOpenMaxCalls {
+			w.setState(gobreaker.StateClosed, now)
+		}
+		return
+	}
+
+	if w.state != gobreaker.StateClosed {
+		return
+	}
+
+	b := w.bucketAt(now)
+	b.calls++
+	if failed {
+		b.failures++
+	}
+	if slow {
+		b.slow++
+	}
+
+	calls, failures, slowCalls := w.totals(now)
+	if calls < w.cfg.MinimumCalls {
+		return
+	}
+	failureRate := float64(failures) / float64(calls)
+	slowRate := float64(slowCalls) / float64(calls)
+	if (w.cfg.FailureRateThreshold > 0 && failureRate >= w.cfg.FailureRateThreshold) ||
+		(w.cfg.SlowCallRateThreshold > 0 && slowRate >= w.cfg.SlowCallRateThreshold) {
+		w.setState(gobreaker.StateOpen, now)
+	}
+}
+
+func (w *slidingWindow) bucketAt(now time.Time) *bucket {
+	start := now.Truncate(w.width)
+	idx := int((start.UnixNano() / int64(w.width)) % windowBuckets)
+	b := &w.buckets[idx]
+	if !b.start.Equal(start) {
+		*b = bucket{start: start}
+	}
+	return b
+}
+
+func (w *slidingWindow) totals(now time.Time) (calls, failures, slow int) {
+	for _, b := range w.buckets {
+		if b.calls == 0 || now.Sub(b.start) >= w.cfg.WindowSize {
+			continue
+		}
+		calls += b.calls
+		failures += b.failures
+		slow += b.slow
+	}
+	return calls, failures, slow
+}
+
+// setState must be called with w.mu held.
+func (w *slidingWindow) setState(to gobreaker.State, now time.Time) {
+	from := w.state
+	if from == to {
+		return
+	}
+	w.state = to
+	w.generation++
+	w.halfOpenInFlight = 0
+	w.halfOpenSucceeded = 0
+
+	switch to {
+	case gobreaker.StateOpen:
+		w.openUntil = now.Add(w.cfg.Timeout)
+	case gobreaker.StateClosed:
+		w.buckets = [windowBuckets]bucket{}
+	}
+
+	if w.cfg.OnStateChange != nil {
+		w.changes = append(w.changes, stateChange{from, to})
+	}
+}
+
+// unlock releases w.mu, then reports the state changes made while it was
+// held.
+func (w *slidingWindow) unlock() {
+	changes := w.changes
+	w.changes = nil
+	w.mu.Unlock()
+	for _, c := range changes {
+		w.cfg.OnStateChange(w.name, c.from, c.to)
+	}
+}
@@ -0,0 +1,99 @@
+package httpc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync/atomic"
+)
+
+// ErrBufferLimitExceeded is returned by the buffering Response helpers (Bytes,
+// String, DecodeJSON) when loading the body would take the client over the
+// limit set with WithMaxBufferedBytes. IntoWriter streams instead.
+var ErrBufferLimitExceeded = errors.New("response buffer limit exceeded")
+
+const bufferChunk = 32 << 10
+
+// bufferBudget accounts the bytes held by buffered Response bodies of one
+// client.
+type bufferBudget struct {
+	limit int64
+	used  atomic.Int64
+}
+
+func newBufferBudget(limit int64) *bufferBudget {
+	if limit <= 0 {
+		return nil
+	}
+	return &bufferBudget{limit: limit}
+}
+
+func (b *bufferBudget) reserve(n int64) bool {
+	if b.used.Add(n) > b.limit {
+		b.used.Add(-n)
+		return false
+	}
+	return true
+}
+
+func (b *bufferBudget) release(n int64) {
+	if n > 0 {
+		b.used.Add(-n)
+	}
+}
+
+type reservation struct {
+	budget *bufferBudget
+	n      int64
+}
+
+// readBudgeted reads the body while reserving budget. On overflow the bytes
+// read so far are kept in r.pending and the body is left open so IntoWriter
+// can continue streaming; callers that do not stream must close Raw().Body.
+func (r *Response) readBudgeted() error {
+	var buf bytes.Buffer
+	chunk := make([]byte, bufferChunk)
+	for {
+		n, err := r.bodySource().Read(chunk)
+		if n > 0 {
+			if !r.budget.reserve(int64(n)) {
+				r.pending = append(buf.Bytes(), chunk[:n]...)
+				r.budget.release(r.reserved)
+				r.reserved = 0
+				return fmt.Errorf("%w: client limit is %d bytes", ErrBufferLimitExceeded, r.budget.limit)
+			}
+			r.reserved += int64(n)
+			buf.Write(chunk[:n])
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			r.budget.release(r.reserved)
+			r.reserved = 0
+			_ = r.bodySource().Close()
+			return err
+		}
+	}
+	_ = r.bodySource().Close()
+	r.body = buf.Bytes()
+	if r.reserved > 0 {
+		r.cleanup = runtime.AddCleanup(r, func(res reservation) { res.budget.release(res.n) }, reservation{r.budget, r.reserved})
+	}
+	return nil
+}
+
+// Release returns the memory accounted for the buffered body to the client's
+// WithMaxBufferedBytes budget and drops the body. It is only needed when the
+// limit is set; otherwise the accounting is settled when the Response is
+// garbage collected.
+func (r *Response) Release() {
+	if r.budget != nil && r.reserved > 0 {
+		r.cleanup.Stop()
+		r.budget.release(r.reserved)
+		r.reserved = 0
+	}
+	r.body = nil
+}
@@ -0,0 +1,154 @@
+package bulkhead
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gostratum/httpc/breaker"
+)
+
+// ErrBulkheadFull is returned when a dependency is at its concurrency limit
+// and its wait queue is full (or the queue wait timed out).
+var ErrBulkheadFull = errors.New("bulkhead full")
+
+// Config bounds concurrency per dependency: the breaker key set with
+// breaker.WithKey, or else the request host.
+type Config struct {
+	// MaxConcurrent is the number of in-flight requests allowed per
+	// dependency. Defaults to 10.
+	MaxConcurrent int
+	// MaxQueue is the number of requests allowed to wait for a slot. Zero
+	// fails fast as soon as all slots are taken.
+	MaxQueue int
+	// QueueTimeout bounds how long a queued request waits; zero waits until
+	// the request context is done.
+	QueueTimeout time.Duration
+}
+
+// NewMiddleware constructs a bulkhead middleware. A slot is held until the
+// response body is read to EOF or closed so slow body reads count against
+// the limit; responses without a body release it with the headers.
+func NewMiddleware(cfg Config) func(http.RoundTripper) http.RoundTripper {
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = 10
+	}
+	b := &bulkheads{cfg: cfg}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			key := breaker.KeyFromContext(req.Context())
+			if key == "" && req.URL != nil {
+				key = req.URL.Host
+			}
+			c := b.get(key)
+			if err := c.acquire(req, cfg); err != nil {
+				return nil, err
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil || resp == nil || resp.Body == nil || resp.Body == http.NoBody || resp.ContentLength == 0 {
+				c.release()
+				return resp, err
+			}
+			resp.Body = &releasingBody{ReadCloser: resp.Body, release: c.release}
+			return resp, nil
+		})
+	}
+}
+
+type bulkheads struct {
+	cfg  Config
+	mu   sync.Mutex
+	keys map[string]*compartment
+}
+
+func (b *bulkheads) get(key string) *compartment {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.keys == nil {
+		b.keys = make(map[string]*compartment)
+	}
+	c, ok := b.keys[key]
+	if !ok {
+		c = &compartment{slots: make(chan struct{}, b.cfg.MaxConcurrent)}
+		b.keys[key] = c
+	}
+	return c
+}
+
+type compartment struct {
+	slots chan struct{}
+
+	mu      sync.Mutex
+	waiting int
+}
+
+func (c *compartment) acquire(req *http.Request, cfg Config) error {
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	c.mu.Lock()
+	if c.waiting >= cfg.MaxQueue {
+		c.mu.Unlock()
+		return ErrBulkheadFull
+	}
+	c.waiting++
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.waiting--
+		c.mu.Unlock()
+	}()
+
+	var timeout <-chan time.Time
+	if cfg.QueueTimeout > 0 {
+		timer := time.NewTimer(cfg.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	case <-timeout:
+		return ErrBulkheadFull
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+func (c *compartment) release() {
+	<-c.slots
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (r *releasingBody) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err == io.EOF {
+		r.once.Do(r.release)
+	}
+	return n, err
+}
+
+func (r *releasingBody) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
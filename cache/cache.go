@@ -0,0 +1,375 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gostratum/httpc/clock"
+)
+
+// HeaderStatus is set to "HIT" on responses served from the cache.
+const HeaderStatus = "X-Cache"
+
+// Entry is a stored response.
+type Entry struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	StoredAt   time.Time
+	Expires    time.Time
+	// Vary lists the request headers named by the response's Vary header.
+	// An entry with Vary set is stored under its URL key as an index only;
+	// the variant for each combination of request header values is stored
+	// under a key extended with a hash of those values.
+	Vary []string
+}
+
+// Store persists cache entries. Implementations must be safe for concurrent
+// use.
+type Store interface {
+	Get(key string) (*Entry, bool)
+	Set(key string, e *Entry)
+	Delete(key string)
+}
+
+// Config controls the response cache. Only GET and HEAD responses with status
+// 200 are stored.
+type Config struct {
+	// TTL is the lifetime of entries when the response carries no max-age.
+	// Defaults to one minute.
+	TTL time.Duration
+	// MaxEntries bounds the default in-memory store. Defaults to 1000.
+	MaxEntries int
+	// MaxBodyBytes skips storing larger responses. Defaults to 1 MiB.
+	MaxBodyBytes int64
+	// Store replaces the in-memory LRU store.
+	Store Store
+	// Clock drives expiry; defaults to the real clock.
+	Clock clock.Clock
+	// Principal identifies who a request is made for, e.g. the auth
+	// provider name plus a hash of its credentials. A non-empty value is
+	// appended to every storage key, including a Directives.Key, so
+	// responses are never served across tenants or users. Defaults to
+	// HeaderPrincipal("Authorization", "Cookie").
+	Principal func(req *http.Request) string
+	// VaryHeaders lists the request headers a response may vary on and still
+	// be stored. Responses varying on any other header, or on "*", are not
+	// cached, since their variants cannot be told apart. Header values are
+	// hashed into storage keys, so credentials never appear in them.
+	// Defaults to DefaultVaryHeaders.
+	VaryHeaders []string
+}
+
+// DefaultVaryHeaders are the request headers responses may vary on by
+// default.
+var DefaultVaryHeaders = []string{"Accept", "Accept-Encoding", "Accept-Language", "Authorization"}
+
+// HeaderPrincipal returns a Principal hashing the values of the named
+// request headers, so credentials never appear in storage keys. Requests
+// without any of the headers have no principal.
+func HeaderPrincipal(headers ...string) func(req *http.Request) string {
+	return func(req *http.Request) string {
+		h := sha256.New()
+		found := false
+		for _, name := range headers {
+			for _, v := range req.Header.Values(name) {
+				found = true
+				_, _ = io.WriteString(h, name+":"+v+"\n")
+			}
+		}
+		if !found {
+			return ""
+		}
+		return hex.EncodeToString(h.Sum(nil)[:16])
+	}
+}
+
+// Directives adjust caching for a single request.
+type Directives struct {
+	// NoCache bypasses the cache entirely: nothing is read or stored.
+	NoCache bool
+	// Refresh skips the lookup but stores the fresh response.
+	Refresh bool
+	// TTL overrides the entry lifetime, including any server max-age.
+	TTL time.Duration
+	// Key replaces the derived storage key (method and URL).
+	Key string
+}
+
+type directivesKey struct{}
+
+// WithDirectives attaches per-request directives to the context.
+func WithDirectives(ctx context.Context, d Directives) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, directivesKey{}, d)
+}
+
+// DirectivesFromContext retrieves directives attached with WithDirectives.
+func DirectivesFromContext(ctx context.Context) Directives {
+	if ctx == nil {
+		return Directives{}
+	}
+	d, _ := ctx.Value(directivesKey{}).(Directives)
+	return d
+}
+
+// NewMiddleware constructs a caching middleware.
+func NewMiddleware(cfg Config) func(http.RoundTripper) http.RoundTripper {
+	if cfg.TTL <= 0 {
+		cfg.TTL = time.Minute
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 1000
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = 1 << 20
+	}
+	store := cfg.Store
+	if store == nil {
+		store = NewMemoryStore(cfg.MaxEntries)
+	}
+	clk := clock.OrReal(cfg.Clock)
+	principal := cfg.Principal
+	if principal == nil {
+		principal = HeaderPrincipal("Authorization", "Cookie")
+	}
+	varyAllowed := make(map[string]bool)
+	varyHeaders := cfg.VaryHeaders
+	if varyHeaders == nil {
+		varyHeaders = DefaultVaryHeaders
+	}
+	for _, name := range varyHeaders {
+		varyAllowed[http.CanonicalHeaderKey(name)] = true
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Method != http.MethodGet && req.Method != http.MethodHead {
+				return next.RoundTrip(req)
+			}
+			d := DirectivesFromContext(req.Context())
+			if d.NoCache {
+				return next.RoundTrip(req)
+			}
+
+			key := d.Key
+			if key == "" {
+				key = req.Method + " " + req.URL.String()
+			}
+			if p := principal(req); p != "" {
+				key += " @" + p
+			}
+
+			if !d.Refresh {
+				if e, ok := lookup(store, key, req, clk.Now()); ok {
+					return e.response(req), nil
+				}
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil || resp.StatusCode != http.StatusOK {
+				return resp, err
+			}
+
+			ttl, cacheable := lifetime(resp.Header, cfg.TTL)
+			if d.TTL > 0 {
+				ttl, cacheable = d.TTL, true
+			}
+			vary, varyOK := varyNames(resp.Header, varyAllowed)
+			if !cacheable || !varyOK || ttl <= 0 || resp.Body == nil {
+				return resp, nil
+			}
+
+			body, err := io.ReadAll(io.LimitReader(resp.Body, cfg.MaxBodyBytes+1))
+			if err != nil {
+				_ = resp.Body.Close()
+				return nil, err
+			}
+			if int64(len(body)) > cfg.MaxBodyBytes {
+				resp.Body = &joinedBody{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+				return resp, nil
+			}
+			_ = resp.Body.Close()
+
+			now := clk.Now()
+			entry := &Entry{
+				StatusCode: resp.StatusCode,
+				Header:     resp.Header.Clone(),
+				Body:       body,
+				StoredAt:   now,
+				Expires:    now.Add(ttl),
+			}
+			if len(vary) > 0 {
+				store.Set(key, &Entry{StoredAt: now, Expires: entry.Expires, Vary: vary})
+				key = variantKey(key, req, vary)
+			}
+			store.Set(key, entry)
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+			return resp, nil
+		})
+	}
+}
+
+// lookup returns the fresh entry stored for req under key, following the
+// Vary index to the variant matching req's headers.
+func lookup(store Store, key string, req *http.Request, now time.Time) (*Entry, bool) {
+	e, ok := store.Get(key)
+	if !ok {
+		return nil, false
+	}
+	if !now.Before(e.Expires) {
+		store.Delete(key)
+		return nil, false
+	}
+	if len(e.Vary) == 0 {
+		return e, true
+	}
+	key = variantKey(key, req, e.Vary)
+	if e, ok = store.Get(key); !ok {
+		return nil, false
+	}
+	if !now.Before(e.Expires) {
+		store.Delete(key)
+		return nil, false
+	}
+	return e, true
+}
+
+// varyNames returns the canonical header names of the Vary header, and
+// false when the response varies on "*" or on a header not allowed.
+func varyNames(h http.Header, allowed map[string]bool) ([]string, bool) {
+	var names []string
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			switch {
+			case name == "":
+				continue
+			case name == "*" || !allowed[name]:
+				return nil, false
+			}
+			names = append(names, name)
+		}
+	}
+	return names, true
+}
+
+// variantKey extends key with a hash of req's values for the vary headers.
+func variantKey(key string, req *http.Request, vary []string) string {
+	h := sha256.New()
+	for _, name := range vary {
+		_, _ = io.WriteString(h, name+":"+strings.Join(req.Header.Values(name), ",")+"\n")
+	}
+	return key + " vary:" + hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+func (e *Entry) response(req *http.Request) *http.Response {
+	header := e.Header.Clone()
+	header.Set(HeaderStatus, "HIT")
+	return &http.Response{
+		Status:        strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// lifetime derives the entry TTL from Cache-Control, falling back to def.
+func lifetime(h http.Header, def time.Duration) (time.Duration, bool) {
+	for _, directive := range strings.Split(h.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store" || directive == "no-cache" || directive == "private":
+			return 0, false
+		case strings.HasPrefix(directive, "max-age="):
+			secs, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+			if err == nil {
+				return time.Duration(secs) * time.Second, true
+			}
+		}
+	}
+	return def, true
+}
+
+// NewMemoryStore returns an LRU Store holding at most maxEntries entries.
+func NewMemoryStore(maxEntries int) Store {
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+	return &memoryStore{max: maxEntries, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+type memoryStore struct {
+	mu    sync.Mutex
+	max   int
+	order *list.List
+	items map[string]*list.Element
+}
+
+type memoryItem struct {
+	key   string
+	entry *Entry
+}
+
+func (s *memoryStore) Get(key string) (*Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	s.order.MoveToFront(el)
+	return el.Value.(*memoryItem).entry, true
+}
+
+func (s *memoryStore) Set(key string, e *Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.items[key]; ok {
+		el.Value.(*memoryItem).entry = e
+		s.order.MoveToFront(el)
+		return
+	}
+	s.items[key] = s.order.PushFront(&memoryItem{key: key, entry: e})
+	for s.order.Len() > s.max {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(*memoryItem).key)
+	}
+}
+
+func (s *memoryStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.items[key]; ok {
+		s.order.Remove(el)
+		delete(s.items, key)
+	}
+}
+
+type joinedBody struct {
+	io.Reader
+	io.Closer
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
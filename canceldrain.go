@@ -0,0 +1,103 @@
+package httpc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// newCancelDrainMiddleware keeps keep-alive connections reusable when the
+// caller's context is cancelled while a response body is still arriving.
+// Requests are sent with a context detached from the caller's cancellation.
+// Before the response headers arrive, cancelling the caller aborts the
+// request as usual. Afterwards, body reads fail with the caller's error and
+// closing the body discards up to maxBytes of what is left in the
+// background, so the transport can return the connection to its pool. The
+// connection is dropped when more than maxBytes remain or when the drain
+// has not finished within timeout of the cancellation.
+func newCancelDrainMiddleware(maxBytes int64, timeout time.Duration) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			callerCtx := req.Context()
+			if callerCtx.Done() == nil {
+				return next.RoundTrip(req)
+			}
+			sendCtx, abort := context.WithCancel(context.WithoutCancel(callerCtx))
+			var gotHeaders atomic.Bool
+			stop := context.AfterFunc(callerCtx, func() {
+				if !gotHeaders.Load() {
+					abort()
+					return
+				}
+				time.AfterFunc(timeout, abort)
+			})
+
+			resp, err := next.RoundTrip(req.WithContext(sendCtx))
+			gotHeaders.Store(true)
+			if err != nil {
+				stop()
+				abort()
+				if cerr := callerCtx.Err(); cerr != nil {
+					return nil, cerr
+				}
+				return nil, err
+			}
+			resp.Request = req
+			resp.Body = &drainingBody{
+				body:     resp.Body,
+				ctx:      callerCtx,
+				stop:     stop,
+				abort:    abort,
+				maxBytes: maxBytes,
+			}
+			return resp, nil
+		})
+	}
+}
+
+// drainingBody fails reads once the caller's context is done and drains the
+// rest of the body on Close in that case.
+type drainingBody struct {
+	body     io.ReadCloser
+	ctx      context.Context
+	stop     func() bool
+	abort    context.CancelFunc
+	maxBytes int64
+	closed   atomic.Bool
+}
+
+func (b *drainingBody) Read(p []byte) (int, error) {
+	if err := b.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := b.body.Read(p)
+	if cerr := b.ctx.Err(); cerr != nil && err != io.EOF {
+		return n, cerr
+	}
+	return n, err
+}
+
+func (b *drainingBody) Close() error {
+	if !b.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	b.stop()
+	if b.ctx.Err() == nil {
+		err := b.body.Close()
+		b.abort()
+		return err
+	}
+	go b.drain()
+	return nil
+}
+
+// drain discards at most maxBytes; reaching EOF within the limit lets the
+// transport keep the connection, anything larger closes it.
+func (b *drainingBody) drain() {
+	defer b.abort()
+	// One byte past the limit lets a body of exactly maxBytes report EOF.
+	_, _ = io.CopyN(io.Discard, b.body, b.maxBytes+1)
+	_ = b.body.Close()
+}
@@ -0,0 +1,147 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gostratum/httpc/clock"
+)
+
+// capabilitiesTTL applies when the OPTIONS response has no
+// Access-Control-Max-Age.
+const capabilitiesTTL = 10 * time.Minute
+
+// maxBodyHeaders are non-standard headers servers use to advertise their
+// request body limit.
+var maxBodyHeaders = []string{"X-Max-Body-Size", "Max-Body-Size", "X-Max-Content-Length"}
+
+// Capabilities is what a host advertised in response to OPTIONS.
+type Capabilities struct {
+	Host string
+	// Methods lists the methods from Allow and Access-Control-Allow-Methods,
+	// upper-cased. Empty when the host advertised nothing.
+	Methods []string
+	// AcceptPatch lists the media types from Accept-Patch.
+	AcceptPatch []string
+	// MaxBodyBytes is the advertised request body limit; zero when unknown.
+	MaxBodyBytes int64
+	FetchedAt    time.Time
+}
+
+// Allows reports whether method was advertised. When nothing was advertised
+// the answer is unknown and Allows returns true.
+func (c Capabilities) Allows(method string) bool {
+	return len(c.Methods) == 0 || slices.Contains(c.Methods, strings.ToUpper(method))
+}
+
+type capabilityCache struct {
+	clk clock.Clock
+
+	mu      sync.Mutex
+	entries map[string]capabilityEntry
+}
+
+type capabilityEntry struct {
+	caps    Capabilities
+	expires time.Time
+}
+
+func newCapabilityCache(clk clock.Clock) *capabilityCache {
+	return &capabilityCache{clk: clock.OrReal(clk), entries: make(map[string]capabilityEntry)}
+}
+
+// Capabilities returns the cached OPTIONS capabilities of host, fetching them
+// on first use or after expiry. host is a host[:port] or an absolute URL;
+// empty means the base URL host. Hosts answering OPTIONS with an error status
+// are cached with no advertised methods.
+func (c *client) Capabilities(ctx context.Context, host string) (Capabilities, error) {
+	origin, err := c.capabilityOrigin(host)
+	if err != nil {
+		return Capabilities{}, err
+	}
+
+	cache := c.capabilities
+	cache.mu.Lock()
+	if e, ok := cache.entries[origin.Host]; ok && cache.clk.Now().Before(e.expires) {
+		cache.mu.Unlock()
+		return e.caps, nil
+	}
+	cache.mu.Unlock()
+
+	resp, err := c.Do(ctx, NewRequest(http.MethodOptions, origin.String(), WithRequestErrorOnStatus(false)))
+	if err != nil {
+		return Capabilities{}, err
+	}
+	defer resp.Release()
+
+	now := cache.clk.Now()
+	caps := Capabilities{Host: origin.Host, FetchedAt: now}
+	ttl := capabilitiesTTL
+	if code := resp.StatusCode(); code >= 200 && code < 300 {
+		h := resp.Headers()
+		caps.Methods = headerList(h, "Allow", "Access-Control-Allow-Methods")
+		caps.AcceptPatch = headerList(h, "Accept-Patch")
+		for _, name := range maxBodyHeaders {
+			if n, err := strconv.ParseInt(h.Get(name), 10, 64); err == nil && n > 0 {
+				caps.MaxBodyBytes = n
+				break
+			}
+		}
+		if secs, err := strconv.Atoi(h.Get("Access-Control-Max-Age")); err == nil && secs > 0 {
+			ttl = time.Duration(secs) * time.Second
+		}
+	}
+
+	cache.mu.Lock()
+	cache.entries[origin.Host] = capabilityEntry{caps: caps, expires: now.Add(ttl)}
+	cache.mu.Unlock()
+	return caps, nil
+}
+
+func (c *client) capabilityOrigin(host string) (*url.URL, error) {
+	base, _ := url.Parse(c.cfg.BaseURL)
+	if host == "" {
+		if base == nil || base.Host == "" {
+			return nil, errors.New("capabilities: no host and no base URL")
+		}
+		return &url.URL{Scheme: base.Scheme, Host: base.Host, Path: "/"}, nil
+	}
+	if strings.Contains(host, "://") {
+		u, err := url.Parse(host)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("capabilities: invalid host %q", host)
+		}
+		return &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}, nil
+	}
+	scheme := "https"
+	if base != nil && base.Host == host && base.Scheme != "" {
+		scheme = base.Scheme
+	}
+	return &url.URL{Scheme: scheme, Host: host, Path: "/"}, nil
+}
+
+func headerList(h http.Header, names ...string) []string {
+	var out []string
+	for _, name := range names {
+		for _, v := range h.Values(name) {
+			for _, item := range strings.Split(v, ",") {
+				item = strings.TrimSpace(item)
+				if name != "Accept-Patch" {
+					item = strings.ToUpper(item)
+				}
+				if item != "" && !slices.Contains(out, item) {
+					out = append(out, item)
+				}
+			}
+		}
+	}
+	return out
+}
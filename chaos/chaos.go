@@ -0,0 +1,176 @@
+package chaos
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrTruncatedBody is returned by response bodies that were cut short by the
+// middleware.
+var ErrTruncatedBody = errors.New("chaos: truncated response body")
+
+// Config controls fault injection. Every fault is disabled unless Enabled is
+// set, so the middleware can be wired unconditionally and switched on per
+// environment.
+type Config struct {
+	Enabled bool
+
+	// LatencyProbability is the chance of delaying a request by a random
+	// duration in [0, Latency].
+	LatencyProbability float64
+	Latency            time.Duration
+
+	// ErrorProbability is the chance of short-circuiting a request with a
+	// synthetic ErrorStatus response (503 by default).
+	ErrorProbability float64
+	ErrorStatus      int
+
+	// ResetProbability is the chance of failing a request with a connection
+	// reset error.
+	ResetProbability float64
+
+	// TruncateProbability is the chance of cutting the response body in half
+	// and failing the read with ErrTruncatedBody.
+	TruncateProbability float64
+
+	// Seed makes the injected faults reproducible when non-zero.
+	Seed int64
+}
+
+// NewMiddleware constructs a fault injection middleware.
+func NewMiddleware(cfg Config) func(http.RoundTripper) http.RoundTripper {
+	if cfg.ErrorStatus == 0 {
+		cfg.ErrorStatus = http.StatusServiceUnavailable
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	inj := &injector{
+		cfg:  cfg,
+		rand: rand.New(rand.NewSource(seed)),
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !inj.cfg.Enabled {
+				return next.RoundTrip(req)
+			}
+
+			if inj.hit(inj.cfg.LatencyProbability) && inj.cfg.Latency > 0 {
+				delay := time.Duration(inj.float() * float64(inj.cfg.Latency))
+				timer := time.NewTimer(delay)
+				select {
+				case <-req.Context().Done():
+					timer.Stop()
+					return nil, req.Context().Err()
+				case <-timer.C:
+				}
+			}
+
+			if inj.hit(inj.cfg.ResetProbability) {
+				return nil, &net.OpError{
+					Op:  "read",
+					Net: "tcp",
+					Err: os.NewSyscallError("read", syscall.ECONNRESET),
+				}
+			}
+
+			if inj.hit(inj.cfg.ErrorProbability) {
+				return syntheticResponse(req, inj.cfg.ErrorStatus), nil
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil || resp == nil || resp.Body == nil {
+				return resp, err
+			}
+
+			if inj.hit(inj.cfg.TruncateProbability) {
+				resp.Body = truncate(resp)
+			}
+			return resp, nil
+		})
+	}
+}
+
+type injector struct {
+	cfg Config
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func (i *injector) hit(p float64) bool {
+	if p <= 0 {
+		return false
+	}
+	return i.float() < p
+}
+
+func (i *injector) float() float64 {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rand.Float64()
+}
+
+func syntheticResponse(req *http.Request, status int) *http.Response {
+	body := "chaos: injected " + http.StatusText(status)
+	header := make(http.Header)
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	header.Set("X-Chaos-Injected", "true")
+	return &http.Response{
+		Status:        http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// truncate allows half of the declared body through (or nothing when the
+// length is unknown) before failing the read.
+func truncate(resp *http.Response) io.ReadCloser {
+	limit := resp.ContentLength / 2
+	if limit < 0 {
+		limit = 0
+	}
+	return &truncatedBody{
+		reader: io.LimitReader(resp.Body, limit),
+		closer: resp.Body,
+	}
+}
+
+type truncatedBody struct {
+	reader io.Reader
+	closer io.Closer
+}
+
+func (t *truncatedBody) Read(p []byte) (int, error) {
+	n, err := t.reader.Read(p)
+	if err == io.EOF {
+		return n, ErrTruncatedBody
+	}
+	return n, err
+}
+
+func (t *truncatedBody) Close() error {
+	return t.closer.Close()
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
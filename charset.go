@@ -0,0 +1,56 @@
+package httpc
+
+import (
+	"mime"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// Charset returns the charset parameter of the response Content-Type,
+// lower-cased, or "" when none is declared.
+func (r *Response) Charset() string {
+	if r.raw == nil {
+		return ""
+	}
+	_, params, err := mime.ParseMediaType(r.raw.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(params["charset"]))
+}
+
+// text returns the buffered body, transcoded to UTF-8 when the client enabled
+// WithCharsetTranscoding and the response declares another charset. Unknown
+// charsets are returned unchanged.
+func (r *Response) text() ([]byte, error) {
+	if err := r.ensureBody(); err != nil {
+		return nil, err
+	}
+	if !r.transcode || len(r.body) == 0 {
+		return r.body, nil
+	}
+	enc := charsetEncoding(r.Charset())
+	if enc == nil {
+		return r.body, nil
+	}
+	return enc.NewDecoder().Bytes(r.body)
+}
+
+// charsetEncoding resolves a charset label using the WHATWG encoding names
+// browsers accept, returning nil for UTF-8, ASCII and unknown labels.
+func charsetEncoding(label string) encoding.Encoding {
+	switch label {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return nil
+	}
+	enc, err := htmlindex.Get(label)
+	if err != nil || enc == encoding.Nop {
+		return nil
+	}
+	if name, _ := htmlindex.Name(enc); name == "utf-8" {
+		return nil
+	}
+	return enc
+}
@@ -1,19 +1,32 @@
 package httpc
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/http/cookiejar"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/gostratum/core/logx"
 	"github.com/gostratum/httpc/auth"
 	"github.com/gostratum/httpc/breaker"
+	"github.com/gostratum/httpc/bulkhead"
+	"github.com/gostratum/httpc/cache"
+	"github.com/gostratum/httpc/chaos"
+	"github.com/gostratum/httpc/clock"
+	"github.com/gostratum/httpc/dns"
+	"github.com/gostratum/httpc/logging"
+	"github.com/gostratum/httpc/metrics"
+	"github.com/gostratum/httpc/ratelimit"
+	"github.com/gostratum/httpc/redact"
 	"github.com/gostratum/httpc/retry"
+	"github.com/gostratum/httpc/tracing"
+	"github.com/sony/gobreaker"
 )
 
 // Client represents the public contract for the HTTP client.
@@ -24,6 +37,14 @@ type Client interface {
 	Put(ctx context.Context, url string, body any, opts ...ReqOption) (*Response, error)
 	Patch(ctx context.Context, url string, body any, opts ...ReqOption) (*Response, error)
 	Delete(ctx context.Context, url string, opts ...ReqOption) (*Response, error)
+	GetIfChanged(ctx context.Context, url string, store ETagStore, opts ...ReqOption) (*Response, error)
+	Stats() Stats
+	Health(host string) HostHealth
+	Prepare(method, urlTemplate string, staticOpts ...ReqOption) (*Prepared, error)
+	Capabilities(ctx context.Context, host string) (Capabilities, error)
+	Presign(method, target string, expires time.Duration) (string, error)
+	UploadPresigned(ctx context.Context, presignedURL string, size int64, open func() (io.ReadCloser, error), opts ...ReqOption) (*Response, error)
+	DownloadPresigned(ctx context.Context, presignedURL string, w io.Writer, opts ...ReqOption) error
 }
 
 const defaultUserAgent = "httpc/0"
@@ -33,6 +54,12 @@ type client struct {
 	httpClient  *http.Client
 	retryPolicy retry.Policy
 	breakerMgr  breaker.Manager
+	redactor    *redact.Redactor
+	stats       *statsRecorder
+	buffers     *bufferBudget
+	defaults    *Request
+
+	capabilities *capabilityCache
 }
 
 // New constructs a Client with the supplied options applied.
@@ -48,10 +75,33 @@ func New(opts ...Option) (Client, error) {
 		switch {
 		case cfg.APIKey.Key != "":
 			cfg.DefaultAuth = auth.NewAPIKey(auth.APIKeyOptions{
-				Key:  cfg.APIKey.Key,
-				In:   cfg.APIKey.In,
-				Name: cfg.APIKey.Name,
+				Key:          cfg.APIKey.Key,
+				In:           cfg.APIKey.In,
+				Name:         cfg.APIKey.Name,
+				SecondaryKey: cfg.APIKey.SecondaryKey,
+			})
+		case cfg.SigV4.AccessKeyID != "":
+			cfg.DefaultAuth = auth.NewSigV4(auth.SigV4Options{
+				AccessKeyID:     cfg.SigV4.AccessKeyID,
+				SecretAccessKey: cfg.SigV4.SecretAccessKey,
+				SessionToken:    cfg.SigV4.SessionToken,
+				Region:          cfg.SigV4.Region,
+				Service:         cfg.SigV4.Service,
+				Now:             clock.OrReal(cfg.Clock).Now,
+			})
+		case cfg.OAuth2.TokenURL != "":
+			provider, err := auth.NewOAuth2ClientCredentials(auth.OAuth2Options{
+				TokenURL:          cfg.OAuth2.TokenURL,
+				ClientID:          cfg.OAuth2.ClientID,
+				ClientSecret:      cfg.OAuth2.ClientSecret,
+				Scopes:            cfg.OAuth2.Scopes,
+				CredentialsInBody: strings.EqualFold(cfg.OAuth2.CredentialsIn, "body"),
+				Clock:             cfg.Clock,
 			})
+			if err != nil {
+				return nil, err
+			}
+			cfg.DefaultAuth = provider
 		case cfg.Basic.Username != "":
 			cfg.DefaultAuth = auth.NewBasic(auth.BasicOptions{
 				Username: cfg.Basic.Username,
@@ -84,7 +134,11 @@ func New(opts ...Option) (Client, error) {
 			default:
 				return nil, fmt.Errorf("unsupported jwt alg %q", cfg.JWT.Alg)
 			}
-			jwtProvider, err := auth.NewJWT(jwtOpts)
+			var jwtMore []auth.JWTOption
+			if cfg.Clock != nil {
+				jwtMore = append(jwtMore, auth.WithClock(cfg.Clock.Now))
+			}
+			jwtProvider, err := auth.NewJWT(jwtOpts, jwtMore...)
 			if err != nil {
 				return nil, err
 			}
@@ -92,6 +146,16 @@ func New(opts ...Option) (Client, error) {
 		}
 	}
 
+	if err := validateAPIVersionPlacement(cfg.APIVersion.In); err != nil {
+		return nil, err
+	}
+	if err := validateURLJoin(cfg.URLJoin); err != nil {
+		return nil, err
+	}
+	if err := validateTimeoutPolicy(cfg.TimeoutPolicy); err != nil {
+		return nil, err
+	}
+
 	logger := cfg.Logger
 	if logger == nil {
 		logger = logx.NewNoopLogger()
@@ -101,9 +165,35 @@ func New(opts ...Option) (Client, error) {
 		cfg.UserAgent = defaultUserAgent
 	}
 
+	redactor := cfg.redactor()
+
+	if cfg.CacheEnabled && cfg.Cache == nil {
+		cfg.Cache = &cache.Config{}
+	}
+	if cfg.MetricsEnabled && cfg.Metrics == nil {
+		cfg.Metrics = metrics.NewRegistry()
+	}
+
+	var defaults *Request
+	if len(cfg.RequestDefaults) > 0 {
+		defaults = buildRequest("", "", cfg.RequestDefaults...)
+		if defaults.err != nil {
+			return nil, fmt.Errorf("request defaults: %w", defaults.err)
+		}
+	}
+
+	proxyURL, err := cfg.proxyURL()
+	if err != nil {
+		return nil, err
+	}
+
+	recycler := cfg.sharedRecycler
 	baseTransport := cfg.Transport
 	if baseTransport == nil {
-		baseTransport = defaultTransport(cfg)
+		if cfg.MaxConnLifetime > 0 {
+			recycler = newConnRecycler(cfg.MaxConnLifetime)
+		}
+		baseTransport = defaultTransport(cfg, proxyURL, recycler)
 	}
 
 	retryPolicy := cfg.RetryPolicy
@@ -118,14 +208,58 @@ func New(opts ...Option) (Client, error) {
 		})
 	}
 
+	stats := newStatsRecorder(cfg.Clock)
+
 	breakerMgr := cfg.Breaker
 	if breakerMgr == nil && cfg.BreakerEnabled {
-		breakerMgr = breaker.NewManager(breaker.Config{})
+		onStateChange := stats.onBreakerStateChange
+		if br, ok := cfg.Metrics.(metrics.BreakerRecorder); ok && cfg.MetricsEnabled {
+			onStateChange = func(host string, from, to gobreaker.State) {
+				stats.onBreakerStateChange(host, from, to)
+				br.BreakerState(host, to.String())
+			}
+		}
+		breakerMgr = breaker.NewManager(breaker.Config{
+			Clock:         cfg.Clock,
+			OnStateChange: onStateChange,
+		})
+	}
+
+	baseTransport = newServerNameTransport(baseTransport)
+
+	if cfg.Chaos.Enabled {
+		baseTransport = wrapTransport(baseTransport, chaos.NewMiddleware(cfg.Chaos))
+	}
+
+	if cfg.CancelDrainBytes > 0 {
+		baseTransport = wrapTransport(baseTransport, newCancelDrainMiddleware(cfg.CancelDrainBytes, cfg.CancelDrainTimeout))
+	}
+
+	if len(cfg.RequestHooks) > 0 || len(cfg.ResponseHooks) > 0 || len(cfg.ErrorHooks) > 0 {
+		baseTransport = wrapTransport(baseTransport, newHooksMiddleware(cfg))
+	}
+
+	if cfg.TracingEnabled {
+		baseTransport = wrapTransport(baseTransport, tracing.NewMiddleware(tracing.WithTracer(cfg.Tracer)))
 	}
 
 	transport := wrapTransport(baseTransport,
 		newGzipMiddleware(),
+		newProxyMiddleware(proxyURL, cfg.proxyHeaders()),
+		auth.NewRefreshMiddleware(),
+		auth.NewKeyFallbackMiddleware(func(req *http.Request, resp *http.Response, err error) {
+			stats.onKeyFallback(req.URL.Host)
+			logger.Warn("primary api key rejected, sent secondary key",
+				logx.String("method", req.Method),
+				logx.String("url", redactor.Request(req)),
+			)
+		}),
+		retry.NewStaleConnMiddleware(),
+		newDynamicQueryMiddleware(),
 	)
+	if recycler != nil {
+		transport = wrapTransport(transport, recycler.middleware())
+	}
 
 	if cfg.BreakerEnabled {
 		if breakerMgr == nil {
@@ -134,11 +268,33 @@ func New(opts ...Option) (Client, error) {
 		transport = wrapTransport(transport, breaker.NewMiddleware(breakerMgr))
 	}
 
+	if cfg.RateLimiter != nil {
+		transport = wrapTransport(transport, ratelimit.NewMiddleware(cfg.RateLimiter))
+	}
+
 	if cfg.RetryEnabled {
 		if retryPolicy == nil {
 			return nil, errors.New("retry enabled but no policy configured")
 		}
-		transport = wrapTransport(transport, retry.NewMiddleware(retryPolicy, logger))
+		transport = wrapTransport(transport, retry.NewMiddleware(retryPolicy, logger,
+			retry.WithClock(cfg.Clock),
+			retry.WithRedactor(redactor),
+		))
+	}
+
+	if cfg.Bulkhead != nil {
+		transport = wrapTransport(transport, bulkhead.NewMiddleware(*cfg.Bulkhead))
+	}
+
+	if cfg.Cache != nil {
+		cacheCfg := *cfg.Cache
+		if cacheCfg.Clock == nil {
+			cacheCfg.Clock = cfg.Clock
+		}
+		if cacheCfg.Principal == nil {
+			cacheCfg.Principal = defaultCachePrincipal(cfg)
+		}
+		transport = wrapTransport(transport, cache.NewMiddleware(cacheCfg))
 	}
 
 	for _, mw := range cfg.Middlewares {
@@ -147,23 +303,86 @@ func New(opts ...Option) (Client, error) {
 		}
 	}
 
+	if cfg.MetricsEnabled {
+		transport = wrapTransport(transport, metrics.NewMiddleware(cfg.Metrics, cfg.Clock))
+	}
+	if cfg.LoggingEnabled {
+		logOpts := []logging.MiddlewareOption{
+			logging.WithClock(cfg.Clock),
+			logging.WithRedactor(redactor),
+			logging.WithAttempts(func(req *http.Request) int {
+				if st := retry.StatsFromContext(req.Context()); st != nil {
+					return st.Attempts
+				}
+				return 0
+			}),
+		}
+		if cfg.LoggingHeaders {
+			logOpts = append(logOpts, logging.WithHeaders())
+		}
+		if cfg.LoggingBodyLimit > 0 {
+			logOpts = append(logOpts, logging.WithBodies(cfg.LoggingBodyLimit))
+		}
+		transport = wrapTransport(transport, logging.NewMiddleware(logger, logOpts...))
+	}
+
+	transport = wrapTransport(transport, stats.middleware())
+	transport = wrapTransport(transport, newRecoverMiddleware(logger, redactor))
+
+	jar := cfg.CookieJar
+	if jar == nil && cfg.Cookies {
+		if jar, err = cookiejar.New(nil); err != nil {
+			return nil, fmt.Errorf("create cookie jar: %w", err)
+		}
+	}
+
 	httpClient := cfg.HTTPClient
 	if httpClient == nil {
 		httpClient = &http.Client{
-			Timeout:   cfg.Timeout,
+			Timeout:   cfg.httpClientTimeout(),
 			Transport: transport,
+			Jar:       jar,
 		}
 	} else {
-		httpClient.Timeout = cfg.Timeout
+		httpClient.Timeout = cfg.httpClientTimeout()
 		httpClient.Transport = transport
+		if jar != nil {
+			httpClient.Jar = jar
+		}
 	}
 
-	return &client{
+	c := &client{
 		cfg:         cfg,
 		httpClient:  httpClient,
 		retryPolicy: retryPolicy,
 		breakerMgr:  breakerMgr,
-	}, nil
+		redactor:    redactor,
+		stats:       stats,
+		buffers:     newBufferBudget(cfg.MaxBufferedBytes),
+		defaults:    defaults,
+
+		capabilities: newCapabilityCache(cfg.Clock),
+	}
+
+	if r, ok := cfg.DefaultAuth.(auth.Refresher); ok && cfg.AuthRefresh {
+		if err := r.Start(context.Background()); err != nil {
+			return nil, fmt.Errorf("start auth refresh: %w", err)
+		}
+	}
+
+	if cfg.StartupProbe != nil && !cfg.StartupProbe.deferred {
+		if err := c.probe(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.ExpvarName != "" {
+		if err := c.publishExpvar(cfg.ExpvarName); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
 }
 
 // Do executes the supplied Request.
@@ -175,14 +394,89 @@ func (c *client) Do(ctx context.Context, req *Request) (*Response, error) {
 		ctx = context.Background()
 	}
 
+	if req.err != nil {
+		return nil, req.err
+	}
+
 	r := req.clone()
+	if c.defaults != nil {
+		r.withDefaults(c.defaults)
+	}
 
-	if r.timeout > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, r.timeout)
-		defer cancel()
+	// The timeout context outlives Do when the response is returned: its
+	// body releases it once read or closed.
+	cancel := context.CancelFunc(func() {})
+	if d := c.cfg.requestTimeout(r.timeout); d > 0 {
+		ctx, cancel = context.WithTimeout(ctx, d)
+	}
+	var headers *headerDeadline
+	if d := c.cfg.headerTimeout(); d > 0 {
+		headers, ctx = newHeaderDeadline(ctx, clock.OrReal(c.cfg.Clock), d)
+		release := cancel
+		cancel = func() {
+			headers.cancel(nil)
+			release()
+		}
+	}
+	returned := false
+	defer func() {
+		if !returned {
+			cancel()
+		}
+	}()
+
+	stats := &retry.Stats{}
+	ctx = retry.WithStats(ctx, stats)
+
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return nil, headers.wrap(ctx, err)
 	}
 
+	if resp.StatusCode == http.StatusUnsupportedMediaType && r.compress && r.compressFallback && r.bodyFactory != nil {
+		drainAndClose(resp.Body)
+		r.compress = false
+		resp, err = c.send(ctx, r)
+		if err != nil {
+			return nil, headers.wrap(ctx, err)
+		}
+	}
+	if err := headers.stop(); err != nil {
+		drainAndClose(resp.Body)
+		return nil, err
+	}
+
+	if r.session != nil && !isSafeMethod(r.method) {
+		r.session.capture(resp)
+	}
+
+	if err := checkContentType(resp, r.expectContentType); err != nil {
+		return nil, err
+	}
+
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	returned = true
+	out, err := newResponse(resp, stats)
+	if err != nil {
+		return nil, err
+	}
+	out.budget = c.buffers
+	out.rawBody = r.rawBody
+	out.transcode = c.cfg.TranscodeCharset
+	out.received = clock.OrReal(c.cfg.Clock).Now()
+	errorOnStatus := c.cfg.ErrorOnStatus
+	if r.errorOnStatus != nil {
+		errorOnStatus = *r.errorOnStatus
+	}
+	if errorOnStatus && resp.StatusCode >= 400 {
+		return nil, newHTTPError(out, c.redactor)
+	}
+	return out, nil
+}
+
+// send builds, authenticates and transmits a single logical request through
+// the middleware chain.
+func (c *client) send(ctx context.Context, r *Request) (*http.Response, error) {
 	httpReq, err := r.buildHTTPRequest(ctx, c.cfg)
 	if err != nil {
 		return nil, err
@@ -193,9 +487,18 @@ func (c *client) Do(ctx context.Context, req *Request) (*Response, error) {
 		authProvider = c.cfg.DefaultAuth
 	}
 	if authProvider != nil {
-		if err := authProvider.Apply(httpReq); err != nil {
+		if err := auth.Apply(ctx, authProvider, httpReq); err != nil {
 			return nil, fmt.Errorf("apply auth: %w", err)
 		}
+		ctx = auth.WithProvider(ctx, authProvider)
+	}
+
+	if r.serverName != "" {
+		ctx = withServerName(ctx, r.serverName)
+	}
+
+	if r.idempotent || (r.overridesMethod(c.cfg) && isIdempotentMethod(r.method)) {
+		ctx = retry.WithIdempotent(ctx)
 	}
 
 	policy := r.retryPolicy
@@ -212,15 +515,40 @@ func (c *client) Do(ctx context.Context, req *Request) (*Response, error) {
 	if r.breakerToggle != nil {
 		ctx = breaker.WithOverride(ctx, *r.breakerToggle)
 	}
+	if r.breakerKey != "" {
+		ctx = breaker.WithKey(ctx, r.breakerKey)
+	}
+
+	if tmpl := r.pathTemplate(c.cfg); tmpl != "" {
+		ctx = redact.WithPathTemplate(ctx, tmpl)
+	}
+
+	if r.logLevel != logging.LevelDefault {
+		ctx = logging.WithLevel(ctx, r.logLevel)
+	}
+
+	if r.cache != (cache.Directives{}) {
+		ctx = cache.WithDirectives(ctx, r.cache)
+	}
+
+	if r.earlyHints != nil {
+		ctx = withEarlyHintsTrace(ctx, httpReq, r.earlyHints)
+	}
+	if r.progress != nil {
+		ctx = withProgressTrace(ctx, r.progress)
+	}
+
+	if len(r.dynamicQuery) > 0 {
+		ctx = withDynamicQuery(ctx, r.dynamicQuery)
+	}
 
 	httpReq = httpReq.WithContext(ctx)
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, err
+		return nil, classifyError(err)
 	}
-
-	return newResponse(resp)
+	return resp, nil
 }
 
 func (c *client) Get(ctx context.Context, url string, opts ...ReqOption) (*Response, error) {
@@ -244,59 +572,157 @@ func (c *client) Delete(ctx context.Context, url string, opts ...ReqOption) (*Re
 }
 
 func (c *client) execute(ctx context.Context, method, target string, body any, opts ...ReqOption) (*Response, error) {
-	opts = append(normalizeBody(body), opts...)
+	bodyOpts, cleanup := normalizeBody(body, c.cfg.BodySpoolLimit)
+	defer cleanup()
+	opts = append(bodyOpts, opts...)
 	req := newRequest(method, target, opts...)
 	return c.Do(ctx, req)
 }
 
-func normalizeBody(body any) []ReqOption {
+// normalizeBody maps the body argument of the verb helpers to a body option.
+// The returned cleanup removes any spool file and must run once the request
+// has completed.
+func normalizeBody(body any, spoolLimit int64) ([]ReqOption, func()) {
+	noop := func() {}
 	if body == nil {
-		return nil
+		return nil, noop
 	}
 	switch v := body.(type) {
 	case ReqOption:
-		return []ReqOption{v}
+		return []ReqOption{v}, noop
 	case []byte:
-		return []ReqOption{WithRaw(v, "application/octet-stream")}
+		return []ReqOption{bodyArgument(WithRaw(v, "application/octet-stream"))}, noop
 	case string:
-		return []ReqOption{WithRaw([]byte(v), "text/plain; charset=utf-8")}
+		return []ReqOption{bodyArgument(WithRaw([]byte(v), "text/plain; charset=utf-8"))}, noop
 	case io.ReadSeeker:
-		return []ReqOption{withReadSeeker(v)}
+		return []ReqOption{withReadSeeker(v)}, noop
 	case io.Reader:
-		data, err := io.ReadAll(v)
+		factory, cleanup, err := spoolBody(v, spoolLimit)
 		if err != nil {
 			return []ReqOption{func(r *Request) {
-				r.bodyFactory = func() (io.ReadCloser, int64, string, error) { return nil, 0, "", err }
-			}}
+				r.setBody("body argument", func() (io.ReadCloser, int64, string, error) { return nil, 0, "", err })
+			}}, noop
 		}
-		return []ReqOption{WithRaw(data, "application/octet-stream")}
+		return []ReqOption{func(r *Request) { r.setBody("body argument", factory) }}, cleanup
 	default:
-		return []ReqOption{WithJSON(v)}
+		return []ReqOption{bodyArgument(WithJSON(v))}, noop
+	}
+}
+
+// bodyArgument labels a body option built from a verb helper's body argument
+// so conflict errors name what the caller actually passed.
+func bodyArgument(opt ReqOption) ReqOption {
+	return func(r *Request) {
+		opt(r)
+		r.bodyFrom = "body argument"
 	}
 }
 
 func withReadSeeker(rs io.ReadSeeker) ReqOption {
 	return func(r *Request) {
-		r.bodyFactory = func() (io.ReadCloser, int64, string, error) {
+		r.setBody("body argument", onceBody(func() ([]byte, string, error) {
 			if _, err := rs.Seek(0, io.SeekStart); err != nil {
-				return nil, 0, "", err
+				return nil, "", err
 			}
 			data, err := io.ReadAll(rs)
-			if err != nil {
-				return nil, 0, "", err
+			return data, "application/octet-stream", err
+		}))
+	}
+}
+
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// drainAndClose discards a small amount of unread body so the connection can
+// be reused, then closes it.
+func drainAndClose(body io.ReadCloser) {
+	if body == nil {
+		return
+	}
+	_, _ = io.CopyN(io.Discard, body, 4<<10)
+	_ = body.Close()
+}
+
+// defaultCachePrincipal keys cached responses by the auth provider that
+// signed the request and a hash of the credentials it carries, so a client
+// shared between tenants or per-request auth overrides never cross-serves.
+func defaultCachePrincipal(cfg Config) func(*http.Request) string {
+	headers := []string{"Authorization", "Cookie"}
+	if cfg.APIKey.Name != "" && !strings.EqualFold(cfg.APIKey.In, "query") {
+		headers = append(headers, cfg.APIKey.Name)
+	}
+	credentials := cache.HeaderPrincipal(headers...)
+	return func(req *http.Request) string {
+		name := ""
+		if p := auth.ProviderFromContext(req.Context()); p != nil {
+			name = p.Name()
+		}
+		hash := credentials(req)
+		if name == "" && hash == "" {
+			return ""
+		}
+		return name + ":" + hash
+	}
+}
+
+// dialNoDelay sets TCP_NODELAY to noDelay on the TCP connections dialed by
+// next; Go enables it on every connection by default.
+func dialNoDelay(next dialFunc, noDelay bool) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		if tc, ok := conn.(*net.TCPConn); ok {
+			if err := tc.SetNoDelay(noDelay); err != nil {
+				conn.Close()
+				return nil, err
 			}
-			return io.NopCloser(bytes.NewReader(data)), int64(len(data)), "application/octet-stream", nil
 		}
+		return conn, nil
 	}
 }
 
-func defaultTransport(cfg Config) http.RoundTripper {
-	return &http.Transport{
-		Proxy:                 http.ProxyFromEnvironment,
-		MaxIdleConns:          cfg.MaxIdleConns,
-		IdleConnTimeout:       cfg.IdleConnTimeout,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-		ForceAttemptHTTP2:     true,
+func defaultTransport(cfg Config, proxyURL *url.URL, recycler *connRecycler) http.RoundTripper {
+	proxy := http.ProxyFromEnvironment
+	if proxyURL != nil {
+		proxy = http.ProxyURL(proxyURL)
+	}
+	transport := &http.Transport{
+		Proxy:                  proxy,
+		ProxyConnectHeader:     cfg.proxyHeaders(),
+		OnProxyConnectResponse: onProxyConnectResponse,
+		MaxIdleConns:           cfg.MaxIdleConns,
+		IdleConnTimeout:        cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:    max(cfg.TLSHandshakeTimeout, 0),
+		ExpectContinueTimeout:  max(cfg.ExpectContinueTimeout, 0),
+		ResponseHeaderTimeout:  cfg.ResponseHeaderTimeout,
+		ForceAttemptHTTP2:      true,
+	}
+	if cfg.HTTP2PingInterval > 0 {
+		transport.HTTP2 = &http.HTTP2Config{
+			SendPingTimeout: cfg.HTTP2PingInterval,
+			PingTimeout:     cfg.HTTP2PingTimeout,
+		}
+	}
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: cfg.TCPKeepAlive,
+	}
+	transport.DialContext = dialer.DialContext
+	if cfg.Resolver != nil {
+		transport.DialContext = dns.DialContext(cfg.Resolver, dialer)
+	}
+	if cfg.DisableTCPNoDelay {
+		transport.DialContext = dialNoDelay(transport.DialContext, false)
+	}
+	if recycler != nil {
+		transport.DialContext = recycler.dial(transport.DialContext)
 	}
+	return transport
 }
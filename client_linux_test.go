@@ -0,0 +1,73 @@
+package httpc
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultTransportSocketOptions(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	// dial opens a connection through the default transport and reports its
+	// TCP_NODELAY, SO_KEEPALIVE and TCP_KEEPIDLE settings.
+	dial := func(t *testing.T, opts ...Option) (noDelay, keepAlive bool, idle time.Duration) {
+		t.Helper()
+		cfg := Config{}
+		for _, opt := range opts {
+			opt(&cfg)
+		}
+		cfg.applyDefaults()
+		transport := defaultTransport(cfg, nil, nil).(*http.Transport)
+		require.NotNil(t, transport.DialContext)
+		conn, err := transport.DialContext(context.Background(), "tcp", ln.Addr().String())
+		require.NoError(t, err)
+		defer conn.Close()
+		raw, err := conn.(*net.TCPConn).SyscallConn()
+		require.NoError(t, err)
+		var nd, ka, secs int
+		require.NoError(t, raw.Control(func(fd uintptr) {
+			nd, _ = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_NODELAY)
+			ka, _ = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_KEEPALIVE)
+			secs, _ = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE)
+		}))
+		return nd != 0, ka != 0, time.Duration(secs) * time.Second
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		noDelay, keepAlive, idle := dial(t)
+		assert.True(t, noDelay)
+		assert.True(t, keepAlive)
+		assert.Equal(t, 30*time.Second, idle)
+	})
+
+	t.Run("custom_keepalive", func(t *testing.T) {
+		noDelay, keepAlive, idle := dial(t, WithTCPKeepAlive(7*time.Second))
+		assert.True(t, noDelay)
+		assert.True(t, keepAlive)
+		assert.Equal(t, 7*time.Second, idle)
+	})
+
+	t.Run("disables_nodelay_and_keepalive", func(t *testing.T) {
+		noDelay, keepAlive, _ := dial(t, WithTCPNoDelay(false), WithTCPKeepAlive(-1))
+		assert.False(t, noDelay)
+		assert.False(t, keepAlive)
+	})
+}
@@ -2,6 +2,7 @@ package httpc
 
 import (
 	"net/http"
+	"runtime"
 	"testing"
 	"time"
 
@@ -98,6 +99,16 @@ func TestConfig_applyDefaults(t *testing.T) {
 		assert.Equal(t, 90*time.Second, cfg.IdleConnTimeout)
 	})
 
+	t.Run("applies_default_transport_timeouts", func(t *testing.T) {
+		cfg := Config{}
+		cfg.applyDefaults()
+		assert.Equal(t, 10*time.Second, cfg.TLSHandshakeTimeout)
+		assert.Equal(t, time.Second, cfg.ExpectContinueTimeout)
+		assert.Zero(t, cfg.ResponseHeaderTimeout)
+		assert.Equal(t, 30*time.Second, cfg.TCPKeepAlive)
+		assert.False(t, cfg.DisableTCPNoDelay)
+	})
+
 	t.Run("applies_default_retry_max_attempts", func(t *testing.T) {
 		cfg := Config{}
 		cfg.applyDefaults()
@@ -236,6 +247,32 @@ func TestWithUserAgent(t *testing.T) {
 	})
 }
 
+func TestBuildUserAgent(t *testing.T) {
+	runtimeComment := "(" + runtime.GOOS + "; " + runtime.GOARCH + "; " + runtime.Version() + ")"
+
+	t.Run("describes_app_and_runtime", func(t *testing.T) {
+		ua := BuildUserAgent(UserAgentInfo{
+			App:      "billing",
+			Version:  "1.4.2",
+			Comments: []string{"+https://example.com/ops"},
+		})
+		assert.Equal(t, "billing/1.4.2 (+https://example.com/ops) httpc/0 "+runtimeComment, ua)
+	})
+
+	t.Run("runtime_only_without_app", func(t *testing.T) {
+		assert.Equal(t, "httpc/0 "+runtimeComment, BuildUserAgent(UserAgentInfo{}))
+	})
+
+	t.Run("sanitises_tokens_and_comments", func(t *testing.T) {
+		ua := BuildUserAgent(UserAgentInfo{
+			App:      "my app",
+			Version:  "1.0/beta",
+			Comments: []string{"team (core)\\ops\r\n"},
+		})
+		assert.Equal(t, `my-app/1.0-beta (team \(core\)\\ops) httpc/0 `+runtimeComment, ua)
+	})
+}
+
 func TestWithTransport(t *testing.T) {
 	t.Run("sets_transport", func(t *testing.T) {
 		transport := &http.Transport{MaxIdleConns: 50}
@@ -246,6 +283,42 @@ func TestWithTransport(t *testing.T) {
 	})
 }
 
+func TestDefaultTransportTimeouts(t *testing.T) {
+	t.Run("applies_configured_timeouts", func(t *testing.T) {
+		cfg := Config{}
+		WithResponseHeaderTimeout(3 * time.Second)(&cfg)
+		WithTLSHandshakeTimeout(4 * time.Second)(&cfg)
+		WithExpectContinueTimeout(500 * time.Millisecond)(&cfg)
+		cfg.applyDefaults()
+
+		transport := defaultTransport(cfg, nil, nil).(*http.Transport)
+		assert.Equal(t, 3*time.Second, transport.ResponseHeaderTimeout)
+		assert.Equal(t, 4*time.Second, transport.TLSHandshakeTimeout)
+		assert.Equal(t, 500*time.Millisecond, transport.ExpectContinueTimeout)
+	})
+
+	t.Run("negative_disables", func(t *testing.T) {
+		cfg := Config{TLSHandshakeTimeout: -1, ExpectContinueTimeout: -1}
+		cfg.applyDefaults()
+
+		transport := defaultTransport(cfg, nil, nil).(*http.Transport)
+		assert.Zero(t, transport.TLSHandshakeTimeout)
+		assert.Zero(t, transport.ExpectContinueTimeout)
+	})
+}
+
+func TestDefaultTransportHTTP2Ping(t *testing.T) {
+	cfg := Config{}
+	cfg.applyDefaults()
+	assert.Nil(t, defaultTransport(cfg, nil, nil).(*http.Transport).HTTP2)
+
+	WithHTTP2Ping(20*time.Second, 5*time.Second)(&cfg)
+	transport := defaultTransport(cfg, nil, nil).(*http.Transport)
+	require.NotNil(t, transport.HTTP2)
+	assert.Equal(t, 20*time.Second, transport.HTTP2.SendPingTimeout)
+	assert.Equal(t, 5*time.Second, transport.HTTP2.PingTimeout)
+}
+
 func TestWithHTTPClient(t *testing.T) {
 	t.Run("sets_http_client", func(t *testing.T) {
 		httpClient := &http.Client{Timeout: 30 * time.Second}
@@ -255,3 +328,84 @@ func TestWithHTTPClient(t *testing.T) {
 		assert.Equal(t, httpClient, cfg.HTTPClient)
 	})
 }
+
+func TestConfig_applyProfile(t *testing.T) {
+	base := func() Config {
+		cfg := Config{
+			Env:              "prod",
+			Timeout:          10 * time.Second,
+			RetryMaxAttempts: 3,
+			Profiles: map[string]map[string]any{
+				"prod": {
+					"timeout":            "3s",
+					"retry_max_attempts": 5,
+					"retry_on_statuses":  "500,503",
+					"jwt":                map[string]any{"ttl": "30s"},
+				},
+				"dev": {"timeout": "1m"},
+			},
+		}
+		cfg.JWT.Alg = "HS256"
+		return cfg
+	}
+
+	t.Run("overlays_matching_env", func(t *testing.T) {
+		cfg := base()
+		require.NoError(t, cfg.applyProfile())
+		assert.Equal(t, 3*time.Second, cfg.Timeout)
+		assert.Equal(t, 5, cfg.RetryMaxAttempts)
+		assert.Equal(t, []int{500, 503}, cfg.RetryOnStatuses)
+		assert.Equal(t, 30*time.Second, cfg.JWT.TTL)
+		assert.Equal(t, "HS256", cfg.JWT.Alg)
+	})
+
+	t.Run("ignores_missing_profile", func(t *testing.T) {
+		cfg := base()
+		cfg.Env = "staging"
+		require.NoError(t, cfg.applyProfile())
+		assert.Equal(t, 10*time.Second, cfg.Timeout)
+	})
+
+	t.Run("rejects_unknown_keys", func(t *testing.T) {
+		cfg := base()
+		cfg.Profiles["prod"]["timout"] = "1s"
+		err := cfg.applyProfile()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `profile "prod"`)
+	})
+}
+
+func TestConfig_resolveBaseURL(t *testing.T) {
+	t.Run("expands_environment", func(t *testing.T) {
+		t.Setenv("HTTPC_TEST_HOST", "api.example.com")
+		cfg := Config{Env: "prod", BaseURL: "https://${HTTPC_TEST_HOST}/v1"}
+		require.NoError(t, cfg.resolveBaseURL())
+		assert.Equal(t, "https://api.example.com/v1", cfg.BaseURL)
+	})
+
+	t.Run("reports_missing_variable", func(t *testing.T) {
+		cfg := Config{BaseURL: "https://${HTTPC_TEST_UNSET_HOST}"}
+		err := cfg.resolveBaseURL()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "HTTPC_TEST_UNSET_HOST is not set")
+	})
+
+	t.Run("validates_scheme_and_host", func(t *testing.T) {
+		cfg := Config{BaseURL: "ftp://files.example.com"}
+		require.ErrorContains(t, cfg.resolveBaseURL(), "scheme must be http or https")
+
+		cfg = Config{BaseURL: "https://"}
+		require.ErrorContains(t, cfg.resolveBaseURL(), "missing host")
+	})
+
+	t.Run("refuses_http_in_prod", func(t *testing.T) {
+		cfg := Config{Env: "prod", BaseURL: "http://api.internal"}
+		require.ErrorContains(t, cfg.resolveBaseURL(), "allow_insecure_http")
+
+		cfg.AllowInsecureHTTP = true
+		require.NoError(t, cfg.resolveBaseURL())
+
+		cfg = Config{Env: "dev", BaseURL: "http://localhost:8080"}
+		require.NoError(t, cfg.resolveBaseURL())
+	})
+}
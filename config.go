@@ -1,25 +1,90 @@
 package httpc
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/gostratum/core/configx"
 	"github.com/gostratum/core/logx"
 	"github.com/gostratum/httpc/auth"
 	"github.com/gostratum/httpc/breaker"
+	"github.com/gostratum/httpc/bulkhead"
+	"github.com/gostratum/httpc/cache"
+	"github.com/gostratum/httpc/chaos"
+	"github.com/gostratum/httpc/clock"
+	"github.com/gostratum/httpc/dns"
+	"github.com/gostratum/httpc/metrics"
+	"github.com/gostratum/httpc/ratelimit"
+	"github.com/gostratum/httpc/redact"
 	"github.com/gostratum/httpc/retry"
+	"github.com/gostratum/httpc/tracing"
 )
 
 // Config describes the runtime configuration for the HTTP client. It is
 // intended to be populated via configx and then optionally overridden via
 // functional options when constructing a client instance.
 type Config struct {
-	Env             string        `mapstructure:"env" default:"dev" validate:"oneof=dev prod"`
-	BaseURL         string        `mapstructure:"base_url"`
-	Timeout         time.Duration `mapstructure:"timeout" default:"10s"`
-	MaxIdleConns    int           `mapstructure:"max_idle_conns" default:"100"`
-	IdleConnTimeout time.Duration `mapstructure:"idle_conn_timeout" default:"90s"`
+	Env              string        `mapstructure:"env" default:"dev" validate:"oneof=dev staging prod"`
+	BaseURL          string        `mapstructure:"base_url"`                // ${VAR} references are expanded by NewConfig
+	URLJoin          string        `mapstructure:"url_join" default:"path"` // path|rfc3986, see URLJoinPath
+	Timeout          time.Duration `mapstructure:"timeout" default:"10s"`
+	TimeoutPolicy    string        `mapstructure:"timeout_policy" default:"min"` // min|override|ignore_client|headers, see TimeoutMinOf
+	MaxIdleConns     int           `mapstructure:"max_idle_conns" default:"100"`
+	IdleConnTimeout  time.Duration `mapstructure:"idle_conn_timeout" default:"90s"`
+	MaxConnLifetime  time.Duration `mapstructure:"max_conn_lifetime"`                  // zero keeps connections until idle timeout
+	BodySpoolLimit   int64         `mapstructure:"body_spool_limit" default:"8388608"` // bytes; negative disables spooling
+	MaxBufferedBytes int64         `mapstructure:"max_buffered_bytes"`                 // bytes across buffered responses; zero is unlimited
+	TranscodeCharset bool          `mapstructure:"transcode_charset"`                  // decode non-UTF-8 bodies in String and DecodeJSON
+	ErrorOnStatus    bool          `mapstructure:"error_on_status"`                    // return 4xx and 5xx responses as *HTTPError
+	Cookies          bool          `mapstructure:"cookies"`                            // keep cookies in an in-memory jar unless CookieJar is set
+
+	// Transport timeouts, applied to the default transport only.
+	// ResponseHeaderTimeout bounds the wait for response headers after the
+	// request is written, separately from Timeout; zero means no limit. For
+	// the other two a negative value means no limit (TLS) or sending the
+	// body without waiting for 100 Continue.
+	ResponseHeaderTimeout time.Duration `mapstructure:"response_header_timeout"`
+	TLSHandshakeTimeout   time.Duration `mapstructure:"tls_handshake_timeout" default:"10s"`
+	ExpectContinueTimeout time.Duration `mapstructure:"expect_continue_timeout" default:"1s"`
+
+	// Socket options of the default dialer. TCPKeepAlive is the idle time
+	// before keepalive probes start and the interval between them; negative
+	// disables probes. TCP_NODELAY is set on every connection unless
+	// DisableTCPNoDelay re-enables Nagle's algorithm.
+	TCPKeepAlive      time.Duration `mapstructure:"tcp_keepalive" default:"30s"`
+	DisableTCPNoDelay bool          `mapstructure:"disable_tcp_nodelay"`
+
+	// HTTP2PingInterval sends an HTTP/2 PING on connections that received
+	// no frame for that long, keeping long-running requests alive through
+	// idle-timeout proxies and detecting dead peers; zero disables pings.
+	// Connections whose PING is unanswered for HTTP2PingTimeout (15s when
+	// zero) are closed.
+	HTTP2PingInterval time.Duration `mapstructure:"http2_ping_interval"`
+	HTTP2PingTimeout  time.Duration `mapstructure:"http2_ping_timeout"`
+
+	// PreserveEncodedPath keeps percent-encoded sequences in request paths
+	// and path parameters as given, e.g. the %2F of GitLab project IDs,
+	// instead of escaping them again or decoding them.
+	PreserveEncodedPath bool `mapstructure:"preserve_encoded_path"`
+
+	// ForwardBaggage allowlists the WithBaggage keys sent on outbound
+	// requests, mapping each key to its header, e.g. tenant-id: X-Tenant-ID.
+	ForwardBaggage map[string]string `mapstructure:"forward_baggage"`
+
+	// JSONAccept is the Accept header WithJSON bodies send when the request
+	// sets none; "none" sends no Accept header.
+	JSONAccept string `mapstructure:"json_accept" default:"application/json"`
+
+	// CancelDrainBytes is how much of an in-flight response body is drained
+	// after the caller's context is cancelled, so the connection can be
+	// reused. Zero closes the connection on cancellation, as net/http does.
+	CancelDrainBytes   int64         `mapstructure:"cancel_drain_bytes"`
+	CancelDrainTimeout time.Duration `mapstructure:"cancel_drain_timeout" default:"1s"`
 
 	RetryEnabled     bool          `mapstructure:"retry_enabled" default:"true"`
 	RetryMaxAttempts int           `mapstructure:"retry_max_attempts" default:"3"`
@@ -29,17 +94,81 @@ type Config struct {
 
 	BreakerEnabled bool `mapstructure:"breaker_enabled" default:"false"`
 
+	// Built-in middlewares that operators can switch on per environment.
+	// cache_enabled uses the default cache settings unless WithCache is given.
+	LoggingEnabled bool `mapstructure:"logging_enabled" default:"false"`
+	MetricsEnabled bool `mapstructure:"metrics_enabled" default:"false"`
+	TracingEnabled bool `mapstructure:"tracing_enabled" default:"false"`
+	CacheEnabled   bool `mapstructure:"cache_enabled" default:"false"`
+
+	// LoggingHeaders adds the redacted request and response headers to each
+	// log line; LoggingBodyLimit logs up to that many bytes of each body,
+	// redacted where JSON or form encoded. Both need logging_enabled.
+	LoggingHeaders   bool `mapstructure:"logging_headers" default:"false"`
+	LoggingBodyLimit int  `mapstructure:"logging_body_limit" default:"0"`
+
+	// MethodOverride lists methods (e.g. PATCH, DELETE) always sent as POST
+	// with X-HTTP-Method-Override.
+	MethodOverride []string `mapstructure:"method_override"`
+
+	// AllowInsecureHTTP permits a plain http base_url when env is prod.
+	AllowInsecureHTTP bool `mapstructure:"allow_insecure_http"`
+
+	// ExpvarName publishes client internals via expvar under this name when
+	// set. Each client needs a distinct name.
+	ExpvarName string `mapstructure:"expvar_name"`
+
+	HealthCheck struct {
+		Enabled bool          `mapstructure:"enabled" default:"false"`
+		Path    string        `mapstructure:"path" default:"/health"`
+		Timeout time.Duration `mapstructure:"timeout" default:"2s"`
+	} `mapstructure:"health_check"`
+
 	APIKey struct {
 		Key  string `mapstructure:"key"`
 		In   string `mapstructure:"in" default:"header"` // header|query
 		Name string `mapstructure:"name" default:"X-API-Key"`
+		// SecondaryKey is tried once when Key is rejected with 401 or 403,
+		// so requests keep working while keys are rotated.
+		SecondaryKey string `mapstructure:"secondary_key"`
 	} `mapstructure:"api_key"`
 
+	APIVersion struct {
+		Version string `mapstructure:"version"`
+		In      string `mapstructure:"in" default:"header"` // header|media_type|query
+		Name    string `mapstructure:"name"`
+	} `mapstructure:"api_version"`
+
 	Basic struct {
 		Username string `mapstructure:"username"`
 		Password string `mapstructure:"password"`
 	} `mapstructure:"basic"`
 
+	SigV4 struct {
+		AccessKeyID     string `mapstructure:"access_key_id"`
+		SecretAccessKey string `mapstructure:"secret_access_key"`
+		SessionToken    string `mapstructure:"session_token"`
+		Region          string `mapstructure:"region" default:"us-east-1"`
+		Service         string `mapstructure:"service" default:"s3"`
+	} `mapstructure:"sigv4"`
+
+	Proxy struct {
+		URL      string            `mapstructure:"url"`
+		Username string            `mapstructure:"username"`
+		Password string            `mapstructure:"password"`
+		Headers  map[string]string `mapstructure:"headers"`
+	} `mapstructure:"proxy"`
+
+	OAuth2 struct {
+		TokenURL     string   `mapstructure:"token_url"`
+		ClientID     string   `mapstructure:"client_id"`
+		ClientSecret string   `mapstructure:"client_secret"`
+		Scopes       []string `mapstructure:"scopes"`
+		// CredentialsIn is where the client credentials go: header (HTTP
+		// Basic) or body (form parameters).
+		CredentialsIn string `mapstructure:"credentials_in" default:"header"`
+	} `mapstructure:"oauth2"`
+
 	JWT struct {
 		Alg        string        `mapstructure:"alg" default:"RS256"`
 		Issuer     string        `mapstructure:"issuer"`
@@ -50,24 +179,141 @@ type Config struct {
 		PrivatePEM string        `mapstructure:"private_pem"`
 	} `mapstructure:"jwt"`
 
+	// Profiles holds per-environment overlays keyed by Env, e.g.
+	// httpc.profiles.prod.timeout. The overlay matching Env is applied on top
+	// of the base settings by NewConfig.
+	Profiles map[string]map[string]any `mapstructure:"profiles"`
+
 	// Runtime-only fields set via functional options (ignored by config loader).
-	Transport   http.RoundTripper `mapstructure:"-"`
-	Logger      logx.Logger       `mapstructure:"-"`
-	DefaultAuth auth.AuthProvider `mapstructure:"-"`
-	RetryPolicy retry.Policy      `mapstructure:"-"`
-	Breaker     breaker.Manager   `mapstructure:"-"`
-	Middlewares []Middleware      `mapstructure:"-"`
-	HTTPClient  *http.Client      `mapstructure:"-"`
-	UserAgent   string            `mapstructure:"-"`
+	Transport    http.RoundTripper `mapstructure:"-"`
+	Logger       logx.Logger       `mapstructure:"-"`
+	DefaultAuth  auth.AuthProvider `mapstructure:"-"`
+	RetryPolicy  retry.Policy      `mapstructure:"-"`
+	Breaker      breaker.Manager   `mapstructure:"-"`
+	Middlewares  []Middleware      `mapstructure:"-"`
+	HTTPClient   *http.Client      `mapstructure:"-"`
+	UserAgent    string            `mapstructure:"-"`
+	Chaos        chaos.Config      `mapstructure:"-"`
+	Clock        clock.Clock       `mapstructure:"-"`
+	Bulkhead     *bulkhead.Config  `mapstructure:"-"`
+	RateLimiter  ratelimit.Limiter `mapstructure:"-"`
+	StartupProbe *StartupProbe     `mapstructure:"-"`
+	Redaction    *redact.Rules     `mapstructure:"-"`
+	Cache        *cache.Config     `mapstructure:"-"`
+	Metrics      metrics.Recorder  `mapstructure:"-"`
+	Tracer       tracing.Tracer    `mapstructure:"-"`
+	Resolver     dns.Resolver      `mapstructure:"-"`
+	CookieJar    http.CookieJar    `mapstructure:"-"`
+
+	BaggageLookup BaggageLookup `mapstructure:"-"`
+
+	RequestHooks  []RequestHook  `mapstructure:"-"`
+	ResponseHooks []ResponseHook `mapstructure:"-"`
+	ErrorHooks    []ErrorHook    `mapstructure:"-"`
+
+	RequestDefaults []ReqOption `mapstructure:"-"`
+	AuthRefresh     bool        `mapstructure:"-"`
+
+	// sharedRecycler accompanies a Transport built by a TenantClientFactory
+	// so derived clients keep recycling its connections.
+	sharedRecycler *connRecycler
 }
 
 // Prefix implements configx.Configurable.
 func (Config) Prefix() string { return "httpc" }
 
-// NewConfig loads the client configuration using the provided config loader.
+// NewConfig loads the client configuration using the provided config loader
+// and resolves the profile matching Env.
 func NewConfig(loader configx.Loader) (Config, error) {
 	var cfg Config
-	return cfg, loader.Bind(&cfg)
+	if err := loader.Bind(&cfg); err != nil {
+		return cfg, err
+	}
+	if err := cfg.applyProfile(); err != nil {
+		return cfg, err
+	}
+	if err := cfg.resolveBaseURL(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// resolveBaseURL expands ${VAR} references in BaseURL from the environment
+// and validates the result: an http or https scheme and a host are required,
+// and plain http is refused in prod unless AllowInsecureHTTP is set.
+func (c *Config) resolveBaseURL() error {
+	if c.BaseURL == "" {
+		return nil
+	}
+	var missing []string
+	expanded := os.Expand(c.BaseURL, func(name string) string {
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return fmt.Errorf("httpc base_url: environment variable %s is not set", strings.Join(missing, ", "))
+	}
+
+	u, err := url.Parse(expanded)
+	if err != nil {
+		return fmt.Errorf("httpc base_url %q: %w", expanded, err)
+	}
+	switch u.Scheme {
+	case "https":
+	case "http":
+		if c.Env == "prod" && !c.AllowInsecureHTTP {
+			return fmt.Errorf("httpc base_url %q: plain http is refused in prod; set allow_insecure_http to permit it", expanded)
+		}
+	default:
+		return fmt.Errorf("httpc base_url %q: scheme must be http or https", expanded)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("httpc base_url %q: missing host", expanded)
+	}
+	c.BaseURL = expanded
+	return nil
+}
+
+// applyProfile overlays the profile for c.Env. Only keys present in the
+// profile are changed.
+func (c *Config) applyProfile() error {
+	overlay, ok := c.Profiles[c.Env]
+	if !ok || len(overlay) == 0 {
+		return nil
+	}
+	if _, nested := overlay["profiles"]; nested {
+		return fmt.Errorf("httpc profile %q: profiles cannot be nested", c.Env)
+	}
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           c,
+		WeaklyTypedInput: true,
+		ErrorUnused:      true,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToWeakSliceHookFunc(","),
+		),
+	})
+	if err != nil {
+		return err
+	}
+	if err := decoder.Decode(overlay); err != nil {
+		return fmt.Errorf("httpc profile %q: %w", c.Env, err)
+	}
+	return nil
+}
+
+// redactor compiles the default redaction rules extended by any configured
+// via WithRedaction.
+func (c Config) redactor() *redact.Redactor {
+	rules := redact.DefaultRules()
+	if c.Redaction != nil {
+		rules = rules.Merge(*c.Redaction)
+	}
+	return redact.New(rules)
 }
 
 // applyDefaults ensures derived defaults that depend on other settings are set.
@@ -81,6 +327,30 @@ func (c *Config) applyDefaults() {
 	if c.IdleConnTimeout == 0 {
 		c.IdleConnTimeout = 90 * time.Second
 	}
+	if c.TLSHandshakeTimeout == 0 {
+		c.TLSHandshakeTimeout = 10 * time.Second
+	}
+	if c.ExpectContinueTimeout == 0 {
+		c.ExpectContinueTimeout = time.Second
+	}
+	if c.TCPKeepAlive == 0 {
+		c.TCPKeepAlive = 30 * time.Second
+	}
+	if c.BodySpoolLimit == 0 {
+		c.BodySpoolLimit = 8 << 20
+	}
+	if c.URLJoin == "" {
+		c.URLJoin = URLJoinPath
+	}
+	if c.TimeoutPolicy == "" {
+		c.TimeoutPolicy = TimeoutMinOf
+	}
+	if c.JSONAccept == "" {
+		c.JSONAccept = "application/json"
+	}
+	if c.CancelDrainTimeout == 0 {
+		c.CancelDrainTimeout = time.Second
+	}
 	if c.RetryMaxAttempts == 0 {
 		c.RetryMaxAttempts = 3
 	}
@@ -105,4 +375,19 @@ func (c *Config) applyDefaults() {
 	if c.APIKey.In == "" {
 		c.APIKey.In = "header"
 	}
+	if c.APIVersion.In == "" {
+		c.APIVersion.In = APIVersionInHeader
+	}
+	if c.SigV4.Region == "" {
+		c.SigV4.Region = "us-east-1"
+	}
+	if c.SigV4.Service == "" {
+		c.SigV4.Service = "s3"
+	}
+	if c.HealthCheck.Path == "" {
+		c.HealthCheck.Path = "/health"
+	}
+	if c.HealthCheck.Timeout == 0 {
+		c.HealthCheck.Timeout = 2 * time.Second
+	}
 }
@@ -0,0 +1,118 @@
+package httpc
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// connRecycler retires pooled connections once they are older than lifetime.
+// Connections idle in the pool when they expire are closed straight away,
+// which evicts them from the transport's pool; connections busy at that point
+// are closed as soon as the transport hands them back, so an in-flight
+// request is never cut short. HTTP/2 connections are multiplexed and never
+// reported idle, so they are only closed when idle at expiry.
+type connRecycler struct {
+	lifetime time.Duration
+}
+
+func newConnRecycler(lifetime time.Duration) *connRecycler {
+	return &connRecycler{lifetime: lifetime}
+}
+
+// dial wraps next so every connection it opens carries an expiry timer.
+func (r *connRecycler) dial(next dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		rc := &recycledConn{Conn: conn}
+		rc.mu.Lock()
+		rc.timer = time.AfterFunc(r.lifetime, rc.expire)
+		rc.mu.Unlock()
+		return rc, nil
+	}
+}
+
+// middleware tracks when the transport takes a connection out of the pool
+// and puts it back, so expired connections are closed only while idle.
+func (r *connRecycler) middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			var conn *recycledConn
+			ctx := httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+				GotConn: func(info httptrace.GotConnInfo) {
+					if conn = asRecycledConn(info.Conn); conn != nil {
+						conn.acquire()
+					}
+				},
+				PutIdleConn: func(err error) {
+					if conn != nil && err == nil {
+						conn.release()
+					}
+				},
+			})
+			return next.RoundTrip(req.WithContext(ctx))
+		})
+	}
+}
+
+func asRecycledConn(c net.Conn) *recycledConn {
+	if tc, ok := c.(*tls.Conn); ok {
+		c = tc.NetConn()
+	}
+	rc, _ := c.(*recycledConn)
+	return rc
+}
+
+type recycledConn struct {
+	net.Conn
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	busy    bool
+	expired bool
+}
+
+func (c *recycledConn) acquire() {
+	c.mu.Lock()
+	c.busy = true
+	c.mu.Unlock()
+}
+
+func (c *recycledConn) release() {
+	c.mu.Lock()
+	c.busy = false
+	expired := c.expired
+	c.mu.Unlock()
+	if expired {
+		_ = c.Close()
+	}
+}
+
+func (c *recycledConn) expire() {
+	c.mu.Lock()
+	c.expired = true
+	busy := c.busy
+	c.mu.Unlock()
+	if !busy {
+		_ = c.Close()
+	}
+}
+
+func (c *recycledConn) Close() error {
+	c.mu.Lock()
+	timer := c.timer
+	c.mu.Unlock()
+	if timer != nil {
+		timer.Stop()
+	}
+	return c.Conn.Close()
+}
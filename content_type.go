@@ -0,0 +1,88 @@
+package httpc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ErrUnexpectedContentType is matched (via errors.Is) by *ContentTypeError.
+var ErrUnexpectedContentType = errors.New("unexpected response content type")
+
+// ContentTypeError is returned when a response does not carry the media type
+// requested with WithExpectContentType, typically an HTML error page served by
+// a proxy or load balancer in place of the upstream API.
+type ContentTypeError struct {
+	Expected   string
+	Actual     string
+	StatusCode int
+	// Snippet holds the start of the response body to aid debugging.
+	Snippet string
+}
+
+func (e *ContentTypeError) Error() string {
+	actual := e.Actual
+	if actual == "" {
+		actual = "none"
+	}
+	return fmt.Sprintf("expected content type %s, got %s (status %d)", e.Expected, actual, e.StatusCode)
+}
+
+// Is reports ErrUnexpectedContentType.
+func (e *ContentTypeError) Is(target error) bool {
+	return target == ErrUnexpectedContentType
+}
+
+const contentTypeSnippetLimit = 512
+
+// checkContentType validates resp against the expected media type. Bodiless
+// responses (204, 304, HEAD) are accepted. On mismatch the body is consumed
+// and closed.
+func checkContentType(resp *http.Response, expected string) error {
+	if expected == "" || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	if resp.Request != nil && resp.Request.Method == http.MethodHead {
+		return nil
+	}
+
+	actual := resp.Header.Get("Content-Type")
+	if mediaTypeMatches(actual, expected) {
+		return nil
+	}
+
+	var snippet []byte
+	if resp.Body != nil {
+		snippet, _ = io.ReadAll(io.LimitReader(resp.Body, contentTypeSnippetLimit))
+		drainAndClose(resp.Body)
+	}
+	return &ContentTypeError{
+		Expected:   expected,
+		Actual:     actual,
+		StatusCode: resp.StatusCode,
+		Snippet:    string(snippet),
+	}
+}
+
+// mediaTypeMatches compares media types ignoring parameters and case. A
+// wildcard subtype such as "application/*" matches any subtype.
+func mediaTypeMatches(actual, expected string) bool {
+	got, _, err := mime.ParseMediaType(actual)
+	if err != nil {
+		return false
+	}
+	want, _, err := mime.ParseMediaType(expected)
+	if err != nil {
+		want = strings.ToLower(strings.TrimSpace(expected))
+	}
+	if got == want {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(want, "/*"); ok {
+		return strings.HasPrefix(got, prefix+"/")
+	}
+	return false
+}
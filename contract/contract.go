@@ -0,0 +1,321 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Request describes the request a consumer is expected to send. Only the
+// headers and query parameters listed are compared; a nil Body is not checked.
+type Request struct {
+	Method  string
+	Path    string
+	Query   url.Values
+	Headers map[string]string
+	Body    any
+}
+
+// Response describes the canned response served for a matching request.
+type Response struct {
+	Status  int
+	Headers map[string]string
+	Body    any
+}
+
+// Interaction pairs an expected request with the response the provider is
+// expected to return, in Pact terms.
+type Interaction struct {
+	Description   string
+	ProviderState string
+	Request       Request
+	Response      Response
+}
+
+// Pact records consumer expectations and serves them through a mock
+// transport so tests exercise the real client stack.
+type Pact struct {
+	consumer string
+	provider string
+
+	mu           sync.Mutex
+	interactions []*recorded
+	mismatches   []string
+}
+
+type recorded struct {
+	Interaction
+	hits int
+}
+
+// New creates an empty Pact between consumer and provider.
+func New(consumer, provider string) *Pact {
+	return &Pact{consumer: consumer, provider: provider}
+}
+
+// AddInteraction registers an expected interaction.
+func (p *Pact) AddInteraction(i Interaction) *Pact {
+	if i.Request.Method == "" {
+		i.Request.Method = http.MethodGet
+	}
+	i.Request.Method = strings.ToUpper(i.Request.Method)
+	if i.Response.Status == 0 {
+		i.Response.Status = http.StatusOK
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.interactions = append(p.interactions, &recorded{Interaction: i})
+	return p
+}
+
+// Transport returns a RoundTripper serving the registered interactions.
+// Requests that match none of them receive a 500 response and are reported
+// by Verify.
+func (p *Pact) Transport() http.RoundTripper {
+	return roundTripperFunc(p.roundTrip)
+}
+
+// Verify reports unexpected requests and interactions never exercised.
+func (p *Pact) Verify() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	problems := append([]string(nil), p.mismatches...)
+	for _, i := range p.interactions {
+		if i.hits == 0 {
+			problems = append(problems, fmt.Sprintf("interaction %q was never exercised", i.Description))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New("contract: " + strings.Join(problems, "; "))
+}
+
+// MarshalJSON renders the pact using the Pact v2 specification.
+func (p *Pact) MarshalJSON() ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	doc := pactFile{
+		Consumer: party{Name: p.consumer},
+		Provider: party{Name: p.provider},
+	}
+	doc.Metadata.PactSpecification.Version = "2.0.0"
+
+	for _, i := range p.interactions {
+		pi := pactInteraction{
+			Description:   i.Description,
+			ProviderState: i.ProviderState,
+			Request: pactRequest{
+				Method:  i.Request.Method,
+				Path:    i.Request.Path,
+				Query:   i.Request.Query.Encode(),
+				Headers: i.Request.Headers,
+				Body:    i.Request.Body,
+			},
+			Response: pactResponse{
+				Status:  i.Response.Status,
+				Headers: i.Response.Headers,
+				Body:    i.Response.Body,
+			},
+		}
+		doc.Interactions = append(doc.Interactions, pi)
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// WriteFile writes the pact to dir using the conventional
+// "<consumer>-<provider>.json" file name and returns the path.
+func (p *Pact) WriteFile(dir string) (string, error) {
+	data, err := p.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	name := filepath.Join(dir, fmt.Sprintf("%s-%s.json", p.consumer, p.provider))
+	return name, os.WriteFile(name, data, 0o644)
+}
+
+func (p *Pact) roundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var reasons []string
+	for _, i := range p.interactions {
+		reason := matchRequest(i.Request, req, body)
+		if reason == "" {
+			i.hits++
+			return buildResponse(req, i.Response)
+		}
+		reasons = append(reasons, fmt.Sprintf("%q: %s", i.Description, reason))
+	}
+
+	msg := fmt.Sprintf("unexpected request %s %s", req.Method, req.URL.RequestURI())
+	if len(reasons) > 0 {
+		msg += " (" + strings.Join(reasons, ", ") + ")"
+	}
+	p.mismatches = append(p.mismatches, msg)
+	return buildResponse(req, Response{
+		Status:  http.StatusInternalServerError,
+		Headers: map[string]string{"Content-Type": "text/plain; charset=utf-8"},
+		Body:    msg,
+	})
+}
+
+func matchRequest(expected Request, req *http.Request, body []byte) string {
+	if expected.Method != req.Method {
+		return "method " + req.Method
+	}
+	if expected.Path != req.URL.Path {
+		return "path " + req.URL.Path
+	}
+	query := req.URL.Query()
+	for k, want := range expected.Query {
+		if !reflect.DeepEqual(want, query[k]) {
+			return fmt.Sprintf("query %s=%v", k, query[k])
+		}
+	}
+	for k, want := range expected.Headers {
+		if got := req.Header.Get(k); got != want {
+			return fmt.Sprintf("header %s=%q", k, got)
+		}
+	}
+	if expected.Body != nil && !bodyEqual(expected.Body, body) {
+		return "body mismatch"
+	}
+	return ""
+}
+
+func bodyEqual(expected any, actual []byte) bool {
+	switch v := expected.(type) {
+	case []byte:
+		return bytes.Equal(v, actual)
+	case string:
+		if string(actual) == v {
+			return true
+		}
+	}
+
+	want, err := normalizeJSON(expected)
+	if err != nil {
+		return false
+	}
+	var got any
+	if err := json.Unmarshal(actual, &got); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(want, got)
+}
+
+// normalizeJSON round-trips v through encoding/json so structs, maps and
+// decoded documents compare equal.
+func normalizeJSON(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	return out, json.Unmarshal(b, &out)
+}
+
+func buildResponse(req *http.Request, r Response) (*http.Response, error) {
+	header := make(http.Header, len(r.Headers))
+	for k, v := range r.Headers {
+		header.Set(k, v)
+	}
+
+	var payload []byte
+	switch v := r.Body.(type) {
+	case nil:
+	case []byte:
+		payload = v
+	case string:
+		payload = []byte(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("contract: encode response body: %w", err)
+		}
+		payload = b
+		if header.Get("Content-Type") == "" {
+			header.Set("Content-Type", "application/json")
+		}
+	}
+
+	return &http.Response{
+		Status:        http.StatusText(r.Status),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(payload)),
+		ContentLength: int64(len(payload)),
+		Request:       req,
+	}, nil
+}
+
+type party struct {
+	Name string `json:"name"`
+}
+
+type pactFile struct {
+	Consumer     party             `json:"consumer"`
+	Provider     party             `json:"provider"`
+	Interactions []pactInteraction `json:"interactions"`
+	Metadata     struct {
+		PactSpecification struct {
+			Version string `json:"version"`
+		} `json:"pactSpecification"`
+	} `json:"metadata"`
+}
+
+type pactInteraction struct {
+	Description   string       `json:"description"`
+	ProviderState string       `json:"providerState,omitempty"`
+	Request       pactRequest  `json:"request"`
+	Response      pactResponse `json:"response"`
+}
+
+type pactRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Query   string            `json:"query,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    any               `json:"body,omitempty"`
+}
+
+type pactResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    any               `json:"body,omitempty"`
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
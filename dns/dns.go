@@ -0,0 +1,208 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gostratum/httpc/clock"
+)
+
+// Resolver resolves a host name to IP addresses.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// DoHConfig configures a DNS-over-HTTPS resolver speaking the JSON API
+// (application/dns-json) offered by Cloudflare, Google and most public DoH
+// services.
+type DoHConfig struct {
+	// Endpoint is the resolver URL, e.g. https://cloudflare-dns.com/dns-query.
+	// Use an IP-addressed or otherwise bootstrapped endpoint so resolving it
+	// does not depend on the untrusted local DNS.
+	Endpoint string
+	// Client issues the DoH queries. It must not itself use this resolver.
+	// Defaults to a client with a 5s timeout.
+	Client *http.Client
+	// MinTTL and MaxTTL clamp how long answers are cached. Defaults are 0
+	// (honour the record TTL) and 5 minutes.
+	MinTTL time.Duration
+	MaxTTL time.Duration
+	// Clock drives cache expiry; defaults to the real clock.
+	Clock clock.Clock
+}
+
+// ErrNoAddresses is returned when the resolver answers without A or AAAA
+// records.
+var ErrNoAddresses = errors.New("dns: no addresses")
+
+type doh struct {
+	cfg DoHConfig
+	clk clock.Clock
+
+	mu    sync.Mutex
+	cache map[string]cached
+}
+
+type cached struct {
+	addrs   []string
+	expires time.Time
+}
+
+// NewDoH returns a Resolver querying cfg.Endpoint over HTTPS.
+func NewDoH(cfg DoHConfig) (Resolver, error) {
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("dns: invalid DoH endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 5 * time.Second}
+	}
+	if cfg.MaxTTL <= 0 {
+		cfg.MaxTTL = 5 * time.Minute
+	}
+	return &doh{cfg: cfg, clk: clock.OrReal(cfg.Clock), cache: make(map[string]cached)}, nil
+}
+
+func (d *doh) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []string{host}, nil
+	}
+
+	d.mu.Lock()
+	if c, ok := d.cache[host]; ok && d.clk.Now().Before(c.expires) {
+		d.mu.Unlock()
+		return c.addrs, nil
+	}
+	d.mu.Unlock()
+
+	var addrs []string
+	var errs []error
+	minTTL := time.Duration(-1)
+	for _, qtype := range []string{"A", "AAAA"} {
+		got, ttl, err := d.query(ctx, host, qtype)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		addrs = append(addrs, got...)
+		if len(got) > 0 && (minTTL < 0 || ttl < minTTL) {
+			minTTL = ttl
+		}
+	}
+	if len(addrs) == 0 {
+		if len(errs) > 0 {
+			return nil, errors.Join(errs...)
+		}
+		return nil, fmt.Errorf("%w for %s", ErrNoAddresses, host)
+	}
+
+	ttl := min(max(minTTL, d.cfg.MinTTL), d.cfg.MaxTTL)
+	if ttl > 0 {
+		d.mu.Lock()
+		d.cache[host] = cached{addrs: addrs, expires: d.clk.Now().Add(ttl)}
+		d.mu.Unlock()
+	}
+	return addrs, nil
+}
+
+type dohAnswer struct {
+	Type int    `json:"type"`
+	TTL  int    `json:"TTL"`
+	Data string `json:"data"`
+}
+
+type dohResponse struct {
+	Status int         `json:"Status"`
+	Answer []dohAnswer `json:"Answer"`
+}
+
+const (
+	typeA    = 1
+	typeAAAA = 28
+)
+
+func (d *doh) query(ctx context.Context, host, qtype string) ([]string, time.Duration, error) {
+	u, _ := url.Parse(d.cfg.Endpoint)
+	q := u.Query()
+	q.Set("name", host)
+	q.Set("type", qtype)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "application/dns-json")
+
+	resp, err := d.cfg.Client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("dns: query %s %s: %w", qtype, host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("dns: query %s %s: status %d", qtype, host, resp.StatusCode)
+	}
+
+	var out dohResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, 0, fmt.Errorf("dns: decode %s %s: %w", qtype, host, err)
+	}
+	// Status 3 is NXDOMAIN; other non-zero codes are resolver failures.
+	if out.Status != 0 {
+		return nil, 0, fmt.Errorf("dns: query %s %s: rcode %d", qtype, host, out.Status)
+	}
+
+	var addrs []string
+	ttl := time.Duration(-1)
+	for _, a := range out.Answer {
+		if a.Type != typeA && a.Type != typeAAAA {
+			continue // CNAME chain entries
+		}
+		if net.ParseIP(a.Data) == nil {
+			continue
+		}
+		addrs = append(addrs, a.Data)
+		if recTTL := time.Duration(a.TTL) * time.Second; ttl < 0 || recTTL < ttl {
+			ttl = recTTL
+		}
+	}
+	return addrs, ttl, nil
+}
+
+// DialContext returns a dial function, suitable for http.Transport, that
+// resolves host names with r and tries each address in turn using dialer
+// (a zero net.Dialer when nil).
+func DialContext(r Resolver, dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := r.LookupHost(ctx, host)
+		if err != nil {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: &net.DNSError{Err: err.Error(), Name: host}}
+		}
+		var errs []error
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			errs = append(errs, err)
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		return nil, errors.Join(errs...)
+	}
+}
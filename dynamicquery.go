@@ -0,0 +1,49 @@
+package httpc
+
+import (
+	"context"
+	"net/http"
+)
+
+type dynamicQuery struct {
+	key   string
+	value func() string
+}
+
+type dynamicQueryKey struct{}
+
+// WithDynamicQuery sets query parameter key to the result of value, evaluated
+// for every attempt, so cache busters, timestamps and nonces are fresh on
+// each retry instead of repeating the first attempt's value.
+func WithDynamicQuery(key string, value func() string) ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.dynamicQuery = append(r.dynamicQuery, dynamicQuery{key: key, value: value})
+	}
+}
+
+// newDynamicQueryMiddleware applies WithDynamicQuery parameters. It sits below
+// the retry middleware so it runs once per attempt.
+func newDynamicQueryMiddleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			params, _ := req.Context().Value(dynamicQueryKey{}).([]dynamicQuery)
+			if len(params) == 0 {
+				return next.RoundTrip(req)
+			}
+			u := *req.URL
+			q := u.Query()
+			for _, p := range params {
+				q.Set(p.key, p.value())
+			}
+			u.RawQuery = q.Encode()
+			attempt := *req
+			attempt.URL = &u
+			return next.RoundTrip(&attempt)
+		})
+	}
+}
+
+func withDynamicQuery(ctx context.Context, params []dynamicQuery) context.Context {
+	return context.WithValue(ctx, dynamicQueryKey{}, params)
+}
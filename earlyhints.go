@@ -0,0 +1,115 @@
+package httpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"net/url"
+	"runtime/debug"
+	"strings"
+)
+
+// EarlyHint is a resource announced in a Link header of a 103 Early Hints
+// response, e.g. `</style.css>; rel=preload; as=style`.
+type EarlyHint struct {
+	// URL is the link target resolved against the request URL.
+	URL string
+	Rel string
+	As  string
+	// Params holds the remaining link parameters, lower-cased.
+	Params map[string]string
+}
+
+// WithEarlyHints calls fn with the Link targets of every 103 Early Hints
+// response received before the final response, so callers can start fetching
+// them, through the same client, while the server is still rendering. fn runs
+// on the transport goroutine and should hand work off rather than block.
+func WithEarlyHints(fn func(hints []EarlyHint)) ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.earlyHints = fn
+	}
+}
+
+// withEarlyHintsTrace registers fn for 103 responses to req.
+func withEarlyHintsTrace(ctx context.Context, req *http.Request, fn func([]EarlyHint)) context.Context {
+	base := req.URL
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) (err error) {
+			if code != http.StatusEarlyHints {
+				return nil
+			}
+			// fn runs on the transport's read goroutine, where a panic would
+			// take the process down; fail the request instead.
+			defer func() {
+				if v := recover(); v != nil {
+					err = &PanicError{Value: v, Stack: debug.Stack()}
+				}
+			}()
+			if hints := parseLinks(header.Values("Link"), base); len(hints) > 0 {
+				fn(hints)
+			}
+			return nil
+		},
+	})
+}
+
+// parseLinks parses RFC 8288 Link header values.
+func parseLinks(values []string, base *url.URL) []EarlyHint {
+	var hints []EarlyHint
+	for _, value := range values {
+		for _, link := range splitLinks(value) {
+			target, params, ok := strings.Cut(link, ">")
+			target = strings.TrimSpace(target)
+			if !ok || !strings.HasPrefix(target, "<") {
+				continue
+			}
+			hint := EarlyHint{URL: strings.TrimPrefix(target, "<")}
+			if ref, err := url.Parse(hint.URL); err == nil && base != nil {
+				hint.URL = base.ResolveReference(ref).String()
+			}
+			for _, param := range strings.Split(params, ";") {
+				name, val, _ := strings.Cut(strings.TrimSpace(param), "=")
+				name = strings.ToLower(strings.TrimSpace(name))
+				val = strings.Trim(strings.TrimSpace(val), `"`)
+				switch name {
+				case "":
+				case "rel":
+					hint.Rel = val
+				case "as":
+					hint.As = val
+				default:
+					if hint.Params == nil {
+						hint.Params = make(map[string]string)
+					}
+					hint.Params[name] = val
+				}
+			}
+			hints = append(hints, hint)
+		}
+	}
+	return hints
+}
+
+// splitLinks splits a Link header on the commas between links, ignoring
+// commas inside <...> targets and quoted parameter values.
+func splitLinks(value string) []string {
+	var out []string
+	var inTarget, inQuote bool
+	start := 0
+	for i, c := range value {
+		switch {
+		case c == '"' && !inTarget:
+			inQuote = !inQuote
+		case c == '<' && !inQuote:
+			inTarget = true
+		case c == '>' && !inQuote:
+			inTarget = false
+		case c == ',' && !inTarget && !inQuote:
+			out = append(out, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, value[start:])
+}
@@ -0,0 +1,60 @@
+package httpc
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// ETagStore remembers the last entity tag seen per URL for GetIfChanged.
+// Implementations must be safe for concurrent use.
+type ETagStore interface {
+	Get(url string) (etag string, ok bool)
+	Set(url, etag string)
+}
+
+// NewMemoryETagStore returns an in-process ETagStore.
+func NewMemoryETagStore() ETagStore {
+	return &memoryETagStore{tags: make(map[string]string)}
+}
+
+type memoryETagStore struct {
+	mu   sync.RWMutex
+	tags map[string]string
+}
+
+func (s *memoryETagStore) Get(url string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	etag, ok := s.tags[url]
+	return etag, ok
+}
+
+func (s *memoryETagStore) Set(url, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags[url] = etag
+}
+
+// GetIfChanged issues a conditional GET using the ETag stored for url. A 200
+// response updates the store; on 304 the returned Response reports
+// NotModified and carries no body.
+func (c *client) GetIfChanged(ctx context.Context, url string, store ETagStore, opts ...ReqOption) (*Response, error) {
+	if store != nil {
+		if etag, ok := store.Get(url); ok && etag != "" {
+			opts = append([]ReqOption{WithHeader("If-None-Match", etag)}, opts...)
+		}
+	}
+
+	resp, err := c.Get(ctx, url, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if store != nil && resp.StatusCode() == http.StatusOK {
+		if etag := resp.Header("ETag"); etag != "" {
+			store.Set(url, etag)
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,130 @@
+package experiment
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"net/http"
+	"strings"
+)
+
+// Variant is one arm of an experiment. Weight is its share of keys relative
+// to the other variants; variants with a zero or negative weight never get
+// assigned.
+type Variant struct {
+	Name   string
+	Weight int
+}
+
+// Experiment assigns every key to one of its variants.
+type Experiment struct {
+	// Name identifies the experiment and salts the hash, so assignments of
+	// the same key are independent across experiments.
+	Name string
+	// Header carries the assigned variant; defaults to "X-Experiment-"
+	// followed by Name.
+	Header   string
+	Variants []Variant
+}
+
+// Assign returns the variant of e for key, or "" when e has no variant with
+// a positive weight. The result depends only on the experiment name, the
+// variants and key, so every client and service instance agrees on it.
+func (e Experiment) Assign(key string) string {
+	var total uint64
+	for _, v := range e.Variants {
+		total += uint64(max(v.Weight, 0))
+	}
+	if total == 0 {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(e.Name + "\x00" + key))
+	bucket := binary.BigEndian.Uint64(sum[:8]) % total
+	for _, v := range e.Variants {
+		w := uint64(max(v.Weight, 0))
+		if bucket < w {
+			return v.Name
+		}
+		bucket -= w
+	}
+	return ""
+}
+
+func (e Experiment) header() string {
+	if e.Header != "" {
+		return e.Header
+	}
+	return "X-Experiment-" + e.Name
+}
+
+// Config lists the experiments the middleware assigns.
+type Config struct {
+	Experiments []Experiment
+	// Key returns the assignment key, e.g. the user ID, of a request's
+	// context. Defaults to the key set with WithKey. Requests without a key
+	// are sent without experiment headers.
+	Key func(ctx context.Context) (string, bool)
+}
+
+type keyCtx struct{}
+
+// WithKey sets the assignment key of the requests sent with ctx.
+func WithKey(ctx context.Context, key string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, keyCtx{}, key)
+}
+
+// KeyFromContext returns the key set with WithKey.
+func KeyFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	key, ok := ctx.Value(keyCtx{}).(string)
+	return key, ok && key != ""
+}
+
+// NewMiddleware sets one header per experiment naming the variant the
+// request's key is assigned to, for server-side A/B tests. Headers the
+// request already sets are left alone, so an explicit assignment wins.
+func NewMiddleware(cfg Config) func(http.RoundTripper) http.RoundTripper {
+	keyOf := cfg.Key
+	if keyOf == nil {
+		keyOf = KeyFromContext
+	}
+	experiments := append([]Experiment(nil), cfg.Experiments...)
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			key, ok := keyOf(req.Context())
+			if !ok || len(experiments) == 0 {
+				return next.RoundTrip(req)
+			}
+			cloned := false
+			for _, e := range experiments {
+				header := e.header()
+				if req.Header.Get(header) != "" {
+					continue
+				}
+				variant := e.Assign(key)
+				// A variant that would split the header is dropped rather
+				// than failing the request.
+				if variant == "" || strings.ContainsAny(variant, "\r\n") {
+					continue
+				}
+				if !cloned {
+					req = req.Clone(req.Context())
+					cloned = true
+				}
+				req.Header.Set(header, variant)
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,70 @@
+package httpc
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+
+	"github.com/gostratum/httpc/breaker"
+	"github.com/gostratum/httpc/metrics"
+)
+
+// expvarMu makes checking and publishing a name atomic; expvar.Publish
+// panics on duplicates.
+var expvarMu sync.Mutex
+
+// publishExpvar exposes the client's internals as a single expvar map under
+// name, so /debug/vars shows them without extra wiring.
+func (c *client) publishExpvar(name string) error {
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	if expvar.Get(name) != nil {
+		return fmt.Errorf("expvar %q is already published; use a distinct prefix per client", name)
+	}
+	expvar.Publish(name, expvar.Func(c.expvarSnapshot))
+	return nil
+}
+
+func (c *client) expvarSnapshot() any {
+	stats := c.stats.snapshot()
+
+	hosts := make(map[string]any, len(stats.Hosts))
+	for host, hs := range stats.Hosts {
+		hosts[host] = map[string]any{
+			"requests":            hs.Requests,
+			"errors":              hs.Errors,
+			"retried":             hs.Retried,
+			"error_rate":          hs.ErrorRate,
+			"retry_rate":          hs.RetryRate,
+			"p50_ms":              hs.P50.Seconds() * 1000,
+			"p90_ms":              hs.P90.Seconds() * 1000,
+			"p99_ms":              hs.P99.Seconds() * 1000,
+			"max_ms":              hs.Max.Seconds() * 1000,
+			"breaker_transitions": hs.BreakerTransitions,
+		}
+	}
+
+	breakers := map[string]string{}
+	if reporter, ok := c.breakerMgr.(breaker.StateReporter); ok {
+		for host, state := range reporter.States() {
+			breakers[host] = state.String()
+		}
+	}
+
+	out := map[string]any{
+		"base_url":  c.redactor.URLString(c.cfg.BaseURL),
+		"in_flight": stats.InFlight,
+		"pool": map[string]any{
+			"max_idle_conns":    c.cfg.MaxIdleConns,
+			"idle_conn_timeout": c.cfg.IdleConnTimeout.String(),
+			"conns_reused":      stats.ConnsReused,
+			"conns_new":         stats.ConnsNew,
+		},
+		"breakers": breakers,
+		"hosts":    hosts,
+	}
+	if reg, ok := c.cfg.Metrics.(*metrics.Registry); ok {
+		out["metrics"] = reg.Snapshot()
+	}
+	return out
+}
@@ -3,6 +3,12 @@ package httpc
 import (
 	"github.com/gostratum/core/configx"
 	"github.com/gostratum/core/logx"
+	"github.com/gostratum/httpc/auth"
+	"github.com/gostratum/httpc/breaker"
+	"github.com/gostratum/httpc/metrics"
+	"github.com/gostratum/httpc/ratelimit"
+	"github.com/gostratum/httpc/retry"
+	"github.com/gostratum/httpc/tracing"
 	"go.uber.org/fx"
 )
 
@@ -18,8 +24,23 @@ type FxParams struct {
 	fx.In
 
 	Config        Config
-	Logger        logx.Logger `optional:"true"`
-	CustomOptions []Option    `group:"httpc_options"`
+	Lifecycle     fx.Lifecycle `optional:"true"`
+	Logger        logx.Logger  `optional:"true"`
+	CustomOptions []Option     `group:"httpc_options"`
+
+	// Middlewares contributed to the group are appended to the transport
+	// chain in the order fx resolves them.
+	Middlewares []Middleware `group:"httpc_middlewares"`
+	// RetryPolicy and BreakerManager replace the config-derived defaults when
+	// provided under these names.
+	RetryPolicy    retry.Policy    `name:"httpc_retry_policy" optional:"true"`
+	BreakerManager breaker.Manager `name:"httpc_breaker_manager" optional:"true"`
+	// RateLimiter, when provided, limits every attempt per host.
+	RateLimiter ratelimit.Limiter `name:"httpc_rate_limiter" optional:"true"`
+	// Metrics, when provided, receives every request as with WithMetrics.
+	Metrics metrics.Recorder `name:"httpc_metrics" optional:"true"`
+	// Tracer, when provided, traces every attempt as with WithTracer.
+	Tracer tracing.Tracer `name:"httpc_tracer" optional:"true"`
 }
 
 // NewFx loads configuration via configx and constructs a Client suitable for fx.
@@ -31,11 +52,75 @@ func NewFx(params FxParams) (Client, error) {
 	if params.Logger != nil {
 		opts = append(opts, WithLogger(params.Logger))
 	}
+	if params.RetryPolicy != nil {
+		opts = append(opts, WithRetryPolicy(params.RetryPolicy))
+	}
+	if params.BreakerManager != nil {
+		opts = append(opts, WithBreakerManager(params.BreakerManager))
+	}
+	if params.RateLimiter != nil {
+		opts = append(opts, WithRateLimiter(params.RateLimiter))
+	}
+	if params.Metrics != nil {
+		opts = append(opts, WithMetrics(params.Metrics))
+	}
+	if params.Tracer != nil {
+		opts = append(opts, WithTracer(params.Tracer))
+	}
+	for _, mw := range params.Middlewares {
+		opts = append(opts, WithMiddleware(mw))
+	}
 	if len(params.CustomOptions) > 0 {
 		opts = append(opts, params.CustomOptions...)
 	}
+	if params.Lifecycle != nil {
+		opts = append(opts, deferStartupProbe(), func(c *Config) { c.AuthRefresh = false })
+	}
+
+	c, err := New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if impl, ok := c.(*client); ok && params.Lifecycle != nil {
+		// Start the token refresher first so the startup probe is authenticated
+		// with a prefetched token.
+		if r, ok := impl.cfg.DefaultAuth.(auth.Refresher); ok {
+			params.Lifecycle.Append(fx.Hook{OnStart: r.Start, OnStop: r.Stop})
+		}
+		if impl.cfg.StartupProbe != nil {
+			params.Lifecycle.Append(fx.Hook{OnStart: impl.probe})
+		}
+	}
+	return c, nil
+}
+
+// deferStartupProbe moves the startup probe from New to the fx OnStart hook.
+func deferStartupProbe() Option {
+	return func(c *Config) {
+		if c.StartupProbe != nil {
+			probe := *c.StartupProbe
+			probe.deferred = true
+			c.StartupProbe = &probe
+		}
+	}
+}
 
-	return New(opts...)
+// FxHealthParams captures dependencies for the optional health check.
+type FxHealthParams struct {
+	fx.In
+
+	Client Client
+	Config Config
+}
+
+// NewHealthCheckFx builds the client health check. Unless health_check.enabled
+// is set, the check only reports breaker states and does not ping upstream.
+func NewHealthCheckFx(params FxHealthParams) *HealthCheck {
+	opts := HealthCheckOptions{Timeout: params.Config.HealthCheck.Timeout}
+	if params.Config.HealthCheck.Enabled {
+		opts.Path = params.Config.HealthCheck.Path
+	}
+	return NewHealthCheck(params.Client, opts)
 }
 
 // NewConfigFx binds the Config using configx.
@@ -2,6 +2,8 @@ package httpcfx
 
 import (
 	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/breaker"
+	"github.com/gostratum/httpc/ratelimit"
 	"go.uber.org/fx"
 )
 
@@ -11,6 +13,70 @@ func Module() fx.Option {
 		fx.Provide(
 			httpc.NewConfigFx,
 			httpc.NewFx,
+			httpc.NewHealthCheckFx,
 		),
 	)
 }
+
+// AsOption annotates a constructor returning httpc.Option so its result joins
+// the httpc_options group.
+func AsOption(f any) any {
+	return fx.Annotate(f, fx.ResultTags(`group:"httpc_options"`))
+}
+
+// AsMiddleware annotates a constructor returning httpc.Middleware so its
+// result joins the httpc_middlewares group.
+func AsMiddleware(f any) any {
+	return fx.Annotate(f, fx.ResultTags(`group:"httpc_middlewares"`))
+}
+
+// AsRetryPolicy annotates a constructor returning retry.Policy so the client
+// uses it instead of the config-derived policy.
+func AsRetryPolicy(f any) any {
+	return fx.Annotate(f, fx.ResultTags(`name:"httpc_retry_policy"`))
+}
+
+// AsBreakerManager annotates a constructor returning breaker.Manager so the
+// client uses it instead of creating its own.
+func AsBreakerManager(f any) any {
+	return fx.Annotate(f, fx.ResultTags(`name:"httpc_breaker_manager"`))
+}
+
+// AsRateLimiter annotates a constructor returning ratelimit.Limiter so the
+// client limits its requests with it.
+func AsRateLimiter(f any) any {
+	return fx.Annotate(f, fx.ResultTags(`name:"httpc_rate_limiter"`))
+}
+
+// AsMetrics annotates a constructor returning metrics.Recorder, e.g. a
+// shared httpcprom.New recorder, so every client reports to it.
+func AsMetrics(f any) any {
+	return fx.Annotate(f, fx.ResultTags(`name:"httpc_metrics"`))
+}
+
+// AsTracer annotates a constructor returning tracing.Tracer so every client
+// traces its attempts with it. For OpenTelemetry, httpcotel.Module provides
+// one from the app's tracer provider.
+func AsTracer(f any) any {
+	return fx.Annotate(f, fx.ResultTags(`name:"httpc_tracer"`))
+}
+
+// SharedResilience provides one process-wide breaker.Manager and, when limit
+// is non-nil, one rate limiter. Every client built by httpc.NewFx in the app
+// uses them, so failures one client observes open the breaker for all
+// clients calling that host and they draw from the same request budget.
+// Clients still need breaker_enabled for the breaker to run.
+func SharedResilience(breakerCfg breaker.Config, limit *ratelimit.Config) fx.Option {
+	opts := []fx.Option{
+		fx.Provide(AsBreakerManager(func() breaker.Manager {
+			return breaker.NewManager(breakerCfg)
+		})),
+	}
+	if limit != nil {
+		limitCfg := *limit
+		opts = append(opts, fx.Provide(AsRateLimiter(func() ratelimit.Limiter {
+			return ratelimit.New(limitCfg)
+		})))
+	}
+	return fx.Options(opts...)
+}
@@ -0,0 +1,129 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// errGroupFailed is the cancellation cause of a CallGroup after a failure.
+var errGroupFailed = errors.New("httpc: call group failed")
+
+// CallGroup runs calls of a fan-out concurrently, like an errgroup: the
+// first failure cancels the context shared by the remaining calls, and Wait
+// reports every failure. Construct it with Group; a CallGroup is used once
+// and must not be copied.
+type CallGroup struct {
+	client Client
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	sem    chan struct{}
+
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	errs []error
+}
+
+// Group returns a CallGroup sending its calls with c under a context derived
+// from ctx, e.g. to load the parts of a dashboard from several services:
+//
+//	g := httpc.Group(ctx, client)
+//	g.SetLimit(4)
+//	g.Get("/users/1", &user)
+//	g.Get("/users/1/orders", &orders)
+//	if err := g.Wait(); err != nil {
+//		var httpErr *httpc.HTTPError
+//		if errors.As(err, &httpErr) { ... }
+//	}
+func Group(ctx context.Context, c Client) *CallGroup {
+	gctx, cancel := context.WithCancelCause(ctx)
+	return &CallGroup{client: c, ctx: gctx, cancel: cancel}
+}
+
+// SetLimit bounds the calls in flight to n; calls beyond it wait for a slot
+// without blocking the caller. Zero or a negative n removes the bound. It
+// must be called before the first call is scheduled.
+func (g *CallGroup) SetLimit(n int) {
+	if n > 0 {
+		g.sem = make(chan struct{}, n)
+	} else {
+		g.sem = nil
+	}
+}
+
+// Get schedules a GET request and decodes the JSON response into dest, a
+// pointer, following the rules of GetAs: a non-2xx status fails the call
+// with an *HTTPError. A nil dest only checks the status.
+func (g *CallGroup) Get(url string, dest any, opts ...ReqOption) {
+	g.Go(func(ctx context.Context) error {
+		resp, err := g.client.Get(ctx, url, opts...)
+		return decodeInto(g.client, resp, err, dest)
+	})
+}
+
+// Post schedules a POST request with body, as accepted by Client.Post, and
+// decodes the JSON response into dest like Get.
+func (g *CallGroup) Post(url string, body, dest any, opts ...ReqOption) {
+	g.Go(func(ctx context.Context) error {
+		resp, err := g.client.Post(ctx, url, body, opts...)
+		return decodeInto(g.client, resp, err, dest)
+	})
+}
+
+// Do schedules req and decodes the JSON response into dest like Get.
+func (g *CallGroup) Do(req *Request, dest any) {
+	g.Go(func(ctx context.Context) error {
+		resp, err := g.client.Do(ctx, req)
+		return decodeInto(g.client, resp, err, dest)
+	})
+}
+
+// Go schedules fn with the group's context, for calls that need more than
+// decoding a response. A non-nil error fails the group like a failed call.
+func (g *CallGroup) Go(fn func(ctx context.Context) error) {
+	g.mu.Lock()
+	slot := len(g.errs)
+	g.errs = append(g.errs, nil)
+	g.mu.Unlock()
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if g.sem != nil {
+			select {
+			case g.sem <- struct{}{}:
+				defer func() { <-g.sem }()
+			case <-g.ctx.Done():
+				g.record(slot, g.ctx.Err())
+				return
+			}
+		}
+		g.record(slot, fn(g.ctx))
+	}()
+}
+
+func (g *CallGroup) record(slot int, err error) {
+	if err == nil {
+		return
+	}
+	// Calls cancelled because another one failed are not failures of their
+	// own; the failure that cancelled them is reported instead.
+	if errors.Is(err, context.Canceled) && errors.Is(context.Cause(g.ctx), errGroupFailed) {
+		return
+	}
+	g.mu.Lock()
+	g.errs[slot] = err
+	g.mu.Unlock()
+	g.cancel(errGroupFailed)
+}
+
+// Wait blocks until every scheduled call has returned and reports their
+// failures joined with errors.Join, in scheduling order, or nil. Use
+// errors.As to reach an *HTTPError or *TransportError.
+func (g *CallGroup) Wait() error {
+	g.wg.Wait()
+	g.cancel(nil)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return errors.Join(g.errs...)
+}
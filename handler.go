@@ -0,0 +1,37 @@
+package httpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+)
+
+// handlerTransport serves requests from an in-process http.Handler instead of
+// the network. The full middleware chain still runs on top of it.
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t *handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	serverReq := req.Clone(req.Context())
+	if serverReq.Body == nil {
+		serverReq.Body = http.NoBody
+	}
+	serverReq.RequestURI = req.URL.RequestURI()
+	if serverReq.Host == "" {
+		serverReq.Host = req.URL.Host
+	}
+	if serverReq.RemoteAddr == "" {
+		serverReq.RemoteAddr = "192.0.2.1:1234"
+	}
+
+	rec := httptest.NewRecorder()
+	t.handler.ServeHTTP(rec, serverReq)
+
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
@@ -0,0 +1,66 @@
+package httpc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithHandler(t *testing.T) {
+	t.Run("serves_requests_in_process", func(t *testing.T) {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/users/1", r.URL.Path)
+			assert.Equal(t, "/users/1?expand=true", r.RequestURI)
+			assert.Equal(t, "api.internal", r.Host)
+			body, _ := io.ReadAll(r.Body)
+			assert.JSONEq(t, `{"name":"demo"}`, string(body))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":1}`))
+		})
+
+		client, err := New(
+			WithBaseURL("http://api.internal"),
+			WithHandler(handler),
+		)
+		require.NoError(t, err)
+
+		resp, err := client.Post(context.Background(), "/users/1", map[string]string{"name": "demo"},
+			WithQuery("expand", "true"),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode())
+
+		var out map[string]int
+		require.NoError(t, resp.DecodeJSON(&out))
+		assert.Equal(t, 1, out["id"])
+	})
+
+	t.Run("keeps_retry_middleware", func(t *testing.T) {
+		var calls int32
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+
+		client, err := New(
+			WithBaseURL("http://api.internal"),
+			WithHandler(handler),
+			WithRetry(true, 2),
+		)
+		require.NoError(t, err)
+
+		resp, err := client.Get(context.Background(), "/")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode())
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+}
@@ -0,0 +1,106 @@
+package httpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gostratum/httpc/breaker"
+	"github.com/sony/gobreaker"
+)
+
+// HealthCheckOptions configures a HealthCheck.
+type HealthCheckOptions struct {
+	// Name identifies the check in health reports. Defaults to "httpc".
+	Name string
+	// Path is requested with GET through the client; an empty path skips the
+	// ping and only breaker states are reported.
+	Path    string
+	Timeout time.Duration
+}
+
+// HealthCheck reports upstream connectivity by pinging an endpoint through
+// the client and inspecting breaker states. It follows the Name/Check shape
+// used by health registries so it can be registered directly.
+type HealthCheck struct {
+	client   Client
+	breakers breaker.Manager
+	opts     HealthCheckOptions
+}
+
+// NewHealthCheck builds a health check for c.
+func NewHealthCheck(c Client, opts HealthCheckOptions) *HealthCheck {
+	if opts.Name == "" {
+		opts.Name = "httpc"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 2 * time.Second
+	}
+	h := &HealthCheck{client: c, opts: opts}
+	if impl, ok := c.(*client); ok {
+		h.breakers = impl.breakerMgr
+	}
+	return h
+}
+
+// Name returns the check name.
+func (h *HealthCheck) Name() string { return h.opts.Name }
+
+// BreakerStates returns the state of every breaker created so far, keyed by
+// host. It is empty when the breaker is disabled.
+func (h *HealthCheck) BreakerStates() map[string]string {
+	states := make(map[string]string)
+	reporter, ok := h.breakers.(breaker.StateReporter)
+	if !ok {
+		return states
+	}
+	for host, state := range reporter.States() {
+		states[host] = state.String()
+	}
+	return states
+}
+
+// Check pings the configured endpoint and fails when it is unreachable,
+// answers with a 5xx status, or any breaker is open.
+func (h *HealthCheck) Check(ctx context.Context) error {
+	if reporter, ok := h.breakers.(breaker.StateReporter); ok {
+		var open []string
+		for host, state := range reporter.States() {
+			if state == gobreaker.StateOpen {
+				open = append(open, host)
+			}
+		}
+		if len(open) > 0 {
+			sort.Strings(open)
+			return fmt.Errorf("%s: circuit breaker open for %s", h.opts.Name, strings.Join(open, ", "))
+		}
+	}
+
+	if h.opts.Path == "" {
+		return nil
+	}
+
+	resp, err := h.client.Get(ctx, h.opts.Path,
+		WithRequestTimeout(h.opts.Timeout),
+		WithRequestRetry(noRetry{}),
+		WithRequestErrorOnStatus(false),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: ping %s: %w", h.opts.Name, h.opts.Path, err)
+	}
+	_, _ = resp.Bytes()
+	if resp.StatusCode() >= 500 {
+		return fmt.Errorf("%s: ping %s: status %d", h.opts.Name, h.opts.Path, resp.StatusCode())
+	}
+	return nil
+}
+
+// noRetry keeps health pings to a single attempt so they report quickly.
+type noRetry struct{}
+
+func (noRetry) ShouldRetry(*http.Request, *http.Response, error, int, bool) (time.Duration, bool) {
+	return 0, false
+}
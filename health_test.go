@@ -0,0 +1,82 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthCheck(t *testing.T) {
+	t.Run("healthy_upstream", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/health", r.URL.Path)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client, err := New(WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		check := NewHealthCheck(client, HealthCheckOptions{Path: "/health"})
+		assert.Equal(t, "httpc", check.Name())
+		assert.NoError(t, check.Check(context.Background()))
+	})
+
+	t.Run("unhealthy_status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		client, err := New(WithBaseURL(server.URL), WithRetry(true, 3))
+		require.NoError(t, err)
+
+		err = NewHealthCheck(client, HealthCheckOptions{Path: "/health"}).Check(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "status 503")
+	})
+
+	t.Run("status_based_under_error_on_status", func(t *testing.T) {
+		status := http.StatusNotFound
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		defer server.Close()
+
+		client, err := New(WithBaseURL(server.URL), WithErrorOnStatus())
+		require.NoError(t, err)
+		check := NewHealthCheck(client, HealthCheckOptions{Path: "/health"})
+
+		assert.NoError(t, check.Check(context.Background()), "a 4xx ping is still healthy")
+
+		status = http.StatusServiceUnavailable
+		err = check.Check(context.Background())
+		require.Error(t, err)
+		var httpErr *HTTPError
+		assert.False(t, errors.As(err, &httpErr))
+		assert.Contains(t, err.Error(), "status 503")
+	})
+
+	t.Run("reports_open_breakers", func(t *testing.T) {
+		failing := roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		})
+		client, err := New(WithTransport(failing), WithBreaker(true), WithRetry(false, 0))
+		require.NoError(t, err)
+
+		for i := 0; i < 5; i++ {
+			_, _ = client.Get(context.Background(), "http://payments.internal/")
+		}
+
+		check := NewHealthCheck(client, HealthCheckOptions{})
+		assert.Equal(t, map[string]string{"payments.internal": "open"}, check.BreakerStates())
+		err = check.Check(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "circuit breaker open for payments.internal")
+	})
+}
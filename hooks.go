@@ -0,0 +1,103 @@
+package httpc
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gostratum/httpc/clock"
+	"github.com/gostratum/httpc/retry"
+)
+
+// AttemptInfo describes one attempt of a request to the hooks.
+type AttemptInfo struct {
+	// Request is the attempt's request. Request hooks may set headers on it;
+	// response and error hooks must not modify it.
+	Request *http.Request
+	// Attempt is the 1-based attempt number.
+	Attempt int
+	// Start is when the attempt was sent; Duration is how long it took to
+	// get the response headers or fail, zero in request hooks.
+	Start    time.Time
+	Duration time.Duration
+	// Response is set for response hooks; its body is not read yet and must
+	// be left alone. Err is set for error hooks.
+	Response *http.Response
+	Err      error
+}
+
+// RequestHook runs before each attempt is sent, e.g. to stamp audit headers.
+// A non-nil error fails the attempt without sending it.
+type RequestHook func(info *AttemptInfo) error
+
+// ResponseHook runs for each attempt that received a response, whatever its
+// status.
+type ResponseHook func(info *AttemptInfo)
+
+// ErrorHook runs for each attempt that failed without a response, e.g. on a
+// connection error or timeout.
+type ErrorHook func(info *AttemptInfo)
+
+// WithRequestHook registers fn to run before every attempt, retries
+// included, after the request has been built, authenticated and traced.
+// Hooks run in registration order on the calling goroutine.
+func WithRequestHook(fn RequestHook) Option {
+	return func(c *Config) {
+		c.RequestHooks = append(c.RequestHooks, fn)
+	}
+}
+
+// WithResponseHook registers fn to run when an attempt receives response
+// headers, before the retry decision, e.g. for auditing.
+func WithResponseHook(fn ResponseHook) Option {
+	return func(c *Config) {
+		c.ResponseHooks = append(c.ResponseHooks, fn)
+	}
+}
+
+// WithErrorHook registers fn to run when an attempt fails with a transport
+// error. 4xx and 5xx responses go to the response hooks.
+func WithErrorHook(fn ErrorHook) Option {
+	return func(c *Config) {
+		c.ErrorHooks = append(c.ErrorHooks, fn)
+	}
+}
+
+// newHooksMiddleware runs the configured hooks around each attempt.
+func newHooksMiddleware(cfg Config) Middleware {
+	clk := clock.OrReal(cfg.Clock)
+	requestHooks := append([]RequestHook(nil), cfg.RequestHooks...)
+	responseHooks := append([]ResponseHook(nil), cfg.ResponseHooks...)
+	errorHooks := append([]ErrorHook(nil), cfg.ErrorHooks...)
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			info := &AttemptInfo{Request: req, Attempt: 1}
+			if st := retry.StatsFromContext(req.Context()); st != nil && st.Attempts > 0 {
+				info.Attempt = st.Attempts
+			}
+			if len(requestHooks) > 0 {
+				info.Request = req.Clone(req.Context())
+				for _, hook := range requestHooks {
+					if err := hook(info); err != nil {
+						return nil, err
+					}
+				}
+			}
+
+			info.Start = clk.Now()
+			resp, err := next.RoundTrip(info.Request)
+			info.Duration = clk.Now().Sub(info.Start)
+			if err != nil {
+				info.Err = err
+				for _, hook := range errorHooks {
+					hook(info)
+				}
+				return nil, err
+			}
+			info.Response = resp
+			for _, hook := range responseHooks {
+				hook(info)
+			}
+			return resp, nil
+		})
+	}
+}
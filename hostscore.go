@@ -0,0 +1,53 @@
+package httpc
+
+import "time"
+
+const (
+	// healthSmoothing weighs each new call in the per-host EWMAs; with 0.2 a
+	// host's score mostly reflects its last ten or so calls.
+	healthSmoothing = 0.2
+	// healthLatencyScale is the smoothed latency at which a host without
+	// errors scores 0.5.
+	healthLatencyScale = 250 * time.Millisecond
+)
+
+// HostHealth is the exponentially smoothed health of one host, a cheap
+// signal for choosing between endpoints (load balancing, hedging) that
+// reacts faster than Stats and, unlike the breaker, never rejects calls.
+type HostHealth struct {
+	// Score is in (0, 1]; higher is healthier. It is (1 - ErrorRate)
+	// scaled down as Latency grows: a host answering without errors in
+	// 250ms scores 0.5.
+	Score float64
+	// Latency is the smoothed time to response headers, including
+	// retries.
+	Latency time.Duration
+	// ErrorRate is the smoothed share of calls failing with a transport
+	// error or a 5xx response.
+	ErrorRate float64
+	// Samples counts the calls observed. A host without samples reports a
+	// Score of 1, so new endpoints get tried.
+	Samples int64
+}
+
+// observe folds one call into the smoothed values.
+func (h *HostHealth) observe(latency time.Duration, failed bool) {
+	errValue := 0.0
+	if failed {
+		errValue = 1
+	}
+	if h.Samples == 0 {
+		h.Latency, h.ErrorRate = latency, errValue
+	} else {
+		h.Latency += time.Duration(healthSmoothing * float64(latency-h.Latency))
+		h.ErrorRate += healthSmoothing * (errValue - h.ErrorRate)
+	}
+	h.Samples++
+	h.Score = (1 - h.ErrorRate) * float64(healthLatencyScale) / float64(healthLatencyScale+h.Latency)
+}
+
+// Health returns the smoothed health of host (as in the request URL, e.g.
+// "api.example.com" or "10.0.0.1:8080").
+func (c *client) Health(host string) HostHealth {
+	return c.stats.health(host)
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/gostratum/core/logx"
+	"github.com/gostratum/httpc/clock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -277,6 +278,150 @@ func TestClientTimeout(t *testing.T) {
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "context deadline exceeded")
 	})
+
+	slow := func(t *testing.T) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(100 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	t.Run("override_extends_client_timeout", func(t *testing.T) {
+		client, err := New(
+			WithBaseURL(slow(t).URL),
+			WithTimeout(50*time.Millisecond),
+			WithTimeoutPolicy(TimeoutOverride),
+		)
+		require.NoError(t, err)
+
+		_, err = client.Get(context.Background(), "/test")
+		require.Error(t, err, "the client timeout applies without a request timeout")
+
+		resp, err := client.Get(context.Background(), "/test", WithRequestTimeout(time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode())
+	})
+
+	t.Run("ignore_client", func(t *testing.T) {
+		client, err := New(
+			WithBaseURL(slow(t).URL),
+			WithTimeout(50*time.Millisecond),
+			WithTimeoutPolicy(TimeoutIgnoreClient),
+		)
+		require.NoError(t, err)
+
+		resp, err := client.Get(context.Background(), "/test")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err = client.Get(ctx, "/test")
+		require.Error(t, err, "context deadlines still apply")
+	})
+
+	t.Run("request_timeout_spares_streamed_body", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("first,"))
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+			w.Write([]byte("second"))
+		}))
+		defer server.Close()
+
+		client, err := New(WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		resp, err := client.Get(context.Background(), "/stream", WithRequestTimeout(time.Second))
+		require.NoError(t, err)
+		body, err := resp.String()
+		require.NoError(t, err)
+		assert.Equal(t, "first,second", body)
+	})
+
+	t.Run("headers_spares_streamed_body", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				time.Sleep(100 * time.Millisecond)
+			}
+			w.Write([]byte("first,"))
+			w.(http.Flusher).Flush()
+			time.Sleep(100 * time.Millisecond)
+			w.Write([]byte("second"))
+		}))
+		defer server.Close()
+
+		client, err := New(
+			WithBaseURL(server.URL),
+			WithTimeout(50*time.Millisecond),
+			WithTimeoutPolicy(TimeoutHeaders),
+			WithRetry(false, 0),
+		)
+		require.NoError(t, err)
+
+		resp, err := client.Get(context.Background(), "/stream")
+		require.NoError(t, err)
+		body, err := resp.String()
+		require.NoError(t, err)
+		assert.Equal(t, "first,second", body)
+
+		_, err = client.Get(context.Background(), "/slow")
+		require.Error(t, err)
+		assert.Equal(t, CodeTimeout, ClassifyTransportError(err))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("headers_follows_clock", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			w.Write([]byte("ok"))
+		}))
+		defer server.Close()
+
+		fake := clock.NewFake(time.Unix(0, 0))
+		client, err := New(
+			WithBaseURL(server.URL),
+			WithTimeout(10*time.Millisecond),
+			WithTimeoutPolicy(TimeoutHeaders),
+			WithRetry(false, 0),
+			WithClock(fake),
+		)
+		require.NoError(t, err)
+
+		// Real time past the timeout does not expire the deadline.
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			release <- struct{}{}
+		}()
+		resp, err := client.Get(context.Background(), "/")
+		require.NoError(t, err)
+		body, err := resp.String()
+		require.NoError(t, err)
+		assert.Equal(t, "ok", body)
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := client.Get(context.Background(), "/")
+			done <- err
+		}()
+		fake.BlockUntil(1)
+		fake.Advance(10 * time.Millisecond)
+		err = <-done
+		require.Error(t, err)
+		assert.Equal(t, CodeTimeout, ClassifyTransportError(err))
+	})
+
+	t.Run("rejects_unknown_policy", func(t *testing.T) {
+		_, err := New(WithTimeoutPolicy("max"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "timeout_policy")
+	})
 }
 
 func TestClientContextCancellation(t *testing.T) {
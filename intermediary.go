@@ -0,0 +1,115 @@
+package httpc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// ErrInfrastructure is matched (via errors.Is) by *InfrastructureError.
+var ErrInfrastructure = errors.New("intermediary error page")
+
+// InfrastructureError describes an HTML error page produced by a load
+// balancer, CDN or proxy in front of the upstream on 502/503/504, as opposed
+// to an error returned by the upstream API itself.
+type InfrastructureError struct {
+	StatusCode int
+	// Source names the intermediary when recognised ("cloudflare", "nginx",
+	// "aws-elb", ...), otherwise it is empty.
+	Source string
+	// Title is the page <title>; Text is the visible text, truncated.
+	Title string
+	Text  string
+}
+
+func (e *InfrastructureError) Error() string {
+	msg := fmt.Sprintf("status %d from intermediary", e.StatusCode)
+	if e.Source != "" {
+		msg += " " + e.Source
+	}
+	if summary := choose(e.Title, e.Text); summary != "" {
+		msg += ": " + summary
+	}
+	return msg
+}
+
+// Is reports ErrInfrastructure.
+func (e *InfrastructureError) Is(target error) bool {
+	return target == ErrInfrastructure
+}
+
+const infrastructureTextLimit = 300
+
+var (
+	htmlTitlePattern  = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	htmlStripPattern  = regexp.MustCompile(`(?is)<(script|style|head)[^>]*>.*?</(script|style|head)>`)
+	htmlTagPattern    = regexp.MustCompile(`(?s)<[^>]*>`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// intermediarySignatures maps markers found in the Server header or body to
+// a source name. Checked in order.
+var intermediarySignatures = []struct {
+	marker string
+	source string
+}{
+	{"cloudflare", "cloudflare"},
+	{"awselb", "aws-elb"},
+	{"cloudfront", "cloudfront"},
+	{"envoy", "envoy"},
+	{"haproxy", "haproxy"},
+	{"varnish", "varnish"},
+	{"akamai", "akamai"},
+	{"google frontend", "google-frontend"},
+	{"nginx", "nginx"},
+}
+
+// classifyIntermediary returns an *InfrastructureError when a 502/503/504
+// response carries an HTML body, or nil otherwise.
+func classifyIntermediary(statusCode int, header http.Header, body []byte) *InfrastructureError {
+	switch statusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+	default:
+		return nil
+	}
+	if !isHTML(header.Get("Content-Type"), body) {
+		return nil
+	}
+
+	e := &InfrastructureError{StatusCode: statusCode}
+	if m := htmlTitlePattern.FindSubmatch(body); m != nil {
+		e.Title = cleanText(m[1])
+	}
+	text := htmlStripPattern.ReplaceAll(body, nil)
+	e.Text = cleanText(htmlTagPattern.ReplaceAll(text, []byte(" ")))
+	if len(e.Text) > infrastructureTextLimit {
+		e.Text = e.Text[:infrastructureTextLimit] + "..."
+	}
+
+	haystack := strings.ToLower(header.Get("Server") + " " + header.Get("Via") + " " + string(body))
+	for _, sig := range intermediarySignatures {
+		if strings.Contains(haystack, sig.marker) {
+			e.Source = sig.source
+			break
+		}
+	}
+	return e
+}
+
+func isHTML(contentType string, body []byte) bool {
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mt == "text/html" || mt == "application/xhtml+xml"
+	}
+	head := bytes.ToLower(bytes.TrimSpace(body))
+	return bytes.HasPrefix(head, []byte("<!doctype html")) || bytes.HasPrefix(head, []byte("<html"))
+}
+
+func cleanText(b []byte) string {
+	s := html.UnescapeString(string(b))
+	return strings.TrimSpace(whitespacePattern.ReplaceAllString(s, " "))
+}
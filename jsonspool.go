@@ -0,0 +1,93 @@
+package httpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// WithJSONSpool sends v as JSON without keeping the encoding in memory for
+// the lifetime of the Request, for bulk payloads where WithJSON would pin
+// the whole document. Each attempt encodes v afresh into a spool holding up
+// to memLimit bytes in memory and the rest in a temporary file, removed
+// when the body is closed, so Content-Length is known and retries and
+// GetBody stay safe; as with WithBodySpoolLimit, a non-positive memLimit
+// keeps everything in memory. Slices and arrays are encoded one element at
+// a time, bounding peak memory by the largest element rather than the
+// document. v must not change while requests using it are in flight.
+func WithJSONSpool(v any, memLimit int64) ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.setBody("WithJSONSpool", func() (io.ReadCloser, int64, string, error) {
+			pr, pw := io.Pipe()
+			go func() {
+				pw.CloseWithError(encodeJSONTo(pw, v))
+			}()
+			factory, cleanup, err := spoolBody(pr, memLimit)
+			_ = pr.Close()
+			if err != nil {
+				return nil, 0, "", fmt.Errorf("encode json: %w", err)
+			}
+			rc, n, _, _ := factory()
+			return struct {
+				io.Reader
+				io.Closer
+			}{rc, closerFunc(cleanup)}, n, "application/json", nil
+		})
+		r.jsonBody = true
+	}
+}
+
+// encodeJSONTo writes the same bytes as json.Marshal(v), streaming the
+// elements of slices and arrays individually.
+func encodeJSONTo(w io.Writer, v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	_, marshaler := v.(json.Marshaler)
+	streamable := !marshaler && (rv.Kind() == reflect.Array ||
+		rv.Kind() == reflect.Slice && !rv.IsNil() && rv.Type().Elem().Kind() != reflect.Uint8)
+	if !streamable {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	}
+
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for i := range rv.Len() {
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		elem := rv.Index(i)
+		if elem.CanAddr() {
+			// Keeps pointer-receiver MarshalJSON methods in play, as for
+			// json.Marshal.
+			elem = elem.Addr()
+		}
+		b, err := json.Marshal(elem.Interface())
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "]")
+	return err
+}
+
+type closerFunc func()
+
+func (f closerFunc) Close() error {
+	f()
+	return nil
+}
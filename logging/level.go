@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Level selects the logger method used for a request's log lines.
+type Level int8
+
+const (
+	// LevelDefault leaves the choice to the emitting middleware.
+	LevelDefault Level = iota
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+	// LevelOff suppresses the request's log lines.
+	LevelOff
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	case LevelOff:
+		return "off"
+	default:
+		return "default"
+	}
+}
+
+// ParseLevel parses debug, info, warn, error or off (case-insensitive). An
+// empty string yields LevelDefault.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "default":
+		return LevelDefault, nil
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	case "off", "none":
+		return LevelOff, nil
+	}
+	return LevelDefault, fmt.Errorf("unknown log level %q", s)
+}
+
+// Or returns l, or def when l is LevelDefault.
+func (l Level) Or(def Level) Level {
+	if l == LevelDefault {
+		return def
+	}
+	return l
+}
+
+// Select returns the logger method matching l, e.g.
+// Select(lvl, logger.Debug, logger.Info, logger.Warn, logger.Error). It
+// returns the zero value (nil) for LevelOff and LevelDefault.
+func Select[F any](l Level, debug, info, warn, errorf F) F {
+	switch l {
+	case LevelDebug:
+		return debug
+	case LevelInfo:
+		return info
+	case LevelWarn:
+		return warn
+	case LevelError:
+		return errorf
+	}
+	var none F
+	return none
+}
+
+type levelKey struct{}
+
+// WithLevel overrides the log level for the request carrying ctx.
+func WithLevel(ctx context.Context, l Level) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, levelKey{}, l)
+}
+
+// LevelFromContext returns the level set with WithLevel, or LevelDefault.
+func LevelFromContext(ctx context.Context) Level {
+	if ctx == nil {
+		return LevelDefault
+	}
+	l, _ := ctx.Value(levelKey{}).(Level)
+	return l
+}
@@ -0,0 +1,236 @@
+package logging
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gostratum/core/logx"
+	"github.com/gostratum/httpc/clock"
+	"github.com/gostratum/httpc/redact"
+)
+
+// MiddlewareOption customises the request logging middleware.
+type MiddlewareOption func(*middlewareOptions)
+
+type middlewareOptions struct {
+	clock     clock.Clock
+	redactor  *redact.Redactor
+	attempts  func(*http.Request) int
+	headers   bool
+	bodyLimit int
+}
+
+// WithClock sets the clock used to measure request durations.
+func WithClock(c clock.Clock) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.clock = c
+	}
+}
+
+// WithRedactor sets the redactor applied to logged URLs, headers and bodies.
+// Defaults to redact.Default().
+func WithRedactor(r *redact.Redactor) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.redactor = r
+	}
+}
+
+// WithAttempts reports how many attempts a request took, logged as
+// "attempts" when positive; the client passes its retry count.
+func WithAttempts(fn func(*http.Request) int) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.attempts = fn
+	}
+}
+
+// WithHeaders logs the request and response headers, masked by the
+// redactor.
+func WithHeaders() MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.headers = true
+	}
+}
+
+// WithBodies logs up to limit bytes of the request and response bodies.
+// JSON and form bodies are masked by the redactor; a truncated JSON body
+// cannot be, so only its size is logged. Streamed request bodies without
+// GetBody are skipped. A response with a body is logged once the body has
+// been read to the end or closed.
+func WithBodies(limit int) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.bodyLimit = limit
+	}
+}
+
+// NewMiddleware logs one line per request once its response headers arrive
+// or it fails, with the method, redacted URL, status and duration. Lines use
+// the level set with WithLevel; otherwise successes are logged at debug and
+// transport errors and 5xx responses at warn.
+func NewMiddleware(logger logx.Logger, opts ...MiddlewareOption) func(http.RoundTripper) http.RoundTripper {
+	if logger == nil {
+		logger = logx.NewNoopLogger()
+	}
+	var mo middlewareOptions
+	for _, opt := range opts {
+		opt(&mo)
+	}
+	clk := clock.OrReal(mo.clock)
+	redactor := mo.redactor
+	if redactor == nil {
+		redactor = redact.Default()
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := clk.Now()
+			resp, err := next.RoundTrip(req)
+			elapsed := clk.Now().Sub(start)
+
+			failed := err != nil || resp.StatusCode >= 500
+			def := LevelDebug
+			if failed {
+				def = LevelWarn
+			}
+			level := LevelFromContext(req.Context()).Or(def)
+			logf := Select(level, logger.Debug, logger.Info, logger.Warn, logger.Error)
+			if logf == nil {
+				return resp, err
+			}
+			fields := fieldList(
+				logx.String("method", req.Method),
+				logx.String("url", redactor.Request(req)),
+				logx.String("duration", elapsed.String()),
+			)
+			if mo.attempts != nil {
+				if n := mo.attempts(req); n > 0 {
+					fields = append(fields, logx.Int("attempts", n))
+				}
+			}
+			if mo.headers {
+				fields = append(fields, logx.String("request_headers", formatHeader(redactor.Header(req.Header))))
+			}
+			if mo.bodyLimit > 0 {
+				if body, ok := requestBody(req, mo.bodyLimit); ok {
+					fields = append(fields, logx.String("request_body", redactBody(redactor, req.Header, body, mo.bodyLimit)))
+				}
+			}
+			if err != nil {
+				logf("http request failed", append(fields, logx.String("error", err.Error()))...)
+				return resp, err
+			}
+
+			fields = append(fields, logx.Int("status", resp.StatusCode))
+			if mo.headers {
+				fields = append(fields, logx.String("response_headers", formatHeader(redactor.Header(resp.Header))))
+			}
+			if mo.bodyLimit <= 0 || resp.Body == nil || resp.Body == http.NoBody {
+				logf("http request", fields...)
+				return resp, nil
+			}
+			body := &capturingBody{ReadCloser: resp.Body, limit: mo.bodyLimit}
+			body.done = func() {
+				logf("http request", append(fields,
+					logx.String("response_body", redactBody(redactor, resp.Header, body.buf.Bytes(), mo.bodyLimit)))...)
+			}
+			resp.Body = body
+			return resp, nil
+		})
+	}
+}
+
+// fieldList collects log fields without naming their type.
+func fieldList[F any](fields ...F) []F {
+	return fields
+}
+
+// requestBody reads up to limit+1 bytes from a fresh copy of the request
+// body, leaving the one being sent untouched.
+func requestBody(req *http.Request, limit int) ([]byte, bool) {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody == nil {
+		return nil, false
+	}
+	rc, err := req.GetBody()
+	if err != nil || rc == nil {
+		return nil, false
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(io.LimitReader(rc, int64(limit)+1))
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// redactBody renders a body captured with up to limit+1 bytes; the extra
+// byte tells whether it was truncated.
+func redactBody(redactor *redact.Redactor, h http.Header, body []byte, limit int) string {
+	truncated := len(body) > limit
+	if truncated {
+		body = body[:limit]
+	}
+	contentType := strings.ToLower(h.Get("Content-Type"))
+	switch {
+	case strings.Contains(contentType, "json"):
+		if truncated {
+			return "[over " + strconv.Itoa(limit) + " bytes of JSON omitted]"
+		}
+		body = redactor.JSON(body)
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		body = []byte(redactor.Query(string(body)))
+	}
+	if truncated {
+		return string(body) + "..."
+	}
+	return string(body)
+}
+
+// formatHeader renders h sorted by name, for stable log lines.
+func formatHeader(h http.Header) string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + ": " + strings.Join(h[k], ", ")
+	}
+	return strings.Join(parts, "; ")
+}
+
+// capturingBody keeps the first limit+1 bytes read through it and calls
+// done once, at EOF or Close.
+type capturingBody struct {
+	io.ReadCloser
+	limit int
+	buf   bytes.Buffer
+	done  func()
+	once  sync.Once
+}
+
+func (b *capturingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := b.limit + 1 - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(n, room)])
+	}
+	if err == io.EOF {
+		b.once.Do(b.done)
+	}
+	return n, err
+}
+
+func (b *capturingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
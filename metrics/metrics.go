@@ -0,0 +1,289 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gostratum/httpc/clock"
+	"github.com/gostratum/httpc/redact"
+	"github.com/gostratum/httpc/retry"
+)
+
+// Observation describes one completed request, including its retries.
+type Observation struct {
+	Host string
+	// Operation is the request's path template (e.g. "/users/{id}") when it
+	// was built from one, otherwise empty, so raw paths never become label
+	// values.
+	Operation  string
+	Method     string
+	StatusCode int
+	Err        error
+	Duration   time.Duration
+	// Attempts is the number of attempts sent, including the first one.
+	Attempts int
+
+	// RequestBytes is the size of the request body as sent, after any
+	// request compression.
+	RequestBytes int64
+	// ResponseBytes counts the response body bytes read by the caller, after
+	// decompression. ResponseWireBytes is the Content-Length received, i.e.
+	// the compressed size for encoded responses, or -1 when unknown.
+	ResponseBytes     int64
+	ResponseWireBytes int64
+}
+
+// Failed reports a transport error or a 5xx response.
+func (o Observation) Failed() bool {
+	return o.Err != nil || o.StatusCode >= 500
+}
+
+// Recorder receives an Observation per request, e.g. to feed Prometheus or
+// OpenTelemetry instruments. Implementations must be safe for concurrent use.
+type Recorder interface {
+	Observe(o Observation)
+}
+
+// RecorderFunc adapts a function into a Recorder.
+type RecorderFunc func(o Observation)
+
+func (f RecorderFunc) Observe(o Observation) { f(o) }
+
+// InFlightRecorder is a Recorder that also tracks requests in flight:
+// NewMiddleware calls Begin as a request starts and End as soon as its round
+// trip returns, whether or not the response body is ever closed.
+type InFlightRecorder interface {
+	Recorder
+	Begin(host, method string)
+	End(host, method string)
+}
+
+// BreakerRecorder is a Recorder that also tracks circuit breaker states. A
+// client calls BreakerState with "closed", "half-open" or "open" whenever
+// the breaker it creates for a host changes state; breaker managers given
+// with WithBreakerManager are not observed.
+type BreakerRecorder interface {
+	Recorder
+	BreakerState(host, state string)
+}
+
+// NewMiddleware reports every request passing through it to rec. The
+// duration runs until the response headers arrive and, placed outside the
+// retry middleware, includes retries. Requests with a response body are
+// observed once the body has been read to the end or closed, so their sizes
+// are known; bodies that are never closed are never observed. clk may be
+// nil for the real clock.
+func NewMiddleware(rec Recorder, clk clock.Clock) func(http.RoundTripper) http.RoundTripper {
+	clk = clock.OrReal(clk)
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			var sent *countingBody
+			if req.ContentLength < 0 && req.Body != nil && req.Body != http.NoBody {
+				// Streamed bodies of unknown length are counted as sent.
+				sent = &countingBody{ReadCloser: req.Body}
+				req = req.Clone(req.Context())
+				req.Body = sent
+			}
+
+			ifr, _ := rec.(InFlightRecorder)
+			if ifr != nil {
+				ifr.Begin(req.URL.Host, req.Method)
+			}
+			start := clk.Now()
+			resp, err := next.RoundTrip(req)
+			if ifr != nil {
+				ifr.End(req.URL.Host, req.Method)
+			}
+			o := Observation{
+				Host:              req.URL.Host,
+				Operation:         redact.PathTemplate(req.Context()),
+				Method:            req.Method,
+				Err:               err,
+				Duration:          clk.Now().Sub(start),
+				Attempts:          1,
+				RequestBytes:      max(req.ContentLength, 0),
+				ResponseWireBytes: -1,
+			}
+			if st := retry.StatsFromContext(req.Context()); st != nil && st.Attempts > 1 {
+				o.Attempts = st.Attempts
+			}
+			if sent != nil {
+				o.RequestBytes = sent.n
+			}
+			if resp == nil || resp.Body == nil || resp.Body == http.NoBody {
+				if resp != nil {
+					o.StatusCode = resp.StatusCode
+					o.ResponseWireBytes = max(resp.ContentLength, 0)
+				}
+				rec.Observe(o)
+				return resp, err
+			}
+
+			o.StatusCode = resp.StatusCode
+			o.ResponseWireBytes = resp.ContentLength
+			body := &countingBody{ReadCloser: resp.Body}
+			body.done = func() {
+				o.ResponseBytes = body.n
+				rec.Observe(o)
+			}
+			resp.Body = body
+			return resp, nil
+		})
+	}
+}
+
+// countingBody counts the bytes read through it and calls done once, at EOF
+// or Close.
+type countingBody struct {
+	io.ReadCloser
+	n    int64
+	done func()
+	once sync.Once
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish()
+	return err
+}
+
+func (b *countingBody) finish() {
+	if b.done != nil {
+		b.once.Do(b.done)
+	}
+}
+
+// DefaultDurationBuckets are the upper bounds, in seconds, of the latency
+// histogram kept by Registry.
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// DefaultSizeBuckets are the upper bounds, in bytes, of the body size
+// histograms kept by Registry.
+var DefaultSizeBuckets = []float64{256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20}
+
+// Histogram counts observations into buckets with the given upper bounds.
+// Counts has one entry per bound plus a final overflow bucket; counts are
+// not cumulative.
+type Histogram struct {
+	Bounds []float64 `json:"bounds"`
+	Counts []int64   `json:"counts"`
+	Count  int64     `json:"count"`
+	Sum    float64   `json:"sum"`
+}
+
+func newHistogram(bounds []float64) Histogram {
+	return Histogram{Bounds: bounds, Counts: make([]int64, len(bounds)+1)}
+}
+
+func (h *Histogram) observe(v float64) {
+	h.Counts[sort.SearchFloat64s(h.Bounds, v)]++
+	h.Count++
+	h.Sum += v
+}
+
+func (h Histogram) clone() Histogram {
+	h.Counts = append([]int64(nil), h.Counts...)
+	return h
+}
+
+// Series aggregates the observations of one host and operation.
+type Series struct {
+	Host      string `json:"host"`
+	Operation string `json:"operation,omitempty"`
+	Requests  int64  `json:"requests"`
+	Errors    int64  `json:"errors"`
+	// Duration is in seconds.
+	Duration Histogram `json:"duration_seconds"`
+	// RequestSize and ResponseSize are body sizes in bytes, the latter after
+	// decompression. ResponseWireBytes sums the received Content-Length of
+	// responses that declared one; compared with ResponseSize.Sum it shows
+	// what compression saves.
+	RequestSize       Histogram `json:"request_size_bytes"`
+	ResponseSize      Histogram `json:"response_size_bytes"`
+	ResponseWireBytes int64     `json:"response_wire_bytes"`
+}
+
+type seriesKey struct{ host, operation string }
+
+// Registry is an in-process Recorder keeping request counts, latency and
+// body size histograms per host and operation. It is the recorder a client
+// uses when metrics are enabled without one of its own; pass one Registry to
+// several clients with WithMetrics for process-wide figures.
+type Registry struct {
+	mu     sync.Mutex
+	series map[seriesKey]*Series
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{series: make(map[seriesKey]*Series)}
+}
+
+// Observe implements Recorder.
+func (r *Registry) Observe(o Observation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := seriesKey{o.Host, o.Operation}
+	s, ok := r.series[key]
+	if !ok {
+		s = &Series{
+			Host:         o.Host,
+			Operation:    o.Operation,
+			Duration:     newHistogram(DefaultDurationBuckets),
+			RequestSize:  newHistogram(DefaultSizeBuckets),
+			ResponseSize: newHistogram(DefaultSizeBuckets),
+		}
+		r.series[key] = s
+	}
+	s.Requests++
+	if o.Failed() {
+		s.Errors++
+	}
+	s.Duration.observe(o.Duration.Seconds())
+	s.RequestSize.observe(float64(o.RequestBytes))
+	if o.Err == nil {
+		s.ResponseSize.observe(float64(o.ResponseBytes))
+		if o.ResponseWireBytes >= 0 {
+			s.ResponseWireBytes += o.ResponseWireBytes
+		}
+	}
+}
+
+// Snapshot returns a copy of every series, ordered by host and operation.
+func (r *Registry) Snapshot() []Series {
+	r.mu.Lock()
+	out := make([]Series, 0, len(r.series))
+	for _, s := range r.series {
+		cp := *s
+		cp.Duration = s.Duration.clone()
+		cp.RequestSize = s.RequestSize.clone()
+		cp.ResponseSize = s.ResponseSize.clone()
+		out = append(out, cp)
+	}
+	r.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Host != out[j].Host {
+			return out[i].Host < out[j].Host
+		}
+		return out[i].Operation < out[j].Operation
+	})
+	return out
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,188 @@
+// Package httpcprom publishes httpc client metrics through the Prometheus
+// client library. It is a separate module so clients that do not use
+// Prometheus do not depend on it.
+package httpcprom
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Options configures a Recorder.
+type Options struct {
+	// Namespace prefixes every metric name; defaults to "httpc".
+	Namespace string
+	// DurationBuckets are the upper bounds, in seconds, of the duration
+	// histogram; defaults to metrics.DefaultDurationBuckets.
+	DurationBuckets []float64
+	// SizeBuckets are the upper bounds, in bytes, of the body size
+	// histograms; defaults to metrics.DefaultSizeBuckets.
+	SizeBuckets []float64
+}
+
+// Recorder is a metrics.Recorder feeding Prometheus collectors. It exports,
+// per namespace:
+//
+//   - <ns>_request_duration_seconds, a histogram by host, method and status
+//     class ("2xx" to "5xx", or "error" for transport errors), including
+//     retries;
+//   - <ns>_request_size_bytes and <ns>_response_size_bytes, histograms of
+//     body sizes by host and method, the latter after decompression;
+//   - <ns>_requests_in_flight, a gauge by host and method;
+//   - <ns>_request_retries_total, a counter by host and method;
+//   - <ns>_breaker_state, a gauge by host: 0 closed, 1 half-open, 2 open.
+//
+// It implements metrics.InFlightRecorder and metrics.BreakerRecorder.
+type Recorder struct {
+	duration     *prometheus.HistogramVec
+	requestSize  *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+	inFlight     *prometheus.GaugeVec
+	retries      *prometheus.CounterVec
+	breaker      *prometheus.GaugeVec
+}
+
+// New registers the collectors of a Recorder on reg, or on
+// prometheus.DefaultRegisterer when reg is nil. Collectors already
+// registered under the same names, e.g. by another client's Recorder, are
+// shared, so several clients report into one set of series.
+func New(reg prometheus.Registerer, opts Options) (*Recorder, error) {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = "httpc"
+	}
+	if len(opts.DurationBuckets) == 0 {
+		opts.DurationBuckets = metrics.DefaultDurationBuckets
+	}
+	if len(opts.SizeBuckets) == 0 {
+		opts.SizeBuckets = metrics.DefaultSizeBuckets
+	}
+	requestLabels := []string{"host", "method"}
+
+	r := &Recorder{}
+	var err error
+	if r.duration, err = register(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: opts.Namespace,
+		Name:      "request_duration_seconds",
+		Help:      "Duration of outbound requests, including retries.",
+		Buckets:   opts.DurationBuckets,
+	}, []string{"host", "method", "status_class"})); err != nil {
+		return nil, err
+	}
+	if r.requestSize, err = register(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: opts.Namespace,
+		Name:      "request_size_bytes",
+		Help:      "Size of outbound request bodies as sent.",
+		Buckets:   opts.SizeBuckets,
+	}, requestLabels)); err != nil {
+		return nil, err
+	}
+	if r.responseSize, err = register(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: opts.Namespace,
+		Name:      "response_size_bytes",
+		Help:      "Size of response bodies read, after decompression.",
+		Buckets:   opts.SizeBuckets,
+	}, requestLabels)); err != nil {
+		return nil, err
+	}
+	if r.inFlight, err = register(reg, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: opts.Namespace,
+		Name:      "requests_in_flight",
+		Help:      "Outbound requests in flight.",
+	}, requestLabels)); err != nil {
+		return nil, err
+	}
+	if r.retries, err = register(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: opts.Namespace,
+		Name:      "request_retries_total",
+		Help:      "Retries of outbound requests.",
+	}, requestLabels)); err != nil {
+		return nil, err
+	}
+	if r.breaker, err = register(reg, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: opts.Namespace,
+		Name:      "breaker_state",
+		Help:      "Circuit breaker state per host: 0 closed, 1 half-open, 2 open.",
+	}, []string{"host"})); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// WithMetrics reports the client's requests to collectors registered on
+// reg, as with New. Like prometheus.MustRegister it panics when reg rejects
+// them, e.g. because other collectors use the same names with different
+// labels.
+func WithMetrics(reg prometheus.Registerer, opts Options) httpc.Option {
+	rec, err := New(reg, opts)
+	if err != nil {
+		panic(err)
+	}
+	return httpc.WithMetrics(rec)
+}
+
+// register registers c on reg, returning the collector already registered
+// in its place if there is one.
+func register[C prometheus.Collector](reg prometheus.Registerer, c C) (C, error) {
+	err := reg.Register(c)
+	if err == nil {
+		return c, nil
+	}
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		if existing, ok := are.ExistingCollector.(C); ok {
+			return existing, nil
+		}
+	}
+	return c, err
+}
+
+// Begin implements metrics.InFlightRecorder.
+func (r *Recorder) Begin(host, method string) {
+	r.inFlight.WithLabelValues(host, method).Inc()
+}
+
+// End implements metrics.InFlightRecorder.
+func (r *Recorder) End(host, method string) {
+	r.inFlight.WithLabelValues(host, method).Dec()
+}
+
+// Observe implements metrics.Recorder.
+func (r *Recorder) Observe(o metrics.Observation) {
+	r.duration.WithLabelValues(o.Host, o.Method, statusClass(o)).Observe(o.Duration.Seconds())
+	r.requestSize.WithLabelValues(o.Host, o.Method).Observe(float64(o.RequestBytes))
+	if o.Err == nil {
+		r.responseSize.WithLabelValues(o.Host, o.Method).Observe(float64(o.ResponseBytes))
+	}
+	// Touched on every request so the series exists at zero before the
+	// first retry.
+	retries := r.retries.WithLabelValues(o.Host, o.Method)
+	if o.Attempts > 1 {
+		retries.Add(float64(o.Attempts - 1))
+	}
+}
+
+// BreakerState implements metrics.BreakerRecorder.
+func (r *Recorder) BreakerState(host, state string) {
+	var v float64
+	switch state {
+	case "half-open":
+		v = 1
+	case "open":
+		v = 2
+	}
+	r.breaker.WithLabelValues(host).Set(v)
+}
+
+func statusClass(o metrics.Observation) string {
+	if o.Err != nil || o.StatusCode < 100 || o.StatusCode > 599 {
+		return "error"
+	}
+	return strconv.Itoa(o.StatusCode/100) + "xx"
+}
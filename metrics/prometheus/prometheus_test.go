@@ -0,0 +1,107 @@
+package httpcprom
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+type noDelayPolicy struct{ max int }
+
+func (p noDelayPolicy) ShouldRetry(_ *http.Request, resp *http.Response, err error, attempt int, _ bool) (time.Duration, bool) {
+	if attempt >= p.max {
+		return 0, false
+	}
+	if err != nil {
+		return 0, true
+	}
+	return 0, resp != nil && resp.StatusCode >= 500
+}
+
+func TestRecorder(t *testing.T) {
+	var calls int
+	transport := roundTripper(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host == "down.example.com" {
+			return nil, errors.New("connection refused")
+		}
+		calls++
+		status := http.StatusOK
+		if calls == 1 {
+			status = http.StatusServiceUnavailable
+		}
+		return &http.Response{StatusCode: status, Header: make(http.Header), Body: io.NopCloser(strings.NewReader("hello")), Request: req}, nil
+	})
+
+	reg := prometheus.NewPedanticRegistry()
+	client, err := httpc.New(
+		httpc.WithTransport(transport),
+		WithMetrics(reg, Options{}),
+		httpc.WithBreaker(true),
+		httpc.WithRetry(true, 2),
+		httpc.WithRetryPolicy(noDelayPolicy{max: 2}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	resp, err := client.Get(context.Background(), "https://api.example.com/items")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if _, err := resp.Bytes(); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	for range 5 {
+		_, _ = client.Get(context.Background(), "https://down.example.com/", httpc.WithRequestRetry(noDelayPolicy{max: 1}))
+	}
+
+	// A second recorder on the same registry shares the collectors.
+	shared, err := New(reg, Options{})
+	if err != nil {
+		t.Fatalf("second recorder: %v", err)
+	}
+
+	if got := testutil.ToFloat64(shared.retries.WithLabelValues("api.example.com", "GET")); got != 1 {
+		t.Fatalf("expected 1 retry, got %v", got)
+	}
+	if got := testutil.ToFloat64(shared.inFlight.WithLabelValues("api.example.com", "GET")); got != 0 {
+		t.Fatalf("expected nothing in flight, got %v", got)
+	}
+	if got := testutil.ToFloat64(shared.breaker.WithLabelValues("down.example.com")); got != 2 {
+		t.Fatalf("expected the breaker of down.example.com open, got %v", got)
+	}
+
+	want := `
+# HELP httpc_response_size_bytes Size of response bodies read, after decompression.
+# TYPE httpc_response_size_bytes histogram
+httpc_response_size_bytes_bucket{host="api.example.com",method="GET",le="256"} 1
+httpc_response_size_bytes_bucket{host="api.example.com",method="GET",le="1024"} 1
+httpc_response_size_bytes_bucket{host="api.example.com",method="GET",le="4096"} 1
+httpc_response_size_bytes_bucket{host="api.example.com",method="GET",le="16384"} 1
+httpc_response_size_bytes_bucket{host="api.example.com",method="GET",le="65536"} 1
+httpc_response_size_bytes_bucket{host="api.example.com",method="GET",le="262144"} 1
+httpc_response_size_bytes_bucket{host="api.example.com",method="GET",le="1.048576e+06"} 1
+httpc_response_size_bytes_bucket{host="api.example.com",method="GET",le="4.194304e+06"} 1
+httpc_response_size_bytes_bucket{host="api.example.com",method="GET",le="1.6777216e+07"} 1
+httpc_response_size_bytes_bucket{host="api.example.com",method="GET",le="+Inf"} 1
+httpc_response_size_bytes_sum{host="api.example.com",method="GET"} 5
+httpc_response_size_bytes_count{host="api.example.com",method="GET"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "httpc_response_size_bytes"); err != nil {
+		t.Fatal(err)
+	}
+	if n := testutil.CollectAndCount(shared.duration); n != 2 {
+		t.Fatalf("expected duration series for both hosts, got %d", n)
+	}
+}
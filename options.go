@@ -1,13 +1,23 @@
 package httpc
 
 import (
+	"maps"
 	"net/http"
 	"time"
 
 	"github.com/gostratum/core/logx"
 	"github.com/gostratum/httpc/auth"
 	"github.com/gostratum/httpc/breaker"
+	"github.com/gostratum/httpc/bulkhead"
+	"github.com/gostratum/httpc/cache"
+	"github.com/gostratum/httpc/chaos"
+	"github.com/gostratum/httpc/clock"
+	"github.com/gostratum/httpc/dns"
+	"github.com/gostratum/httpc/metrics"
+	"github.com/gostratum/httpc/ratelimit"
+	"github.com/gostratum/httpc/redact"
 	"github.com/gostratum/httpc/retry"
+	"github.com/gostratum/httpc/tracing"
 )
 
 // Option mutates the client configuration before a Client is constructed.
@@ -29,6 +39,45 @@ func WithBaseURL(u string) Option {
 	}
 }
 
+// WithURLJoin selects how request targets are joined to the base URL:
+// URLJoinPath (the default) or URLJoinRFC3986.
+func WithURLJoin(strategy string) Option {
+	return func(c *Config) {
+		c.URLJoin = strategy
+	}
+}
+
+// WithPreserveEncodedPath keeps caller-supplied percent-encoding in request
+// paths and WithPathParams values, for APIs such as artifact registries and
+// GitLab whose IDs contain encoded slashes: "group%2Fproject" is sent as is
+// rather than as "group%252Fproject", and never decoded into a separator.
+func WithPreserveEncodedPath(enabled bool) Option {
+	return func(c *Config) {
+		c.PreserveEncodedPath = enabled
+	}
+}
+
+// WithBaggageForwarding sends the WithBaggage value of key, when the request
+// context carries one, as header on every request. Keys that are not
+// forwarded this way never leave the process.
+func WithBaggageForwarding(key, header string) Option {
+	return func(c *Config) {
+		c.ForwardBaggage = maps.Clone(c.ForwardBaggage)
+		if c.ForwardBaggage == nil {
+			c.ForwardBaggage = make(map[string]string)
+		}
+		c.ForwardBaggage[key] = header
+	}
+}
+
+// WithBaggageLookup resolves forwarded baggage keys that are not set with
+// WithBaggage, e.g. from the context values of the service framework.
+func WithBaggageLookup(lookup BaggageLookup) Option {
+	return func(c *Config) {
+		c.BaggageLookup = lookup
+	}
+}
+
 // WithTimeout overrides the default client timeout.
 func WithTimeout(d time.Duration) Option {
 	return func(c *Config) {
@@ -36,6 +85,53 @@ func WithTimeout(d time.Duration) Option {
 	}
 }
 
+// WithTimeoutPolicy sets how the client timeout and WithRequestTimeout
+// combine: TimeoutMinOf (the default), TimeoutOverride, TimeoutIgnoreClient
+// or TimeoutHeaders, the last for clients serving long-lived streams.
+func WithTimeoutPolicy(policy string) Option {
+	return func(c *Config) {
+		c.TimeoutPolicy = policy
+	}
+}
+
+// WithBodySpoolLimit sets how many bytes of an io.Reader body passed to the
+// verb helpers are buffered in memory; the remainder is spooled to a
+// temporary file so large streamed bodies stay retryable. Defaults to 8 MiB; a
+// negative value buffers the whole body in memory.
+func WithBodySpoolLimit(n int64) Option {
+	return func(c *Config) {
+		c.BodySpoolLimit = n
+	}
+}
+
+// WithJSONAccept sets the Accept header implied by WithJSON bodies, e.g. a
+// vendor media type such as application/vnd.api+json; an empty accept sends
+// none. WithAccept and WithRequestJSONAccept take precedence.
+func WithJSONAccept(accept string) Option {
+	return func(c *Config) {
+		c.JSONAccept = choose(accept, jsonAcceptNone)
+	}
+}
+
+// WithMaxBufferedBytes caps the total bytes held by buffered Response bodies
+// of this client. Past the cap, Bytes, String and DecodeJSON fail with
+// ErrBufferLimitExceeded while IntoWriter streams the body. Call
+// Response.Release to return a body's share early.
+func WithMaxBufferedBytes(n int64) Option {
+	return func(c *Config) {
+		c.MaxBufferedBytes = n
+	}
+}
+
+// WithCharsetTranscoding makes Response.String and DecodeJSON transcode
+// bodies from the Content-Type charset (e.g. ISO-8859-1, Shift_JIS) to UTF-8.
+// Bytes and IntoWriter always return the body as received.
+func WithCharsetTranscoding(enabled bool) Option {
+	return func(c *Config) {
+		c.TranscodeCharset = enabled
+	}
+}
+
 // WithRetry toggles retry behaviour at the client level.
 func WithRetry(enabled bool, maxAttempts int) Option {
 	return func(c *Config) {
@@ -68,6 +164,35 @@ func WithHTTPClient(client *http.Client) Option {
 	}
 }
 
+// WithErrorOnStatus makes Do and the verb helpers return 4xx and 5xx
+// responses, after any retries, as an *HTTPError carrying the status,
+// method, redacted URL and headers, and the start of the body, instead of a
+// nil error. WithRequestErrorOnStatus overrides it per request.
+func WithErrorOnStatus() Option {
+	return func(c *Config) {
+		c.ErrorOnStatus = true
+	}
+}
+
+// WithCookies stores cookies set by responses in an in-memory jar and sends
+// them back on later requests, following RFC 6265 domain and path rules, for
+// session-based APIs such as CSRF-protected forms or sticky sessions. The jar
+// lives as long as the client. Use WithCookieJar to supply another jar.
+func WithCookies(enabled bool) Option {
+	return func(c *Config) {
+		c.Cookies = enabled
+	}
+}
+
+// WithCookieJar stores and sends cookies with jar, e.g. one that persists
+// them across restarts by wrapping a net/http/cookiejar.Jar and saving
+// whatever SetCookies receives. It takes precedence over WithCookies.
+func WithCookieJar(jar http.CookieJar) Option {
+	return func(c *Config) {
+		c.CookieJar = jar
+	}
+}
+
 // WithLogger sets the logger used for request logging middleware.
 func WithLogger(l logx.Logger) Option {
 	return func(c *Config) {
@@ -75,6 +200,65 @@ func WithLogger(l logx.Logger) Option {
 	}
 }
 
+// WithLogging toggles the request logging middleware, which writes one line
+// per request to the WithLogger logger.
+func WithLogging(enabled bool) Option {
+	return func(c *Config) {
+		c.LoggingEnabled = enabled
+	}
+}
+
+// WithLogHeaders adds the request and response headers, masked by the
+// WithRedaction rules, to the request log lines.
+func WithLogHeaders(enabled bool) Option {
+	return func(c *Config) {
+		c.LoggingHeaders = enabled
+	}
+}
+
+// WithLogBodies adds up to limit bytes of the request and response bodies to
+// the request log lines. JSON and form fields matched by the WithRedaction
+// rules are masked; a JSON body over the limit is omitted instead, since it
+// cannot be masked. A response with a body is logged once it has been read.
+// Zero disables body logging.
+func WithLogBodies(limit int) Option {
+	return func(c *Config) {
+		c.LoggingBodyLimit = limit
+	}
+}
+
+// WithMetrics reports every request to rec, e.g. the Prometheus recorder of
+// the metrics/prometheus module or an adapter for OpenTelemetry. Recorders implementing
+// metrics.InFlightRecorder or metrics.BreakerRecorder also see requests in
+// flight and breaker state changes. With metrics_enabled and no recorder,
+// the client keeps a metrics.Registry, published under WithExpvar.
+func WithMetrics(rec metrics.Recorder) Option {
+	return func(c *Config) {
+		c.Metrics = rec
+		c.MetricsEnabled = rec != nil
+	}
+}
+
+// WithTracing toggles W3C Trace Context propagation: requests carry a
+// traceparent header, child of the span set with tracing.WithSpanContext.
+func WithTracing(enabled bool) Option {
+	return func(c *Config) {
+		c.TracingEnabled = enabled
+	}
+}
+
+// WithTracer traces every attempt with t, e.g. httpcotel.NewTracer over an
+// OpenTelemetry tracer provider: each attempt gets a client span, child of
+// the span in the request context, propagated in the traceparent header; its
+// status code and retry count are reported when the span ends. It enables
+// tracing_enabled.
+func WithTracer(t tracing.Tracer) Option {
+	return func(c *Config) {
+		c.Tracer = t
+		c.TracingEnabled = t != nil
+	}
+}
+
 // WithAuth configures the default auth provider applied to every request (can
 // be overridden per-request via ReqOption).
 func WithAuth(p auth.AuthProvider) Option {
@@ -104,9 +288,263 @@ func WithMiddleware(m Middleware) Option {
 	}
 }
 
+// WithAuthRefresh starts the background refresher of the default auth
+// provider, when it implements auth.Refresher (e.g. auth.NewToken), so tokens
+// are prefetched and renewed ahead of expiry. Stop it through the provider's
+// Stop method. Under fx the refresher is bound to the application lifecycle
+// instead, without this option.
+func WithAuthRefresh() Option {
+	return func(c *Config) {
+		c.AuthRefresh = true
+	}
+}
+
+// WithRequestDefaults applies opts to every request made by the client, e.g.
+// a Preset shared across call sites. Each request's own options win over the
+// defaults, exactly as if the defaults had been passed first. Calling it again
+// appends further defaults.
+func WithRequestDefaults(opts ...ReqOption) Option {
+	return func(c *Config) {
+		c.RequestDefaults = append(c.RequestDefaults, opts...)
+	}
+}
+
+// WithMethodOverrideFor tunnels the given methods (e.g. http.MethodPatch,
+// http.MethodDelete) through POST with X-HTTP-Method-Override on every
+// request. Use the WithMethodOverride request option for individual calls.
+func WithMethodOverrideFor(methods ...string) Option {
+	return func(c *Config) {
+		c.MethodOverride = append(c.MethodOverride, methods...)
+	}
+}
+
 // WithUserAgent overrides the default User-Agent header applied to requests.
 func WithUserAgent(ua string) Option {
 	return func(c *Config) {
 		c.UserAgent = ua
 	}
 }
+
+// WithChaos enables fault injection below the retry and breaker middlewares so
+// resilience settings can be exercised in staging. Faults are only injected
+// when cfg.Enabled is true.
+func WithChaos(cfg chaos.Config) Option {
+	return func(c *Config) {
+		c.Chaos = cfg
+	}
+}
+
+// WithClock sets the time source shared by retry waits, breaker windows,
+// token TTLs and the TimeoutHeaders deadline. Inject a clock.Fake in tests
+// to avoid real sleeps.
+func WithClock(c clock.Clock) Option {
+	return func(cfg *Config) {
+		cfg.Clock = c
+	}
+}
+
+// WithHandler routes every request to the supplied in-process handler,
+// bypassing the network while keeping the middleware chain intact. Intended
+// for fast unit tests of code built on Client.
+func WithHandler(h http.Handler) Option {
+	return func(c *Config) {
+		c.Transport = &handlerTransport{handler: h}
+	}
+}
+
+// WithProxy routes all requests through the given forward proxy instead of
+// the HTTP_PROXY/HTTPS_PROXY environment.
+func WithProxy(rawURL string) Option {
+	return func(c *Config) {
+		c.Proxy.URL = rawURL
+	}
+}
+
+// WithProxyAuth sets basic credentials sent to the proxy via
+// Proxy-Authorization.
+func WithProxyAuth(username, password string) Option {
+	return func(c *Config) {
+		c.Proxy.Username = username
+		c.Proxy.Password = password
+	}
+}
+
+// WithProxyHeader adds a header sent to the proxy, e.g. a bearer
+// Proxy-Authorization for proxies that do not speak basic auth.
+func WithProxyHeader(key, value string) Option {
+	return func(c *Config) {
+		if c.Proxy.Headers == nil {
+			c.Proxy.Headers = make(map[string]string)
+		}
+		c.Proxy.Headers[key] = value
+	}
+}
+
+// WithBulkhead caps concurrent requests per dependency, the WithBreakerKey
+// key or else the host, so one slow upstream cannot exhaust the process.
+// Requests beyond the limit and queue fail with bulkhead.ErrBulkheadFull. A
+// slot is released once the response body has been read (Bytes,
+// DecodeJSON, ...) or closed, and right away for responses without a body.
+func WithBulkhead(cfg bulkhead.Config) Option {
+	return func(c *Config) {
+		c.Bulkhead = &cfg
+	}
+}
+
+// WithRateLimiter waits on l before every attempt, retries included, keyed by
+// request host. Pass the same limiter to several clients to share a budget.
+func WithRateLimiter(l ratelimit.Limiter) Option {
+	return func(c *Config) {
+		c.RateLimiter = l
+	}
+}
+
+// WithStartupProbe verifies DNS, TLS and auth configuration by requesting path
+// (HEAD, falling back to GET) when the client is constructed, or in OnStart
+// when built through fx. Construction fails with a *StartupProbeError.
+func WithStartupProbe(path string, timeout time.Duration) Option {
+	return func(c *Config) {
+		c.StartupProbe = &StartupProbe{Path: path, Timeout: timeout}
+	}
+}
+
+// WithRedaction extends the default redaction rules (credential headers,
+// token query parameters, secret JSON fields) applied to every diagnostics
+// channel of the client: logs, errors and dumps.
+func WithRedaction(rules redact.Rules) Option {
+	return func(c *Config) {
+		c.Redaction = &rules
+	}
+}
+
+// WithExpvar publishes pool stats, breaker states, in-flight counts and the
+// Stats snapshot via expvar under name, e.g. "httpc.billing". New fails if the
+// name is already published.
+func WithExpvar(name string) Option {
+	return func(c *Config) {
+		c.ExpvarName = name
+	}
+}
+
+// WithCache enables the response cache for GET and HEAD requests. Hits skip
+// the retry, breaker and bulkhead middlewares and carry an X-Cache: HIT
+// header. Use WithNoCache, WithCacheRefresh, WithCacheTTL and WithCacheKey to
+// adjust individual requests.
+func WithCache(cfg cache.Config) Option {
+	return func(c *Config) {
+		c.Cache = &cfg
+	}
+}
+
+// WithResolver resolves host names with r instead of the system resolver,
+// e.g. a dns.NewDoH resolver where local DNS is untrusted or filtered. It
+// applies to the default transport; a transport set with WithTransport must
+// be given its own dialer via dns.DialContext.
+func WithResolver(r dns.Resolver) Option {
+	return func(c *Config) {
+		c.Resolver = r
+	}
+}
+
+// WithMaxConnLifetime recycles pooled connections once they are older than d
+// so long-lived keep-alive connections do not outlive NAT or load balancer
+// mappings. Busy connections finish their request first. It applies to the
+// default transport only.
+func WithMaxConnLifetime(d time.Duration) Option {
+	return func(c *Config) {
+		c.MaxConnLifetime = d
+	}
+}
+
+// WithResponseHeaderTimeout bounds the time to first byte: how long each
+// attempt waits for response headers once the request has been written,
+// whatever the overall timeout. It applies to the default transport only.
+func WithResponseHeaderTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.ResponseHeaderTimeout = d
+	}
+}
+
+// WithTLSHandshakeTimeout bounds the TLS handshake of new connections.
+// Defaults to 10s; a negative value removes the limit. It applies to the
+// default transport only.
+func WithTLSHandshakeTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.TLSHandshakeTimeout = d
+	}
+}
+
+// WithExpectContinueTimeout sets how long a request sent with
+// "Expect: 100-continue" waits for the server's go-ahead before sending its
+// body anyway. Defaults to 1s; a negative value sends the body at once. It
+// applies to the default transport only.
+func WithExpectContinueTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.ExpectContinueTimeout = d
+	}
+}
+
+// WithTCPKeepAlive sets the TCP keepalive period of new connections: the
+// idle time before the first probe and the interval between probes. Keep it
+// below the idle timeout of load balancers and NAT gateways on the path so
+// pooled connections are not dropped silently. Defaults to 30s; a negative
+// value disables keepalive probes. It applies to the default transport only.
+func WithTCPKeepAlive(d time.Duration) Option {
+	return func(c *Config) {
+		c.TCPKeepAlive = d
+	}
+}
+
+// WithHTTP2Ping sends an HTTP/2 PING frame on connections idle for
+// interval, e.g. while a report generation endpoint works on a minutes-long
+// response, so intermediaries with idle timeouts keep the connection open.
+// A connection whose PING is unanswered for timeout (15s when zero) is
+// closed. It applies to the default transport only.
+func WithHTTP2Ping(interval, timeout time.Duration) Option {
+	return func(c *Config) {
+		c.HTTP2PingInterval = interval
+		c.HTTP2PingTimeout = timeout
+	}
+}
+
+// WithTCPNoDelay sets TCP_NODELAY on new connections, sending small writes
+// at once rather than coalescing them with Nagle's algorithm. It is enabled
+// by default; pass false to trade latency for fewer packets. It applies to
+// the default transport only.
+func WithTCPNoDelay(enabled bool) Option {
+	return func(c *Config) {
+		c.DisableTCPNoDelay = !enabled
+	}
+}
+
+// WithCancelDrain drains up to maxBytes of a response body that is still
+// arriving when the request context is cancelled, for at most timeout, so
+// the keep-alive connection goes back to the pool instead of being closed.
+// Reads of the body still fail with the context error straight away. It
+// reduces connection churn for callers that routinely abandon requests,
+// e.g. hedged or deadline-bound fan-out calls.
+func WithCancelDrain(maxBytes int64, timeout time.Duration) Option {
+	return func(c *Config) {
+		c.CancelDrainBytes = maxBytes
+		c.CancelDrainTimeout = timeout
+	}
+}
+
+// WithAPIVersion sends version with every request, placed as configured by
+// WithAPIVersionPlacement (a header named API-Version by default).
+func WithAPIVersion(version string) Option {
+	return func(c *Config) {
+		c.APIVersion.Version = version
+	}
+}
+
+// WithAPIVersionPlacement selects where the API version goes: in is one of
+// APIVersionInHeader, APIVersionInMediaType or APIVersionInQuery, and name is
+// the header, media type parameter or query parameter name (empty picks the
+// placement's default).
+func WithAPIVersionPlacement(in, name string) Option {
+	return func(c *Config) {
+		c.APIVersion.In = in
+		c.APIVersion.Name = name
+	}
+}
@@ -0,0 +1,407 @@
+// Package outbox delivers requests with at-least-once guarantees: requests
+// are persisted in a Journal when enqueued and a background worker sends
+// them through an httpc.Client, retrying with backoff until they succeed or
+// are rejected, so callers no longer need their own goroutine and retry
+// loops around fire-and-forget calls.
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/clock"
+	"github.com/gostratum/httpc/retry"
+	"github.com/sony/gobreaker"
+)
+
+// Entry is a persisted delivery.
+type Entry struct {
+	ID string
+	// Request is the request as encoded by httpc.MarshalRequest.
+	Request []byte
+	// Attempts counts the attempts made so far.
+	Attempts int
+	// NextAttempt is when the entry is due; zero is immediately.
+	NextAttempt time.Time
+	// LastError describes the failure of the previous attempt.
+	LastError string
+	Created   time.Time
+}
+
+// Journal persists entries until they are delivered or have failed for
+// good, so deliveries survive restarts when it is backed by durable
+// storage. Implementations must be safe for concurrent use.
+type Journal interface {
+	// Save inserts e or replaces the entry with the same ID.
+	Save(ctx context.Context, e Entry) error
+	// Pending returns every saved entry, oldest first.
+	Pending(ctx context.Context) ([]Entry, error)
+	// Delete removes the entry with the given ID, if any.
+	Delete(ctx context.Context, id string) error
+}
+
+// NewMemoryJournal returns a Journal kept in memory, for tests and for
+// deliveries that need not survive the process.
+func NewMemoryJournal() Journal {
+	return &memoryJournal{entries: make(map[string]Entry)}
+}
+
+type memoryJournal struct {
+	mu      sync.Mutex
+	entries map[string]Entry
+}
+
+func (j *memoryJournal) Save(_ context.Context, e Entry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries[e.ID] = e
+	return nil
+}
+
+func (j *memoryJournal) Pending(context.Context) ([]Entry, error) {
+	j.mu.Lock()
+	out := make([]Entry, 0, len(j.entries))
+	for _, e := range j.entries {
+		out = append(out, e)
+	}
+	j.mu.Unlock()
+	sort.Slice(out, func(i, k int) bool {
+		if !out[i].Created.Equal(out[k].Created) {
+			return out[i].Created.Before(out[k].Created)
+		}
+		return out[i].ID < out[k].ID
+	})
+	return out, nil
+}
+
+func (j *memoryJournal) Delete(_ context.Context, id string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delete(j.entries, id)
+	return nil
+}
+
+// Status is the outcome of a delivery attempt.
+type Status int
+
+const (
+	// StatusDelivered: the server answered 2xx; the entry is removed.
+	StatusDelivered Status = iota
+	// StatusRetrying: the attempt failed and another is scheduled.
+	StatusRetrying
+	// StatusFailed: the server rejected the request or every attempt
+	// failed; the entry is removed.
+	StatusFailed
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusDelivered:
+		return "delivered"
+	case StatusRetrying:
+		return "retrying"
+	case StatusFailed:
+		return "failed"
+	}
+	return fmt.Sprintf("Status(%d)", int(s))
+}
+
+// Event reports a delivery attempt to Config.OnStatus.
+type Event struct {
+	ID      string
+	Status  Status
+	Attempt int
+	// StatusCode is the response status, or zero after a transport error.
+	StatusCode int
+	Err        error
+	// NextAttempt is when the next attempt is due, for StatusRetrying.
+	NextAttempt time.Time
+}
+
+// StatusError reports a non-2xx answer.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("outbox: server answered %d", e.StatusCode)
+}
+
+// DefaultIdempotencyHeader carries the entry ID, stable across attempts, so
+// servers can drop the duplicates at-least-once delivery implies.
+const DefaultIdempotencyHeader = "Idempotency-Key"
+
+// Config controls persistence and retries.
+type Config struct {
+	// Journal stores pending entries. Defaults to NewMemoryJournal().
+	Journal Journal
+	// MaxAttempts, BaseBackoff and MaxBackoff shape the exponential retries;
+	// they default to 10 attempts between 1s and 5m.
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// PollInterval is how often the worker looks for due entries besides
+	// right after Enqueue. Defaults to 1s.
+	PollInterval time.Duration
+	// IdempotencyHeader defaults to DefaultIdempotencyHeader; requests that
+	// already set it keep their value.
+	IdempotencyHeader string
+	// OnStatus is called after every attempt, from the worker goroutine.
+	OnStatus func(Event)
+	// Clock drives scheduling; defaults to the real clock.
+	Clock clock.Clock
+}
+
+// Deliverer persists and sends requests. The client should have its own
+// retries disabled, or limited to quick transport retries: the Deliverer
+// retries deliveries itself, across restarts. Attempts refused by an open
+// circuit breaker are postponed by MaxBackoff without spending an attempt.
+type Deliverer struct {
+	client   httpc.Client
+	cfg      Config
+	clk      clock.Clock
+	strategy *retry.Strategy
+	wake     chan struct{}
+
+	// flushMu serialises passes so an entry is never sent twice at once.
+	flushMu sync.Mutex
+
+	mu   sync.Mutex
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+// New returns a Deliverer sending through client. Call Start to run the
+// worker.
+func New(client httpc.Client, cfg Config) (*Deliverer, error) {
+	if client == nil {
+		return nil, errors.New("outbox: nil client")
+	}
+	if cfg.Journal == nil {
+		cfg.Journal = NewMemoryJournal()
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 10
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 5 * time.Minute
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.IdempotencyHeader == "" {
+		cfg.IdempotencyHeader = DefaultIdempotencyHeader
+	}
+	return &Deliverer{
+		client: client,
+		cfg:    cfg,
+		clk:    clock.OrReal(cfg.Clock),
+		strategy: retry.NewStrategy(retry.PolicyConfig{
+			MaxAttempts: cfg.MaxAttempts,
+			BaseBackoff: cfg.BaseBackoff,
+			MaxBackoff:  cfg.MaxBackoff,
+			Clock:       cfg.Clock,
+		}, retryable),
+		wake: make(chan struct{}, 1),
+	}, nil
+}
+
+// Enqueue persists req and returns its entry ID once the journal has
+// stored it; delivery happens in the background. Requests with streamed
+// bodies cannot be enqueued, and per-request auth and retry policies are
+// not kept (see httpc.MarshalRequest).
+func (d *Deliverer) Enqueue(ctx context.Context, req *httpc.Request) (string, error) {
+	data, err := httpc.MarshalRequest(req)
+	if err != nil {
+		return "", fmt.Errorf("outbox: %w", err)
+	}
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+	if err := d.cfg.Journal.Save(ctx, Entry{ID: id, Request: data, Created: d.clk.Now()}); err != nil {
+		return "", fmt.Errorf("outbox: save entry: %w", err)
+	}
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return id, nil
+}
+
+// Flush makes one attempt at every entry that is due, oldest first. The
+// worker calls it; it is exported for callers driving deliveries
+// themselves, e.g. from a cron job or at shutdown.
+func (d *Deliverer) Flush(ctx context.Context) error {
+	d.flushMu.Lock()
+	defer d.flushMu.Unlock()
+	entries, err := d.cfg.Journal.Pending(ctx)
+	if err != nil {
+		return fmt.Errorf("outbox: load entries: %w", err)
+	}
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if e.NextAttempt.After(d.clk.Now()) {
+			continue
+		}
+		if err := d.attempt(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attempt sends e once and records the outcome; it only fails when the
+// journal does.
+func (d *Deliverer) attempt(ctx context.Context, e Entry) error {
+	req, err := httpc.UnmarshalRequest(e.Request)
+	if err == nil && req.Header(d.cfg.IdempotencyHeader) == "" {
+		req = req.With(httpc.WithHeader(d.cfg.IdempotencyHeader, e.ID))
+	}
+	if err == nil {
+		// The status decides between retry and rejection, so it must reach
+		// us as a response even from a client with WithErrorOnStatus.
+		req = req.With(httpc.WithRequestErrorOnStatus(false))
+	}
+	var status int
+	if err == nil {
+		var resp *httpc.Response
+		resp, err = d.client.Do(ctx, req)
+		if err == nil {
+			status = resp.StatusCode()
+			_, _ = resp.Bytes()
+			resp.Release()
+			if status < 200 || status >= 300 {
+				err = &StatusError{StatusCode: status}
+			}
+		}
+	} else {
+		err = fmt.Errorf("%w: %w", errDecode, err)
+	}
+	if err != nil && ctx.Err() != nil {
+		// Interrupted by shutdown: the attempt does not count.
+		return nil
+	}
+
+	ev := Event{ID: e.ID, Attempt: e.Attempts + 1, StatusCode: status, Err: err}
+	switch {
+	case err == nil:
+		ev.Status = StatusDelivered
+	case errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests):
+		ev.Status, ev.Attempt = StatusRetrying, e.Attempts
+		ev.NextAttempt = d.clk.Now().Add(d.cfg.MaxBackoff)
+	default:
+		e.Attempts++
+		// A per-attempt timeout, e.g. WithRequestTimeout, is a transient
+		// failure like any other; only the Deliverer's ctx ends delivery.
+		if delay, ok := d.strategy.NextContext(ctx, err, e.Attempts); ok {
+			ev.Status = StatusRetrying
+			ev.NextAttempt = d.clk.Now().Add(delay)
+		} else {
+			ev.Status = StatusFailed
+		}
+	}
+
+	if ev.Status == StatusRetrying {
+		e.NextAttempt = ev.NextAttempt
+		e.LastError = err.Error()
+		if err := d.cfg.Journal.Save(ctx, e); err != nil {
+			return fmt.Errorf("outbox: save entry: %w", err)
+		}
+	} else if err := d.cfg.Journal.Delete(ctx, e.ID); err != nil {
+		return fmt.Errorf("outbox: delete entry: %w", err)
+	}
+	if d.cfg.OnStatus != nil {
+		d.cfg.OnStatus(ev)
+	}
+	return nil
+}
+
+// Start launches the worker, which first sends whatever the journal still
+// holds. Calling it again while running is a no-op. Start and Stop match
+// fx.Hook so they can be registered on an application lifecycle.
+func (d *Deliverer) Start(context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d.stop = cancel
+	d.done = make(chan struct{})
+	go d.run(ctx, d.done)
+	return nil
+}
+
+// Stop halts the worker, interrupting any attempt in flight, and waits for
+// it to exit or ctx to end. Pending entries stay in the journal.
+func (d *Deliverer) Stop(ctx context.Context) error {
+	d.mu.Lock()
+	stop, done := d.stop, d.done
+	d.stop, d.done = nil, nil
+	d.mu.Unlock()
+	if stop == nil {
+		return nil
+	}
+	stop()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Deliverer) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	for {
+		// Journal errors are retried on the next pass.
+		_ = d.Flush(ctx)
+
+		timer := d.clk.NewTimer(d.cfg.PollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-d.wake:
+			timer.Stop()
+		case <-timer.C():
+		}
+	}
+}
+
+// retryable retries transport failures, 408, 429 and 5xx answers; other
+// 4xx answers are permanent rejections, as are entries that cannot be
+// decoded.
+func retryable(err error) bool {
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.StatusCode == http.StatusRequestTimeout ||
+			status.StatusCode == http.StatusTooManyRequests ||
+			status.StatusCode >= 500
+	}
+	return !errors.Is(err, errDecode)
+}
+
+var errDecode = errors.New("outbox: decode entry")
+
+func newID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("outbox: generate id: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
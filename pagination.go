@@ -0,0 +1,157 @@
+package httpc
+
+import (
+	"context"
+	"iter"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gostratum/httpc/clock"
+	"github.com/gostratum/httpc/ratelimit"
+)
+
+// NextPage derives the request for the page after resp from the request
+// that fetched it, or returns nil when resp is the last page.
+type NextPage func(prev *Request, resp *Response) (*Request, error)
+
+// NextLink is the NextPage of APIs paginating with RFC 8288 Link headers, as
+// GitHub and GitLab do: it follows the rel="next" link, resolved against the
+// URL of the previous page, keeping the headers and other options of prev.
+func NextLink(prev *Request, resp *Response) (*Request, error) {
+	raw := resp.Raw()
+	if raw == nil || raw.Request == nil {
+		return nil, nil
+	}
+	for _, link := range parseLinks(raw.Header.Values("Link"), raw.Request.URL) {
+		if slices.Contains(strings.Fields(link.Rel), "next") {
+			next := prev.clone()
+			next.url = link.URL
+			// The link carries the query of the next page in full.
+			next.queries = nil
+			next.pathParams = nil
+			next.frozen = true
+			return next, nil
+		}
+	}
+	return nil, nil
+}
+
+// PageOption configures the pacing of Pages.
+type PageOption func(*pageOptions)
+
+type pageOptions struct {
+	delay    time.Duration
+	limiter  ratelimit.Limiter
+	adaptive bool
+}
+
+// WithPageDelay waits at least d between pages.
+func WithPageDelay(d time.Duration) PageOption {
+	return func(o *pageOptions) {
+		o.delay = d
+	}
+}
+
+// WithPageLimiter takes a token from l, keyed by host, before each page
+// after the first, e.g. to share one budget between concurrent exports.
+func WithPageLimiter(l ratelimit.Limiter) PageOption {
+	return func(o *pageOptions) {
+		o.limiter = l
+	}
+}
+
+// WithAdaptivePacing paces pages by the rate limit headers of the previous
+// page: the remaining quota is spread evenly over what is left of the
+// window, and an exhausted quota or a Retry-After holds the next page until
+// the window resets. WithPageDelay still sets the minimum interval.
+func WithAdaptivePacing() PageOption {
+	return func(o *pageOptions) {
+		o.adaptive = true
+	}
+}
+
+// Pages fetches req and then every page next derives from its predecessor,
+// yielding each response, until next returns nil, a request or next fails
+// (the error is yielded last) or the loop body breaks. Pacing waits respect
+// ctx. Responses are yielded as returned by Client.Do, whatever their
+// status; a page that is not read before the next iteration still has its
+// body available.
+//
+//	for resp, err := range httpc.Pages(ctx, client, httpc.NewRequest("GET", "/items"), httpc.NextLink,
+//		httpc.WithAdaptivePacing()) {
+//		...
+//	}
+func Pages(ctx context.Context, c Client, req *Request, next NextPage, opts ...PageOption) iter.Seq2[*Response, error] {
+	var o pageOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	clk := clock.Real()
+	if cc, ok := c.(*client); ok {
+		clk = clock.OrReal(cc.cfg.Clock)
+	}
+	return func(yield func(*Response, error) bool) {
+		var prev *Response
+		for req := req; req != nil; {
+			if prev != nil {
+				if err := o.pace(ctx, clk, prev); err != nil {
+					yield(nil, err)
+					return
+				}
+			}
+			resp, err := c.Do(ctx, req)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(resp, nil) {
+				return
+			}
+			prev = resp
+			if req, err = next(req, resp); err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+	}
+}
+
+// pace waits before the page following prev.
+func (o pageOptions) pace(ctx context.Context, clk clock.Clock, prev *Response) error {
+	wait := o.delay
+	if o.adaptive {
+		wait = max(wait, adaptiveWait(prev.RateLimit(), clk.Now()))
+	}
+	if wait > 0 {
+		timer := clk.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C():
+		}
+	}
+	if o.limiter != nil {
+		var host string
+		if raw := prev.Raw(); raw != nil && raw.Request != nil {
+			host = raw.Request.URL.Host
+		}
+		return o.limiter.Wait(ctx, host)
+	}
+	return nil
+}
+
+// adaptiveWait spreads the quota left in info over the rest of its window.
+func adaptiveWait(info ratelimit.Info, now time.Time) time.Duration {
+	if !info.RetryAt.IsZero() || info.Exhausted() {
+		if resume, ok := info.ResumeAt(); ok {
+			return resume.Sub(now)
+		}
+		return 0
+	}
+	if info.Remaining > 0 && !info.Reset.IsZero() {
+		return info.Reset.Sub(now) / time.Duration(info.Remaining)
+	}
+	return 0
+}
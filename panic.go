@@ -0,0 +1,62 @@
+package httpc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gostratum/core/logx"
+	"github.com/gostratum/httpc/redact"
+)
+
+// ErrPanic is matched (via errors.Is) by *PanicError.
+var ErrPanic = errors.New("panic in transport chain")
+
+// PanicError is returned in place of a panic raised by a middleware, a
+// recorder or a WithEarlyHints callback while a request was in flight, so a
+// faulty plugin fails one call instead of crashing the calling goroutine.
+// Panics in goroutines a middleware starts itself are not recovered.
+type PanicError struct {
+	// Value is what was passed to panic.
+	Value any
+	// Stack is the goroutine stack at the point of the panic.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in transport chain: %v", e.Value)
+}
+
+// Unwrap returns Value when it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Is reports ErrPanic.
+func (e *PanicError) Is(target error) bool {
+	return target == ErrPanic
+}
+
+// newRecoverMiddleware turns panics below it into *PanicError failures and
+// logs them with their stack.
+func newRecoverMiddleware(logger logx.Logger, redactor *redact.Redactor) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (resp *http.Response, err error) {
+			defer func() {
+				if v := recover(); v != nil {
+					pe := &PanicError{Value: v, Stack: debug.Stack()}
+					logger.Error("recovered panic in http transport chain",
+						logx.String("method", req.Method),
+						logx.String("url", redactor.Request(req)),
+						logx.String("panic", fmt.Sprint(v)),
+						logx.String("stack", string(pe.Stack)),
+					)
+					resp, err = nil, pe
+				}
+			}()
+			return next.RoundTrip(req)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPanicRecovery(t *testing.T) {
+	t.Run("middleware_panic_becomes_error", func(t *testing.T) {
+		var reached bool
+		client, err := New(
+			WithTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				reached = true
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+			})),
+			WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+				return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					var m map[string]int
+					m["boom"]++ // nil map write
+					return next.RoundTrip(req)
+				})
+			}),
+		)
+		require.NoError(t, err)
+
+		_, err = client.Get(context.Background(), "http://api.test/")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrPanic))
+		var pe *PanicError
+		require.True(t, errors.As(err, &pe))
+		assert.Contains(t, string(pe.Stack), "panic_test.go")
+		assert.False(t, reached)
+		assert.Zero(t, client.Stats().InFlight)
+
+		var rerr interface{ RuntimeError() }
+		assert.True(t, errors.As(err, &rerr), "runtime errors stay reachable")
+	})
+
+	t.Run("early_hints_callback_panic_fails_request", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Link", `</app.css>; rel=preload`)
+			w.WriteHeader(http.StatusEarlyHints)
+			_, _ = w.Write([]byte("page"))
+		}))
+		defer server.Close()
+
+		client, err := New(WithBaseURL(server.URL), WithRetry(false, 0))
+		require.NoError(t, err)
+		_, err = client.Get(context.Background(), "/", WithEarlyHints(func([]EarlyHint) {
+			panic("plugin bug")
+		}))
+		require.Error(t, err)
+		var pe *PanicError
+		require.True(t, errors.As(err, &pe))
+		assert.Equal(t, "plugin bug", pe.Value)
+	})
+}
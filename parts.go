@@ -0,0 +1,58 @@
+package httpc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"mime"
+	"mime/multipart"
+	"strings"
+)
+
+// ErrNotMultipart is returned by Parts for a response that is not a
+// multipart/* body with a boundary.
+var ErrNotMultipart = errors.New("response is not multipart")
+
+// Parts iterates over the parts of a multipart response, e.g. multipart/mixed
+// batch replies or multipart/related metadata and payload pairs. Each part
+// exposes its headers and reads its content, and is valid only until the
+// loop moves on. The body is streamed as with Body and closed when the loop
+// ends. A malformed body yields its error last; a response that is not
+// multipart yields ErrNotMultipart.
+func (r *Response) Parts() iter.Seq2[*multipart.Part, error] {
+	return func(yield func(*multipart.Part, error) bool) {
+		boundary, err := r.multipartBoundary()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		body := r.Body()
+		defer drainAndClose(body)
+		mr := multipart.NewReader(body, boundary)
+		for {
+			part, err := mr.NextPart()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(nil, fmt.Errorf("read multipart response: %w", err))
+				return
+			}
+			if !yield(part, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (r *Response) multipartBoundary() (string, error) {
+	if r.raw == nil {
+		return "", ErrNotMultipart
+	}
+	mediaType, params, err := mime.ParseMediaType(r.raw.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return "", fmt.Errorf("%w: content type %q", ErrNotMultipart, r.raw.Header.Get("Content-Type"))
+	}
+	return params["boundary"], nil
+}
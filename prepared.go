@@ -0,0 +1,54 @@
+package httpc
+
+import (
+	"context"
+	"strings"
+)
+
+// Prepared is a request template compiled once by Client.Prepare for hot
+// paths. URL joining, static options and the auth provider are resolved up
+// front; each call only substitutes path parameters and applies per-call
+// options such as the body. A Prepared is safe for concurrent use.
+type Prepared struct {
+	client *client
+	base   *Request
+}
+
+// Prepare compiles method and urlTemplate (e.g. "/users/{id}", relative to
+// the base URL) together with staticOpts into a reusable Prepared request.
+func (c *client) Prepare(method, urlTemplate string, staticOpts ...ReqOption) (*Prepared, error) {
+	target, err := joinURL(c.cfg, urlTemplate)
+	if err != nil {
+		return nil, err
+	}
+	// joinURL escapes the placeholder braces; keep them literal so
+	// WithPathParams can substitute them.
+	target = braceUnescaper.Replace(target)
+
+	base := buildRequest(method, target, staticOpts...)
+	if base.err != nil {
+		return nil, base.err
+	}
+	if base.authProvider == nil {
+		base.authProvider = c.cfg.DefaultAuth
+	}
+	base.frozen = true
+	return &Prepared{client: c, base: base}, nil
+}
+
+// Do executes the prepared request with params filling the URL template.
+func (p *Prepared) Do(ctx context.Context, params map[string]string, opts ...ReqOption) (*Response, error) {
+	req := p.base
+	if len(params) > 0 || len(opts) > 0 {
+		if len(params) > 0 {
+			opts = append([]ReqOption{WithPathParams(params)}, opts...)
+		}
+		req = req.With(opts...)
+	}
+	return p.client.Do(ctx, req)
+}
+
+// Request returns the compiled template, e.g. to serialize it.
+func (p *Prepared) Request() *Request { return p.base }
+
+var braceUnescaper = strings.NewReplacer("%7B", "{", "%7D", "}", "%7b", "{", "%7d", "}")
@@ -0,0 +1,41 @@
+package httpc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gostratum/httpc/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepare(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "v1", r.Header.Get("X-Api-Version"))
+		assert.Equal(t, "secret", r.Header.Get("X-API-Key"))
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.EscapedPath() + " " + string(body)))
+	}))
+	defer server.Close()
+
+	client, err := New(
+		WithBaseURL(server.URL+"/api"),
+		WithAuth(auth.NewAPIKey(auth.APIKeyOptions{Key: "secret"})),
+	)
+	require.NoError(t, err)
+
+	prepared, err := client.Prepare(http.MethodPut, "/users/{id}", WithHeader("X-Api-Version", "v1"))
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/api/users/{id}", prepared.Request().URL())
+
+	for _, id := range []string{"1", "a b"} {
+		resp, err := prepared.Do(context.Background(), map[string]string{"id": id}, WithRaw([]byte("x"), "text/plain"))
+		require.NoError(t, err)
+		body, _ := resp.String()
+		assert.Equal(t, "PUT /api/users/"+url.PathEscape(id)+" x", body)
+	}
+}
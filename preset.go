@@ -0,0 +1,102 @@
+package httpc
+
+import (
+	"maps"
+	"net/http"
+
+	"github.com/gostratum/httpc/cache"
+	"github.com/gostratum/httpc/logging"
+)
+
+// Preset bundles opts into a single ReqOption, e.g. a team-wide "JSON API
+// call" made of an Accept header, a tenant header and a timeout. The options
+// apply in order, exactly as if they had been passed individually.
+func Preset(opts ...ReqOption) ReqOption {
+	return func(r *Request) {
+		for _, opt := range opts {
+			if opt != nil {
+				opt(r)
+			}
+		}
+	}
+}
+
+// withDefaults fills everything r leaves unset from d, the request built from
+// the client's WithRequestDefaults. Settings on r always win, as if d's
+// options had been applied first; query parameters and path parameters are
+// merged per key.
+func (r *Request) withDefaults(d *Request) {
+	for k, vv := range d.headers {
+		if _, ok := r.headers[k]; !ok {
+			r.headers[k] = append([]string(nil), vv...)
+		}
+	}
+	for k, vv := range d.queries {
+		if _, ok := r.queries[k]; !ok {
+			r.queries[k] = append([]string(nil), vv...)
+		}
+	}
+	if len(d.pathParams) > 0 {
+		params := maps.Clone(d.pathParams)
+		maps.Copy(params, r.pathParams)
+		r.pathParams = params
+	}
+	r.dynamicQuery = append(append([]dynamicQuery(nil), d.dynamicQuery...), r.dynamicQuery...)
+
+	if r.timeout == 0 {
+		r.timeout = d.timeout
+	}
+	if r.authProvider == nil {
+		r.authProvider = d.authProvider
+	}
+	if r.retryPolicy == nil {
+		r.retryPolicy = d.retryPolicy
+	}
+	if r.breakerToggle == nil {
+		r.breakerToggle = d.breakerToggle
+	}
+	if r.errorOnStatus == nil {
+		r.errorOnStatus = d.errorOnStatus
+	}
+	if r.earlyHints == nil {
+		r.earlyHints = d.earlyHints
+	}
+	if r.progress == nil {
+		r.progress = d.progress
+	}
+	if r.session == nil {
+		r.session = d.session
+	}
+	if len(d.cookies) > 0 {
+		own := r.cookies
+		r.cookies = append([]*http.Cookie(nil), d.cookies...)
+		for _, c := range own {
+			r.setCookie(c)
+		}
+	}
+	if !r.HasBody() {
+		r.bodyFactory, r.bodyStream, r.jsonBody = d.bodyFactory, d.bodyStream, d.jsonBody
+	}
+	if r.jsonAccept == nil {
+		r.jsonAccept = d.jsonAccept
+	}
+	if !r.compress && d.compress {
+		r.compress, r.compressFallback = true, d.compressFallback
+	}
+	if r.cache == (cache.Directives{}) {
+		r.cache = d.cache
+	}
+	if r.logLevel == logging.LevelDefault {
+		r.logLevel = d.logLevel
+	}
+	r.forceRetry = r.forceRetry || d.forceRetry
+	r.idempotent = r.idempotent || d.idempotent
+	r.overrideVerb = r.overrideVerb || d.overrideVerb
+	r.rawBody = r.rawBody || d.rawBody
+	r.serverName = choose(r.serverName, d.serverName)
+	r.breakerKey = choose(r.breakerKey, d.breakerKey)
+	r.apiVersion = choose(r.apiVersion, d.apiVersion)
+	r.contentType = choose(r.contentType, d.contentType)
+	r.accept = choose(r.accept, d.accept)
+	r.expectContentType = choose(r.expectContentType, d.expectContentType)
+}
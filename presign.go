@@ -0,0 +1,76 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gostratum/httpc/auth"
+)
+
+// ErrPresignUnsupported is returned by Presign when the client's default auth
+// provider cannot sign URLs (see auth.Presigner).
+var ErrPresignUnsupported = errors.New("default auth provider cannot presign URLs")
+
+// skipAuth keeps the default provider off requests whose URL already carries
+// a signature; S3 rejects requests authorised twice.
+var skipAuth = auth.ProviderFunc(func(*http.Request) error { return nil })
+
+// Presign returns a URL for method on target (joined to the base URL) that
+// is authorised by query signature for expires, using the client's SigV4
+// credentials and region. Anyone holding the URL can use it until it expires.
+func (c *client) Presign(method, target string, expires time.Duration) (string, error) {
+	presigner, ok := c.cfg.DefaultAuth.(auth.Presigner)
+	if !ok {
+		return "", ErrPresignUnsupported
+	}
+	joined, err := joinURL(c.cfg, target)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(joined)
+	if err != nil {
+		return "", fmt.Errorf("presign: %w", err)
+	}
+	signed, err := presigner.Presign(method, u, expires)
+	if err != nil {
+		return "", err
+	}
+	return signed.String(), nil
+}
+
+// UploadPresigned PUTs size bytes from open to presignedURL without
+// buffering them. open is called again for every retry.
+func (c *client) UploadPresigned(ctx context.Context, presignedURL string, size int64, open func() (io.ReadCloser, error), opts ...ReqOption) (*Response, error) {
+	stream := func(r *Request) {
+		r.ensureMutable()
+		r.setBody("UploadPresigned body", func() (io.ReadCloser, int64, string, error) {
+			rc, err := open()
+			return rc, size, "application/octet-stream", err
+		})
+	}
+	base := []ReqOption{stream, WithRequestAuth(skipAuth)}
+	return c.Do(ctx, NewRequest(http.MethodPut, presignedURL, append(base, opts...)...))
+}
+
+// DownloadPresigned streams the body at presignedURL into w without
+// buffering it. Non-2xx answers are returned as errors.
+func (c *client) DownloadPresigned(ctx context.Context, presignedURL string, w io.Writer, opts ...ReqOption) error {
+	base := []ReqOption{WithRequestAuth(skipAuth), WithRawBody()}
+	resp, err := c.Do(ctx, NewRequest(http.MethodGet, presignedURL, append(base, opts...)...))
+	if err != nil {
+		return err
+	}
+	raw := resp.Raw()
+	if raw.StatusCode < 200 || raw.StatusCode >= 300 {
+		drainAndClose(raw.Body)
+		return fmt.Errorf("download: unexpected status %d", raw.StatusCode)
+	}
+	defer raw.Body.Close()
+	_, err = io.Copy(w, raw.Body)
+	return err
+}
@@ -0,0 +1,111 @@
+package httpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// StartupProbe describes the connectivity check performed at startup.
+type StartupProbe struct {
+	Path    string
+	Timeout time.Duration
+
+	// deferred is set by NewFx so the probe runs in OnStart instead of New.
+	deferred bool
+}
+
+// StartupProbeError explains why the startup probe failed.
+type StartupProbeError struct {
+	URL        string
+	StatusCode int
+	// Hint describes the likely misconfiguration.
+	Hint string
+	Err  error
+}
+
+func (e *StartupProbeError) Error() string {
+	msg := "startup probe " + e.URL + ": " + e.Hint
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *StartupProbeError) Unwrap() error { return e.Err }
+
+// probe issues a HEAD request (falling back to GET when HEAD is not allowed)
+// and translates failures into actionable errors.
+func (c *client) probe(ctx context.Context) error {
+	p := c.cfg.StartupProbe
+	if p == nil {
+		return nil
+	}
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req := newRequest(http.MethodHead, p.Path, WithRequestRetry(noRetry{}), WithRequestErrorOnStatus(false))
+	resp, err := c.Do(ctx, req)
+	if err == nil && (resp.StatusCode() == http.StatusMethodNotAllowed || resp.StatusCode() == http.StatusNotImplemented) {
+		req = newRequest(http.MethodGet, p.Path, WithRequestRetry(noRetry{}), WithRequestErrorOnStatus(false))
+		resp, err = c.Do(ctx, req)
+	}
+
+	target := c.redactor.URLString(c.cfg.BaseURL + p.Path)
+	if err != nil {
+		return &StartupProbeError{URL: target, Hint: probeHint(err), Err: err}
+	}
+	_, _ = resp.Bytes()
+
+	status := resp.StatusCode()
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return &StartupProbeError{URL: target, StatusCode: status, Hint: fmt.Sprintf("credentials rejected with status %d; check the auth configuration", status)}
+	case status == http.StatusNotFound:
+		return &StartupProbeError{URL: target, StatusCode: status, Hint: "endpoint not found; check base_url and the probe path"}
+	case status >= 400:
+		return &StartupProbeError{URL: target, StatusCode: status, Hint: fmt.Sprintf("upstream answered with status %d", status)}
+	}
+	return nil
+}
+
+func probeHint(err error) string {
+	var dnsErr *net.DNSError
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var recordErr tls.RecordHeaderError
+	var proxyErr *ProxyError
+
+	switch {
+	case errors.As(err, &proxyErr):
+		return "rejected by proxy; check proxy credentials"
+	case errors.As(err, &dnsErr):
+		return fmt.Sprintf("DNS lookup for %q failed; check base_url", dnsErr.Name)
+	case errors.As(err, &hostnameErr):
+		return "TLS certificate does not match the host; check base_url or the server name"
+	case errors.As(err, &unknownAuthority), errors.As(err, &certErr):
+		return "TLS certificate is not trusted; check the CA bundle"
+	case errors.As(err, &recordErr):
+		return "TLS handshake failed; the endpoint may not speak TLS (http vs https)"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection refused; check the host and port"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timed out; check network reachability and firewall rules"
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "timed out; check network reachability and firewall rules"
+	}
+	return "request failed"
+}
@@ -0,0 +1,64 @@
+package httpc
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithStartupProbe(t *testing.T) {
+	t.Run("passes_and_falls_back_to_get", func(t *testing.T) {
+		var methods []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			methods = append(methods, r.Method)
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		_, err := New(WithBaseURL(server.URL), WithStartupProbe("/ping", time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, []string{http.MethodHead, http.MethodGet}, methods)
+
+		methods = nil
+		_, err = New(WithBaseURL(server.URL), WithErrorOnStatus(), WithStartupProbe("/ping", time.Second))
+		require.NoError(t, err, "WithErrorOnStatus must not stop the GET fallback")
+		assert.Equal(t, []string{http.MethodHead, http.MethodGet}, methods)
+	})
+
+	t.Run("reports_rejected_credentials", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer server.Close()
+
+		_, err := New(
+			WithBaseURL(server.URL),
+			WithAuth(auth.NewAPIKey(auth.APIKeyOptions{Key: "wrong"})),
+			WithStartupProbe("/ping", time.Second),
+		)
+		var probeErr *StartupProbeError
+		require.True(t, errors.As(err, &probeErr))
+		assert.Equal(t, http.StatusUnauthorized, probeErr.StatusCode)
+		assert.Contains(t, err.Error(), "check the auth configuration")
+	})
+
+	t.Run("reports_connection_refused", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		addr := server.URL
+		server.Close()
+
+		_, err := New(WithBaseURL(addr), WithRetry(false, 0), WithStartupProbe("/ping", time.Second))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "connection refused")
+	})
+}
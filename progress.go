@@ -0,0 +1,48 @@
+package httpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"runtime/debug"
+)
+
+// Progress is an informational response received while a long-running
+// request is processed, e.g. 102 Processing from a report generation
+// endpoint, with any progress headers the server sent along.
+type Progress struct {
+	StatusCode int
+	Header     http.Header
+}
+
+// WithProgress calls fn for every informational response other than 100
+// Continue and 103 Early Hints received before the final response, so slow
+// endpoints can report that work is under way. Like WithEarlyHints, fn runs
+// on the transport goroutine and should not block. Pair it with a long
+// enough WithRequestTimeout and, against idle-timeout proxies, with
+// WithHTTP2Ping or WithTCPKeepAlive.
+func WithProgress(fn func(Progress)) ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.progress = fn
+	}
+}
+
+// withProgressTrace registers fn for informational responses.
+func withProgressTrace(ctx context.Context, fn func(Progress)) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) (err error) {
+			if code == http.StatusContinue || code == http.StatusEarlyHints {
+				return nil
+			}
+			defer func() {
+				if v := recover(); v != nil {
+					err = &PanicError{Value: v, Stack: debug.Stack()}
+				}
+			}()
+			fn(Progress{StatusCode: code, Header: http.Header(header).Clone()})
+			return nil
+		},
+	})
+}
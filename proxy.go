@@ -0,0 +1,125 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// ErrProxyAuthRequired is matched (via errors.Is) by errors returned when a
+// forward proxy answers 407 Proxy Authentication Required.
+var ErrProxyAuthRequired = errors.New("proxy authentication required")
+
+// ProxyError describes a request rejected by a forward proxy.
+type ProxyError struct {
+	// Proxy is the proxy URL with credentials stripped.
+	Proxy string
+	// StatusCode is the status returned by the proxy.
+	StatusCode int
+	// Challenge carries the Proxy-Authenticate header, if any.
+	Challenge string
+	// Tunnel reports whether the failure happened while establishing a
+	// CONNECT tunnel (https targets) rather than on a forwarded request.
+	Tunnel bool
+}
+
+func (e *ProxyError) Error() string {
+	via := "forwarded request"
+	if e.Tunnel {
+		via = "CONNECT tunnel"
+	}
+	msg := fmt.Sprintf("proxy %s rejected %s with status %d", e.Proxy, via, e.StatusCode)
+	if e.Challenge != "" {
+		msg += " (challenge: " + e.Challenge + ")"
+	}
+	return msg
+}
+
+// Is reports ErrProxyAuthRequired for 407 responses.
+func (e *ProxyError) Is(target error) bool {
+	return target == ErrProxyAuthRequired && e.StatusCode == http.StatusProxyAuthRequired
+}
+
+// proxyURL resolves the configured proxy, embedding basic credentials so the
+// transport sends Proxy-Authorization on both CONNECT and forwarded requests.
+func (c Config) proxyURL() (*url.URL, error) {
+	if c.Proxy.URL == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(c.Proxy.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: scheme and host are required", c.Proxy.URL)
+	}
+	if c.Proxy.Username != "" {
+		u.User = url.UserPassword(c.Proxy.Username, c.Proxy.Password)
+	}
+	return u, nil
+}
+
+func (c Config) proxyHeaders() http.Header {
+	if len(c.Proxy.Headers) == 0 {
+		return nil
+	}
+	h := make(http.Header, len(c.Proxy.Headers))
+	for k, v := range c.Proxy.Headers {
+		h.Set(k, v)
+	}
+	return h
+}
+
+func onProxyConnectResponse(_ context.Context, proxy *url.URL, _ *http.Request, resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	return &ProxyError{
+		Proxy:      redactedProxy(proxy),
+		StatusCode: resp.StatusCode,
+		Challenge:  resp.Header.Get("Proxy-Authenticate"),
+		Tunnel:     true,
+	}
+}
+
+// newProxyMiddleware adds header-based proxy credentials to plain-http
+// requests (https requests carry them on CONNECT) and converts 407 responses
+// from the proxy into *ProxyError.
+func newProxyMiddleware(proxy *url.URL, headers http.Header) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if proxy != nil && len(headers) > 0 && req.URL.Scheme == "http" {
+				// The caller's request must not be modified, and retries
+				// resend it.
+				req = req.Clone(req.Context())
+				for k, vv := range headers {
+					if req.Header.Get(k) == "" {
+						req.Header[k] = append([]string(nil), vv...)
+					}
+				}
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil || resp == nil || resp.StatusCode != http.StatusProxyAuthRequired {
+				return resp, err
+			}
+			if resp.Body != nil {
+				_ = resp.Body.Close()
+			}
+			return nil, &ProxyError{
+				Proxy:      redactedProxy(proxy),
+				StatusCode: resp.StatusCode,
+				Challenge:  resp.Header.Get("Proxy-Authenticate"),
+			}
+		})
+	}
+}
+
+func redactedProxy(u *url.URL) string {
+	if u == nil {
+		return "(environment)"
+	}
+	return u.Redacted()
+}
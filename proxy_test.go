@@ -0,0 +1,111 @@
+package httpc
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyAuth(t *testing.T) {
+	newProxy := func(t *testing.T, wantAuth string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Proxy-Authorization") != wantAuth {
+				w.Header().Set("Proxy-Authenticate", `Basic realm="corp"`)
+				w.WriteHeader(http.StatusProxyAuthRequired)
+				return
+			}
+			assert.Equal(t, "upstream.invalid", r.Host)
+			w.WriteHeader(http.StatusOK)
+		}))
+	}
+
+	t.Run("sends_basic_credentials", func(t *testing.T) {
+		basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("alice:s3cret"))
+		proxy := newProxy(t, basic)
+		defer proxy.Close()
+
+		client, err := New(WithProxy(proxy.URL), WithProxyAuth("alice", "s3cret"))
+		require.NoError(t, err)
+
+		resp, err := client.Get(context.Background(), "http://upstream.invalid/")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode())
+	})
+
+	t.Run("sends_header_credentials", func(t *testing.T) {
+		proxy := newProxy(t, "Bearer proxy-token")
+		defer proxy.Close()
+
+		client, err := New(WithProxy(proxy.URL), WithProxyHeader("Proxy-Authorization", "Bearer proxy-token"))
+		require.NoError(t, err)
+
+		resp, err := client.Get(context.Background(), "http://upstream.invalid/")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode())
+	})
+
+	t.Run("forwarded_407_is_typed", func(t *testing.T) {
+		proxy := newProxy(t, "never")
+		defer proxy.Close()
+
+		client, err := New(WithProxy(proxy.URL), WithRetry(false, 0))
+		require.NoError(t, err)
+
+		_, err = client.Get(context.Background(), "http://upstream.invalid/")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrProxyAuthRequired))
+
+		var proxyErr *ProxyError
+		require.True(t, errors.As(err, &proxyErr))
+		assert.False(t, proxyErr.Tunnel)
+		assert.Equal(t, `Basic realm="corp"`, proxyErr.Challenge)
+	})
+
+	t.Run("connect_407_is_typed", func(t *testing.T) {
+		proxy := newProxy(t, "never")
+		defer proxy.Close()
+
+		client, err := New(WithProxy(proxy.URL), WithProxyAuth("alice", "wrong"), WithRetry(false, 0))
+		require.NoError(t, err)
+
+		_, err = client.Get(context.Background(), "https://upstream.invalid/")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrProxyAuthRequired))
+
+		var proxyErr *ProxyError
+		require.True(t, errors.As(err, &proxyErr))
+		assert.True(t, proxyErr.Tunnel)
+		assert.NotContains(t, proxyErr.Error(), "wrong")
+	})
+
+	t.Run("leaves_caller_request_untouched", func(t *testing.T) {
+		proxy, err := url.Parse("http://proxy.invalid:3128")
+		require.NoError(t, err)
+		headers := http.Header{"Proxy-Authorization": {"Bearer proxy-token"}}
+
+		var sent *http.Request
+		rt := newProxyMiddleware(proxy, headers)(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			sent = req
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		}))
+
+		req, err := http.NewRequest(http.MethodGet, "http://upstream.invalid/", nil)
+		require.NoError(t, err)
+		_, err = rt.RoundTrip(req)
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer proxy-token", sent.Header.Get("Proxy-Authorization"))
+		assert.Empty(t, req.Header.Get("Proxy-Authorization"))
+	})
+
+	t.Run("rejects_invalid_proxy_url", func(t *testing.T) {
+		_, err := New(WithProxy("not a url"))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,138 @@
+package ratelimit
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Info is the quota a server reported in its response headers. Limit and
+// Remaining are -1 when not reported; Reset and RetryAt are zero.
+type Info struct {
+	// Limit is the request quota for the current window.
+	Limit int
+	// Remaining is the quota left in the current window.
+	Remaining int
+	// Reset is when the current window ends.
+	Reset time.Time
+	// Window is the quota window length, when the server states it (w= in
+	// RateLimit-Limit or RateLimit-Policy).
+	Window time.Duration
+	// RetryAt is the Retry-After time.
+	RetryAt time.Time
+}
+
+// Reported reports whether any rate limit header was present.
+func (i Info) Reported() bool {
+	return i.Limit >= 0 || i.Remaining >= 0 || !i.Reset.IsZero() || !i.RetryAt.IsZero()
+}
+
+// Exhausted reports whether the server said no quota is left.
+func (i Info) Exhausted() bool { return i.Remaining == 0 }
+
+// ResumeAt is when requests should resume: RetryAt when set, otherwise
+// Reset. ok is false when the server reported neither.
+func (i Info) ResumeAt() (t time.Time, ok bool) {
+	if !i.RetryAt.IsZero() {
+		return i.RetryAt, true
+	}
+	return i.Reset, !i.Reset.IsZero()
+}
+
+// ParseHeaders extracts rate limit information from h, resolving relative
+// times against now. It understands the de facto X-RateLimit-* headers, the
+// IETF draft RateLimit-Limit/Remaining/Reset headers, the combined draft
+// RateLimit header (limit=, remaining=, reset= or r=, t=), and Retry-After.
+// Reset values above one billion are read as Unix timestamps, as GitHub and
+// others send them; smaller values are seconds from now.
+func ParseHeaders(h http.Header, now time.Time) Info {
+	info := Info{Limit: -1, Remaining: -1}
+
+	if v := h.Get("RateLimit"); v != "" {
+		for _, param := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ';' }) {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok {
+				continue
+			}
+			n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				continue
+			}
+			switch strings.ToLower(name) {
+			case "limit", "q":
+				info.Limit = int(n)
+			case "remaining", "r":
+				info.Remaining = int(n)
+			case "reset", "t":
+				info.Reset = resetTime(n, now)
+			case "w":
+				info.Window = time.Duration(n) * time.Second
+			}
+		}
+	}
+
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		if n, ok := leadingInt(h.Get(prefix + "Limit")); ok && info.Limit < 0 {
+			info.Limit = int(n)
+		}
+		if n, ok := leadingInt(h.Get(prefix + "Remaining")); ok && info.Remaining < 0 {
+			info.Remaining = int(n)
+		}
+		if n, ok := leadingInt(h.Get(prefix + "Reset")); ok && info.Reset.IsZero() {
+			info.Reset = resetTime(n, now)
+		}
+	}
+
+	if info.Window == 0 {
+		for _, name := range []string{"RateLimit-Limit", "RateLimit-Policy", "X-RateLimit-Limit"} {
+			if w := windowParam(h.Get(name)); w > 0 {
+				info.Window = w
+				break
+			}
+		}
+	}
+
+	if v := strings.TrimSpace(h.Get("Retry-After")); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			info.RetryAt = now.Add(time.Duration(secs) * time.Second)
+		} else if t, err := http.ParseTime(v); err == nil {
+			info.RetryAt = t
+		}
+	}
+	return info
+}
+
+func resetTime(n int64, now time.Time) time.Time {
+	if n > 1_000_000_000 {
+		return time.Unix(n, 0)
+	}
+	return now.Add(time.Duration(n) * time.Second)
+}
+
+// leadingInt parses the integer before any list or parameter separator, so
+// "100, 100;w=60" yields 100.
+func leadingInt(v string) (int64, bool) {
+	v = strings.TrimSpace(v)
+	if i := strings.IndexAny(v, ",;"); i >= 0 {
+		v = strings.TrimSpace(v[:i])
+	}
+	if v == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	return n, err == nil && n >= 0
+}
+
+// windowParam extracts the w= parameter from a quota policy value.
+func windowParam(v string) time.Duration {
+	for _, param := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ';' }) {
+		name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if ok && strings.EqualFold(name, "w") {
+			if secs, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && secs > 0 {
+				return time.Duration(secs) * time.Second
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,212 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gostratum/httpc/clock"
+)
+
+// ErrRateLimited is returned when a request would have to wait longer than
+// MaxWait, or past its context deadline, for a token.
+var ErrRateLimited = errors.New("rate limited")
+
+// Config sets a token-bucket limit per key (the request host).
+type Config struct {
+	// Rate is the sustained number of requests per second allowed per host.
+	// Defaults to 10.
+	Rate float64
+	// Burst is the number of requests that may be sent back to back.
+	// Defaults to the rate rounded up.
+	Burst int
+	// MaxWait bounds how long a request waits for a token; zero waits until
+	// the request context is done.
+	MaxWait time.Duration
+	// Clock drives token refill and waits; defaults to the real clock.
+	Clock clock.Clock
+	// Learn paces hosts from their responses. A 429, or a response reporting
+	// no remaining quota, holds the host until its Retry-After or
+	// X-RateLimit-Reset time and halves its rate (down to Rate/16); the rate
+	// recovers gradually as requests succeed.
+	Learn bool
+}
+
+// Observer is implemented by limiters that learn from responses.
+// NewMiddleware reports every response to limiters implementing it.
+type Observer interface {
+	Observe(key string, resp *http.Response)
+}
+
+// Limiter admits requests for a key. Implementations must be safe for
+// concurrent use so one limiter can be shared by several clients.
+type Limiter interface {
+	Wait(ctx context.Context, key string) error
+}
+
+// New returns a token-bucket Limiter keyed by host.
+func New(cfg Config) Limiter {
+	if cfg.Rate <= 0 {
+		cfg.Rate = 10
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = int(math.Ceil(cfg.Rate))
+	}
+	return &limiter{cfg: cfg, clk: clock.OrReal(cfg.Clock), buckets: make(map[string]*tokenBucket)}
+}
+
+type limiter struct {
+	cfg Config
+	clk clock.Clock
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	rate   float64
+	paused time.Time
+}
+
+func (l *limiter) Wait(ctx context.Context, key string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	wait := l.reserve(key)
+	if wait <= 0 {
+		return nil
+	}
+	if l.cfg.MaxWait > 0 && wait > l.cfg.MaxWait {
+		l.cancel(key)
+		return ErrRateLimited
+	}
+	if deadline, ok := ctx.Deadline(); ok && l.clk.Now().Add(wait).After(deadline) {
+		l.cancel(key)
+		return ErrRateLimited
+	}
+
+	timer := l.clk.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		l.cancel(key)
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token, letting the bucket go negative so later callers
+// queue behind earlier ones, and returns how long the caller must wait.
+func (l *limiter) reserve(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clk.Now()
+	b := l.bucket(key, now)
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(float64(l.cfg.Burst), b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+	b.tokens--
+
+	var wait time.Duration
+	if b.paused.After(now) {
+		wait = b.paused.Sub(now)
+	}
+	if b.tokens < 0 {
+		wait += time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	return wait
+}
+
+// bucket must be called with l.mu held.
+func (l *limiter) bucket(key string, now time.Time) *tokenBucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(l.cfg.Burst), last: now, rate: l.cfg.Rate}
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// Observe implements Observer. It is a no-op unless Config.Learn is set.
+func (l *limiter) Observe(key string, resp *http.Response) {
+	if !l.cfg.Learn || resp == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clk.Now()
+	b := l.bucket(key, now)
+	info := ParseHeaders(resp.Header, now)
+	limited := resp.StatusCode == http.StatusTooManyRequests
+	if !limited && !info.Exhausted() {
+		if resp.StatusCode < 400 && b.rate < l.cfg.Rate {
+			b.rate = math.Min(l.cfg.Rate, b.rate+l.cfg.Rate/20)
+		}
+		return
+	}
+
+	if limited {
+		b.rate = math.Max(l.cfg.Rate/16, b.rate/2)
+	}
+	until, ok := info.ResumeAt()
+	if !ok {
+		if !limited {
+			return
+		}
+		until = now.Add(time.Duration(float64(time.Second) / b.rate))
+	}
+	if until.After(b.paused) {
+		b.paused = until
+	}
+	// Drop the burst to a single request and earn no tokens while paused so
+	// the host is not hit by a burst the moment the pause ends.
+	b.tokens = math.Min(b.tokens, 1)
+	if b.paused.After(b.last) {
+		b.last = b.paused
+	}
+}
+
+// cancel returns a reserved token that was not used.
+func (l *limiter) cancel(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b, ok := l.buckets[key]; ok {
+		b.tokens = math.Min(float64(l.cfg.Burst), b.tokens+1)
+	}
+}
+
+// NewMiddleware constructs a middleware that waits on l before every attempt,
+// keyed by request host, and reports responses to l if it is an Observer.
+func NewMiddleware(l Limiter) func(http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			host := ""
+			if req.URL != nil {
+				host = req.URL.Host
+			}
+			if err := l.Wait(req.Context(), host); err != nil {
+				return nil, err
+			}
+			resp, err := next.RoundTrip(req)
+			if observer, ok := l.(Observer); ok && err == nil {
+				observer.Observe(host, resp)
+			}
+			return resp, err
+		})
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,152 @@
+package httpc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrRawBody is returned by the buffering Response helpers for requests sent
+// with WithRawBody, whose body belongs to the caller.
+var ErrRawBody = errors.New("response body is owned by the caller (WithRawBody)")
+
+// ErrBodyConsumed is returned when a response body is read both through
+// Raw().Body and through a Response helper. Either reader would otherwise
+// see a truncated or empty body.
+var ErrBodyConsumed = errors.New("response body already consumed")
+
+// WithRawBody hands the response body to the caller: Response helpers such
+// as Bytes, String, DecodeJSON and IntoWriter fail with ErrRawBody instead of
+// buffering it, so Raw().Body is always unread. The caller owns the body and
+// must close it, or the connection is never returned to the pool. Raw bodies
+// are not counted against WithMaxBufferedBytes.
+func WithRawBody() ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.rawBody = true
+	}
+}
+
+// WithStreamResponse is WithRawBody for downloads and other large or
+// long-lived bodies: the body is never buffered and is consumed with
+// Response.Body or Response.Stream.
+func WithStreamResponse() ReqOption {
+	return WithRawBody()
+}
+
+// Body returns the response body as a stream, without buffering it; the
+// caller must close it. Once it has been read, the buffering helpers fail
+// with ErrBodyConsumed. A body a helper already buffered is read from the
+// buffer, and one that overflowed WithMaxBufferedBytes includes the part
+// read before the limit was hit.
+func (r *Response) Body() io.ReadCloser {
+	switch {
+	case r.loaded:
+		return io.NopCloser(bytes.NewReader(r.body))
+	case r.raw == nil || r.raw.Body == nil:
+		return http.NoBody
+	case r.pending != nil:
+		pending := r.pending
+		r.pending = nil
+		body := r.bodySource()
+		return struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(pending), body), body}
+	}
+	return r.raw.Body
+}
+
+// Stream copies the body to w as it arrives and closes it, returning the
+// number of bytes copied. Unlike IntoWriter it never buffers the body.
+func (r *Response) Stream(w io.Writer) (int64, error) {
+	body := r.Body()
+	defer body.Close()
+	return io.Copy(w, body)
+}
+
+// guardedBody is the body exposed through Raw(). It records reads made by
+// the caller and rejects them once a helper has consumed the body; helpers
+// read the wrapped body directly.
+type guardedBody struct {
+	io.ReadCloser
+	read     bool
+	consumed bool
+}
+
+func (g *guardedBody) Read(p []byte) (int, error) {
+	if g.consumed {
+		return 0, fmt.Errorf("%w: it was read by a Response helper; send the request WithRawBody to read Raw().Body", ErrBodyConsumed)
+	}
+	g.read = true
+	return g.ReadCloser.Read(p)
+}
+
+// bodySource is the body the helpers read, bypassing the Raw() guard.
+func (r *Response) bodySource() io.ReadCloser {
+	if r.guard != nil {
+		return r.guard.ReadCloser
+	}
+	return r.raw.Body
+}
+
+// maxErrorDrain bounds how much of a long error body is discarded so its
+// connection can be reused; longer bodies close the connection.
+const maxErrorDrain = 256 << 10
+
+// keepPrefix reads up to n bytes from the start of the body and closes it,
+// releasing the connection, timeout and bulkhead slot held by the request.
+// A body of at most n bytes stays readable through the helpers; a longer
+// one is drained up to maxErrorDrain and the helpers then fail with
+// ErrBodyConsumed. Bytes read before a read error are returned with it.
+func (r *Response) keepPrefix(n int64) ([]byte, error) {
+	switch {
+	case r.loaded:
+		return r.body[:min(int64(len(r.body)), n)], nil
+	case r.pending != nil:
+		return r.pending[:min(int64(len(r.pending)), n)], nil
+	case r.err != nil:
+		return nil, r.err
+	case r.raw == nil || r.raw.Body == nil:
+		return nil, nil
+	}
+	if err := r.claimBody(); err != nil {
+		return nil, err
+	}
+
+	src := r.bodySource()
+	defer src.Close()
+	r.markConsumed()
+	b, err := io.ReadAll(io.LimitReader(src, n+1))
+	switch {
+	case err != nil:
+		r.err = err
+		return b[:min(int64(len(b)), n)], err
+	case int64(len(b)) <= n:
+		r.body = b
+		r.loaded = true
+		return b, nil
+	}
+	_, _ = io.CopyN(io.Discard, src, maxErrorDrain)
+	r.err = fmt.Errorf("%w: only its first %d bytes were kept for HTTPError.Body", ErrBodyConsumed, n)
+	return b[:n], nil
+}
+
+// claimBody reports whether the helpers may read the body.
+func (r *Response) claimBody() error {
+	if r.rawBody {
+		return ErrRawBody
+	}
+	if r.guard != nil && r.guard.read {
+		return fmt.Errorf("%w: it was read through Raw().Body", ErrBodyConsumed)
+	}
+	return nil
+}
+
+func (r *Response) markConsumed() {
+	if r.guard != nil {
+		r.guard.consumed = true
+	}
+}
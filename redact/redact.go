@@ -0,0 +1,265 @@
+package redact
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// DefaultMask replaces redacted values.
+const DefaultMask = "[REDACTED]"
+
+// Rules lists what must be masked in diagnostics output. Names are matched
+// case-insensitively and may contain path.Match style wildcards such as
+// "x-*-token".
+type Rules struct {
+	Headers     []string
+	QueryParams []string
+	JSONFields  []string
+	// Mask replaces redacted values; defaults to DefaultMask.
+	Mask string
+}
+
+// DefaultRules covers the credentials handled by this module's auth providers
+// plus common token and signature parameters.
+func DefaultRules() Rules {
+	return Rules{
+		Headers: []string{
+			"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie",
+			"X-API-Key", "X-Auth-Token", "*-token", "*-secret",
+		},
+		QueryParams: []string{
+			"api_key", "apikey", "access_token", "token", "sig", "signature",
+			"password", "secret", "client_secret", "x-amz-signature", "x-amz-credential",
+		},
+		JSONFields: []string{
+			"password", "secret", "token", "access_token", "refresh_token",
+			"id_token", "client_secret", "api_key",
+		},
+	}
+}
+
+// Merge returns the union of r and other. A non-empty Mask on other wins.
+func (r Rules) Merge(other Rules) Rules {
+	out := Rules{
+		Headers:     append(append([]string(nil), r.Headers...), other.Headers...),
+		QueryParams: append(append([]string(nil), r.QueryParams...), other.QueryParams...),
+		JSONFields:  append(append([]string(nil), r.JSONFields...), other.JSONFields...),
+		Mask:        r.Mask,
+	}
+	if other.Mask != "" {
+		out.Mask = other.Mask
+	}
+	return out
+}
+
+// Redactor applies Rules. It is safe for concurrent use.
+type Redactor struct {
+	headers []string
+	query   []string
+	fields  []string
+	mask    string
+}
+
+// New compiles rules into a Redactor.
+func New(rules Rules) *Redactor {
+	mask := rules.Mask
+	if mask == "" {
+		mask = DefaultMask
+	}
+	return &Redactor{
+		headers: lower(rules.Headers),
+		query:   lower(rules.QueryParams),
+		fields:  lower(rules.JSONFields),
+		mask:    mask,
+	}
+}
+
+// Default returns a Redactor using DefaultRules.
+func Default() *Redactor { return New(DefaultRules()) }
+
+// Mask returns the replacement string.
+func (r *Redactor) Mask() string { return r.mask }
+
+// IsSensitiveHeader reports whether the header must be masked.
+func (r *Redactor) IsSensitiveHeader(name string) bool {
+	return matches(r.headers, name)
+}
+
+// IsSensitiveQuery reports whether the query parameter must be masked.
+func (r *Redactor) IsSensitiveQuery(name string) bool {
+	return matches(r.query, name)
+}
+
+// Header returns a copy of h with sensitive values masked.
+func (r *Redactor) Header(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for k, vv := range h {
+		if r.IsSensitiveHeader(k) {
+			masked := make([]string, len(vv))
+			for i := range masked {
+				masked[i] = r.mask
+			}
+			out[k] = masked
+			continue
+		}
+		out[k] = append([]string(nil), vv...)
+	}
+	return out
+}
+
+// URL renders u with user info and sensitive query values masked.
+func (r *Redactor) URL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	cp := *u
+	if cp.User != nil {
+		if _, hasPassword := cp.User.Password(); hasPassword {
+			cp.User = url.UserPassword(cp.User.Username(), r.mask)
+		}
+	}
+	if cp.RawQuery != "" {
+		cp.RawQuery = r.Query(cp.RawQuery)
+	}
+	return cp.String()
+}
+
+type pathTemplateKey struct{}
+
+// WithPathTemplate records the unexpanded path (e.g. /users/{id}) of the
+// request carrying ctx so diagnostics can log it instead of the real path.
+func WithPathTemplate(ctx context.Context, template string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, pathTemplateKey{}, template)
+}
+
+// PathTemplate returns the template set with WithPathTemplate, if any.
+func PathTemplate(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	t, _ := ctx.Value(pathTemplateKey{}).(string)
+	return t
+}
+
+// Request renders the request URL like URL, substituting the path template
+// from the request context when one is set so path parameter values such as
+// emails never reach logs.
+func (r *Redactor) Request(req *http.Request) string {
+	if req == nil {
+		return ""
+	}
+	template := PathTemplate(req.Context())
+	if template == "" || req.URL == nil {
+		return r.URL(req.URL)
+	}
+	cp := *req.URL
+	cp.Path, cp.RawPath, cp.RawQuery, cp.Fragment = "", "", "", ""
+	out := r.URL(&cp) + template
+	if req.URL.RawQuery != "" {
+		out += "?" + r.Query(req.URL.RawQuery)
+	}
+	return out
+}
+
+// URLString is URL for raw strings; unparsable input is returned unchanged.
+func (r *Redactor) URLString(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return r.URL(u)
+}
+
+// Query masks sensitive parameters in an encoded query string, preserving
+// parameter order.
+func (r *Redactor) Query(rawQuery string) string {
+	parts := strings.Split(rawQuery, "&")
+	for i, part := range parts {
+		key, _, hasValue := strings.Cut(part, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if hasValue && r.IsSensitiveQuery(name) {
+			parts[i] = key + "=" + url.QueryEscape(r.mask)
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// JSON masks sensitive fields at any depth of a JSON document. Bodies that
+// are not valid JSON are returned unchanged.
+func (r *Redactor) JSON(body []byte) []byte {
+	if len(r.fields) == 0 || len(bytes.TrimSpace(body)) == 0 {
+		return body
+	}
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return body
+	}
+	if !r.walk(doc) {
+		return body
+	}
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+func (r *Redactor) walk(v any) bool {
+	changed := false
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			if matches(r.fields, k) {
+				t[k] = r.mask
+				changed = true
+				continue
+			}
+			if r.walk(child) {
+				changed = true
+			}
+		}
+	case []any:
+		for _, child := range t {
+			if r.walk(child) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+func matches(patterns []string, name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range patterns {
+		if p == name {
+			return true
+		}
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func lower(in []string) []string {
+	out := make([]string, 0, len(in))
+	for _, s := range in {
+		if s = strings.ToLower(strings.TrimSpace(s)); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -2,8 +2,10 @@ package httpc
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -12,13 +14,26 @@ import (
 	"net/url"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gostratum/httpc/auth"
+	"github.com/gostratum/httpc/cache"
+	"github.com/gostratum/httpc/logging"
 	"github.com/gostratum/httpc/retry"
 )
 
 // ReqOption applies configuration to a Request before it is executed.
+//
+// Options apply in order and the last one wins when two set the same thing:
+// WithHeader, WithHeaders, WithAccept and WithContentType replace earlier
+// values, while WithQuery, WithQueryMap and WithPathParams accumulate. Body
+// options (WithJSON, WithForm, WithRaw, WithMultipart, WithBodyStream or a
+// verb helper's body argument) are the exception: supplying two in one
+// construction is an ErrConflictingBody error, reported by Request.Err and
+// returned by Do. Request.With may replace the body of the request it
+// derives from. Wrapper libraries can inspect the result with Header, Query
+// and HasBody and adjust it with With.
 type ReqOption func(*Request)
 
 // MultipartFile describes a file part within a multipart/form-data request.
@@ -31,28 +46,80 @@ type MultipartFile struct {
 
 type bodyProvider func() (io.ReadCloser, int64, string, error)
 
-// Request captures the data required to execute an HTTP call.
+// Request captures the data required to execute an HTTP call. A Request is
+// not modified once built by its options, so the same *Request may be
+// executed concurrently and repeatedly; each Do works on a private copy.
 type Request struct {
 	method string
 	url    string
 
-	headers http.Header
-	queries url.Values
+	headers    http.Header
+	queries    url.Values
+	pathParams map[string]string
+
+	dynamicQuery []dynamicQuery
 
 	timeout       time.Duration
 	authProvider  auth.AuthProvider
 	retryPolicy   retry.Policy
 	forceRetry    bool
+	idempotent    bool
+	overrideVerb  bool
+	serverName    string
+	apiVersion    string
 	breakerToggle *bool
+	breakerKey    string
+	errorOnStatus *bool
 
 	bodyFactory bodyProvider
+	bodyStream  func() (io.ReadCloser, error)
 	contentType string
 	accept      string
+	jsonBody    bool
+	jsonAccept  *string
+
+	expectContentType string
+
+	compress         bool
+	compressFallback bool
+
+	cache      cache.Directives
+	logLevel   logging.Level
+	earlyHints func([]EarlyHint)
+	progress   func(Progress)
+	rawBody    bool
+	session    *Session
+	cookies    []*http.Cookie
+
+	// bodyFrom names the body option applied during the current
+	// construction; err records a construction failure reported by Do.
+	bodyFrom string
+	err      error
+
+	// frozen is set once construction finishes; options applied afterwards
+	// panic so a Request shared between goroutines cannot change under Do.
+	frozen bool
+}
+
+// NewRequest constructs a Request for use with Client.Do. Most callers use the
+// verb helpers on Client instead. The returned Request is frozen: it can be
+// executed many times, concurrently, and is never modified by Do. Use With to
+// derive a variant.
+func NewRequest(method, target string, opts ...ReqOption) *Request {
+	return newRequest(method, target, opts...)
 }
 
-// newRequest constructs a Request with defaults and applies the provided
-// options. Users typically rely on the helper methods on Client instead.
+// newRequest constructs a Request with defaults, applies the provided options
+// and freezes it. Users typically rely on the helper methods on Client instead.
 func newRequest(method, target string, opts ...ReqOption) *Request {
+	req := buildRequest(method, target, opts...)
+	req.frozen = true
+	return req
+}
+
+// buildRequest is newRequest without freezing, for callers that still need to
+// set fields directly.
+func buildRequest(method, target string, opts ...ReqOption) *Request {
 	req := &Request{
 		method:  strings.ToUpper(method),
 		url:     target,
@@ -65,27 +132,115 @@ func newRequest(method, target string, opts ...ReqOption) *Request {
 	return req
 }
 
+// With returns a frozen copy of r with opts applied; r is left unchanged.
+func (r *Request) With(opts ...ReqOption) *Request {
+	clone := r.clone()
+	clone.bodyFrom = ""
+	for _, opt := range opts {
+		opt(clone)
+	}
+	clone.frozen = true
+	return clone
+}
+
+func (r *Request) ensureMutable() {
+	if r.frozen {
+		panic("httpc: ReqOption applied to a built Request; use Request.With to derive a modified copy")
+	}
+}
+
 // Method returns the HTTP method associated with the request.
 func (r *Request) Method() string { return r.method }
 
 // URL returns the raw URL (possibly relative) associated with the request.
 func (r *Request) URL() string { return r.url }
 
-// clone produces a deep copy used when attempts are retried.
+// Header returns the first value set for the header key, including values
+// set through WithAccept and WithContentType. Headers added later by the
+// client (auth, User-Agent, a body's default Content-Type or a JSON body's
+// default Accept) are not reported.
+func (r *Request) Header(key string) string {
+	if v := r.headers.Get(key); v != "" {
+		return v
+	}
+	switch http.CanonicalHeaderKey(key) {
+	case "Accept":
+		return r.accept
+	case "Content-Type":
+		return r.contentType
+	}
+	return ""
+}
+
+// Query returns the first value of the query parameter key.
+func (r *Request) Query(key string) string { return r.queries.Get(key) }
+
+// HasBody reports whether a body option has been applied.
+func (r *Request) HasBody() bool { return r.bodyFactory != nil || r.bodyStream != nil }
+
+// Err returns the error, if any, recorded while applying the request options,
+// such as an ErrConflictingBody. Do returns it without sending the request.
+func (r *Request) Err() error { return r.err }
+
+// ErrConflictingBody is matched (via errors.Is) when more than one body
+// option is applied to the same request.
+var ErrConflictingBody = errors.New("conflicting body options")
+
+// claimBody records that option is setting the body, failing the request if
+// another body option already did during this construction.
+func (r *Request) claimBody(option string) {
+	if r.bodyFrom != "" && r.err == nil {
+		r.err = fmt.Errorf("%w: %s after %s", ErrConflictingBody, option, r.bodyFrom)
+	}
+	r.bodyFrom = option
+	r.jsonBody = false
+}
+
+// setBody installs factory as the request body for the named option.
+func (r *Request) setBody(option string, factory bodyProvider) {
+	r.claimBody(option)
+	r.bodyFactory = factory
+	r.bodyStream = nil
+}
+
+// clone produces an unfrozen deep copy, used by Do as its working copy and by
+// With.
 func (r *Request) clone() *Request {
 	clone := &Request{
-		method:        r.method,
-		url:           r.url,
-		timeout:       r.timeout,
-		authProvider:  r.authProvider,
-		retryPolicy:   r.retryPolicy,
-		forceRetry:    r.forceRetry,
-		breakerToggle: r.breakerToggle,
-		contentType:   r.contentType,
-		accept:        r.accept,
-		bodyFactory:   r.bodyFactory,
-		headers:       make(http.Header, len(r.headers)),
-		queries:       make(url.Values, len(r.queries)),
+		method:            r.method,
+		url:               r.url,
+		timeout:           r.timeout,
+		authProvider:      r.authProvider,
+		retryPolicy:       r.retryPolicy,
+		forceRetry:        r.forceRetry,
+		idempotent:        r.idempotent,
+		overrideVerb:      r.overrideVerb,
+		serverName:        r.serverName,
+		apiVersion:        r.apiVersion,
+		breakerToggle:     r.breakerToggle,
+		breakerKey:        r.breakerKey,
+		errorOnStatus:     r.errorOnStatus,
+		contentType:       r.contentType,
+		accept:            r.accept,
+		jsonBody:          r.jsonBody,
+		jsonAccept:        r.jsonAccept,
+		expectContentType: r.expectContentType,
+		bodyFactory:       r.bodyFactory,
+		bodyStream:        r.bodyStream,
+		compress:          r.compress,
+		compressFallback:  r.compressFallback,
+		cache:             r.cache,
+		logLevel:          r.logLevel,
+		earlyHints:        r.earlyHints,
+		progress:          r.progress,
+		rawBody:           r.rawBody,
+		session:           r.session,
+		cookies:           append([]*http.Cookie(nil), r.cookies...),
+		bodyFrom:          r.bodyFrom,
+		err:               r.err,
+		dynamicQuery:      append([]dynamicQuery(nil), r.dynamicQuery...),
+		headers:           make(http.Header, len(r.headers)),
+		queries:           make(url.Values, len(r.queries)),
 	}
 	for k, vv := range r.headers {
 		cp := make([]string, len(vv))
@@ -97,23 +252,21 @@ func (r *Request) clone() *Request {
 		copy(cp, vv)
 		clone.queries[k] = cp
 	}
+	if r.pathParams != nil {
+		clone.pathParams = make(map[string]string, len(r.pathParams))
+		for k, v := range r.pathParams {
+			clone.pathParams[k] = v
+		}
+	}
 	return clone
 }
 
 // buildHTTPRequest expands the request into a concrete *http.Request using the
 // supplied base URL and context.
 func (r *Request) buildHTTPRequest(ctx context.Context, cfg Config) (*http.Request, error) {
-	baseURL := cfg.BaseURL
-	target := r.url
-	if baseURL != "" && !isAbsoluteURL(target) {
-		// Parse base URL to get proper URL structure
-		base, err := url.Parse(baseURL)
-		if err != nil {
-			return nil, fmt.Errorf("invalid base URL: %w", err)
-		}
-		// Join paths properly
-		base.Path = path.Join(base.Path, r.url)
-		target = base.String()
+	target, err := joinURL(cfg, r.expandPath(cfg.PreserveEncodedPath))
+	if err != nil {
+		return nil, err
 	}
 
 	if len(r.queries) > 0 {
@@ -121,42 +274,62 @@ func (r *Request) buildHTTPRequest(ctx context.Context, cfg Config) (*http.Reque
 		if err != nil {
 			return nil, err
 		}
-		q := u.Query()
-		for k, vv := range r.queries {
-			for _, v := range vv {
-				q.Add(k, v)
-			}
-		}
-		u.RawQuery = q.Encode()
+		// Appended rather than re-encoded, so a signed query already in the
+		// target reaches the server byte for byte.
+		u.RawQuery = joinQuery(u.RawQuery, r.queries.Encode())
 		target = u.String()
 	}
 
+	factory := r.bodyFactory
+	if r.compress && factory != nil {
+		factory = gzipBody(factory)
+	}
+
 	var body io.ReadCloser
 	var contentLength int64
 	var factoryContentType string
-	if r.bodyFactory != nil {
-		rc, cl, ctype, err := r.bodyFactory()
+	if factory != nil {
+		rc, cl, ctype, err := factory()
 		if err != nil {
 			return nil, err
 		}
 		body = rc
 		contentLength = cl
 		factoryContentType = ctype
+	} else if r.bodyStream != nil {
+		rc, err := r.bodyStream()
+		if err != nil {
+			return nil, fmt.Errorf("open body stream: %w", err)
+		}
+		body = rc
+		contentLength = -1
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, r.method, target, body)
+	method := r.method
+	if r.overridesMethod(cfg) {
+		method = http.MethodPost
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, target, body)
 	if err != nil {
 		if body != nil {
 			_ = body.Close()
 		}
 		return nil, err
 	}
+	if method != r.method {
+		httpReq.Header.Set(methodOverrideHeader, r.method)
+	}
 
-	if r.bodyFactory != nil {
+	if factory != nil {
 		httpReq.GetBody = func() (io.ReadCloser, error) {
-			rc, _, _, err := r.bodyFactory()
+			rc, _, _, err := factory()
 			return rc, err
 		}
+	} else if r.bodyStream != nil {
+		// Streams are opened once and cannot be replayed, so GetBody stays
+		// nil and the retry middleware will not resend the request.
+		httpReq.GetBody = nil
 	} else {
 		// For requests without a body (like GET), set GetBody to return nil
 		// This allows the retry mechanism to work properly
@@ -172,6 +345,14 @@ func (r *Request) buildHTTPRequest(ctx context.Context, cfg Config) (*http.Reque
 		}
 	}
 
+	for _, c := range r.cookies {
+		httpReq.AddCookie(c)
+	}
+	applyBaggage(ctx, httpReq, cfg)
+	if r.session != nil {
+		r.session.apply(httpReq)
+	}
+
 	if cfg.UserAgent != "" && httpReq.Header.Get("User-Agent") == "" {
 		httpReq.Header.Set("User-Agent", cfg.UserAgent)
 	}
@@ -185,19 +366,124 @@ func (r *Request) buildHTTPRequest(ctx context.Context, cfg Config) (*http.Reque
 		httpReq.Header.Set("Content-Type", contentType)
 	}
 
-	if r.accept != "" && httpReq.Header.Get("Accept") == "" {
-		httpReq.Header.Set("Accept", r.accept)
+	if r.compress && factory != nil && httpReq.Header.Get("Content-Encoding") == "" {
+		httpReq.Header.Set("Content-Encoding", "gzip")
 	}
-	if contentLength >= 0 {
-		httpReq.ContentLength = contentLength
+
+	if accept := r.acceptHeader(cfg); accept != "" && httpReq.Header.Get("Accept") == "" {
+		httpReq.Header.Set("Accept", accept)
 	}
 
+	if version := choose(r.apiVersion, cfg.APIVersion.Version); version != "" {
+		applyAPIVersion(httpReq, version, cfg)
+	}
+	httpReq.ContentLength = contentLength
+
 	return httpReq, nil
 }
 
+const jsonAcceptNone = "none"
+
+// acceptHeader returns the explicit Accept value or, for JSON bodies, the one
+// chosen by WithRequestJSONAccept or Config.JSONAccept.
+func (r *Request) acceptHeader(cfg Config) string {
+	if r.accept != "" || !r.jsonBody {
+		return r.accept
+	}
+	accept := choose(cfg.JSONAccept, "application/json")
+	if r.jsonAccept != nil {
+		accept = *r.jsonAccept
+	}
+	if accept == jsonAcceptNone {
+		return ""
+	}
+	return accept
+}
+
+const methodOverrideHeader = "X-HTTP-Method-Override"
+
+// overridesMethod reports whether the request is tunnelled through POST with
+// X-HTTP-Method-Override, either per request or via Config.MethodOverride.
+// GET, HEAD and POST are always sent as-is.
+func (r *Request) overridesMethod(cfg Config) bool {
+	switch r.method {
+	case http.MethodGet, http.MethodHead, http.MethodPost:
+		return false
+	}
+	if r.overrideVerb {
+		return true
+	}
+	for _, m := range cfg.MethodOverride {
+		if strings.EqualFold(m, r.method) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandPath substitutes {name} placeholders in the URL with the path-escaped
+// values given to WithPathParams. With preserveEncoded, percent-encoded
+// sequences already in a value are kept instead of being escaped again.
+func (r *Request) expandPath(preserveEncoded bool) string {
+	if len(r.pathParams) == 0 {
+		return r.url
+	}
+	escape := url.PathEscape
+	if preserveEncoded {
+		escape = escapeSegmentPreserving
+	}
+	pairs := make([]string, 0, 2*len(r.pathParams))
+	for k, v := range r.pathParams {
+		pairs = append(pairs, "{"+k+"}", escape(v))
+	}
+	return strings.NewReplacer(pairs...).Replace(r.url)
+}
+
+// pathTemplate returns the unexpanded request path, including any base URL
+// path, for use in logs and traces. It is empty unless WithPathParams is used.
+func (r *Request) pathTemplate(cfg Config) string {
+	if len(r.pathParams) == 0 {
+		return ""
+	}
+	target, err := joinURL(cfg, r.url)
+	if err != nil {
+		return ""
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return ""
+	}
+	return u.Path
+}
+
+// joinBaseURL resolves a relative target against baseURL. target is treated
+// as an already-escaped path, optionally followed by a query string. A query
+// in baseURL, such as a shared access signature, is kept verbatim and the
+// target's query is appended to it.
+func joinBaseURL(baseURL, target string) (string, error) {
+	if baseURL == "" || isAbsoluteURL(target) {
+		return target, nil
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL: %w", err)
+	}
+	target, query, _ := strings.Cut(target, "?")
+	base.RawQuery = joinQuery(base.RawQuery, query)
+	joined := path.Join(base.EscapedPath(), target)
+	if unescaped, err := url.PathUnescape(joined); err == nil {
+		base.Path = unescaped
+		base.RawPath = joined
+	} else {
+		base.Path = joined
+	}
+	return base.String(), nil
+}
+
 // WithHeader sets a header value on the outgoing request.
 func WithHeader(key, value string) ReqOption {
 	return func(r *Request) {
+		r.ensureMutable()
 		r.headers.Set(key, value)
 	}
 }
@@ -206,6 +492,7 @@ func WithHeader(key, value string) ReqOption {
 // replaced.
 func WithHeaders(headers map[string]string) ReqOption {
 	return func(r *Request) {
+		r.ensureMutable()
 		for k, v := range headers {
 			r.headers.Set(k, v)
 		}
@@ -215,29 +502,69 @@ func WithHeaders(headers map[string]string) ReqOption {
 // WithAccept sets the Accept header.
 func WithAccept(value string) ReqOption {
 	return func(r *Request) {
+		r.ensureMutable()
 		r.accept = value
 	}
 }
 
+// WithRequestJSONAccept replaces the Accept header WithJSON implies for this
+// request, e.g. with a vendor media type; an empty accept sends none. It
+// overrides Config.JSONAccept and yields to WithAccept.
+func WithRequestJSONAccept(accept string) ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.jsonAccept = ptr(choose(accept, jsonAcceptNone))
+	}
+}
+
 // WithContentType sets the Content-Type header for the body.
 func WithContentType(value string) ReqOption {
 	return func(r *Request) {
+		r.ensureMutable()
 		r.contentType = value
 	}
 }
 
+// WithExpectContentType makes Do fail with a *ContentTypeError when the
+// response media type differs from mediaType, e.g. an HTML error page from an
+// intermediary where JSON was expected. Parameters such as charset are
+// ignored and "type/*" matches any subtype.
+func WithExpectContentType(mediaType string) ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.expectContentType = mediaType
+	}
+}
+
 // WithIdempotencyKey sets the Idempotency-Key header.
 func WithIdempotencyKey(key string) ReqOption {
 	return func(r *Request) {
+		r.ensureMutable()
 		if key != "" {
 			r.headers.Set("Idempotency-Key", key)
 		}
 	}
 }
 
+// WithPathParams fills {name} placeholders in the request URL, e.g.
+// "/users/{id}". Values are path-escaped, and logs show the template rather
+// than the values so identifiers such as emails stay out of diagnostics.
+func WithPathParams(params map[string]string) ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		if r.pathParams == nil {
+			r.pathParams = make(map[string]string, len(params))
+		}
+		for k, v := range params {
+			r.pathParams[k] = v
+		}
+	}
+}
+
 // WithQuery appends a query parameter to the request.
 func WithQuery(key, value string) ReqOption {
 	return func(r *Request) {
+		r.ensureMutable()
 		r.queries.Add(key, value)
 	}
 }
@@ -245,15 +572,19 @@ func WithQuery(key, value string) ReqOption {
 // WithQueryMap appends multiple query parameters.
 func WithQueryMap(values map[string]string) ReqOption {
 	return func(r *Request) {
+		r.ensureMutable()
 		for k, v := range values {
 			r.queries.Add(k, v)
 		}
 	}
 }
 
-// WithRequestTimeout overrides the timeout for this specific request.
+// WithRequestTimeout sets a timeout for this specific request, covering the
+// response body until it is read or closed. How it combines with the client
+// timeout depends on Config.TimeoutPolicy.
 func WithRequestTimeout(d time.Duration) ReqOption {
 	return func(r *Request) {
+		r.ensureMutable()
 		r.timeout = d
 	}
 }
@@ -261,6 +592,7 @@ func WithRequestTimeout(d time.Duration) ReqOption {
 // WithRequestAuth overrides the auth provider used for the request.
 func WithRequestAuth(provider auth.AuthProvider) ReqOption {
 	return func(r *Request) {
+		r.ensureMutable()
 		r.authProvider = provider
 	}
 }
@@ -268,6 +600,7 @@ func WithRequestAuth(provider auth.AuthProvider) ReqOption {
 // WithRequestRetry overrides the retry policy for this request.
 func WithRequestRetry(policy retry.Policy) ReqOption {
 	return func(r *Request) {
+		r.ensureMutable()
 		r.retryPolicy = policy
 	}
 }
@@ -276,63 +609,250 @@ func WithRequestRetry(policy retry.Policy) ReqOption {
 // methods.
 func WithRequestRetryForce() ReqOption {
 	return func(r *Request) {
+		r.ensureMutable()
 		r.forceRetry = true
 	}
 }
 
+// WithIdempotent declares the call idempotent regardless of method, e.g. a
+// POST the server deduplicates via WithIdempotencyKey. Unlike
+// WithRequestRetryForce it states a property of the call, which the retry
+// policy and any other resend logic honour through retry.IsIdempotent.
+func WithIdempotent() ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.idempotent = true
+	}
+}
+
+// WithMethodOverride sends the request as POST with an
+// X-HTTP-Method-Override header carrying the real method, for environments
+// whose proxies block PATCH, PUT or DELETE. Retries still treat the call by
+// its real method's idempotency.
+func WithMethodOverride() ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.overrideVerb = true
+	}
+}
+
+// WithServerName sets the TLS server name (SNI), also used to verify the
+// certificate, when it must differ from the URL host, e.g. when calling an
+// IP-addressed endpoint or a shared ingress. Connections for each server name
+// are pooled separately. Requires the default transport or an
+// *http.Transport passed to WithTransport.
+func WithServerName(sni string) ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.serverName = sni
+	}
+}
+
+// WithRequestAPIVersion overrides the client's API version for this request,
+// using the placement configured on the client.
+func WithRequestAPIVersion(version string) ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.apiVersion = version
+	}
+}
+
 // WithRequestBreaker toggles the circuit breaker for this request.
 func WithRequestBreaker(enabled bool) ReqOption {
 	return func(r *Request) {
+		r.ensureMutable()
 		r.breakerToggle = ptr(enabled)
 	}
 }
 
+// WithRequestErrorOnStatus overrides the client's WithErrorOnStatus setting
+// for this request, e.g. to inspect an expected 404 without an error.
+func WithRequestErrorOnStatus(enabled bool) ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.errorOnStatus = ptr(enabled)
+	}
+}
+
+// WithCookie sends the cookie name=value with this request, ahead of any
+// cookies from the client's jar. Setting the same name again replaces the
+// value.
+func WithCookie(name, value string) ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.setCookie(&http.Cookie{Name: name, Value: value})
+	}
+}
+
+func (r *Request) setCookie(c *http.Cookie) {
+	for i, existing := range r.cookies {
+		if existing.Name == c.Name {
+			r.cookies[i] = c
+			return
+		}
+	}
+	r.cookies = append(r.cookies, c)
+}
+
+// WithBreakerKey places this request in the circuit breaker named key rather
+// than the one of its host, decoupling isolation domains from URLs, e.g.
+// WithBreakerKey("payments-critical"). The breaker's state, e.g. in health
+// and Stats, is reported under the key.
+func WithBreakerKey(key string) ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.breakerKey = key
+	}
+}
+
+// WithRequestLogLevel overrides the level at which this request's log lines
+// are emitted, e.g. logging.LevelDebug for a noisy polling endpoint or
+// logging.LevelOff to silence it.
+func WithRequestLogLevel(level logging.Level) ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.logLevel = level
+	}
+}
+
+// WithRequestCompression gzip-compresses the request body and sets
+// Content-Encoding. When fallbackOn415 is true and the server answers 415
+// Unsupported Media Type, the request is resent once uncompressed.
+func WithRequestCompression(fallbackOn415 bool) ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.compress = true
+		r.compressFallback = fallbackOn415
+	}
+}
+
+// WithNoCache bypasses the response cache for this request; the response is
+// neither served from nor written to the cache.
+func WithNoCache() ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.cache.NoCache = true
+	}
+}
+
+// WithCacheRefresh skips the cached entry and replaces it with the fresh
+// response.
+func WithCacheRefresh() ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.cache.Refresh = true
+	}
+}
+
+// WithCacheTTL stores the response for d, overriding the configured TTL and
+// any Cache-Control the server sent.
+func WithCacheTTL(d time.Duration) ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.cache.TTL = d
+	}
+}
+
+// WithCacheKey stores and looks up the response under key instead of the
+// method and URL, e.g. to share an entry across equivalent URLs.
+func WithCacheKey(key string) ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.cache.Key = key
+	}
+}
+
+// WithBodyStream sends the body returned by open with unknown length, using
+// chunked transfer encoding on HTTP/1.1. open is called once per request, so
+// the request is never retried; use WithRaw or an io.ReadSeeker body when
+// retries matter. Executing the Request concurrently calls open concurrently.
+func WithBodyStream(open func() (io.ReadCloser, error)) ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.claimBody("WithBodyStream")
+		r.bodyFactory = nil
+		r.bodyStream = open
+	}
+}
+
+// WithBodyReaderAt sends size bytes of ra, such as an *os.File or a mapped
+// buffer, without buffering them. Every attempt reads through a fresh
+// io.SectionReader, so retries and GetBody replay the body from the start and
+// Content-Length is known. ra must stay readable, and its first size bytes
+// unchanged, until the request completes.
+func WithBodyReaderAt(ra io.ReaderAt, size int64, contentType string) ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.setBody("WithBodyReaderAt", func() (io.ReadCloser, int64, string, error) {
+			return io.NopCloser(io.NewSectionReader(ra, 0, size)), size, contentType, nil
+		})
+	}
+}
+
 // WithRaw sets an arbitrary payload with a custom Content-Type.
 func WithRaw(body []byte, contentType string) ReqOption {
 	return func(r *Request) {
-		r.bodyFactory = func() (io.ReadCloser, int64, string, error) {
+		r.ensureMutable()
+		r.setBody("WithRaw", func() (io.ReadCloser, int64, string, error) {
 			buf := make([]byte, len(body))
 			copy(buf, body)
 			return io.NopCloser(bytes.NewReader(buf)), int64(len(buf)), contentType, nil
-		}
+		})
 	}
 }
 
 // WithJSON serialises the provided value as JSON and applies the appropriate
-// Content-Type.
+// Content-Type. The value is encoded once, on first send, and the same bytes
+// are replayed on retries and repeated executions. Unless the request sets
+// its own Accept header, it sends the client's Config.JSONAccept
+// (application/json by default); see WithRequestJSONAccept.
 func WithJSON(v any) ReqOption {
 	return func(r *Request) {
-		r.bodyFactory = func() (io.ReadCloser, int64, string, error) {
+		r.ensureMutable()
+		r.setBody("WithJSON", onceBody(func() ([]byte, string, error) {
 			b, err := json.Marshal(v)
-			if err != nil {
-				return nil, 0, "", err
-			}
-			return io.NopCloser(bytes.NewReader(b)), int64(len(b)), "application/json", nil
-		}
-		r.accept = choose(r.accept, "application/json")
+			return b, "application/json", err
+		}))
+		r.jsonBody = true
+	}
+}
+
+// WithEncodedBody serialises v with marshal and sends it with contentType.
+// Like WithJSON, the value is encoded once, on first send, and the bytes are
+// replayed on retries. It lets codec packages such as httpc/yaml add body
+// formats without the core depending on them.
+func WithEncodedBody(v any, contentType string, marshal func(any) ([]byte, error)) ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.setBody("WithEncodedBody("+contentType+")", onceBody(func() ([]byte, string, error) {
+			b, err := marshal(v)
+			return b, contentType, err
+		}))
 	}
 }
 
 // WithForm encodes the provided values as application/x-www-form-urlencoded.
 func WithForm(values url.Values) ReqOption {
 	return func(r *Request) {
-		r.bodyFactory = func() (io.ReadCloser, int64, string, error) {
-			encoded := values.Encode()
-			return io.NopCloser(strings.NewReader(encoded)), int64(len(encoded)), "application/x-www-form-urlencoded", nil
-		}
+		r.ensureMutable()
+		r.setBody("WithForm", onceBody(func() ([]byte, string, error) {
+			return []byte(values.Encode()), "application/x-www-form-urlencoded", nil
+		}))
 	}
 }
 
-// WithMultipart builds a multipart/form-data body.
+// WithMultipart builds a multipart/form-data body. File readers are consumed
+// once, on first send; the encoded body is replayed on retries.
 func WithMultipart(files []MultipartFile, fields map[string]string) ReqOption {
 	return func(r *Request) {
-		r.bodyFactory = func() (io.ReadCloser, int64, string, error) {
+		r.ensureMutable()
+		r.setBody("WithMultipart", onceBody(func() ([]byte, string, error) {
 			var buf bytes.Buffer
 			writer := multipart.NewWriter(&buf)
 
 			for k, v := range fields {
 				if err := writer.WriteField(k, v); err != nil {
-					return nil, 0, "", fmt.Errorf("write field %q: %w", k, err)
+					return nil, "", fmt.Errorf("write field %q: %w", k, err)
 				}
 			}
 
@@ -348,24 +868,74 @@ func WithMultipart(files []MultipartFile, fields map[string]string) ReqOption {
 					part, err = writer.CreateFormFile(file.FieldName, path.Base(file.FileName))
 				}
 				if err != nil {
-					return nil, 0, "", fmt.Errorf("create part for %q: %w", file.FieldName, err)
+					return nil, "", fmt.Errorf("create part for %q: %w", file.FieldName, err)
 				}
 				if _, err := io.Copy(part, file.Reader); err != nil {
-					return nil, 0, "", fmt.Errorf("copy part %q: %w", file.FieldName, err)
+					return nil, "", fmt.Errorf("copy part %q: %w", file.FieldName, err)
 				}
 			}
 
 			if err := writer.Close(); err != nil {
-				return nil, 0, "", fmt.Errorf("close multipart writer: %w", err)
+				return nil, "", fmt.Errorf("close multipart writer: %w", err)
 			}
 
-			body := buf.Bytes()
-			return io.NopCloser(bytes.NewReader(body)), int64(len(body)), writer.FormDataContentType(), nil
-		}
+			return buf.Bytes(), writer.FormDataContentType(), nil
+		}))
 		// Accept header is typically omitted for multipart.
 	}
 }
 
+// onceBody memoises an encoded body so concurrent and repeated sends share
+// one encoding instead of re-reading caller-owned values. Each call returns
+// an independent reader over the shared bytes.
+func onceBody(encode func() ([]byte, string, error)) bodyProvider {
+	var (
+		once  sync.Once
+		data  []byte
+		ctype string
+		err   error
+	)
+	return func() (io.ReadCloser, int64, string, error) {
+		once.Do(func() { data, ctype, err = encode() })
+		if err != nil {
+			return nil, 0, "", err
+		}
+		return io.NopCloser(bytes.NewReader(data)), int64(len(data)), ctype, nil
+	}
+}
+
+// gzipBody wraps a body factory so every produced body is gzip-compressed.
+func gzipBody(factory bodyProvider) bodyProvider {
+	return func() (io.ReadCloser, int64, string, error) {
+		rc, _, ctype, err := factory()
+		if err != nil {
+			return nil, 0, "", err
+		}
+		defer rc.Close()
+
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := io.Copy(zw, rc); err != nil {
+			return nil, 0, "", fmt.Errorf("compress body: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, 0, "", fmt.Errorf("compress body: %w", err)
+		}
+		return io.NopCloser(bytes.NewReader(buf.Bytes())), int64(buf.Len()), ctype, nil
+	}
+}
+
+// joinQuery concatenates two encoded query strings.
+func joinQuery(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	}
+	return a + "&" + b
+}
+
 func choose(current, fallback string) string {
 	if current != "" {
 		return current
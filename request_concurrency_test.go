@@ -0,0 +1,122 @@
+package httpc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// These tests are meaningful under `go test -race`.
+func TestRequestConcurrentExecution(t *testing.T) {
+	const workers = 16
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		// Fail the first round of calls so retries replay the shared body.
+		if calls.Add(1) <= workers {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	client, err := New(
+		WithBaseURL(server.URL),
+		WithRetry(true, workers+1),
+		WithRetryPolicy(retry.NewPolicy(retry.PolicyConfig{
+			MaxAttempts: workers + 1,
+			BaseBackoff: time.Millisecond,
+			MaxBackoff:  time.Millisecond,
+			StatusCodes: []int{http.StatusServiceUnavailable},
+		})),
+	)
+	require.NoError(t, err)
+
+	requests := map[string]*Request{
+		"json": NewRequest(http.MethodPost, "/echo",
+			WithJSON(map[string]any{"name": "demo", "tags": []string{"a", "b"}}),
+			WithHeader("X-Trace", "1"),
+			WithQuery("q", "1"),
+			WithRequestRetryForce(),
+		),
+		"multipart": NewRequest(http.MethodPost, "/echo",
+			WithMultipart([]MultipartFile{{
+				FieldName: "file",
+				FileName:  "a.txt",
+				Reader:    strings.NewReader("file contents"),
+			}}, map[string]string{"k": "v"}),
+			WithRequestRetryForce(),
+		),
+	}
+
+	for name, req := range requests {
+		t.Run(name, func(t *testing.T) {
+			calls.Store(0)
+			bodies := make([]string, workers)
+			var wg sync.WaitGroup
+			for i := 0; i < workers; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					resp, err := client.Do(context.Background(), req)
+					if !assert.NoError(t, err) {
+						return
+					}
+					bodies[i], err = resp.String()
+					assert.NoError(t, err)
+				}(i)
+			}
+			wg.Wait()
+
+			require.NotEmpty(t, bodies[0])
+			for i := 1; i < workers; i++ {
+				assert.Equal(t, bodies[0], bodies[i], "worker %d saw a different body", i)
+			}
+			if name == "multipart" {
+				assert.Contains(t, bodies[0], "file contents")
+			}
+		})
+	}
+}
+
+func TestRequestFrozen(t *testing.T) {
+	req := NewRequest(http.MethodGet, "/health", WithHeader("X-Probe", "1"))
+
+	t.Run("rejects_options_after_build", func(t *testing.T) {
+		assert.Panics(t, func() { WithHeader("X-Other", "2")(req) })
+	})
+
+	t.Run("with_derives_a_frozen_copy", func(t *testing.T) {
+		derived := req.With(WithHeader("X-Other", "2"), WithQuery("verbose", "1"))
+		assert.Equal(t, "2", derived.headers.Get("X-Other"))
+		assert.Empty(t, req.headers.Get("X-Other"))
+		assert.Empty(t, req.queries)
+		assert.Panics(t, func() { WithQuery("k", "v")(derived) })
+	})
+
+	t.Run("do_leaves_request_untouched", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+		client, err := New(WithBaseURL(server.URL), WithUserAgent("probe"))
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			_, err := client.Do(context.Background(), req)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, http.Header{"X-Probe": {"1"}}, req.headers)
+	})
+}
@@ -1,16 +1,21 @@
 package httpc
 
 import (
+	"compress/gzip"
 	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gostratum/core/logx"
 	"github.com/gostratum/httpc/auth"
+	"github.com/gostratum/httpc/redact"
+	"github.com/gostratum/httpc/retry"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -39,6 +44,40 @@ func TestWithJSON(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusOK, resp.StatusCode())
 	})
+
+	t.Run("implied_accept_is_configurable", func(t *testing.T) {
+		var accepts []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accepts = append(accepts, r.Header.Get("Accept"))
+		}))
+		defer server.Close()
+
+		vendor := "application/vnd.api+json"
+		client, err := New(WithBaseURL(server.URL), WithJSONAccept(vendor))
+		require.NoError(t, err)
+		noAccept, err := New(WithBaseURL(server.URL), WithJSONAccept(""))
+		require.NoError(t, err)
+		withDefaults, err := New(WithBaseURL(server.URL), WithRequestDefaults(WithAccept("application/hal+json")))
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		for _, send := range []func() (*Response, error){
+			func() (*Response, error) { return client.Post(ctx, "/", nil, WithJSON(1)) },
+			func() (*Response, error) { return client.Post(ctx, "/", nil, WithJSON(1), WithRequestJSONAccept("")) },
+			func() (*Response, error) {
+				return noAccept.Post(ctx, "/", nil, WithJSON(1), WithRequestJSONAccept("application/json"))
+			},
+			func() (*Response, error) { return noAccept.Post(ctx, "/", nil, WithJSON(1)) },
+			func() (*Response, error) {
+				return client.Post(ctx, "/", nil, WithJSON(1), WithAccept("text/plain"), WithRequestJSONAccept(""))
+			},
+			func() (*Response, error) { return withDefaults.Post(ctx, "/", nil, WithJSON(1)) },
+		} {
+			_, err := send()
+			require.NoError(t, err)
+		}
+		assert.Equal(t, []string{vendor, "", "application/json", "", "text/plain", "application/hal+json"}, accepts)
+	})
 }
 
 func TestWithRaw(t *testing.T) {
@@ -99,6 +138,89 @@ func TestWithForm(t *testing.T) {
 	})
 }
 
+func TestWithFormStruct(t *testing.T) {
+	type item struct {
+		ID  int    `form:"id"`
+		Tag string `form:"tag,omitempty"`
+	}
+	type Common struct {
+		ClientID string `form:"client_id"`
+	}
+	type tokenRequest struct {
+		Common
+		GrantType string            `form:"grant_type"`
+		Scopes    []string          `form:"scope"`
+		Filter    map[string]string `form:"filter"`
+		Items     []item            `form:"items"`
+		Since     time.Time         `form:"since" time_format:"unix"`
+		Until     *time.Time        `form:"until"`
+		Day       time.Time         `form:"day" time_format:"2006-01-02"`
+		Note      string            `form:"note,omitempty"`
+		Secret    string            `form:"-"`
+		Limit     int
+	}
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("encodes_tagged_fields", func(t *testing.T) {
+		var got url.Values
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+			require.NoError(t, r.ParseForm())
+			got = r.PostForm
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client, err := New(WithBaseURL(server.URL), WithLogger(logx.NewNoopLogger()))
+		require.NoError(t, err)
+
+		resp, err := client.Post(context.Background(), "/token", nil, WithFormStruct(&tokenRequest{
+			Common:    Common{ClientID: "app"},
+			GrantType: "client_credentials",
+			Scopes:    []string{"read", "write"},
+			Filter:    map[string]string{"status": "open"},
+			Items:     []item{{ID: 7, Tag: "a"}, {ID: 8}},
+			Since:     at,
+			Until:     &at,
+			Day:       at,
+			Secret:    "x",
+			Limit:     5,
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode())
+
+		assert.Equal(t, url.Values{
+			"client_id":      {"app"},
+			"grant_type":     {"client_credentials"},
+			"scope":          {"read", "write"},
+			"filter[status]": {"open"},
+			"items[0][id]":   {"7"},
+			"items[0][tag]":  {"a"},
+			"items[1][id]":   {"8"},
+			"since":          {"1709294400"},
+			"until":          {"2024-03-01T12:00:00Z"},
+			"day":            {"2024-03-01"},
+			"Limit":          {"5"},
+		}, got)
+	})
+
+	t.Run("rejects_unsupported_types", func(t *testing.T) {
+		client, err := New(WithTransport(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			t.Fatal("request should not be sent")
+			return nil, nil
+		})), WithRetry(false, 0))
+		require.NoError(t, err)
+
+		_, err = client.Post(context.Background(), "http://example.com", nil, WithFormStruct(struct {
+			Fn func() `form:"fn"`
+		}{}))
+		require.ErrorContains(t, err, `unsupported type func() for "fn"`)
+
+		_, err = client.Post(context.Background(), "http://example.com", nil, WithFormStruct("nope"))
+		require.ErrorContains(t, err, "expected a struct")
+	})
+}
+
 func TestWithAccept(t *testing.T) {
 	t.Run("sets_accept_header", func(t *testing.T) {
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -234,3 +356,517 @@ func TestResponse_IntoWriter(t *testing.T) {
 		assert.Equal(t, "", buf.String())
 	})
 }
+
+func TestWithRequestCompression(t *testing.T) {
+	newServer := func(t *testing.T, acceptGzip bool, calls *int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*calls++
+			body := io.Reader(r.Body)
+			if r.Header.Get("Content-Encoding") == "gzip" {
+				if !acceptGzip {
+					w.WriteHeader(http.StatusUnsupportedMediaType)
+					return
+				}
+				zr, err := gzip.NewReader(r.Body)
+				require.NoError(t, err)
+				body = zr
+			}
+			data, _ := io.ReadAll(body)
+			assert.JSONEq(t, `{"name":"demo"}`, string(data))
+			w.WriteHeader(http.StatusOK)
+		}))
+	}
+
+	t.Run("compresses_body", func(t *testing.T) {
+		var calls int
+		server := newServer(t, true, &calls)
+		defer server.Close()
+
+		client, err := New(WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		resp, err := client.Post(context.Background(), "/", map[string]string{"name": "demo"},
+			WithRequestCompression(false),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode())
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("falls_back_on_415", func(t *testing.T) {
+		var calls int
+		server := newServer(t, false, &calls)
+		defer server.Close()
+
+		client, err := New(WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		resp, err := client.Post(context.Background(), "/", map[string]string{"name": "demo"},
+			WithRequestCompression(true),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode())
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("surfaces_415_without_fallback", func(t *testing.T) {
+		var calls int
+		server := newServer(t, false, &calls)
+		defer server.Close()
+
+		client, err := New(WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		resp, err := client.Post(context.Background(), "/", map[string]string{"name": "demo"},
+			WithRequestCompression(false),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode())
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestWithBodyStream(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.EqualValues(t, -1, r.ContentLength)
+		assert.Equal(t, []string{"chunked"}, r.TransferEncoding)
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "streamed", string(body))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client, err := New(WithBaseURL(server.URL), WithRetry(true, 3))
+	require.NoError(t, err)
+
+	var opened int
+	resp, err := client.Post(context.Background(), "/", WithBodyStream(func() (io.ReadCloser, error) {
+		opened++
+		return io.NopCloser(strings.NewReader("streamed")), nil
+	}), WithRequestRetryForce())
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+	assert.Equal(t, 1, calls, "non-replayable stream must not be retried")
+	assert.Equal(t, 1, opened)
+	assert.Equal(t, 1, resp.Attempts())
+}
+
+func TestWithPathParams(t *testing.T) {
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+	}))
+	defer server.Close()
+
+	cfg := Config{BaseURL: server.URL + "/api"}
+	req := newRequest(http.MethodGet, "/users/{email}/orders/{id}",
+		WithPathParams(map[string]string{"email": "jane@example.com", "id": "a/b"}),
+	)
+
+	t.Run("expands_and_escapes_values", func(t *testing.T) {
+		client, err := New(WithBaseURL(cfg.BaseURL))
+		require.NoError(t, err)
+		_, err = client.Do(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, "/api/users/jane@example.com/orders/a%2Fb", gotPath)
+	})
+
+	t.Run("logs_template_instead_of_values", func(t *testing.T) {
+		assert.Equal(t, "/api/users/{email}/orders/{id}", req.pathTemplate(cfg))
+
+		httpReq, err := req.buildHTTPRequest(context.Background(), cfg)
+		require.NoError(t, err)
+		httpReq = httpReq.WithContext(redact.WithPathTemplate(httpReq.Context(), req.pathTemplate(cfg)))
+		logged := redact.Default().Request(httpReq)
+		assert.Equal(t, server.URL+"/api/users/{email}/orders/{id}", logged)
+		assert.NotContains(t, logged, "jane")
+	})
+}
+
+func TestMethodOverride(t *testing.T) {
+	type seen struct{ method, override string }
+	var got []seen
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, seen{r.Method, r.Header.Get("X-HTTP-Method-Override")})
+	}))
+	defer server.Close()
+
+	t.Run("per_request", func(t *testing.T) {
+		got = nil
+		client, err := New(WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		_, err = client.Patch(context.Background(), "/", map[string]string{"a": "b"}, WithMethodOverride())
+		require.NoError(t, err)
+		_, err = client.Get(context.Background(), "/", WithMethodOverride())
+		require.NoError(t, err)
+		_, err = client.Delete(context.Background(), "/")
+		require.NoError(t, err)
+
+		assert.Equal(t, []seen{
+			{http.MethodPost, http.MethodPatch},
+			{http.MethodGet, ""},
+			{http.MethodDelete, ""},
+		}, got)
+	})
+
+	t.Run("global", func(t *testing.T) {
+		got = nil
+		client, err := New(WithBaseURL(server.URL), WithMethodOverrideFor("delete"))
+		require.NoError(t, err)
+
+		_, err = client.Delete(context.Background(), "/")
+		require.NoError(t, err)
+		_, err = client.Put(context.Background(), "/", "x")
+		require.NoError(t, err)
+
+		assert.Equal(t, []seen{
+			{http.MethodPost, http.MethodDelete},
+			{http.MethodPut, ""},
+		}, got)
+	})
+}
+
+func TestWithEarlyHints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Link", `</assets/app.css>; rel=preload; as=style, <https://cdn.example.com/app.js>; rel=preload; as=script; crossorigin`)
+		w.WriteHeader(http.StatusEarlyHints)
+		w.Header().Del("Link")
+		_, _ = w.Write([]byte("page"))
+	}))
+	defer server.Close()
+
+	client, err := New(WithBaseURL(server.URL), WithRetry(false, 0))
+	require.NoError(t, err)
+
+	var hints []EarlyHint
+	resp, err := client.Get(context.Background(), "/page", WithEarlyHints(func(h []EarlyHint) {
+		hints = append(hints, h...)
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+
+	require.Len(t, hints, 2)
+	assert.Equal(t, server.URL+"/assets/app.css", hints[0].URL)
+	assert.Equal(t, "preload", hints[0].Rel)
+	assert.Equal(t, "style", hints[0].As)
+	assert.Equal(t, "https://cdn.example.com/app.js", hints[1].URL)
+	assert.Equal(t, "script", hints[1].As)
+	assert.Contains(t, hints[1].Params, "crossorigin")
+}
+
+func TestWithProgress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, pct := range []string{"40", "80"} {
+			w.Header().Set("X-Progress", pct)
+			w.WriteHeader(http.StatusProcessing)
+		}
+		w.Header().Del("X-Progress")
+		w.Header().Set("Link", `</report.css>; rel=preload`)
+		w.WriteHeader(http.StatusEarlyHints)
+		_, _ = w.Write([]byte("report"))
+	}))
+	defer server.Close()
+
+	client, err := New(WithBaseURL(server.URL), WithRetry(false, 0))
+	require.NoError(t, err)
+
+	var progress []string
+	var hints int
+	resp, err := client.Get(context.Background(), "/report",
+		WithProgress(func(p Progress) {
+			assert.Equal(t, http.StatusProcessing, p.StatusCode)
+			progress = append(progress, p.Header.Get("X-Progress"))
+		}),
+		WithEarlyHints(func(h []EarlyHint) { hints += len(h) }),
+	)
+	require.NoError(t, err)
+	body, err := resp.String()
+	require.NoError(t, err)
+	assert.Equal(t, "report", body)
+	assert.Equal(t, []string{"40", "80"}, progress)
+	assert.Equal(t, 1, hints)
+}
+
+func TestWithAPIVersion(t *testing.T) {
+	var last *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		last = r
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	t.Run("header_with_request_override", func(t *testing.T) {
+		client, err := New(WithBaseURL(server.URL), WithAPIVersion("2024-01-01"), WithAPIVersionPlacement(APIVersionInHeader, "Stripe-Version"))
+		require.NoError(t, err)
+
+		_, err = client.Get(context.Background(), "/")
+		require.NoError(t, err)
+		assert.Equal(t, "2024-01-01", last.Header.Get("Stripe-Version"))
+
+		_, err = client.Get(context.Background(), "/", WithRequestAPIVersion("2025-06-30"))
+		require.NoError(t, err)
+		assert.Equal(t, "2025-06-30", last.Header.Get("Stripe-Version"))
+	})
+
+	t.Run("media_type_parameter", func(t *testing.T) {
+		client, err := New(WithBaseURL(server.URL), WithAPIVersion("2"), WithAPIVersionPlacement(APIVersionInMediaType, ""))
+		require.NoError(t, err)
+
+		_, err = client.Get(context.Background(), "/", WithAccept("application/json, text/plain"))
+		require.NoError(t, err)
+		assert.Equal(t, "application/json; version=2, text/plain; version=2", last.Header.Get("Accept"))
+	})
+
+	t.Run("query_parameter", func(t *testing.T) {
+		client, err := New(WithBaseURL(server.URL), WithAPIVersion("7.1"), WithAPIVersionPlacement(APIVersionInQuery, ""))
+		require.NoError(t, err)
+
+		_, err = client.Get(context.Background(), "/items", WithQuery("top", "5"))
+		require.NoError(t, err)
+		assert.Equal(t, "7.1", last.URL.Query().Get("api-version"))
+		assert.Equal(t, "5", last.URL.Query().Get("top"))
+	})
+
+	t.Run("rejects_unknown_placement", func(t *testing.T) {
+		_, err := New(WithAPIVersionPlacement("cookie", ""))
+		require.Error(t, err)
+	})
+}
+
+func TestWithDynamicQuery(t *testing.T) {
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.URL.Query().Get("_ts"))
+		assert.Equal(t, "static", r.URL.Query().Get("q"))
+		if len(seen) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := New(WithBaseURL(server.URL), WithRetry(true, 3), WithRetryPolicy(retry.NewPolicy(retry.PolicyConfig{
+		MaxAttempts: 3,
+		BaseBackoff: time.Millisecond,
+		StatusCodes: []int{http.StatusServiceUnavailable},
+	})))
+	require.NoError(t, err)
+
+	n := 0
+	resp, err := client.Get(context.Background(), "/", WithQuery("q", "static"), WithDynamicQuery("_ts", func() string {
+		n++
+		return strconv.Itoa(n)
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, []string{"1", "2", "3"}, seen)
+}
+
+func TestRequestIntrospection(t *testing.T) {
+	req := NewRequest(http.MethodPost, "/orders",
+		WithHeader("X-Tenant", "a"),
+		WithHeader("x-tenant", "b"),
+		WithAccept("application/xml"),
+		WithQuery("page", "1"),
+		WithQuery("page", "2"),
+	)
+	assert.Equal(t, "b", req.Header("X-Tenant"), "later WithHeader wins")
+	assert.Equal(t, "application/xml", req.Header("accept"))
+	assert.Equal(t, "1", req.Query("page"), "WithQuery accumulates")
+	assert.False(t, req.HasBody())
+
+	withBody := req.With(WithBodyStream(func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("stream")), nil
+	})).With(WithRaw([]byte("raw"), "text/plain"))
+	assert.True(t, withBody.HasBody())
+	assert.False(t, req.HasBody(), "With must not modify the original")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+	client, err := New(WithBaseURL(server.URL), WithRetry(false, 0))
+	require.NoError(t, err)
+
+	resp, err := client.Do(context.Background(), withBody)
+	require.NoError(t, err)
+	body, err := resp.String()
+	require.NoError(t, err)
+	assert.Equal(t, "raw", body, "With replaces the derived request's body")
+}
+
+func TestConflictingBodyOptions(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+	client, err := New(WithBaseURL(server.URL), WithRetry(false, 0))
+	require.NoError(t, err)
+
+	t.Run("options", func(t *testing.T) {
+		req := NewRequest(http.MethodPost, "/", WithJSON(map[string]int{"a": 1}), WithForm(url.Values{"a": {"1"}}))
+		require.ErrorIs(t, req.Err(), ErrConflictingBody)
+		assert.Contains(t, req.Err().Error(), "WithForm after WithJSON")
+
+		_, err := client.Do(context.Background(), req)
+		require.ErrorIs(t, err, ErrConflictingBody)
+	})
+
+	t.Run("body_argument", func(t *testing.T) {
+		_, err := client.Post(context.Background(), "/", map[string]int{"a": 1}, WithRaw([]byte("x"), "text/plain"))
+		require.ErrorIs(t, err, ErrConflictingBody)
+		assert.Contains(t, err.Error(), "WithRaw after body argument")
+	})
+
+	t.Run("body_argument_option", func(t *testing.T) {
+		_, err := client.Post(context.Background(), "/", WithJSON(1))
+		require.NoError(t, err)
+	})
+
+	assert.Equal(t, 1, calls, "conflicting requests must not be sent")
+}
+
+func TestRequestDefaults(t *testing.T) {
+	type seen struct{ accept, tenant, trace, page string }
+	var got seen
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = seen{r.Header.Get("Accept"), r.Header.Get("X-Tenant"), r.Header.Get("X-Trace"), r.URL.Query().Get("page")}
+	}))
+	defer server.Close()
+
+	jsonAPI := Preset(
+		WithAccept("application/json"),
+		WithHeader("X-Tenant", "acme"),
+		WithQuery("page", "1"),
+		WithRequestTimeout(time.Second),
+	)
+	client, err := New(WithBaseURL(server.URL), WithRetry(false, 0), WithRequestDefaults(jsonAPI))
+	require.NoError(t, err)
+
+	t.Run("applied_to_every_request", func(t *testing.T) {
+		_, err := client.Get(context.Background(), "/", WithHeader("X-Trace", "t1"))
+		require.NoError(t, err)
+		assert.Equal(t, seen{"application/json", "acme", "t1", "1"}, got)
+
+		_, err = client.Do(context.Background(), NewRequest(http.MethodGet, "/"))
+		require.NoError(t, err)
+		assert.Equal(t, seen{"application/json", "acme", "", "1"}, got)
+	})
+
+	t.Run("request_options_win", func(t *testing.T) {
+		_, err := client.Get(context.Background(), "/",
+			WithHeader("X-Tenant", "globex"),
+			WithAccept("text/csv"),
+			WithQuery("page", "7"),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, seen{"text/csv", "globex", "", "7"}, got)
+	})
+
+	t.Run("conflicting_defaults_rejected", func(t *testing.T) {
+		_, err := New(WithRequestDefaults(WithJSON(1), WithRaw(nil, "text/plain")))
+		require.ErrorIs(t, err, ErrConflictingBody)
+	})
+}
+
+func TestBaseURLWithQuery(t *testing.T) {
+	t.Run("joins_paths_and_merges_queries", func(t *testing.T) {
+		tests := []struct{ base, target, want string }{
+			{"https://acct.example.com/container?sv=2024&sig=a%2Fb%3D", "/blob.txt", "https://acct.example.com/container/blob.txt?sv=2024&sig=a%2Fb%3D"},
+			{"https://acct.example.com/container?sig=a%2Fb", "blob.txt?comp=block", "https://acct.example.com/container/blob.txt?sig=a%2Fb&comp=block"},
+			{"https://api.example.com/v1/", "/users", "https://api.example.com/v1/users"},
+			{"https://api.example.com/v1?key=1", "https://other.example.com/x", "https://other.example.com/x"},
+		}
+		for _, tt := range tests {
+			got, err := joinBaseURL(tt.base, tt.target)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		}
+	})
+
+	t.Run("keeps_signed_base_query_verbatim", func(t *testing.T) {
+		var rawQuery string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rawQuery = r.URL.RawQuery
+		}))
+		defer server.Close()
+
+		client, err := New(WithBaseURL(server.URL + "/container?sv=2024&sp=r&sig=a%2Bb%3D"))
+		require.NoError(t, err)
+		_, err = client.Get(context.Background(), "/blob.txt?comp=list", WithQuery("marker", "m 1"))
+		require.NoError(t, err)
+		assert.Equal(t, "sv=2024&sp=r&sig=a%2Bb%3D&comp=list&marker=m+1", rawQuery)
+	})
+}
+
+func TestURLJoinStrategies(t *testing.T) {
+	tests := []struct{ strategy, base, target, want string }{
+		{URLJoinPath, "https://api.example.com/v1/orders", "../users", "https://api.example.com/v1/users"},
+		{URLJoinPath, "https://api.example.com/v1", "/users", "https://api.example.com/v1/users"},
+		{URLJoinRFC3986, "https://api.example.com/v1/orders", "../users", "https://api.example.com/users"},
+		{URLJoinRFC3986, "https://api.example.com/v1/", "users/{id}", "https://api.example.com/v1/users/%7Bid%7D"},
+		{URLJoinRFC3986, "https://api.example.com/v1", "users", "https://api.example.com/users"},
+		{URLJoinRFC3986, "https://api.example.com/v1/", "/health", "https://api.example.com/health"},
+		{URLJoinRFC3986, "https://api.example.com/v1/", "docs#section", "https://api.example.com/v1/docs#section"},
+		{URLJoinRFC3986, "https://api.example.com/v1/?sig=1", "?page=2", "https://api.example.com/v1/?page=2"},
+	}
+	for _, tt := range tests {
+		got, err := joinURL(Config{BaseURL: tt.base, URLJoin: tt.strategy}, tt.target)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got, "%s %s + %s", tt.strategy, tt.base, tt.target)
+	}
+
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	}))
+	defer server.Close()
+	client, err := New(WithBaseURL(server.URL+"/api/v1/"), WithURLJoin(URLJoinRFC3986))
+	require.NoError(t, err)
+	_, err = client.Get(context.Background(), "../v2/users/{id}", WithPathParams(map[string]string{"id": "a/b"}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/api/v2/users/a/b"}, paths)
+
+	_, err = New(WithURLJoin("concat"))
+	assert.ErrorContains(t, err, `unsupported url_join "concat"`)
+}
+
+func TestPreserveEncodedPath(t *testing.T) {
+	var uris []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uris = append(uris, r.RequestURI)
+	}))
+	defer server.Close()
+
+	send := func(client Client, target string, opts ...ReqOption) {
+		t.Helper()
+		_, err := client.Get(context.Background(), target, opts...)
+		require.NoError(t, err)
+	}
+	params := WithPathParams(map[string]string{"id": "group%2Fproject", "file": "docs/read me.md"})
+
+	plain, err := New(WithBaseURL(server.URL + "/api/v4"))
+	require.NoError(t, err)
+	send(plain, "/projects/{id}/files/{file}", params)
+	send(plain, "/projects/group%2Fproject/files/a b")
+
+	preserving, err := New(WithBaseURL(server.URL+"/api/v4"), WithPreserveEncodedPath(true))
+	require.NoError(t, err)
+	send(preserving, "/projects/{id}/files/{file}", params, WithQuery("ref", "main"))
+	send(preserving, "/projects/group%2Fproject/files/a b")
+	send(preserving, "/projects/100%/raw?path=a%2Fb")
+
+	assert.Equal(t, []string{
+		"/api/v4/projects/group%252Fproject/files/docs%2Fread%20me.md",
+		"/api/v4/projects/group/project/files/a%20b",
+		"/api/v4/projects/group%2Fproject/files/docs%2Fread%20me.md?ref=main",
+		"/api/v4/projects/group%2Fproject/files/a%20b",
+		"/api/v4/projects/100%25/raw?path=a%2Fb",
+	}, uris)
+}
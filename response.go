@@ -1,9 +1,18 @@
 package httpc
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gostratum/httpc/ratelimit"
+	"github.com/gostratum/httpc/retry"
 )
 
 // Response wraps an http.Response with convenience helpers for decoding and
@@ -13,10 +22,33 @@ type Response struct {
 	body   []byte
 	loaded bool
 	err    error
+
+	attempts   int
+	retryDelay time.Duration
+	history    []retry.Attempt
+	received   time.Time
+	transcode  bool
+
+	budget   *bufferBudget
+	reserved int64
+	pending  []byte
+	cleanup  runtime.Cleanup
+
+	rawBody bool
+	guard   *guardedBody
 }
 
-func newResponse(resp *http.Response) (*Response, error) {
-	r := &Response{raw: resp}
+func newResponse(resp *http.Response, stats *retry.Stats) (*Response, error) {
+	r := &Response{raw: resp, attempts: 1}
+	if resp != nil && resp.Body != nil {
+		r.guard = &guardedBody{ReadCloser: resp.Body}
+		resp.Body = r.guard
+	}
+	if stats != nil && stats.Attempts > 0 {
+		r.attempts = stats.Attempts
+		r.retryDelay = stats.TotalDelay
+		r.history = stats.History
+	}
 	return r, nil
 }
 
@@ -26,20 +58,33 @@ func (r *Response) ensureBody() error {
 	if r.loaded || r.err != nil {
 		return r.err
 	}
-	defer func() {
-		if r.raw != nil && r.raw.Body != nil {
-			_ = r.raw.Body.Close()
-		}
-	}()
-
-	if r.raw != nil && r.raw.Body != nil {
-		b, err := io.ReadAll(r.raw.Body)
-		if err != nil {
+	if r.raw == nil || r.raw.Body == nil {
+		r.loaded = true
+		return nil
+	}
+	if err := r.claimBody(); err != nil {
+		r.err = err
+		return err
+	}
+	if r.budget != nil {
+		if err := r.readBudgeted(); err != nil {
 			r.err = err
 			return err
 		}
-		r.body = b
+		r.markConsumed()
+		r.loaded = true
+		return nil
 	}
+
+	body := r.bodySource()
+	defer body.Close()
+	b, err := io.ReadAll(body)
+	if err != nil {
+		r.err = err
+		return err
+	}
+	r.body = b
+	r.markConsumed()
 	r.loaded = true
 	return nil
 }
@@ -68,6 +113,15 @@ func (r *Response) Headers() http.Header {
 	return r.raw.Header.Clone()
 }
 
+// Cookies parses the Set-Cookie headers of the response, e.g. to read a
+// CSRF token handed out by a login endpoint.
+func (r *Response) Cookies() []*http.Cookie {
+	if r.raw == nil {
+		return nil
+	}
+	return r.raw.Cookies()
+}
+
 // Bytes returns the response body as a byte slice.
 func (r *Response) Bytes() ([]byte, error) {
 	if err := r.ensureBody(); err != nil {
@@ -76,29 +130,117 @@ func (r *Response) Bytes() ([]byte, error) {
 	return append([]byte(nil), r.body...), nil
 }
 
-// String returns the body as a string.
+// String returns the body as a string, transcoded to UTF-8 from the
+// Content-Type charset when WithCharsetTranscoding is enabled.
 func (r *Response) String() (string, error) {
-	b, err := r.Bytes()
+	b, err := r.text()
 	if err != nil {
 		return "", err
 	}
 	return string(b), nil
 }
 
-// DecodeJSON decodes the response body into the supplied destination.
+// DecodeJSON decodes the response body into the supplied destination. When
+// the body is an HTML error page from a load balancer or proxy, the
+// *InfrastructureError is returned instead of a JSON syntax error. Bodies in
+// a non-UTF-8 charset are transcoded first when WithCharsetTranscoding is
+// enabled.
 func (r *Response) DecodeJSON(dest any) error {
-	if err := r.ensureBody(); err != nil {
+	return r.Decode(json.Unmarshal, dest)
+}
+
+// Decode decodes the response body into dest with unmarshal, applying the
+// same intermediary-page detection and charset transcoding as DecodeJSON.
+// It lets codec packages such as httpc/yaml add response formats.
+func (r *Response) Decode(unmarshal func([]byte, any) error, dest any) error {
+	body, err := r.text()
+	if err != nil {
 		return err
 	}
-	if len(r.body) == 0 {
+	if infra := r.InfrastructureError(); infra != nil {
+		return infra
+	}
+	if len(body) == 0 {
 		return io.EOF
 	}
-	return json.Unmarshal(r.body, dest)
+	return unmarshal(body, dest)
 }
 
-// IntoWriter copies the body into the provided writer.
+// ErrBodyStreamed is returned by the body helpers once DecodeJSONStreamInto
+// has consumed the response body.
+var ErrBodyStreamed = errors.New("response body already streamed")
+
+// DecodeJSONStreamInto decodes a single JSON document into dest straight from
+// the network with a json.Decoder instead of buffering the body first, for
+// documents too large to hold in memory twice. It is not subject to
+// WithMaxBufferedBytes and picks up where a buffering helper stopped after
+// ErrBufferLimitExceeded. Afterwards Bytes, String, DecodeJSON and IntoWriter
+// fail with ErrBodyStreamed. Bodies that are already buffered, and 502/503/504
+// responses that may be intermediary error pages, are decoded as in
+// DecodeJSON.
+func (r *Response) DecodeJSONStreamInto(dest any) error {
+	var src io.Reader
+	switch {
+	case r.pending != nil:
+		src = io.MultiReader(bytes.NewReader(r.pending), r.bodySource())
+		r.pending = nil
+	case r.loaded || r.err != nil || r.raw == nil || r.raw.Body == nil:
+		return r.DecodeJSON(dest)
+	case r.raw.StatusCode == http.StatusBadGateway, r.raw.StatusCode == http.StatusServiceUnavailable,
+		r.raw.StatusCode == http.StatusGatewayTimeout:
+		return r.DecodeJSON(dest)
+	default:
+		if err := r.claimBody(); err != nil {
+			r.err = err
+			return err
+		}
+		src = r.bodySource()
+	}
+	defer drainAndClose(r.bodySource())
+	r.markConsumed()
+	r.loaded, r.err = true, ErrBodyStreamed
+
+	if r.transcode {
+		if enc := charsetEncoding(r.Charset()); enc != nil {
+			src = enc.NewDecoder().Reader(src)
+		}
+	}
+	dec := json.NewDecoder(src)
+	if err := dec.Decode(dest); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return errors.New("unexpected data after JSON document")
+	}
+	return nil
+}
+
+// InfrastructureError classifies a 502/503/504 HTML error page served by an
+// intermediary (load balancer, CDN, proxy), returning nil for any other
+// response. The body is read if it has not been already.
+func (r *Response) InfrastructureError() *InfrastructureError {
+	if r.raw == nil || r.ensureBody() != nil {
+		return nil
+	}
+	return classifyIntermediary(r.raw.StatusCode, r.raw.Header, r.body)
+}
+
+// IntoWriter copies the body into the provided writer. When buffering would
+// exceed the client's WithMaxBufferedBytes limit, the remaining body is
+// streamed to w instead.
 func (r *Response) IntoWriter(w io.Writer) error {
 	if err := r.ensureBody(); err != nil {
+		if !errors.Is(err, ErrBufferLimitExceeded) || r.pending == nil {
+			return err
+		}
+		pending := r.pending
+		r.pending = nil
+		body := r.bodySource()
+		defer body.Close()
+		if _, err := w.Write(pending); err != nil {
+			return err
+		}
+		_, err := io.Copy(w, body)
 		return err
 	}
 	if len(r.body) == 0 {
@@ -108,7 +250,76 @@ func (r *Response) IntoWriter(w io.Writer) error {
 	return err
 }
 
-// Raw exposes the underlying http.Response for advanced consumers.
+// Attempts returns how many attempts were sent to obtain this response,
+// including the first one. A value of 1 means the call succeeded first-try.
+func (r *Response) Attempts() int {
+	return r.attempts
+}
+
+// RetryHistory lists the attempts sent to obtain this response when it was
+// retried, e.g. to see the statuses of a request whose retries ran out on a
+// 503; it is empty for requests sent without a retry policy.
+func (r *Response) RetryHistory() []retry.Attempt {
+	return append([]retry.Attempt(nil), r.history...)
+}
+
+// RetryDelay returns the total backoff waited between attempts.
+func (r *Response) RetryDelay() time.Duration {
+	return r.retryDelay
+}
+
+// NotModified reports whether the server answered 304 Not Modified, e.g. to
+// a GetIfChanged call whose stored ETag is still current.
+func (r *Response) NotModified() bool {
+	return r.StatusCode() == http.StatusNotModified
+}
+
+// idempotentReplayHeaders are the headers servers use to flag a response
+// replayed from an earlier request with the same Idempotency-Key.
+var idempotentReplayHeaders = []string{"Idempotent-Replayed", "Idempotency-Replayed", "X-Idempotent-Replayed"}
+
+// IdempotentReplay reports whether the server says it answered from the
+// result of an earlier request with the same Idempotency-Key (for example
+// Stripe's Idempotent-Replayed: true), i.e. a retried POST was deduplicated
+// rather than executed twice. An echoed key alone does not count, since many
+// servers echo it on every response; see IdempotencyKey.
+func (r *Response) IdempotentReplay() bool {
+	if r.raw == nil {
+		return false
+	}
+	for _, name := range idempotentReplayHeaders {
+		if ok, err := strconv.ParseBool(strings.TrimSpace(r.raw.Header.Get(name))); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// IdempotencyKey returns the Idempotency-Key echoed by the server, or "" when
+// it echoed none.
+func (r *Response) IdempotencyKey() string {
+	return r.Header("Idempotency-Key")
+}
+
+// RateLimit parses the rate limit headers of the response (X-RateLimit-*,
+// the IETF RateLimit-* draft headers and Retry-After). Relative reset times
+// are resolved against when the response was received.
+func (r *Response) RateLimit() ratelimit.Info {
+	received := r.received
+	if received.IsZero() {
+		received = time.Now()
+	}
+	var h http.Header
+	if r.raw != nil {
+		h = r.raw.Header
+	}
+	return ratelimit.ParseHeaders(h, received)
+}
+
+// Raw exposes the underlying http.Response for advanced consumers. Its body
+// is shared with the buffering helpers, so read it only for requests sent
+// with WithRawBody; otherwise reading it after a helper buffered the body,
+// or calling a helper after reading it, fails with ErrBodyConsumed.
 func (r *Response) Raw() *http.Response {
 	return r.raw
 }
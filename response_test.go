@@ -3,11 +3,19 @@ package httpc
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gostratum/core/logx"
+	"github.com/gostratum/httpc/clock"
+	"github.com/gostratum/httpc/retry"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -328,4 +336,514 @@ func TestResponse_Raw(t *testing.T) {
 		assert.Equal(t, http.StatusOK, rawResp.StatusCode)
 		assert.Equal(t, "value", rawResp.Header.Get("X-Test"))
 	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("payload"))
+	}))
+	defer server.Close()
+	client, err := New(WithBaseURL(server.URL), WithLogger(logx.NewNoopLogger()))
+	require.NoError(t, err)
+
+	t.Run("raw_body_is_left_to_the_caller", func(t *testing.T) {
+		resp, err := client.Get(context.Background(), "/", WithRawBody())
+		require.NoError(t, err)
+
+		_, err = resp.Bytes()
+		assert.ErrorIs(t, err, ErrRawBody)
+		assert.ErrorIs(t, resp.DecodeJSON(&struct{}{}), ErrRawBody)
+
+		body := resp.Raw().Body
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		require.NoError(t, err)
+		assert.Equal(t, "payload", string(data))
+	})
+
+	t.Run("reading_raw_after_buffering_fails", func(t *testing.T) {
+		resp, err := client.Get(context.Background(), "/")
+		require.NoError(t, err)
+		s, err := resp.String()
+		require.NoError(t, err)
+		assert.Equal(t, "payload", s)
+
+		_, err = io.ReadAll(resp.Raw().Body)
+		assert.ErrorIs(t, err, ErrBodyConsumed)
+	})
+
+	t.Run("buffering_after_raw_read_fails", func(t *testing.T) {
+		resp, err := client.Get(context.Background(), "/")
+		require.NoError(t, err)
+		_, err = resp.Raw().Body.Read(make([]byte, 3))
+		require.NoError(t, err)
+		defer resp.Raw().Body.Close()
+
+		_, err = resp.String()
+		assert.ErrorIs(t, err, ErrBodyConsumed)
+	})
+}
+
+func TestWithExpectContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("<html><title>502 Bad Gateway</title></html>"))
+		}
+	}))
+	defer server.Close()
+
+	client, err := New(WithBaseURL(server.URL), WithRetry(false, 0))
+	require.NoError(t, err)
+
+	t.Run("accepts_matching_media_type", func(t *testing.T) {
+		resp, err := client.Get(context.Background(), "/json", WithExpectContentType("application/json"))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode())
+	})
+
+	t.Run("accepts_wildcard_subtype", func(t *testing.T) {
+		_, err := client.Get(context.Background(), "/json", WithExpectContentType("application/*"))
+		require.NoError(t, err)
+	})
+
+	t.Run("skips_bodiless_responses", func(t *testing.T) {
+		_, err := client.Get(context.Background(), "/empty", WithExpectContentType("application/json"))
+		require.NoError(t, err)
+	})
+
+	t.Run("rejects_html_error_page", func(t *testing.T) {
+		_, err := client.Get(context.Background(), "/html", WithExpectContentType("application/json"))
+		require.ErrorIs(t, err, ErrUnexpectedContentType)
+
+		var ctErr *ContentTypeError
+		require.ErrorAs(t, err, &ctErr)
+		assert.Equal(t, "text/html", ctErr.Actual)
+		assert.Equal(t, http.StatusBadGateway, ctErr.StatusCode)
+		assert.Contains(t, ctErr.Snippet, "502 Bad Gateway")
+	})
+}
+
+func TestResponse_InfrastructureError(t *testing.T) {
+	newClient := func(t *testing.T, status int, header http.Header, body string) Client {
+		t.Helper()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for k, vv := range header {
+				w.Header()[k] = vv
+			}
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(body))
+		}))
+		t.Cleanup(server.Close)
+		client, err := New(WithBaseURL(server.URL), WithRetry(false, 0))
+		require.NoError(t, err)
+		return client
+	}
+
+	t.Run("classifies_lb_error_page", func(t *testing.T) {
+		client := newClient(t, http.StatusBadGateway,
+			http.Header{"Content-Type": {"text/html"}, "Server": {"awselb/2.0"}},
+			"<html><head><title>502 Bad Gateway</title></head><body><center><h1>502 Bad Gateway</h1></center></body></html>")
+
+		resp, err := client.Get(context.Background(), "/")
+		require.NoError(t, err)
+
+		var out map[string]any
+		err = resp.DecodeJSON(&out)
+		require.ErrorIs(t, err, ErrInfrastructure)
+
+		infra := resp.InfrastructureError()
+		require.NotNil(t, infra)
+		assert.Equal(t, "aws-elb", infra.Source)
+		assert.Equal(t, "502 Bad Gateway", infra.Title)
+		assert.Equal(t, "502 Bad Gateway", infra.Text)
+	})
+
+	t.Run("sniffs_html_without_content_type", func(t *testing.T) {
+		client := newClient(t, http.StatusServiceUnavailable, nil,
+			"<!DOCTYPE html><html><body>Service &amp; site temporarily unavailable</body></html>")
+
+		resp, err := client.Get(context.Background(), "/")
+		require.NoError(t, err)
+		infra := resp.InfrastructureError()
+		require.NotNil(t, infra)
+		assert.Equal(t, "Service & site temporarily unavailable", infra.Text)
+	})
+
+	t.Run("ignores_upstream_json_errors", func(t *testing.T) {
+		client := newClient(t, http.StatusServiceUnavailable,
+			http.Header{"Content-Type": {"application/json"}}, `{"error":"maintenance"}`)
+
+		resp, err := client.Get(context.Background(), "/")
+		require.NoError(t, err)
+		assert.Nil(t, resp.InfrastructureError())
+
+		var out map[string]string
+		require.NoError(t, resp.DecodeJSON(&out))
+		assert.Equal(t, "maintenance", out["error"])
+	})
+}
+
+func TestResponse_Stream(t *testing.T) {
+	payload := strings.Repeat("chunk,", 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(payload))
+	}))
+	defer server.Close()
+	client, err := New(WithBaseURL(server.URL), WithLogger(logx.NewNoopLogger()))
+	require.NoError(t, err)
+
+	t.Run("streams_without_buffering", func(t *testing.T) {
+		resp, err := client.Get(context.Background(), "/", WithStreamResponse())
+		require.NoError(t, err)
+
+		var out strings.Builder
+		n, err := resp.Stream(&out)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(payload)), n)
+		assert.Equal(t, payload, out.String())
+
+		_, err = resp.Bytes()
+		assert.ErrorIs(t, err, ErrRawBody)
+	})
+
+	t.Run("body_reader_blocks_helpers", func(t *testing.T) {
+		resp, err := client.Get(context.Background(), "/")
+		require.NoError(t, err)
+		body := resp.Body()
+		head := make([]byte, 6)
+		_, err = io.ReadFull(body, head)
+		require.NoError(t, err)
+		assert.Equal(t, "chunk,", string(head))
+		require.NoError(t, body.Close())
+
+		_, err = resp.String()
+		assert.ErrorIs(t, err, ErrBodyConsumed)
+	})
+
+	t.Run("body_after_buffering_reads_buffer", func(t *testing.T) {
+		resp, err := client.Get(context.Background(), "/")
+		require.NoError(t, err)
+		_, err = resp.Bytes()
+		require.NoError(t, err)
+
+		data, err := io.ReadAll(resp.Body())
+		require.NoError(t, err)
+		assert.Equal(t, payload, string(data))
+	})
+}
+
+func TestResponse_Parts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/plain" {
+			_, _ = w.Write([]byte("not multipart"))
+			return
+		}
+		mw := multipart.NewWriter(w)
+		w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+		for i, body := range []string{`{"id":1}`, "payload"} {
+			part, _ := mw.CreatePart(textproto.MIMEHeader{
+				"Content-Type": {[]string{"application/json", "application/octet-stream"}[i]},
+				"Content-Id":   {fmt.Sprintf("<item-%d>", i+1)},
+			})
+			_, _ = part.Write([]byte(body))
+		}
+		_ = mw.Close()
+	}))
+	defer server.Close()
+	client, err := New(WithBaseURL(server.URL), WithLogger(logx.NewNoopLogger()))
+	require.NoError(t, err)
+
+	t.Run("iterates_parts", func(t *testing.T) {
+		resp, err := client.Get(context.Background(), "/batch")
+		require.NoError(t, err)
+
+		var ids, bodies []string
+		for part, err := range resp.Parts() {
+			require.NoError(t, err)
+			data, err := io.ReadAll(part)
+			require.NoError(t, err)
+			ids = append(ids, part.Header.Get("Content-Id"))
+			bodies = append(bodies, string(data))
+		}
+		assert.Equal(t, []string{"<item-1>", "<item-2>"}, ids)
+		assert.Equal(t, []string{`{"id":1}`, "payload"}, bodies)
+	})
+
+	t.Run("stops_early", func(t *testing.T) {
+		resp, err := client.Get(context.Background(), "/batch", WithStreamResponse())
+		require.NoError(t, err)
+		var n int
+		for range resp.Parts() {
+			n++
+			break
+		}
+		assert.Equal(t, 1, n)
+	})
+
+	t.Run("rejects_non_multipart", func(t *testing.T) {
+		resp, err := client.Get(context.Background(), "/plain")
+		require.NoError(t, err)
+		for _, err := range resp.Parts() {
+			assert.ErrorIs(t, err, ErrNotMultipart)
+		}
+		body, err := resp.String()
+		require.NoError(t, err)
+		assert.Equal(t, "not multipart", body)
+	})
+}
+
+func TestWithMaxBufferedBytes(t *testing.T) {
+	payload := strings.Repeat("x", 60)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(payload))
+	}))
+	defer server.Close()
+
+	client, err := New(WithBaseURL(server.URL), WithMaxBufferedBytes(100))
+	require.NoError(t, err)
+	get := func() *Response {
+		resp, err := client.Get(context.Background(), "/")
+		require.NoError(t, err)
+		return resp
+	}
+
+	first := get()
+	body, err := first.String()
+	require.NoError(t, err)
+	assert.Equal(t, payload, body)
+
+	second := get()
+	_, err = second.Bytes()
+	require.ErrorIs(t, err, ErrBufferLimitExceeded)
+
+	var streamed strings.Builder
+	require.NoError(t, second.IntoWriter(&streamed))
+	assert.Equal(t, payload, streamed.String(), "IntoWriter must stream past the limit")
+
+	first.Release()
+	third := get()
+	body, err = third.String()
+	require.NoError(t, err)
+	assert.Equal(t, payload, body)
+}
+
+func TestResponse_RateLimit(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	newClient := func(t *testing.T, header http.Header) Client {
+		t.Helper()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for k, vv := range header {
+				w.Header()[k] = vv
+			}
+		}))
+		t.Cleanup(server.Close)
+		client, err := New(WithBaseURL(server.URL), WithRetry(false, 0), WithClock(clock.NewFake(start)))
+		require.NoError(t, err)
+		return client
+	}
+
+	t.Run("github_style_headers", func(t *testing.T) {
+		client := newClient(t, http.Header{
+			"X-Ratelimit-Limit":     {"5000"},
+			"X-Ratelimit-Remaining": {"4999"},
+			"X-Ratelimit-Reset":     {"1700003600"},
+		})
+		resp, err := client.Get(context.Background(), "/")
+		require.NoError(t, err)
+
+		info := resp.RateLimit()
+		assert.True(t, info.Reported())
+		assert.Equal(t, 5000, info.Limit)
+		assert.Equal(t, 4999, info.Remaining)
+		assert.Equal(t, start.Add(time.Hour), info.Reset)
+		assert.False(t, info.Exhausted())
+	})
+
+	t.Run("ietf_draft_headers", func(t *testing.T) {
+		client := newClient(t, http.Header{
+			"Ratelimit-Limit":     {"100, 100;w=60"},
+			"Ratelimit-Remaining": {"0"},
+			"Ratelimit-Reset":     {"30"},
+			"Retry-After":         {"45"},
+		})
+		resp, err := client.Get(context.Background(), "/")
+		require.NoError(t, err)
+
+		info := resp.RateLimit()
+		assert.Equal(t, 100, info.Limit)
+		assert.Equal(t, time.Minute, info.Window)
+		assert.Equal(t, start.Add(30*time.Second), info.Reset)
+		assert.True(t, info.Exhausted())
+		resume, ok := info.ResumeAt()
+		assert.True(t, ok)
+		assert.Equal(t, start.Add(45*time.Second), resume)
+	})
+
+	t.Run("not_reported", func(t *testing.T) {
+		client := newClient(t, nil)
+		resp, err := client.Get(context.Background(), "/")
+		require.NoError(t, err)
+		info := resp.RateLimit()
+		assert.False(t, info.Reported())
+		assert.Equal(t, -1, info.Remaining)
+	})
+}
+
+func TestResponse_CharsetTranscoding(t *testing.T) {
+	newClient := func(t *testing.T, contentType string, body []byte, opts ...Option) Client {
+		t.Helper()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			_, _ = w.Write(body)
+		}))
+		t.Cleanup(server.Close)
+		client, err := New(append([]Option{WithBaseURL(server.URL), WithRetry(false, 0)}, opts...)...)
+		require.NoError(t, err)
+		return client
+	}
+
+	t.Run("latin1_string", func(t *testing.T) {
+		client := newClient(t, "text/plain; charset=ISO-8859-1", []byte("caf\xe9"), WithCharsetTranscoding(true))
+		resp, err := client.Get(context.Background(), "/")
+		require.NoError(t, err)
+		assert.Equal(t, "iso-8859-1", resp.Charset())
+
+		s, err := resp.String()
+		require.NoError(t, err)
+		assert.Equal(t, "café", s)
+
+		raw, err := resp.Bytes()
+		require.NoError(t, err)
+		assert.Equal(t, []byte("caf\xe9"), raw)
+	})
+
+	t.Run("shift_jis_json", func(t *testing.T) {
+		// {"name":"日本"} encoded as Shift_JIS.
+		body := []byte("{\"name\":\"\x93\xfa\x96\x7b\"}")
+		client := newClient(t, "application/json; charset=Shift_JIS", body, WithCharsetTranscoding(true))
+		resp, err := client.Get(context.Background(), "/")
+		require.NoError(t, err)
+
+		var out struct{ Name string }
+		require.NoError(t, resp.DecodeJSON(&out))
+		assert.Equal(t, "日本", out.Name)
+	})
+
+	t.Run("disabled_by_default", func(t *testing.T) {
+		client := newClient(t, "text/plain; charset=ISO-8859-1", []byte("caf\xe9"))
+		resp, err := client.Get(context.Background(), "/")
+		require.NoError(t, err)
+
+		s, err := resp.String()
+		require.NoError(t, err)
+		assert.Equal(t, "caf\xe9", s)
+	})
+
+	t.Run("unknown_charset_unchanged", func(t *testing.T) {
+		client := newClient(t, "text/plain; charset=x-unknown", []byte("plain"), WithCharsetTranscoding(true))
+		resp, err := client.Get(context.Background(), "/")
+		require.NoError(t, err)
+
+		s, err := resp.String()
+		require.NoError(t, err)
+		assert.Equal(t, "plain", s)
+	})
+}
+
+func TestResponse_DecodeJSONStreamInto(t *testing.T) {
+	items := make([]int, 1000)
+	for i := range items {
+		items[i] = i
+	}
+	doc, err := json.Marshal(map[string]any{"items": items})
+	require.NoError(t, err)
+
+	newClient := func(t *testing.T, body []byte) Client {
+		t.Helper()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(body)
+		}))
+		t.Cleanup(server.Close)
+		client, err := New(WithBaseURL(server.URL), WithRetry(false, 0), WithMaxBufferedBytes(256))
+		require.NoError(t, err)
+		return client
+	}
+	var out struct{ Items []int }
+
+	t.Run("decodes_past_buffer_limit", func(t *testing.T) {
+		resp, err := newClient(t, doc).Get(context.Background(), "/")
+		require.NoError(t, err)
+
+		require.NoError(t, resp.DecodeJSONStreamInto(&out))
+		assert.Len(t, out.Items, 1000)
+		assert.Equal(t, 999, out.Items[999])
+
+		_, err = resp.Bytes()
+		assert.ErrorIs(t, err, ErrBodyStreamed)
+	})
+
+	t.Run("resumes_after_limit_exceeded", func(t *testing.T) {
+		resp, err := newClient(t, doc).Get(context.Background(), "/")
+		require.NoError(t, err)
+		_, err = resp.String()
+		require.ErrorIs(t, err, ErrBufferLimitExceeded)
+
+		out.Items = nil
+		require.NoError(t, resp.DecodeJSONStreamInto(&out))
+		assert.Len(t, out.Items, 1000)
+	})
+
+	t.Run("rejects_trailing_data", func(t *testing.T) {
+		resp, err := newClient(t, []byte(`{"items":[1]} {"items":[2]}`)).Get(context.Background(), "/")
+		require.NoError(t, err)
+		assert.Error(t, resp.DecodeJSONStreamInto(&out))
+	})
+}
+
+func TestResponse_IdempotentReplay(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		keys = append(keys, key)
+		w.Header().Set("Idempotency-Key", key)
+		if len(keys) == 1 {
+			// The order was created but the response was lost.
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client, err := New(WithBaseURL(server.URL), WithRetry(true, 2), WithRetryPolicy(retry.NewPolicy(retry.PolicyConfig{
+		MaxAttempts:    2,
+		BaseBackoff:    time.Millisecond,
+		StatusCodes:    []int{http.StatusBadGateway},
+		IdempotentOnly: true,
+	})))
+	require.NoError(t, err)
+
+	resp, err := client.Post(context.Background(), "/orders", map[string]int{"qty": 1},
+		WithIdempotencyKey("order-42"), WithIdempotent())
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode())
+	assert.Equal(t, 2, resp.Attempts())
+	assert.True(t, resp.IdempotentReplay())
+	assert.Equal(t, "order-42", resp.IdempotencyKey())
+	assert.Equal(t, []string{"order-42", "order-42"}, keys)
+
+	for header, want := range map[string]bool{"Idempotency-Replayed": true, "X-Idempotent-Replayed": true} {
+		r, _ := newResponse(&http.Response{Header: http.Header{header: {"1"}}}, nil)
+		assert.Equal(t, want, r.IdempotentReplay(), header)
+	}
+	r, _ := newResponse(&http.Response{Header: http.Header{"Idempotency-Key": {"k"}, "Idempotent-Replayed": {"false"}}}, nil)
+	assert.False(t, r.IdempotentReplay(), "an echoed key alone is not a replay")
 }
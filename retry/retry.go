@@ -4,15 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"math"
 	"math/rand"
 	"net"
 	"net/http"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/gostratum/core/logx"
+	"github.com/gostratum/httpc/clock"
+	"github.com/gostratum/httpc/logging"
+	"github.com/gostratum/httpc/redact"
 )
 
 // Policy determines if and when a request should be retried.
@@ -20,7 +21,8 @@ type Policy interface {
 	ShouldRetry(req *http.Request, resp *http.Response, err error, attempt int, force bool) (time.Duration, bool)
 }
 
-// PolicyConfig configures the default retry strategy.
+// PolicyConfig configures the default retry strategy. StatusCodes, Env and
+// IdempotentOnly only apply to HTTP policies.
 type PolicyConfig struct {
 	MaxAttempts    int
 	BaseBackoff    time.Duration
@@ -28,109 +30,127 @@ type PolicyConfig struct {
 	StatusCodes    []int
 	Env            string
 	IdempotentOnly bool
+
+	// Rand supplies the randomness used for jitter. Inject a seeded source to
+	// make backoff delays reproducible.
+	Rand *rand.Rand
+	// JitterFunc, when set, replaces the random jitter entirely. It receives
+	// the capped exponential delay and returns the jitter to add to it.
+	JitterFunc func(attempt int, delay time.Duration) time.Duration
+	// Clock drives the waits in Strategy.Do; the HTTP middleware uses the
+	// clock given to WithClock instead.
+	Clock clock.Clock
 }
 
-// NewPolicy constructs a Policy using exponential backoff with jitter.
+// NoJitter is a JitterFunc yielding plain exponential backoff.
+func NoJitter(int, time.Duration) time.Duration { return 0 }
+
+// NewPolicy constructs an HTTP Policy using exponential backoff with jitter.
 func NewPolicy(cfg PolicyConfig) Policy {
-	if cfg.MaxAttempts <= 0 {
-		cfg.MaxAttempts = 3
-	}
-	if cfg.BaseBackoff <= 0 {
-		cfg.BaseBackoff = 200 * time.Millisecond
-	}
-	if cfg.MaxBackoff <= 0 {
-		cfg.MaxBackoff = 2 * time.Second
-	}
+	return HTTPPolicy(NewStrategy(cfg, nil), cfg.StatusCodes, cfg.IdempotentOnly)
+}
 
-	codeSet := make(map[int]struct{}, len(cfg.StatusCodes))
-	for _, code := range cfg.StatusCodes {
-		codeSet[code] = struct{}{}
+func isIdempotent(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
 	}
+}
 
-	return &policy{
-		maxAttempts:    cfg.MaxAttempts,
-		baseBackoff:    cfg.BaseBackoff,
-		maxBackoff:     cfg.MaxBackoff,
-		statusCodes:    codeSet,
-		idempotentOnly: cfg.IdempotentOnly,
-		rand:           rand.New(rand.NewSource(time.Now().UnixNano())),
+func isNetErrorRetryable(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout() || netErr.Temporary()
 	}
+	return false
 }
 
-type policy struct {
-	maxAttempts    int
-	baseBackoff    time.Duration
-	maxBackoff     time.Duration
-	statusCodes    map[int]struct{}
-	idempotentOnly bool
+type policyKey struct{}
+type forceKey struct{}
 
-	mu   sync.Mutex
-	rand *rand.Rand
+type idempotentKey struct{}
+type statsKey struct{}
+
+// Stats records what the retry middleware did for a request. The caller owns
+// the value and must not read it until the round trip has returned.
+type Stats struct {
+	// Attempts is the number of attempts sent, including the first one.
+	Attempts int
+	// TotalDelay is the cumulative backoff waited between attempts.
+	TotalDelay time.Duration
+	// History lists every attempt in order.
+	History []Attempt
 }
 
-func (p *policy) ShouldRetry(req *http.Request, resp *http.Response, err error, attempt int, force bool) (time.Duration, bool) {
-	if attempt >= p.maxAttempts {
-		return 0, false
-	}
-
-	if !force && p.idempotentOnly && !isIdempotent(req.Method) {
-		return 0, false
-	}
-
-	if err != nil {
-		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-			return 0, false
-		}
-		if isNetErrorRetryable(err) {
-			return p.backoff(attempt), true
-		}
-		return 0, false
-	}
+// Attempt describes the outcome of a single attempt.
+type Attempt struct {
+	Number     int
+	StatusCode int
+	Err        error
+	Duration   time.Duration
+	// Delay is the backoff waited after this attempt (zero for the last one).
+	Delay time.Duration
+}
 
-	if resp == nil {
-		return 0, false
+func (a Attempt) String() string {
+	outcome := fmt.Sprintf("status %d", a.StatusCode)
+	if a.Err != nil {
+		outcome = a.Err.Error()
 	}
-
-	if _, ok := p.statusCodes[resp.StatusCode]; ok {
-		return p.backoff(attempt), true
+	s := fmt.Sprintf("attempt %d: %s", a.Number, outcome)
+	if a.Delay > 0 {
+		s += fmt.Sprintf(" (backoff %s)", a.Delay)
 	}
+	return s
+}
 
-	return 0, false
+// ExhaustedError is returned when a request was retried but its final attempt
+// still failed with a transport error, or when the wait before the next
+// attempt was interrupted. It wraps that last error. A request whose retries
+// run out on a retryable status is exempt: its last response is returned
+// with a nil error, as an http.RoundTripper must, and the attempts are in
+// Stats.History.
+type ExhaustedError struct {
+	Attempts []Attempt
+	Err      error
+	// Interrupted reports that the wait before another attempt was cut
+	// short; Err is then its cause rather than an attempt's outcome.
+	Interrupted bool
 }
 
-func (p *policy) backoff(attempt int) time.Duration {
-	factor := math.Pow(2, float64(attempt-1))
-	delay := time.Duration(float64(p.baseBackoff) * factor)
-	if delay > p.maxBackoff {
-		delay = p.maxBackoff
+func (e *ExhaustedError) Error() string {
+	parts := make([]string, len(e.Attempts))
+	for i, a := range e.Attempts {
+		parts[i] = a.String()
 	}
-
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	jitter := p.rand.Float64() * float64(delay) * 0.2
-	return delay + time.Duration(jitter)
+	msg := fmt.Sprintf("retries exhausted after %d attempts: %s", len(e.Attempts), strings.Join(parts, "; "))
+	if e.Interrupted && e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
 }
 
-func isIdempotent(method string) bool {
-	switch strings.ToUpper(method) {
-	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
-		return true
-	default:
-		return false
+func (e *ExhaustedError) Unwrap() error { return e.Err }
+
+// WithStats attaches stats to the context for the middleware to populate.
+func WithStats(ctx context.Context, s *Stats) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
 	}
+	return context.WithValue(ctx, statsKey{}, s)
 }
 
-func isNetErrorRetryable(err error) bool {
-	var netErr net.Error
-	if errors.As(err, &netErr) {
-		return netErr.Timeout() || netErr.Temporary()
+// StatsFromContext retrieves the stats attached with WithStats.
+func StatsFromContext(ctx context.Context) *Stats {
+	if ctx == nil {
+		return nil
 	}
-	return false
+	s, _ := ctx.Value(statsKey{}).(*Stats)
+	return s
 }
 
-type policyKey struct{}
-type forceKey struct{}
-
 // WithPolicy stores the policy in the request context.
 func WithPolicy(ctx context.Context, p Policy) context.Context {
 	if ctx == nil {
@@ -156,6 +176,29 @@ func WithForce(ctx context.Context) context.Context {
 	return context.WithValue(ctx, forceKey{}, true)
 }
 
+// WithIdempotent declares the request idempotent regardless of its method,
+// e.g. a POST the server deduplicates by Idempotency-Key.
+func WithIdempotent(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// IsIdempotent reports whether req may safely be sent more than once: its
+// method is idempotent or it was declared so with WithIdempotent. Retry,
+// hedging and failover logic should all decide through this.
+func IsIdempotent(req *http.Request) bool {
+	if req == nil {
+		return false
+	}
+	if isIdempotent(req.Method) {
+		return true
+	}
+	declared, _ := req.Context().Value(idempotentKey{}).(bool)
+	return declared
+}
+
 // IsForce returns true if the context requested forced retries.
 func IsForce(ctx context.Context) bool {
 	if ctx == nil {
@@ -165,11 +208,43 @@ func IsForce(ctx context.Context) bool {
 	return force
 }
 
+// MiddlewareOption customises the retry middleware.
+type MiddlewareOption func(*middlewareOptions)
+
+type middlewareOptions struct {
+	clock    clock.Clock
+	redactor *redact.Redactor
+}
+
+// WithClock overrides the clock used to wait between attempts.
+func WithClock(c clock.Clock) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.clock = c
+	}
+}
+
+// WithRedactor sets the redactor applied to URLs in retry logs. Defaults to
+// redact.Default().
+func WithRedactor(r *redact.Redactor) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.redactor = r
+	}
+}
+
 // NewMiddleware constructs a retry middleware.
-func NewMiddleware(defaultPolicy Policy, logger logx.Logger) func(http.RoundTripper) http.RoundTripper {
+func NewMiddleware(defaultPolicy Policy, logger logx.Logger, opts ...MiddlewareOption) func(http.RoundTripper) http.RoundTripper {
 	if logger == nil {
 		logger = logx.NewNoopLogger()
 	}
+	var mo middlewareOptions
+	for _, opt := range opts {
+		opt(&mo)
+	}
+	clk := clock.OrReal(mo.clock)
+	redactor := mo.redactor
+	if redactor == nil {
+		redactor = redact.Default()
+	}
 	return func(next http.RoundTripper) http.RoundTripper {
 		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
 			policy := PolicyFromContext(req.Context())
@@ -181,7 +256,9 @@ func NewMiddleware(defaultPolicy Policy, logger logx.Logger) func(http.RoundTrip
 			}
 
 			force := IsForce(req.Context())
+			stats := StatsFromContext(req.Context())
 			attempt := 1
+			var history []Attempt
 
 			orig := req
 
@@ -191,10 +268,33 @@ func NewMiddleware(defaultPolicy Policy, logger logx.Logger) func(http.RoundTrip
 					return nil, err
 				}
 
+				if stats != nil {
+					stats.Attempts++
+				}
+				started := clk.Now()
 				resp, err := next.RoundTrip(currentReq)
 
 				delay, retryable := policy.ShouldRetry(currentReq, resp, err, attempt, force)
+				if retryable && !replayable(orig) {
+					// A streamed body without GetBody has been consumed.
+					retryable = false
+				}
+				record := Attempt{Number: attempt, Err: err, Duration: clk.Now().Sub(started)}
+				if resp != nil {
+					record.StatusCode = resp.StatusCode
+				}
+				if retryable {
+					record.Delay = delay
+				}
+				history = append(history, record)
+				if stats != nil {
+					stats.History = append(stats.History, record)
+				}
+
 				if !retryable {
+					if err != nil && attempt > 1 {
+						return nil, &ExhaustedError{Attempts: history, Err: err}
+					}
 					return resp, err
 				}
 
@@ -203,31 +303,37 @@ func NewMiddleware(defaultPolicy Policy, logger logx.Logger) func(http.RoundTrip
 				}
 
 				attempt++
-				if err := waitWithContext(req.Context(), delay); err != nil {
-					return nil, fmt.Errorf("retry interrupted: %w", err)
+				if err := waitWithContext(req.Context(), clk, delay); err != nil {
+					return nil, &ExhaustedError{Attempts: history, Err: fmt.Errorf("retry interrupted: %w", err), Interrupted: true}
+				}
+				if stats != nil {
+					stats.TotalDelay += delay
+				}
+				level := logging.LevelFromContext(req.Context()).Or(logging.LevelDebug)
+				if logf := logging.Select(level, logger.Debug, logger.Info, logger.Warn, logger.Error); logf != nil {
+					logf("retrying http request",
+						logx.String("method", req.Method),
+						logx.String("url", redactor.Request(req)),
+						logx.Int("attempt", attempt),
+					)
 				}
-				logger.Debug("retrying http request",
-					logx.String("method", req.Method),
-					logx.String("url", req.URL.String()),
-					logx.Int("attempt", attempt),
-				)
 			}
 		})
 	}
 }
 
-func waitWithContext(ctx context.Context, d time.Duration) error {
+func waitWithContext(ctx context.Context, clk clock.Clock, d time.Duration) error {
 	if d <= 0 {
 		return nil
 	}
 
-	timer := time.NewTimer(d)
+	timer := clk.NewTimer(d)
 	defer timer.Stop()
 
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
-	case <-timer.C:
+	case <-timer.C():
 		return nil
 	}
 }
@@ -255,6 +361,11 @@ func prepareAttemptRequest(req *http.Request, attempt int) (*http.Request, error
 	return clone, nil
 }
 
+// replayable reports whether the request body can be sent again.
+func replayable(req *http.Request) bool {
+	return req.GetBody != nil || req.Body == nil || req.Body == http.NoBody
+}
+
 type roundTripperFunc func(*http.Request) (*http.Response, error)
 
 func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
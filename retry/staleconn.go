@@ -0,0 +1,82 @@
+package retry
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync/atomic"
+	"syscall"
+)
+
+// NewStaleConnMiddleware retries a request once, immediately, when it failed
+// on a reused keep-alive connection that the server had already closed before
+// the request body was transmitted (for bodyless requests: before the request
+// was written at all). The server cannot have acted on such a request, so the
+// retry is safe for any method, mirroring net/http's own handling of idle
+// connections lost in a race with the server. It runs independently of any
+// retry Policy and never delays.
+func NewStaleConnMiddleware() func(http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, unsent, err := roundTripTraced(next, req)
+			if err == nil || !unsent || !IsStaleConnError(err) || !replayable(req) {
+				return resp, err
+			}
+			retryReq, rerr := prepareAttemptRequest(req, 2)
+			if rerr != nil {
+				return nil, err
+			}
+			resp, _, err = roundTripTraced(next, retryReq)
+			return resp, err
+		})
+	}
+}
+
+// roundTripTraced sends req and reports whether it went out on a reused
+// connection without its body being transmitted.
+func roundTripTraced(next http.RoundTripper, req *http.Request) (*http.Response, bool, error) {
+	var reused, wrote atomic.Bool
+	ctx := httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		GotConn:      func(info httptrace.GotConnInfo) { reused.Store(info.Reused) },
+		WroteRequest: func(info httptrace.WroteRequestInfo) { wrote.Store(info.Err == nil) },
+	})
+	out := req.WithContext(ctx)
+	var body *readTracker
+	if req.Body != nil && req.Body != http.NoBody {
+		body = &readTracker{ReadCloser: req.Body}
+		out.Body = body
+	}
+	resp, err := next.RoundTrip(out)
+	unsent := !wrote.Load() || (body != nil && !body.read.Load())
+	return resp, reused.Load() && unsent, err
+}
+
+// readTracker records whether the transport started reading the body.
+type readTracker struct {
+	io.ReadCloser
+	read atomic.Bool
+}
+
+func (r *readTracker) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.read.Store(true)
+	}
+	return n, err
+}
+
+// IsStaleConnError reports whether err is the kind of failure seen when a
+// pooled connection was closed by the server while idle: an EOF, reset or
+// broken pipe before any response.
+func IsStaleConnError(err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return true
+	}
+	return strings.Contains(err.Error(), "server closed idle connection")
+}
@@ -0,0 +1,194 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gostratum/httpc/clock"
+)
+
+// Strategy is the protocol-agnostic core of a retry policy: an attempt
+// budget, exponential backoff with jitter and a classifier deciding which
+// errors are transient. Database, queue and other clients use it directly;
+// HTTPPolicy adapts it to HTTP requests.
+type Strategy struct {
+	maxAttempts int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+	jitter      func(attempt int, delay time.Duration) time.Duration
+	retryable   func(error) bool
+	clk         clock.Clock
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewStrategy builds a Strategy from the MaxAttempts, BaseBackoff,
+// MaxBackoff, Rand, JitterFunc and Clock fields of cfg; the HTTP-only fields
+// are ignored. retryable classifies failures and defaults to Transient.
+func NewStrategy(cfg PolicyConfig, retryable func(error) bool) *Strategy {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = 200 * time.Millisecond
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 2 * time.Second
+	}
+	if retryable == nil {
+		retryable = Transient
+	}
+
+	rnd := cfg.Rand
+	if rnd == nil {
+		rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	return &Strategy{
+		maxAttempts: cfg.MaxAttempts,
+		baseBackoff: cfg.BaseBackoff,
+		maxBackoff:  cfg.MaxBackoff,
+		jitter:      cfg.JitterFunc,
+		retryable:   retryable,
+		clk:         clock.OrReal(cfg.Clock),
+		rand:        rnd,
+	}
+}
+
+// Transient reports whether err is a temporary network failure. Context
+// cancellation and deadlines are never transient.
+func Transient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return isNetErrorRetryable(err)
+}
+
+// MaxAttempts returns the attempt budget, including the first attempt.
+func (s *Strategy) MaxAttempts() int { return s.maxAttempts }
+
+// Backoff returns the delay to wait after the given (1-based) attempt.
+func (s *Strategy) Backoff(attempt int) time.Duration {
+	factor := math.Pow(2, float64(attempt-1))
+	delay := time.Duration(float64(s.baseBackoff) * factor)
+	if delay > s.maxBackoff {
+		delay = s.maxBackoff
+	}
+
+	if s.jitter != nil {
+		return delay + s.jitter(attempt, delay)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jitter := s.rand.Float64() * float64(delay) * 0.2
+	return delay + time.Duration(jitter)
+}
+
+// Next reports whether to try again after attempt failed with err, and how
+// long to wait first. Context cancellation and deadlines are never retried.
+func (s *Strategy) Next(err error, attempt int) (time.Duration, bool) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return 0, false
+	}
+	return s.next(err, attempt)
+}
+
+// NextContext is Next for an attempt made under ctx. While ctx is live, a
+// deadline error can only come from the attempt's own timeout, so it is left
+// to the classifier like any other failure instead of ending the retries.
+func (s *Strategy) NextContext(ctx context.Context, err error, attempt int) (time.Duration, bool) {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return 0, false
+	}
+	return s.next(err, attempt)
+}
+
+func (s *Strategy) next(err error, attempt int) (time.Duration, bool) {
+	if err == nil || attempt >= s.maxAttempts || !s.retryable(err) {
+		return 0, false
+	}
+	return s.Backoff(attempt), true
+}
+
+// Do calls fn until it succeeds, fails with a non-retryable error or the
+// attempt budget is spent. Failures are classified with NextContext, so an
+// attempt that times out on its own deadline is retried while ctx is live.
+// When more than one attempt failed the last error is returned wrapped in an
+// *ExhaustedError.
+func (s *Strategy) Do(ctx context.Context, fn func(ctx context.Context, attempt int) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var history []Attempt
+	for attempt := 1; ; attempt++ {
+		started := s.clk.Now()
+		err := fn(ctx, attempt)
+		if err == nil {
+			return nil
+		}
+		delay, retryable := s.NextContext(ctx, err, attempt)
+		record := Attempt{Number: attempt, Err: err, Duration: s.clk.Now().Sub(started)}
+		if retryable {
+			record.Delay = delay
+		}
+		history = append(history, record)
+		if !retryable {
+			if attempt > 1 {
+				return &ExhaustedError{Attempts: history, Err: err}
+			}
+			return err
+		}
+		if err := waitWithContext(ctx, s.clk, delay); err != nil {
+			return &ExhaustedError{Attempts: history, Err: fmt.Errorf("retry interrupted: %w", err), Interrupted: true}
+		}
+	}
+}
+
+// HTTPPolicy adapts s to HTTP. Transport errors are classified by s,
+// responses with one of statusCodes are retried, and with idempotentOnly a
+// request that is not IsIdempotent is only retried when forced.
+func HTTPPolicy(s *Strategy, statusCodes []int, idempotentOnly bool) Policy {
+	codeSet := make(map[int]struct{}, len(statusCodes))
+	for _, code := range statusCodes {
+		codeSet[code] = struct{}{}
+	}
+	return &policy{strategy: s, statusCodes: codeSet, idempotentOnly: idempotentOnly}
+}
+
+type policy struct {
+	strategy       *Strategy
+	statusCodes    map[int]struct{}
+	idempotentOnly bool
+}
+
+func (p *policy) ShouldRetry(req *http.Request, resp *http.Response, err error, attempt int, force bool) (time.Duration, bool) {
+	if attempt >= p.strategy.maxAttempts {
+		return 0, false
+	}
+
+	if !force && p.idempotentOnly && !IsIdempotent(req) {
+		return 0, false
+	}
+
+	if err != nil {
+		return p.strategy.Next(err, attempt)
+	}
+
+	if resp == nil {
+		return 0, false
+	}
+
+	if _, ok := p.statusCodes[resp.StatusCode]; ok {
+		return p.strategy.Backoff(attempt), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,193 @@
+package httpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gostratum/httpc/logging"
+)
+
+const requestWireVersion = 1
+
+// wireRequest is the stable serialized form of a Request.
+type wireRequest struct {
+	Version     int               `json:"version"`
+	Method      string            `json:"method"`
+	URL         string            `json:"url"`
+	Headers     http.Header       `json:"headers,omitempty"`
+	Query       url.Values        `json:"query,omitempty"`
+	PathParams  map[string]string `json:"path_params,omitempty"`
+	Timeout     string            `json:"timeout,omitempty"`
+	ContentType string            `json:"content_type,omitempty"`
+	Accept      string            `json:"accept,omitempty"`
+	JSONBody    bool              `json:"json_body,omitempty"`
+	JSONAccept  *string           `json:"json_accept,omitempty"`
+	Expect      string            `json:"expect_content_type,omitempty"`
+	LogLevel    string            `json:"log_level,omitempty"`
+	Body        []byte            `json:"body,omitempty"`
+	HasBody     bool              `json:"has_body,omitempty"`
+	ForceRetry  bool              `json:"force_retry,omitempty"`
+	Idempotent  bool              `json:"idempotent,omitempty"`
+	Override    bool              `json:"method_override,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	APIVersion  string            `json:"api_version,omitempty"`
+	Breaker     *bool             `json:"breaker,omitempty"`
+	BreakerKey  string            `json:"breaker_key,omitempty"`
+	ErrorOn     *bool             `json:"error_on_status,omitempty"`
+	Cookies     []wireCookie      `json:"cookies,omitempty"`
+	Compression *wireCompress     `json:"compression,omitempty"`
+	RawBody     bool              `json:"raw_body,omitempty"`
+}
+
+type wireCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type wireCompress struct {
+	FallbackOn415 bool `json:"fallback_on_415"`
+}
+
+// MarshalRequest encodes r into a stable JSON wire format so it can be queued
+// and executed later, e.g. by an outbox worker using the same client. The body
+// factory is evaluated once and its bytes are stored. Per-request auth,
+// retry policy overrides, early hint and progress callbacks, sessions and
+// dynamic query parameters are runtime values and are not serialized; the executing
+// client's defaults apply.
+func MarshalRequest(r *Request) ([]byte, error) {
+	if r == nil {
+		return nil, fmt.Errorf("marshal request: nil request")
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("marshal request: %w", r.err)
+	}
+
+	w := wireRequest{
+		Version:     requestWireVersion,
+		Method:      r.method,
+		URL:         r.url,
+		Headers:     r.headers,
+		Query:       r.queries,
+		PathParams:  r.pathParams,
+		ContentType: r.contentType,
+		Accept:      r.accept,
+		JSONBody:    r.jsonBody,
+		JSONAccept:  r.jsonAccept,
+		Expect:      r.expectContentType,
+		ForceRetry:  r.forceRetry,
+		Idempotent:  r.idempotent,
+		Override:    r.overrideVerb,
+		ServerName:  r.serverName,
+		APIVersion:  r.apiVersion,
+		Breaker:     r.breakerToggle,
+		BreakerKey:  r.breakerKey,
+		ErrorOn:     r.errorOnStatus,
+		RawBody:     r.rawBody,
+	}
+	if r.timeout > 0 {
+		w.Timeout = r.timeout.String()
+	}
+	if r.logLevel != logging.LevelDefault {
+		w.LogLevel = r.logLevel.String()
+	}
+	for _, c := range r.cookies {
+		w.Cookies = append(w.Cookies, wireCookie{Name: c.Name, Value: c.Value})
+	}
+	if r.compress {
+		w.Compression = &wireCompress{FallbackOn415: r.compressFallback}
+	}
+
+	if r.bodyFactory == nil && r.bodyStream != nil {
+		return nil, fmt.Errorf("marshal request: streamed bodies cannot be serialized")
+	}
+	if r.bodyFactory != nil {
+		rc, _, ctype, err := r.bodyFactory()
+		if err != nil {
+			return nil, fmt.Errorf("marshal request body: %w", err)
+		}
+		body, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("marshal request body: %w", err)
+		}
+		w.Body = body
+		w.HasBody = true
+		if w.ContentType == "" {
+			w.ContentType = ctype
+		}
+	}
+
+	return json.Marshal(w)
+}
+
+// UnmarshalRequest decodes a Request produced by MarshalRequest. Additional
+// options are applied on top, e.g. to attach auth or a retry policy.
+func UnmarshalRequest(data []byte, opts ...ReqOption) (*Request, error) {
+	var w wireRequest
+	if err := json.Unmarshal(data, &w); err != nil {
+		return nil, fmt.Errorf("unmarshal request: %w", err)
+	}
+	if w.Version != requestWireVersion {
+		return nil, fmt.Errorf("unmarshal request: unsupported version %d", w.Version)
+	}
+	if w.Method == "" {
+		return nil, fmt.Errorf("unmarshal request: missing method")
+	}
+
+	r := buildRequest(w.Method, w.URL)
+	for k, vv := range w.Headers {
+		r.headers[http.CanonicalHeaderKey(k)] = append([]string(nil), vv...)
+	}
+	for k, vv := range w.Query {
+		r.queries[k] = append([]string(nil), vv...)
+	}
+	if len(w.PathParams) > 0 {
+		WithPathParams(w.PathParams)(r)
+	}
+	if w.Timeout != "" {
+		d, err := time.ParseDuration(w.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal request: timeout: %w", err)
+		}
+		r.timeout = d
+	}
+	r.accept = w.Accept
+	r.expectContentType = w.Expect
+	level, err := logging.ParseLevel(w.LogLevel)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal request: %w", err)
+	}
+	r.logLevel = level
+	r.forceRetry = w.ForceRetry
+	r.idempotent = w.Idempotent
+	r.overrideVerb = w.Override
+	r.serverName = w.ServerName
+	r.apiVersion = w.APIVersion
+	r.breakerToggle = w.Breaker
+	r.breakerKey = w.BreakerKey
+	r.errorOnStatus = w.ErrorOn
+	r.rawBody = w.RawBody
+	for _, c := range w.Cookies {
+		r.setCookie(&http.Cookie{Name: c.Name, Value: c.Value})
+	}
+	if w.Compression != nil {
+		r.compress = true
+		r.compressFallback = w.Compression.FallbackOn415
+	}
+	if w.HasBody {
+		WithRaw(w.Body, w.ContentType)(r)
+		r.jsonBody = w.JSONBody
+	}
+	r.jsonAccept = w.JSONAccept
+	r.contentType = w.ContentType
+
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.frozen = true
+	return r, nil
+}
@@ -0,0 +1,75 @@
+package httpc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalRequest(t *testing.T) {
+	t.Run("round_trips_and_executes", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/orders", r.URL.Path)
+			assert.Equal(t, "eu", r.URL.Query().Get("region"))
+			assert.Equal(t, "order-1", r.Header.Get("Idempotency-Key"))
+			if c, err := r.Cookie("tenant"); assert.NoError(t, err) {
+				assert.Equal(t, "acme", c.Value)
+			}
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			body, _ := io.ReadAll(r.Body)
+			assert.JSONEq(t, `{"sku":"abc","qty":2}`, string(body))
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+
+		original := NewRequest(http.MethodPost, "/orders",
+			WithJSON(map[string]any{"sku": "abc", "qty": 2}),
+			WithQuery("region", "eu"),
+			WithIdempotencyKey("order-1"),
+			WithCookie("tenant", "acme"),
+			WithRequestTimeout(3*time.Second),
+			WithRequestRetryForce(),
+			WithRequestLogLevel(logging.LevelInfo),
+		)
+
+		data, err := MarshalRequest(original)
+		require.NoError(t, err)
+
+		var wire map[string]any
+		require.NoError(t, json.Unmarshal(data, &wire))
+		assert.EqualValues(t, 1, wire["version"])
+		assert.Equal(t, "3s", wire["timeout"])
+
+		restored, err := UnmarshalRequest(data)
+		require.NoError(t, err)
+		assert.Equal(t, http.MethodPost, restored.Method())
+		assert.Equal(t, 3*time.Second, restored.timeout)
+		assert.True(t, restored.forceRetry)
+		assert.Equal(t, logging.LevelInfo, restored.logLevel)
+
+		client, err := New(WithBaseURL(server.URL))
+		require.NoError(t, err)
+		resp, err := client.Do(context.Background(), restored)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode())
+	})
+
+	t.Run("surfaces_body_errors", func(t *testing.T) {
+		_, err := MarshalRequest(NewRequest(http.MethodPost, "/", WithJSON(make(chan int))))
+		require.Error(t, err)
+	})
+
+	t.Run("rejects_unknown_version", func(t *testing.T) {
+		_, err := UnmarshalRequest([]byte(`{"version":99,"method":"GET","url":"/"}`))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,69 @@
+package httpc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+type serverNameKey struct{}
+
+func withServerName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, serverNameKey{}, name)
+}
+
+func serverNameFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(serverNameKey{}).(string)
+	return name
+}
+
+// serverNameTransport routes requests carrying a WithServerName override to a
+// clone of the base transport whose TLS config uses that SNI. Each name gets
+// its own connection pool so a connection negotiated for one name is never
+// reused for another.
+type serverNameTransport struct {
+	base http.RoundTripper
+
+	mu     sync.Mutex
+	byName map[string]http.RoundTripper
+}
+
+func newServerNameTransport(base http.RoundTripper) *serverNameTransport {
+	return &serverNameTransport{base: base, byName: make(map[string]http.RoundTripper)}
+}
+
+func (t *serverNameTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	name := serverNameFromContext(req.Context())
+	if name == "" {
+		return t.base.RoundTrip(req)
+	}
+	rt, err := t.forName(name)
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+	return rt.RoundTrip(req)
+}
+
+func (t *serverNameTransport) forName(name string) (http.RoundTripper, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if rt, ok := t.byName[name]; ok {
+		return rt, nil
+	}
+	base, ok := t.base.(*http.Transport)
+	if !ok {
+		return nil, errors.New("httpc: WithServerName requires the base transport to be an *http.Transport")
+	}
+	clone := base.Clone()
+	if clone.TLSClientConfig == nil {
+		clone.TLSClientConfig = &tls.Config{}
+	}
+	clone.TLSClientConfig.ServerName = name
+	t.byName[name] = clone
+	return clone, nil
+}
@@ -0,0 +1,38 @@
+package httpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithServerName(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.ServerName))
+	}))
+	defer server.Close()
+
+	client, err := New(WithBaseURL(server.URL), WithTransport(server.Client().Transport))
+	require.NoError(t, err)
+
+	resp, err := client.Get(context.Background(), "/", WithServerName("example.com"))
+	require.NoError(t, err)
+	body, _ := resp.String()
+	assert.Equal(t, "example.com", body)
+
+	resp, err = client.Get(context.Background(), "/")
+	require.NoError(t, err)
+	body, _ = resp.String()
+	assert.Empty(t, body, "requests without an override keep the default SNI (none for IP hosts)")
+
+	t.Run("requires_http_transport", func(t *testing.T) {
+		client, err := New(WithHandler(http.NotFoundHandler()))
+		require.NoError(t, err)
+		_, err = client.Get(context.Background(), "https://10.0.0.1/", WithServerName("example.com"))
+		require.ErrorContains(t, err, "WithServerName requires")
+	})
+}
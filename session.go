@@ -0,0 +1,117 @@
+package httpc
+
+import (
+	"net/http"
+	"sync"
+)
+
+// SessionOptions names where an upstream hands out its affinity token.
+type SessionOptions struct {
+	// Header names a response header carrying the token, e.g. a replication
+	// position; it is sent back under the same name.
+	Header string
+	// Cookie names a cookie carrying the token, e.g. a sticky-session cookie
+	// set by the load balancer.
+	Cookie string
+}
+
+// Session gives read-your-writes consistency against eventually consistent
+// upstreams: the affinity token returned by a write (any method other than
+// GET, HEAD, OPTIONS and TRACE) is replayed on every later request of the
+// session, so reads reach a replica that has seen the write. Pass it to each
+// request of the logical session with WithSession. A Session is safe for
+// concurrent use; the latest write response carrying a token wins.
+type Session struct {
+	opts SessionOptions
+
+	mu     sync.Mutex
+	token  string
+	cookie *http.Cookie
+}
+
+// NewSession returns an empty Session capturing the token named by opts.
+func NewSession(opts SessionOptions) *Session {
+	return &Session{opts: opts}
+}
+
+// WithSession sends the request within s, replaying its affinity token and,
+// for writes, capturing a new one from the response. Headers and cookies set
+// explicitly on the request take precedence.
+func WithSession(s *Session) ReqOption {
+	return func(r *Request) {
+		r.ensureMutable()
+		r.session = s
+	}
+}
+
+// Token returns the affinity token captured last, from the header or else
+// the cookie, or "" before the first write.
+func (s *Session) Token() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" || s.cookie == nil {
+		return s.token
+	}
+	return s.cookie.Value
+}
+
+// Reset forgets the captured token, e.g. when the logical session ends.
+func (s *Session) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token, s.cookie = "", nil
+}
+
+func (s *Session) apply(req *http.Request) {
+	s.mu.Lock()
+	token, cookie := s.token, s.cookie
+	s.mu.Unlock()
+	if token != "" && req.Header.Get(s.opts.Header) == "" {
+		req.Header.Set(s.opts.Header, token)
+	}
+	if cookie != nil {
+		if _, err := req.Cookie(cookie.Name); err != nil {
+			req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+		}
+	}
+}
+
+func (s *Session) capture(resp *http.Response) {
+	var token string
+	if s.opts.Header != "" {
+		token = resp.Header.Get(s.opts.Header)
+	}
+	var cookie *http.Cookie
+	if s.opts.Cookie != "" {
+		for _, c := range resp.Cookies() {
+			if c.Name == s.opts.Cookie {
+				cookie = c
+			}
+		}
+	}
+	if token == "" && cookie == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token != "" {
+		s.token = token
+	}
+	if cookie != nil {
+		if cookie.MaxAge < 0 || cookie.Value == "" {
+			s.cookie = nil
+		} else {
+			s.cookie = cookie
+		}
+	}
+}
+
+// isSafeMethod reports the methods that never carry a write (RFC 9110
+// section 9.2.1).
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
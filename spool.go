@@ -0,0 +1,63 @@
+package httpc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// spoolBody buffers r so it can be replayed on retries. The first limit bytes
+// are kept in memory; anything beyond is written to a temporary file that the
+// returned cleanup removes. A non-positive limit keeps everything in memory.
+func spoolBody(r io.Reader, limit int64) (bodyProvider, func(), error) {
+	noop := func() {}
+	if limit <= 0 {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, noop, err
+		}
+		return memoryBody(data), noop, nil
+	}
+
+	head, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, noop, err
+	}
+	if int64(len(head)) <= limit {
+		return memoryBody(head), noop, nil
+	}
+
+	f, err := os.CreateTemp("", "httpc-body-*")
+	if err != nil {
+		return nil, noop, fmt.Errorf("spool body: %w", err)
+	}
+	cleanup := func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}
+	if _, err := f.Write(head); err != nil {
+		cleanup()
+		return nil, noop, fmt.Errorf("spool body: %w", err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		cleanup()
+		return nil, noop, fmt.Errorf("spool body: %w", err)
+	}
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		cleanup()
+		return nil, noop, fmt.Errorf("spool body: %w", err)
+	}
+
+	factory := func() (io.ReadCloser, int64, string, error) {
+		return io.NopCloser(io.NewSectionReader(f, 0, size)), size, "application/octet-stream", nil
+	}
+	return factory, cleanup, nil
+}
+
+func memoryBody(data []byte) bodyProvider {
+	return func() (io.ReadCloser, int64, string, error) {
+		return io.NopCloser(bytes.NewReader(data)), int64(len(data)), "application/octet-stream", nil
+	}
+}
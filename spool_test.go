@@ -0,0 +1,165 @@
+package httpc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodySpooling(t *testing.T) {
+	t.Run("replays_spooled_body_on_retry_and_removes_file", func(t *testing.T) {
+		tmp := t.TempDir()
+		t.Setenv("TMPDIR", tmp)
+
+		payload := strings.Repeat("x", 100)
+		var bodies []string
+		var spooled int
+		transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			entries, _ := os.ReadDir(tmp)
+			spooled = len(entries)
+			data, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			bodies = append(bodies, string(data))
+			status := http.StatusOK
+			if len(bodies) == 1 {
+				status = http.StatusServiceUnavailable
+			}
+			return &http.Response{StatusCode: status, Body: http.NoBody, Header: make(http.Header)}, nil
+		})
+
+		client, err := New(
+			WithTransport(transport),
+			WithRetry(true, 2),
+			WithRetryPolicy(retry.NewPolicy(retry.PolicyConfig{
+				MaxAttempts: 2,
+				BaseBackoff: time.Millisecond,
+				MaxBackoff:  time.Millisecond,
+				StatusCodes: []int{http.StatusServiceUnavailable},
+			})),
+			WithBodySpoolLimit(16),
+		)
+		require.NoError(t, err)
+
+		resp, err := client.Post(context.Background(), "http://example.test/upload",
+			io.MultiReader(strings.NewReader(payload)), WithRequestRetryForce())
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode())
+		assert.Equal(t, []string{payload, payload}, bodies)
+		assert.Equal(t, 1, spooled)
+
+		entries, err := os.ReadDir(tmp)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("keeps_small_bodies_in_memory", func(t *testing.T) {
+		factory, cleanup, err := spoolBody(strings.NewReader("small"), 16)
+		require.NoError(t, err)
+		defer cleanup()
+
+		rc, n, _, err := factory()
+		require.NoError(t, err)
+		data, _ := io.ReadAll(rc)
+		assert.Equal(t, "small", string(data))
+		assert.EqualValues(t, 5, n)
+	})
+}
+
+func TestWithJSONSpool(t *testing.T) {
+	type item struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	t.Run("matches_json_marshal", func(t *testing.T) {
+		for _, v := range []any{
+			[]item{{1, "a"}, {2, "b"}},
+			&[]item{{3, "c"}},
+			[2]int{4, 5},
+			[]item{},
+			[]item(nil),
+			[]byte("raw"),
+			map[string]int{"n": 1},
+		} {
+			want, err := json.Marshal(v)
+			require.NoError(t, err)
+			var got strings.Builder
+			require.NoError(t, encodeJSONTo(&got, v))
+			assert.Equal(t, string(want), got.String())
+		}
+	})
+
+	t.Run("spools_to_file_and_replays_on_retry", func(t *testing.T) {
+		tmp := t.TempDir()
+		t.Setenv("TMPDIR", tmp)
+
+		items := make([]item, 100)
+		for i := range items {
+			items[i] = item{ID: i, Name: strings.Repeat("n", 10)}
+		}
+		want, err := json.Marshal(items)
+		require.NoError(t, err)
+
+		var bodies []string
+		var spooled int
+		transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			entries, _ := os.ReadDir(tmp)
+			spooled = len(entries)
+			assert.Equal(t, int64(len(want)), req.ContentLength)
+			assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+			data, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			_ = req.Body.Close()
+			bodies = append(bodies, string(data))
+			status := http.StatusOK
+			if len(bodies) == 1 {
+				status = http.StatusServiceUnavailable
+			}
+			return &http.Response{StatusCode: status, Body: http.NoBody, Header: make(http.Header)}, nil
+		})
+
+		client, err := New(
+			WithTransport(transport),
+			WithRetry(true, 2),
+			WithRetryPolicy(retry.NewPolicy(retry.PolicyConfig{
+				MaxAttempts: 2,
+				BaseBackoff: time.Millisecond,
+				MaxBackoff:  time.Millisecond,
+				StatusCodes: []int{http.StatusServiceUnavailable},
+			})),
+		)
+		require.NoError(t, err)
+
+		resp, err := client.Post(context.Background(), "http://example.test/import", nil,
+			WithJSONSpool(items, 256), WithRequestRetryForce())
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode())
+		assert.Equal(t, []string{string(want), string(want)}, bodies)
+		assert.Equal(t, 1, spooled)
+
+		entries, err := os.ReadDir(tmp)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("reports_encoding_errors", func(t *testing.T) {
+		client, err := New(WithTransport(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			t.Fatal("request must not be sent")
+			return nil, nil
+		})))
+		require.NoError(t, err)
+
+		_, err = client.Post(context.Background(), "http://example.test/import", nil,
+			WithJSONSpool([]any{1, make(chan int)}, 0))
+		assert.ErrorContains(t, err, "encode json")
+	})
+}
@@ -0,0 +1,225 @@
+package httpc
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gostratum/httpc/clock"
+	"github.com/gostratum/httpc/retry"
+	"github.com/sony/gobreaker"
+)
+
+// latencySamples bounds the rolling latency window kept per host.
+const latencySamples = 1024
+
+// Stats is an in-process snapshot of client activity, keyed by host.
+type Stats struct {
+	Hosts map[string]HostStats
+	// InFlight is the number of requests currently awaiting response headers.
+	InFlight int64
+	// ConnsReused and ConnsNew count connections obtained from the pool versus
+	// dialled.
+	ConnsReused int64
+	ConnsNew    int64
+}
+
+// HostStats summarises the calls made to one host since the client was
+// created. Latency percentiles cover the most recent 1024 calls and measure
+// time to response headers, including retries.
+type HostStats struct {
+	Requests int64
+	// Errors counts transport errors and 5xx responses.
+	Errors int64
+	// Retried counts requests that needed more than one attempt.
+	Retried int64
+	// ErrorRate and RetryRate are Errors and Retried over Requests.
+	ErrorRate float64
+	RetryRate float64
+
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+
+	// BreakerTransitions counts state changes of the host's breaker;
+	// BreakerState is its last known state. Only tracked for breaker
+	// managers created by the client.
+	BreakerTransitions int64
+	BreakerState       string
+
+	// KeyFallbacks counts requests resent with the secondary API key after
+	// the primary was rejected; a non-zero value means the primary key is
+	// no longer accepted and rotation should be completed.
+	KeyFallbacks int64
+}
+
+type statsRecorder struct {
+	clk   clock.Clock
+	mu    sync.Mutex
+	hosts map[string]*hostRecord
+
+	inFlight    atomic.Int64
+	connsReused atomic.Int64
+	connsNew    atomic.Int64
+}
+
+type hostRecord struct {
+	requests    int64
+	errors      int64
+	retried     int64
+	latencies   [latencySamples]time.Duration
+	next        int
+	filled      bool
+	transitions int64
+	state       string
+	fallbacks   int64
+	health      HostHealth
+}
+
+func newStatsRecorder(clk clock.Clock) *statsRecorder {
+	return &statsRecorder{clk: clock.OrReal(clk), hosts: make(map[string]*hostRecord)}
+}
+
+// host must be called with s.mu held.
+func (s *statsRecorder) host(name string) *hostRecord {
+	h, ok := s.hosts[name]
+	if !ok {
+		h = &hostRecord{}
+		s.hosts[name] = h
+	}
+	return h
+}
+
+func (s *statsRecorder) middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			trace := &httptrace.ClientTrace{
+				GotConn: func(info httptrace.GotConnInfo) {
+					if info.Reused {
+						s.connsReused.Add(1)
+					} else {
+						s.connsNew.Add(1)
+					}
+				},
+			}
+			req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+			s.inFlight.Add(1)
+			defer s.inFlight.Add(-1)
+			start := s.clk.Now()
+			resp, err := next.RoundTrip(req)
+			elapsed := s.clk.Now().Sub(start)
+
+			attempts := 1
+			if st := retry.StatsFromContext(req.Context()); st != nil && st.Attempts > 1 {
+				attempts = st.Attempts
+			}
+
+			s.mu.Lock()
+			h := s.host(req.URL.Host)
+			h.requests++
+			failed := err != nil || (resp != nil && resp.StatusCode >= 500)
+			if failed {
+				h.errors++
+			}
+			h.health.observe(elapsed, failed)
+			if attempts > 1 {
+				h.retried++
+			}
+			h.latencies[h.next] = elapsed
+			h.next = (h.next + 1) % latencySamples
+			if h.next == 0 {
+				h.filled = true
+			}
+			s.mu.Unlock()
+
+			return resp, err
+		})
+	}
+}
+
+func (s *statsRecorder) onBreakerStateChange(host string, _, to gobreaker.State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := s.host(host)
+	h.transitions++
+	h.state = to.String()
+}
+
+func (s *statsRecorder) health(host string) HostHealth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if h, ok := s.hosts[host]; ok {
+		return h.health
+	}
+	return HostHealth{Score: 1}
+}
+
+func (s *statsRecorder) onKeyFallback(host string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.host(host).fallbacks++
+}
+
+func (s *statsRecorder) snapshot() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := Stats{
+		Hosts:       make(map[string]HostStats, len(s.hosts)),
+		InFlight:    s.inFlight.Load(),
+		ConnsReused: s.connsReused.Load(),
+		ConnsNew:    s.connsNew.Load(),
+	}
+	for name, h := range s.hosts {
+		hs := HostStats{
+			Requests:           h.requests,
+			Errors:             h.errors,
+			Retried:            h.retried,
+			BreakerTransitions: h.transitions,
+			BreakerState:       h.state,
+			KeyFallbacks:       h.fallbacks,
+		}
+		if h.requests > 0 {
+			hs.ErrorRate = float64(h.errors) / float64(h.requests)
+			hs.RetryRate = float64(h.retried) / float64(h.requests)
+		}
+
+		n := h.next
+		if h.filled {
+			n = latencySamples
+		}
+		if n > 0 {
+			sorted := append([]time.Duration(nil), h.latencies[:n]...)
+			sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+			hs.P50 = percentile(sorted, 0.50)
+			hs.P90 = percentile(sorted, 0.90)
+			hs.P99 = percentile(sorted, 0.99)
+			hs.Max = sorted[n-1]
+		}
+		out.Hosts[name] = hs
+	}
+	return out
+}
+
+// percentile uses the nearest-rank method on sorted samples.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// Stats returns rolling latency percentiles, error and retry rates and breaker
+// transitions per host, computed in-process.
+func (c *client) Stats() Stats {
+	return c.stats.snapshot()
+}
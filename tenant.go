@@ -0,0 +1,169 @@
+package httpc
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gostratum/httpc/auth"
+)
+
+// Tenant describes how one tenant's client differs from the parent
+// configuration of a TenantClientFactory.
+type Tenant struct {
+	// Auth replaces the parent's default auth provider when set.
+	Auth auth.AuthProvider
+	// BaseURLSuffix is appended to the parent base URL, e.g. "/tenants/acme".
+	BaseURLSuffix string
+	// Headers are sent with every request of the tenant's client.
+	Headers map[string]string
+}
+
+// TenantResolver looks up the settings of a tenant by ID.
+type TenantResolver func(ctx context.Context, tenantID string) (Tenant, error)
+
+// TenantFactoryConfig configures a TenantClientFactory.
+type TenantFactoryConfig struct {
+	Resolve TenantResolver
+	// MaxClients bounds the derived clients kept; the least recently used is
+	// dropped past it. Defaults to 100.
+	MaxClients int
+}
+
+// TenantClientFactory derives per-tenant clients from shared parent options.
+// All derived clients share one connection pool, so adding tenants does not
+// multiply idle connections. It is safe for concurrent use.
+type TenantClientFactory struct {
+	cfg     TenantFactoryConfig
+	parent  Config
+	opts    []Option
+	shared  http.RoundTripper
+	recycle *connRecycler
+
+	mu      sync.Mutex
+	order   *list.List
+	clients map[string]*list.Element
+}
+
+type tenantEntry struct {
+	id     string
+	client Client
+}
+
+// NewTenantClientFactory prepares a factory whose clients are built from
+// opts plus each tenant's overrides. Expvar publishing and startup probes of
+// the parent options are not applied to derived clients.
+func NewTenantClientFactory(cfg TenantFactoryConfig, opts ...Option) (*TenantClientFactory, error) {
+	if cfg.Resolve == nil {
+		return nil, errors.New("tenant client factory requires a resolver")
+	}
+	if cfg.MaxClients <= 0 {
+		cfg.MaxClients = 100
+	}
+
+	parent := Config{}
+	parent.applyDefaults()
+	for _, opt := range opts {
+		opt(&parent)
+	}
+	parent.applyDefaults()
+
+	f := &TenantClientFactory{
+		cfg:     cfg,
+		parent:  parent,
+		opts:    append([]Option(nil), opts...),
+		shared:  parent.Transport,
+		order:   list.New(),
+		clients: make(map[string]*list.Element),
+	}
+	if f.shared == nil && parent.HTTPClient == nil {
+		proxyURL, err := parent.proxyURL()
+		if err != nil {
+			return nil, err
+		}
+		if parent.MaxConnLifetime > 0 {
+			f.recycle = newConnRecycler(parent.MaxConnLifetime)
+		}
+		f.shared = defaultTransport(parent, proxyURL, f.recycle)
+	}
+	return f, nil
+}
+
+// Client returns the client of tenantID, resolving and building it on first
+// use.
+func (f *TenantClientFactory) Client(ctx context.Context, tenantID string) (Client, error) {
+	if c, ok := f.lookup(tenantID); ok {
+		return c, nil
+	}
+
+	tenant, err := f.cfg.Resolve(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	opts := append(append(make([]Option, 0, len(f.opts)+1), f.opts...), f.derive(tenant))
+	c, err := New(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if el, ok := f.clients[tenantID]; ok {
+		// Another caller built it concurrently; keep the first.
+		f.order.MoveToFront(el)
+		return el.Value.(*tenantEntry).client, nil
+	}
+	f.clients[tenantID] = f.order.PushFront(&tenantEntry{id: tenantID, client: c})
+	for f.order.Len() > f.cfg.MaxClients {
+		oldest := f.order.Back()
+		f.order.Remove(oldest)
+		delete(f.clients, oldest.Value.(*tenantEntry).id)
+	}
+	return c, nil
+}
+
+// Forget drops the cached client of tenantID, e.g. after its settings
+// changed.
+func (f *TenantClientFactory) Forget(tenantID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if el, ok := f.clients[tenantID]; ok {
+		f.order.Remove(el)
+		delete(f.clients, tenantID)
+	}
+}
+
+func (f *TenantClientFactory) lookup(tenantID string) (Client, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	el, ok := f.clients[tenantID]
+	if !ok {
+		return nil, false
+	}
+	f.order.MoveToFront(el)
+	return el.Value.(*tenantEntry).client, true
+}
+
+// derive applies tenant on top of the parent options.
+func (f *TenantClientFactory) derive(tenant Tenant) Option {
+	return func(c *Config) {
+		if f.shared != nil {
+			c.Transport = f.shared
+			c.sharedRecycler = f.recycle
+		}
+		c.ExpvarName = ""
+		c.StartupProbe = nil
+		if tenant.Auth != nil {
+			c.DefaultAuth = tenant.Auth
+		}
+		if tenant.BaseURLSuffix != "" {
+			c.BaseURL = strings.TrimRight(f.parent.BaseURL, "/") + "/" + strings.TrimLeft(tenant.BaseURLSuffix, "/")
+		}
+		if len(tenant.Headers) > 0 {
+			c.RequestDefaults = append(append([]ReqOption(nil), c.RequestDefaults...), WithHeaders(tenant.Headers))
+		}
+	}
+}
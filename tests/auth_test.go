@@ -1,17 +1,26 @@
 package httpc_test
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/gostratum/httpc"
 	"github.com/gostratum/httpc/auth"
+	"github.com/gostratum/httpc/clock"
 )
 
 func TestAPIKeyHeader(t *testing.T) {
@@ -125,3 +134,318 @@ func TestJWTAuthRS256(t *testing.T) {
 		t.Fatalf("failed to load key via PEM: %v", err)
 	}
 }
+
+func TestContextProviderReceivesCallerContext(t *testing.T) {
+	type traceKey struct{}
+	var gotTrace any
+	var hadDeadline bool
+	provider := auth.ContextProviderFunc(func(ctx context.Context, req *http.Request) error {
+		gotTrace = ctx.Value(traceKey{})
+		_, hadDeadline = ctx.Deadline()
+		req.Header.Set("Authorization", "Bearer fetched")
+		return nil
+	})
+
+	client, err := httpc.New(
+		httpc.WithTransport(roundTripper(func(req *http.Request) (*http.Response, error) {
+			if got := req.Header.Get("Authorization"); got != "Bearer fetched" {
+				t.Errorf("expected authorization header, got %q", got)
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+		})),
+		httpc.WithAuth(provider),
+		httpc.WithRetry(false, 0),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), traceKey{}, "trace-1")
+	if _, err := client.Get(ctx, "http://api.test/", httpc.WithRequestTimeout(time.Second)); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if gotTrace != "trace-1" || !hadDeadline {
+		t.Fatalf("provider did not get the caller context: trace=%v deadline=%v", gotTrace, hadDeadline)
+	}
+}
+
+func TestTokenProviderBackgroundRefresh(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	var fetches atomic.Int32
+	source := auth.TokenSourceFunc(func(ctx context.Context) (auth.Token, error) {
+		n := fetches.Add(1)
+		return auth.Token{AccessToken: fmt.Sprintf("tok-%d", n), Expiry: fake.Now().Add(10 * time.Minute)}, nil
+	})
+	provider, err := auth.NewToken(auth.TokenOptions{Source: source, RefreshBefore: time.Minute, Clock: fake})
+	if err != nil {
+		t.Fatalf("new token provider: %v", err)
+	}
+	refresher, ok := provider.(auth.Refresher)
+	if !ok {
+		t.Fatal("token provider should implement auth.Refresher")
+	}
+	if err := refresher.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer func() { _ = refresher.Stop(context.Background()) }()
+
+	// The refresher prefetches, then sleeps until a minute before expiry.
+	fake.BlockUntil(1)
+	if got := fetches.Load(); got != 1 {
+		t.Fatalf("expected a prefetched token, got %d fetches", got)
+	}
+	authorization := func() string {
+		req, _ := http.NewRequest(http.MethodGet, "http://api.test/", nil)
+		if err := auth.Apply(context.Background(), provider, req); err != nil {
+			t.Fatalf("apply: %v", err)
+		}
+		return req.Header.Get("Authorization")
+	}
+	if got := authorization(); got != "Bearer tok-1" {
+		t.Fatalf("expected cached token, got %q", got)
+	}
+
+	fake.Advance(9 * time.Minute)
+	fake.BlockUntil(1)
+	if got := fetches.Load(); got != 2 {
+		t.Fatalf("expected a background refresh ahead of expiry, got %d fetches", got)
+	}
+	if got := authorization(); got != "Bearer tok-2" {
+		t.Fatalf("expected refreshed token, got %q", got)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Fatalf("requests must not fetch tokens inline, got %d fetches", got)
+	}
+}
+
+func TestTokenProviderInlineFetch(t *testing.T) {
+	var fetches int
+	provider, err := auth.NewToken(auth.TokenOptions{Source: auth.TokenSourceFunc(func(ctx context.Context) (auth.Token, error) {
+		fetches++
+		return auth.Token{AccessToken: "abc", TokenType: "DPoP"}, nil
+	})})
+	if err != nil {
+		t.Fatalf("new token provider: %v", err)
+	}
+	for range 2 {
+		req, _ := http.NewRequest(http.MethodGet, "http://api.test/", nil)
+		if err := provider.Apply(req); err != nil {
+			t.Fatalf("apply: %v", err)
+		}
+		if got := req.Header.Get("Authorization"); got != "DPoP abc" {
+			t.Fatalf("unexpected authorization %q", got)
+		}
+	}
+	if fetches != 1 {
+		t.Fatalf("expected the token to be cached, got %d fetches", fetches)
+	}
+	if _, err := auth.NewToken(auth.TokenOptions{}); !errors.Is(err, auth.ErrNoTokenSource) {
+		t.Fatalf("expected ErrNoTokenSource, got %v", err)
+	}
+}
+
+func TestRefreshOn401(t *testing.T) {
+	var fetches atomic.Int32
+	provider, err := auth.NewToken(auth.TokenOptions{Source: auth.TokenSourceFunc(func(ctx context.Context) (auth.Token, error) {
+		return auth.Token{AccessToken: fmt.Sprintf("tok-%d", fetches.Add(1))}, nil
+	})})
+	if err != nil {
+		t.Fatalf("new token provider: %v", err)
+	}
+
+	var calls int
+	var bodies []string
+	client, err := httpc.New(
+		httpc.WithTransport(roundTripper(func(req *http.Request) (*http.Response, error) {
+			calls++
+			if req.Body != nil {
+				body, _ := io.ReadAll(req.Body)
+				bodies = append(bodies, string(body))
+			}
+			status := http.StatusOK
+			if req.Header.Get("Authorization") != "Bearer tok-2" {
+				status = http.StatusUnauthorized // tok-1 was revoked server-side
+			}
+			return &http.Response{StatusCode: status, Body: http.NoBody, Request: req}, nil
+		})),
+		httpc.WithAuth(provider),
+		httpc.WithRetry(false, 0),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	resp, err := client.Post(context.Background(), "http://api.test/orders", "payload")
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	if resp.StatusCode() != http.StatusOK || calls != 2 || fetches.Load() != 2 {
+		t.Fatalf("expected one refresh and retry: status=%d calls=%d fetches=%d", resp.StatusCode(), calls, fetches.Load())
+	}
+	if bodies[1] != "payload" {
+		t.Fatalf("expected the body to be replayed, got %q", bodies[1])
+	}
+
+	// A token that keeps being rejected is retried only once.
+	calls = 0
+	provider2, _ := auth.NewToken(auth.TokenOptions{Source: auth.TokenSourceFunc(func(ctx context.Context) (auth.Token, error) {
+		return auth.Token{AccessToken: "bad"}, nil
+	})})
+	resp, err = client.Get(context.Background(), "http://api.test/", httpc.WithRequestAuth(provider2))
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if resp.StatusCode() != http.StatusUnauthorized || calls != 2 {
+		t.Fatalf("expected the 401 after a single retry: status=%d calls=%d", resp.StatusCode(), calls)
+	}
+}
+
+func TestAPIKeyRotationFallsBackToSecondary(t *testing.T) {
+	var keys, bodies []string
+	cfg := httpc.Config{Transport: roundTripper(func(req *http.Request) (*http.Response, error) {
+		key := req.Header.Get("X-API-Key")
+		keys = append(keys, key)
+		if req.Body != nil {
+			body, _ := io.ReadAll(req.Body)
+			bodies = append(bodies, string(body))
+		}
+		status := http.StatusOK
+		if key != "new-key" {
+			status = http.StatusForbidden
+		}
+		return &http.Response{StatusCode: status, Body: http.NoBody, Request: req}, nil
+	})}
+	cfg.APIKey.Key = "old-key"
+	cfg.APIKey.SecondaryKey = "new-key"
+	client, err := httpc.New(httpc.WithConfig(cfg), httpc.WithRetry(false, 0))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	resp, err := client.Post(context.Background(), "http://api.test/orders", "payload")
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	if resp.StatusCode() != http.StatusOK || strings.Join(keys, ",") != "old-key,new-key" {
+		t.Fatalf("expected a resend with the secondary key: status=%d keys=%v", resp.StatusCode(), keys)
+	}
+	if bodies[1] != "payload" {
+		t.Fatalf("expected the body to be replayed, got %q", bodies[1])
+	}
+	if got := client.Stats().Hosts["api.test"].KeyFallbacks; got != 1 {
+		t.Fatalf("expected one key fallback recorded, got %d", got)
+	}
+
+	// Without a secondary key the rejection is returned as is.
+	keys = nil
+	plain := auth.NewAPIKey(auth.APIKeyOptions{Key: "old-key"})
+	resp, err = client.Get(context.Background(), "http://api.test/", httpc.WithRequestAuth(plain))
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if resp.StatusCode() != http.StatusForbidden || len(keys) != 1 {
+		t.Fatalf("expected the 403 without a resend: status=%d keys=%v", resp.StatusCode(), keys)
+	}
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	var fetches atomic.Int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := fetches.Add(1)
+		// Hold the first response so concurrent requests pile up on it.
+		time.Sleep(20 * time.Millisecond)
+		id, secret, _ := r.BasicAuth()
+		_ = r.ParseForm()
+		if id != "svc" || secret != "s3cret" || r.PostForm.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_client","error_description":"bad credentials"}`)
+			return
+		}
+		if got := r.PostForm.Get("scope"); got != "read write" {
+			t.Errorf("unexpected scope %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"tok-%d","token_type":"bearer","expires_in":3600}`, n)
+	}))
+	defer tokenServer.Close()
+
+	fake := clock.NewFake(time.Unix(0, 0))
+	provider, err := auth.NewOAuth2ClientCredentials(auth.OAuth2Options{
+		TokenURL:     tokenServer.URL,
+		ClientID:     "svc",
+		ClientSecret: "s3cret",
+		Scopes:       []string{"read", "write"},
+		Clock:        fake,
+	})
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			req, _ := http.NewRequest(http.MethodGet, "http://api.test/", nil)
+			if err := provider.Apply(req); err != nil {
+				t.Errorf("apply: %v", err)
+			}
+			if got := req.Header.Get("Authorization"); got != "Bearer tok-1" {
+				t.Errorf("unexpected authorization %q", got)
+			}
+		})
+	}
+	wg.Wait()
+	if got := fetches.Load(); got != 1 {
+		t.Fatalf("expected concurrent requests to share one token fetch, got %d", got)
+	}
+
+	fake.Advance(time.Hour)
+	req, _ := http.NewRequest(http.MethodGet, "http://api.test/", nil)
+	if err := provider.Apply(req); err != nil {
+		t.Fatalf("apply after expiry: %v", err)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer tok-2" {
+		t.Fatalf("expected a new token after expiry, got %q", got)
+	}
+
+	bad, _ := auth.NewOAuth2ClientCredentials(auth.OAuth2Options{TokenURL: tokenServer.URL, ClientID: "svc"})
+	var oauthErr *auth.OAuth2Error
+	if err := bad.Apply(req); !errors.As(err, &oauthErr) || oauthErr.Code != "invalid_client" {
+		t.Fatalf("expected an OAuth2Error, got %v", err)
+	}
+	if _, err := auth.NewOAuth2ClientCredentials(auth.OAuth2Options{}); !errors.Is(err, auth.ErrOAuth2Config) {
+		t.Fatalf("expected ErrOAuth2Config, got %v", err)
+	}
+}
+
+func TestOAuth2FromConfig(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if _, _, ok := r.BasicAuth(); ok || r.PostForm.Get("client_id") != "svc" || r.PostForm.Get("client_secret") != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"access_token":"from-config","expires_in":"60"}`)
+	}))
+	defer tokenServer.Close()
+
+	var cfg httpc.Config
+	cfg.OAuth2.TokenURL = tokenServer.URL
+	cfg.OAuth2.ClientID = "svc"
+	cfg.OAuth2.ClientSecret = "s3cret"
+	cfg.OAuth2.CredentialsIn = "body"
+	var got string
+	cfg.Transport = roundTripper(func(req *http.Request) (*http.Response, error) {
+		got = req.Header.Get("Authorization")
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+	client, err := httpc.New(httpc.WithConfig(cfg))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	if _, err := client.Get(context.Background(), "http://api.test/"); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got != "Bearer from-config" {
+		t.Fatalf("unexpected authorization %q", got)
+	}
+}
@@ -0,0 +1,61 @@
+package httpc_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gostratum/httpc"
+)
+
+type frameworkUserKey struct{}
+
+func TestBaggageForwarding(t *testing.T) {
+	var got http.Header
+	transport := roundTripper(func(req *http.Request) (*http.Response, error) {
+		got = req.Header.Clone()
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+
+	client, err := httpc.New(
+		httpc.WithTransport(transport),
+		httpc.WithBaggageForwarding("tenant-id", "X-Tenant-ID"),
+		httpc.WithBaggageForwarding("user-id", "X-User-ID"),
+		httpc.WithBaggageForwarding("flags", "X-Feature-Flags"),
+		httpc.WithBaggageLookup(func(ctx context.Context, key string) (string, bool) {
+			if key != "user-id" {
+				return "", false
+			}
+			v, ok := ctx.Value(frameworkUserKey{}).(string)
+			return v, ok
+		}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	ctx := httpc.WithBaggage(context.Background(), "tenant-id", "acme")
+	ctx = httpc.WithBaggage(ctx, "session-secret", "do-not-send")
+	ctx = httpc.WithBaggage(ctx, "flags", "evil\r\nX-Injected: 1")
+	ctx = context.WithValue(ctx, frameworkUserKey{}, "u-42")
+
+	if _, err := client.Get(ctx, "https://api.example.com/orders"); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Get("X-Tenant-ID") != "acme" || got.Get("X-User-ID") != "u-42" {
+		t.Fatalf("baggage not forwarded: %v", got)
+	}
+	for _, name := range []string{"Session-Secret", "X-Feature-Flags", "X-Injected"} {
+		if v := got.Get(name); v != "" {
+			t.Fatalf("unexpected header %s: %q", name, v)
+		}
+	}
+
+	// Headers set on the request win over baggage.
+	if _, err := client.Get(ctx, "https://api.example.com/orders", httpc.WithHeader("X-Tenant-ID", "explicit")); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Get("X-Tenant-ID") != "explicit" {
+		t.Fatalf("explicit header overridden: %q", got.Get("X-Tenant-ID"))
+	}
+}
@@ -0,0 +1,332 @@
+package httpc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/breaker"
+	"github.com/gostratum/httpc/clock"
+	"github.com/sony/gobreaker"
+)
+
+func TestSlidingWindowBreakerTripsOnFailureRate(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1000, 0))
+	var transitions []gobreaker.State
+	mgr := breaker.NewManager(breaker.Config{
+		Strategy:             breaker.StrategySlidingWindow,
+		WindowSize:           10 * time.Second,
+		MinimumCalls:         4,
+		FailureRateThreshold: 0.5,
+		HalfOpenMaxCalls:     1,
+		Timeout:              5 * time.Second,
+		Clock:                fake,
+		OnStateChange: func(_ string, _ gobreaker.State, to gobreaker.State) {
+			transitions = append(transitions, to)
+		},
+	})
+
+	ok := func() (*http.Response, error) { return &http.Response{StatusCode: http.StatusOK}, nil }
+	fail := func() (*http.Response, error) { return nil, errors.New("boom") }
+
+	for _, fn := range []func() (*http.Response, error){ok, ok, fail} {
+		_, _ = mgr.Do("api", fn)
+	}
+	if _, err := mgr.Do("api", ok); err != nil {
+		t.Fatalf("breaker should stay closed below the failure rate: %v", err)
+	}
+	_, _ = mgr.Do("api", fail)
+	_, _ = mgr.Do("api", fail)
+
+	if _, err := mgr.Do("api", ok); !errors.Is(err, gobreaker.ErrOpenState) {
+		t.Fatalf("expected open breaker, got %v", err)
+	}
+
+	fake.Advance(5 * time.Second)
+	if _, err := mgr.Do("api", ok); err != nil {
+		t.Fatalf("half-open probe should pass: %v", err)
+	}
+	if _, err := mgr.Do("api", ok); err != nil {
+		t.Fatalf("breaker should be closed after successful probe: %v", err)
+	}
+
+	want := []gobreaker.State{gobreaker.StateOpen, gobreaker.StateHalfOpen, gobreaker.StateClosed}
+	if len(transitions) != len(want) {
+		t.Fatalf("unexpected transitions %v", transitions)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Fatalf("unexpected transitions %v", transitions)
+		}
+	}
+}
+
+func TestConsecutiveBreakerUsesClock(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1000, 0))
+	var transitions []gobreaker.State
+	var mgr breaker.Manager
+	mgr = breaker.NewManager(breaker.Config{
+		Interval: 10 * time.Second,
+		Timeout:  5 * time.Second,
+		Clock:    fake,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= 2
+		},
+		OnStateChange: func(name string, _ gobreaker.State, to gobreaker.State) {
+			// Re-entering the breaker from the callback must not deadlock.
+			_ = mgr.(breaker.StateReporter).States()[name]
+			transitions = append(transitions, to)
+		},
+	})
+
+	ok := func() (*http.Response, error) { return &http.Response{StatusCode: http.StatusOK}, nil }
+	fail := func() (*http.Response, error) { return nil, errors.New("boom") }
+
+	_, _ = mgr.Do("api", fail)
+	fake.Advance(10 * time.Second)
+	_, _ = mgr.Do("api", fail)
+	if _, err := mgr.Do("api", ok); err != nil {
+		t.Fatalf("interval should have reset the failure count: %v", err)
+	}
+
+	_, _ = mgr.Do("api", fail)
+	_, _ = mgr.Do("api", fail)
+	if _, err := mgr.Do("api", ok); !errors.Is(err, gobreaker.ErrOpenState) {
+		t.Fatalf("expected open breaker, got %v", err)
+	}
+
+	fake.Advance(4 * time.Second)
+	if _, err := mgr.Do("api", ok); !errors.Is(err, gobreaker.ErrOpenState) {
+		t.Fatalf("breaker should stay open before the timeout, got %v", err)
+	}
+	fake.Advance(time.Second)
+	if got := mgr.(breaker.StateReporter).States()["api"]; got != gobreaker.StateHalfOpen {
+		t.Fatalf("expected half-open after the timeout, got %v", got)
+	}
+	if _, err := mgr.Do("api", ok); err != nil {
+		t.Fatalf("half-open probe should pass: %v", err)
+	}
+
+	want := []gobreaker.State{gobreaker.StateOpen, gobreaker.StateHalfOpen, gobreaker.StateClosed}
+	if len(transitions) != len(want) {
+		t.Fatalf("unexpected transitions %v", transitions)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Fatalf("unexpected transitions %v", transitions)
+		}
+	}
+}
+
+func TestSlidingWindowBreakerCallbackReentersBreaker(t *testing.T) {
+	var mgr breaker.Manager
+	var states []map[string]gobreaker.State
+	mgr = breaker.NewManager(breaker.Config{
+		Strategy:             breaker.StrategySlidingWindow,
+		WindowSize:           5 * time.Nanosecond,
+		MinimumCalls:         1,
+		FailureRateThreshold: 0.5,
+		Clock:                clock.NewFake(time.Unix(1000, 0)),
+		OnStateChange: func(string, gobreaker.State, gobreaker.State) {
+			// Reading the state from the callback must not deadlock.
+			states = append(states, mgr.(breaker.StateReporter).States())
+		},
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = mgr.Do("api", func() (*http.Response, error) { return nil, errors.New("boom") })
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnStateChange deadlocked on the breaker")
+	}
+	if len(states) != 1 || states[0]["api"] != gobreaker.StateOpen {
+		t.Fatalf("expected one open transition, got %v", states)
+	}
+}
+
+func TestSlidingWindowBreakerIgnoresStaleProbes(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1000, 0))
+	mgr := breaker.NewManager(breaker.Config{
+		Strategy:             breaker.StrategySlidingWindow,
+		WindowSize:           10 * time.Second,
+		MinimumCalls:         2,
+		FailureRateThreshold: 0.5,
+		HalfOpenMaxCalls:     2,
+		Timeout:              5 * time.Second,
+		Clock:                fake,
+	})
+	states := mgr.(breaker.StateReporter)
+
+	ok := func() (*http.Response, error) { return &http.Response{StatusCode: http.StatusOK}, nil }
+	fail := func() (*http.Response, error) { return nil, errors.New("boom") }
+	// blocked starts a probe and returns a func that finishes it successfully.
+	blocked := func() func() {
+		started, finish, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = mgr.Do("api", func() (*http.Response, error) {
+				close(started)
+				<-finish
+				return ok()
+			})
+		}()
+		<-started
+		return func() { close(finish); <-done }
+	}
+
+	_, _ = mgr.Do("api", fail)
+	_, _ = mgr.Do("api", fail)
+	fake.Advance(5 * time.Second)
+
+	stale := blocked()
+	_, _ = mgr.Do("api", fail) // reopens the breaker
+	fake.Advance(5 * time.Second)
+	current := blocked()
+	stale()
+
+	// The stale success must neither free a half-open slot nor count
+	// toward closing the breaker.
+	if _, err := mgr.Do("api", ok); err != nil {
+		t.Fatalf("second half-open probe should be admitted: %v", err)
+	}
+	if got := states.States()["api"]; got != gobreaker.StateHalfOpen {
+		t.Fatalf("stale probe counted toward closing, state %v", got)
+	}
+	if _, err := mgr.Do("api", ok); !errors.Is(err, gobreaker.ErrTooManyRequests) {
+		t.Fatalf("expected ErrTooManyRequests, got %v", err)
+	}
+	current()
+	if got := states.States()["api"]; got != gobreaker.StateClosed {
+		t.Fatalf("expected closed after both probes passed, got %v", got)
+	}
+}
+
+func TestSlidingWindowBreakerCountsPanicsAsFailures(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1000, 0))
+	mgr := breaker.NewManager(breaker.Config{
+		Strategy:             breaker.StrategySlidingWindow,
+		WindowSize:           10 * time.Second,
+		MinimumCalls:         1,
+		FailureRateThreshold: 0.5,
+		HalfOpenMaxCalls:     1,
+		Timeout:              5 * time.Second,
+		Clock:                fake,
+	})
+
+	_, _ = mgr.Do("api", func() (*http.Response, error) { return nil, errors.New("boom") })
+	fake.Advance(5 * time.Second)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected the probe panic to propagate")
+			}
+		}()
+		_, _ = mgr.Do("api", func() (*http.Response, error) { panic("probe") })
+	}()
+
+	if _, err := mgr.Do("api", func() (*http.Response, error) { return nil, nil }); !errors.Is(err, gobreaker.ErrOpenState) {
+		t.Fatalf("a panicking probe should reopen the breaker, got %v", err)
+	}
+	fake.Advance(5 * time.Second)
+	if _, err := mgr.Do("api", func() (*http.Response, error) { return &http.Response{StatusCode: http.StatusOK}, nil }); err != nil {
+		t.Fatalf("half-open probe should pass after the timeout: %v", err)
+	}
+}
+
+func TestSlidingWindowBreakerForgetsOldFailures(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1000, 0))
+	mgr := breaker.NewManager(breaker.Config{
+		Strategy:             breaker.StrategySlidingWindow,
+		WindowSize:           10 * time.Second,
+		MinimumCalls:         2,
+		FailureRateThreshold: 0.6,
+		Clock:                fake,
+	})
+
+	fail := func() (*http.Response, error) { return nil, errors.New("boom") }
+	ok := func() (*http.Response, error) { return &http.Response{StatusCode: http.StatusOK}, nil }
+
+	_, _ = mgr.Do("api", fail)
+	fake.Advance(11 * time.Second)
+	_, _ = mgr.Do("api", fail)
+	_, _ = mgr.Do("api", ok)
+
+	if _, err := mgr.Do("api", ok); err != nil {
+		t.Fatalf("expired failures must not count towards the rate: %v", err)
+	}
+}
+
+func TestSlidingWindowBreakerTripsOnSlowCalls(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1000, 0))
+	mgr := breaker.NewManager(breaker.Config{
+		Strategy:              breaker.StrategySlidingWindow,
+		MinimumCalls:          2,
+		SlowCallDuration:      time.Second,
+		SlowCallRateThreshold: 1,
+		Clock:                 fake,
+	})
+
+	slow := func() (*http.Response, error) {
+		fake.Advance(2 * time.Second)
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}
+
+	_, _ = mgr.Do("api", slow)
+	_, _ = mgr.Do("api", slow)
+
+	if _, err := mgr.Do("api", slow); !errors.Is(err, gobreaker.ErrOpenState) {
+		t.Fatalf("expected breaker opened by slow calls, got %v", err)
+	}
+}
+
+func TestBreakerKeyIsolatesRequests(t *testing.T) {
+	var keys []string
+	mgr := breaker.NewManager(breaker.Config{
+		ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 2 },
+		OnStateChange: func(name string, _ gobreaker.State, to gobreaker.State) {
+			if to == gobreaker.StateOpen {
+				keys = append(keys, name)
+			}
+		},
+	})
+	client, err := httpc.New(
+		httpc.WithBaseURL("https://api.example.com"),
+		httpc.WithRetry(false, 0),
+		httpc.WithBreaker(true),
+		httpc.WithBreakerManager(mgr),
+		httpc.WithTransport(roundTripper(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path == "/export" {
+				return nil, errors.New("boom")
+			}
+			return &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: http.NoBody}, nil
+		})),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	bulk := httpc.WithBreakerKey("bulk-export")
+	for range 2 {
+		_, _ = client.Get(context.Background(), "/export", bulk)
+	}
+	if _, err := client.Get(context.Background(), "/export", bulk); !errors.Is(err, gobreaker.ErrOpenState) {
+		t.Fatalf("expected the bulk-export breaker to be open, got %v", err)
+	}
+	if _, err := client.Get(context.Background(), "/pay", httpc.WithBreakerKey("payments-critical")); err != nil {
+		t.Fatalf("payments-critical should be isolated from bulk-export: %v", err)
+	}
+	if _, err := client.Get(context.Background(), "/pay"); err != nil {
+		t.Fatalf("host breaker should be isolated from bulk-export: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "bulk-export" {
+		t.Fatalf("unexpected tripped breakers %v", keys)
+	}
+}
@@ -0,0 +1,187 @@
+package httpc_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/bulkhead"
+)
+
+func TestBulkheadFailsFastWhenFull(t *testing.T) {
+	release := make(chan struct{})
+	entered := make(chan struct{}, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(
+		httpc.WithBaseURL(server.URL),
+		httpc.WithRetry(false, 0),
+		httpc.WithBulkhead(bulkhead.Config{MaxConcurrent: 1, MaxQueue: 1, QueueTimeout: 50 * time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	first := make(chan error, 1)
+	go func() {
+		resp, err := client.Get(context.Background(), "/")
+		if err == nil {
+			_, err = resp.Bytes()
+		}
+		first <- err
+	}()
+	<-entered
+
+	// The queue admits one waiter which times out; nothing else is let in.
+	if _, err := client.Get(context.Background(), "/"); !errors.Is(err, bulkhead.ErrBulkheadFull) {
+		t.Fatalf("expected ErrBulkheadFull, got %v", err)
+	}
+
+	close(release)
+	if err := <-first; err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+
+	resp, err := client.Get(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("slot should be released after body close: %v", err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode())
+	}
+}
+
+func TestBulkheadQueuedRequestProceeds(t *testing.T) {
+	release := make(chan struct{})
+	entered := make(chan struct{}, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(
+		httpc.WithBaseURL(server.URL),
+		httpc.WithRetry(false, 0),
+		httpc.WithBulkhead(bulkhead.Config{MaxConcurrent: 1, MaxQueue: 1}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	results := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			resp, err := client.Get(context.Background(), "/")
+			if err == nil {
+				_, err = resp.Bytes()
+			}
+			results <- err
+		}()
+	}
+
+	<-entered
+	close(release)
+	for i := 0; i < 2; i++ {
+		if err := <-results; err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+}
+
+func TestBulkheadIsolatesBreakerKeys(t *testing.T) {
+	release := make(chan struct{})
+	entered := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/export" {
+			entered <- struct{}{}
+			<-release
+		}
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(
+		httpc.WithBaseURL(server.URL),
+		httpc.WithRetry(false, 0),
+		httpc.WithBulkhead(bulkhead.Config{MaxConcurrent: 1}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	first := make(chan error, 1)
+	go func() {
+		resp, err := client.Get(context.Background(), "/export", httpc.WithBreakerKey("bulk"))
+		if err == nil {
+			_, err = resp.Bytes()
+		}
+		first <- err
+	}()
+	<-entered
+
+	if _, err := client.Get(context.Background(), "/pay", httpc.WithBreakerKey("payments")); err != nil {
+		t.Fatalf("another key on the same host must not be limited: %v", err)
+	}
+	if _, err := client.Get(context.Background(), "/export", httpc.WithBreakerKey("bulk")); !errors.Is(err, bulkhead.ErrBulkheadFull) {
+		t.Fatalf("expected ErrBulkheadFull for the busy key, got %v", err)
+	}
+	close(release)
+	if err := <-first; err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+}
+
+func TestBulkheadReleasesWithoutClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		_, _ = w.Write([]byte("payload"))
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(
+		httpc.WithBaseURL(server.URL),
+		httpc.WithRetry(false, 0),
+		httpc.WithBulkhead(bulkhead.Config{MaxConcurrent: 1}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Delete(context.Background(), "/item")
+		if err != nil {
+			t.Fatalf("delete %d: %v", i, err)
+		}
+		if resp.StatusCode() != http.StatusNoContent {
+			t.Fatalf("unexpected status %d", resp.StatusCode())
+		}
+	}
+
+	// A raw body read to EOF frees the slot before the caller closes it.
+	resp, err := client.Get(context.Background(), "/", httpc.WithRawBody())
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	body := resp.Raw().Body
+	defer body.Close()
+	if _, err := io.ReadAll(body); err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if _, err := client.Delete(context.Background(), "/item"); err != nil {
+		t.Fatalf("slot should be free after EOF: %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package httpc_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/auth"
+	"github.com/gostratum/httpc/cache"
+	"github.com/gostratum/httpc/clock"
+)
+
+func TestCacheDirectives(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := hits.Add(1)
+		_, _ = fmt.Fprintf(w, "%s-%d", r.URL.Path, n)
+	}))
+	defer server.Close()
+
+	fake := clock.NewFake(time.Unix(1000, 0))
+	client, err := httpc.New(
+		httpc.WithBaseURL(server.URL),
+		httpc.WithRetry(false, 0),
+		httpc.WithClock(fake),
+		httpc.WithCache(cache.Config{TTL: time.Minute}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	get := func(path string, opts ...httpc.ReqOption) (string, string) {
+		t.Helper()
+		resp, err := client.Get(context.Background(), path, opts...)
+		if err != nil {
+			t.Fatalf("get %s: %v", path, err)
+		}
+		body, err := resp.String()
+		if err != nil {
+			t.Fatalf("read %s: %v", path, err)
+		}
+		return body, resp.Header(cache.HeaderStatus)
+	}
+
+	if body, _ := get("/a"); body != "/a-1" {
+		t.Fatalf("unexpected first body %q", body)
+	}
+	if body, status := get("/a"); body != "/a-1" || status != "HIT" {
+		t.Fatalf("expected cached body, got %q (%q)", body, status)
+	}
+
+	if body, _ := get("/a", httpc.WithNoCache()); body != "/a-2" {
+		t.Fatalf("WithNoCache must reach the server, got %q", body)
+	}
+	if body, _ := get("/a"); body != "/a-1" {
+		t.Fatalf("WithNoCache must not overwrite the entry, got %q", body)
+	}
+
+	if body, _ := get("/a", httpc.WithCacheRefresh()); body != "/a-3" {
+		t.Fatalf("WithCacheRefresh must reach the server, got %q", body)
+	}
+	if body, _ := get("/a"); body != "/a-3" {
+		t.Fatalf("WithCacheRefresh must replace the entry, got %q", body)
+	}
+
+	if body, _ := get("/b", httpc.WithCacheKey("shared")); body != "/b-4" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	if body, _ := get("/c", httpc.WithCacheKey("shared")); body != "/b-4" {
+		t.Fatalf("WithCacheKey must share the entry, got %q", body)
+	}
+
+	if body, _ := get("/d", httpc.WithCacheTTL(5*time.Minute)); body != "/d-5" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	fake.Advance(2 * time.Minute)
+	if body, _ := get("/a"); body != "/a-6" {
+		t.Fatalf("default TTL entry should have expired, got %q", body)
+	}
+	if body, _ := get("/d"); body != "/d-5" {
+		t.Fatalf("WithCacheTTL entry should still be fresh, got %q", body)
+	}
+}
+
+func TestCacheHonoursNoStore(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Cache-Control", "no-store")
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(httpc.WithBaseURL(server.URL), httpc.WithCache(cache.Config{}))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get(context.Background(), "/"); err != nil {
+			t.Fatalf("get: %v", err)
+		}
+	}
+	if hits.Load() != 2 {
+		t.Fatalf("no-store responses must not be cached, server saw %d calls", hits.Load())
+	}
+}
+
+func TestCacheKeyedByPrincipal(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "profile of %s", r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(
+		httpc.WithBaseURL(server.URL),
+		httpc.WithRetry(false, 0),
+		httpc.WithCache(cache.Config{TTL: time.Minute}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	as := func(user string) httpc.ReqOption {
+		return httpc.WithRequestAuth(auth.ProviderFunc(func(req *http.Request) error {
+			req.Header.Set("Authorization", "Bearer "+user)
+			return nil
+		}))
+	}
+	get := func(opt httpc.ReqOption) (string, string) {
+		t.Helper()
+		resp, err := client.Get(context.Background(), "/me", opt)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		body, _ := resp.String()
+		return body, resp.Header(cache.HeaderStatus)
+	}
+
+	if body, _ := get(as("alice")); body != "profile of Bearer alice" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	if body, _ := get(as("bob")); body != "profile of Bearer bob" {
+		t.Fatalf("bob must not be served alice's cached profile, got %q", body)
+	}
+	if body, status := get(as("alice")); body != "profile of Bearer alice" || status != "HIT" {
+		t.Fatalf("expected alice's own cached entry, got %q (%q)", body, status)
+	}
+}
+
+func TestCacheVariesOnAllowedHeaders(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path == "/custom" {
+			w.Header().Set("Vary", "X-Tenant")
+		} else {
+			w.Header().Set("Vary", "Accept-Language")
+		}
+		_, _ = fmt.Fprintf(w, "greeting in %s", r.Header.Get("Accept-Language"))
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(httpc.WithBaseURL(server.URL), httpc.WithCache(cache.Config{TTL: time.Minute}))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	get := func(path, lang string) (string, string) {
+		t.Helper()
+		resp, err := client.Get(context.Background(), path, httpc.WithHeader("Accept-Language", lang))
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		body, _ := resp.String()
+		return body, resp.Header(cache.HeaderStatus)
+	}
+
+	for _, step := range []struct {
+		lang, status string
+	}{{"en", ""}, {"de", ""}, {"en", "HIT"}, {"de", "HIT"}} {
+		body, status := get("/hello", step.lang)
+		if body != "greeting in "+step.lang || status != step.status {
+			t.Fatalf("%s: got %q (%q), want status %q", step.lang, body, status, step.status)
+		}
+	}
+	if hits.Load() != 2 {
+		t.Fatalf("expected one upstream call per variant, got %d", hits.Load())
+	}
+
+	get("/custom", "en")
+	if _, status := get("/custom", "en"); status == "HIT" {
+		t.Fatal("responses varying on a header outside the allowlist must not be cached")
+	}
+}
@@ -0,0 +1,78 @@
+package httpc_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc"
+)
+
+func TestCancelDrainKeepsConnection(t *testing.T) {
+	run := func(t *testing.T, opts ...httpc.Option) int32 {
+		var conns atomic.Int32
+		release := make(chan struct{})
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/stream" {
+				_, _ = w.Write([]byte("ok"))
+				return
+			}
+			rest := strings.Repeat("x", 512<<10)
+			w.Header().Set("Content-Length", strconv.Itoa(5+len(rest)))
+			_, _ = w.Write([]byte("part1"))
+			w.(http.Flusher).Flush()
+			<-release
+			_, _ = w.Write([]byte(rest))
+		}))
+		srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				conns.Add(1)
+			}
+		}
+		srv.Start()
+		defer srv.Close()
+
+		client, err := httpc.New(append([]httpc.Option{httpc.WithBaseURL(srv.URL), httpc.WithRetry(false, 0)}, opts...)...)
+		if err != nil {
+			t.Fatalf("new client: %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		resp, err := client.Get(ctx, "/stream")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		cancel()
+		close(release)
+		if _, err := resp.String(); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled reading the body, got %v", err)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+		resp, err = client.Get(context.Background(), "/")
+		if err != nil {
+			t.Fatalf("get after cancel: %v", err)
+		}
+		if _, err := resp.String(); err != nil {
+			t.Fatalf("read after cancel: %v", err)
+		}
+		return conns.Load()
+	}
+
+	if got := run(t, httpc.WithCancelDrain(1<<20, time.Second)); got != 1 {
+		t.Fatalf("expected the drained connection to be reused, got %d connections", got)
+	}
+	if got := run(t, httpc.WithCancelDrain(16<<10, time.Second)); got != 2 {
+		t.Fatalf("expected a body over the drain limit to close the connection, got %d connections", got)
+	}
+	if got := run(t); got != 2 {
+		t.Fatalf("expected cancellation to close the connection by default, got %d connections", got)
+	}
+}
@@ -0,0 +1,82 @@
+package httpc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/clock"
+)
+
+func TestCapabilitiesCachedPerHost(t *testing.T) {
+	var probes int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions {
+			t.Errorf("unexpected method %s", r.Method)
+		}
+		atomic.AddInt32(&probes, 1)
+		w.Header().Set("Allow", "GET, POST")
+		w.Header().Set("Access-Control-Allow-Methods", "post, DELETE")
+		w.Header().Set("Accept-Patch", "application/merge-patch+json")
+		w.Header().Set("X-Max-Body-Size", "1048576")
+		w.Header().Set("Access-Control-Max-Age", "60")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	fake := clock.NewFake(time.Unix(0, 0))
+	client, err := httpc.New(httpc.WithBaseURL(server.URL), httpc.WithClock(fake), httpc.WithRetry(false, 0))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	caps, err := client.Capabilities(context.Background(), "")
+	if err != nil {
+		t.Fatalf("capabilities: %v", err)
+	}
+	if len(caps.Methods) != 3 || !caps.Allows("delete") || caps.Allows(http.MethodPatch) {
+		t.Fatalf("unexpected methods %v", caps.Methods)
+	}
+	if caps.MaxBodyBytes != 1<<20 || len(caps.AcceptPatch) != 1 {
+		t.Fatalf("unexpected hints %+v", caps)
+	}
+
+	if _, err := client.Capabilities(context.Background(), server.URL); err != nil {
+		t.Fatalf("cached capabilities: %v", err)
+	}
+	if got := atomic.LoadInt32(&probes); got != 1 {
+		t.Fatalf("expected a single OPTIONS probe, got %d", got)
+	}
+
+	fake.Advance(61 * time.Second)
+	if _, err := client.Capabilities(context.Background(), ""); err != nil {
+		t.Fatalf("expired capabilities: %v", err)
+	}
+	if got := atomic.LoadInt32(&probes); got != 2 {
+		t.Fatalf("expected re-probe after Access-Control-Max-Age, got %d", got)
+	}
+}
+
+func TestCapabilitiesErrorStatusUnderErrorOnStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(httpc.WithBaseURL(server.URL), httpc.WithRetry(false, 0), httpc.WithErrorOnStatus())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	caps, err := client.Capabilities(context.Background(), "")
+	if err != nil {
+		t.Fatalf("expected an empty capability set, got %v", err)
+	}
+	if len(caps.Methods) != 0 || caps.Host == "" {
+		t.Fatalf("unexpected capabilities %+v", caps)
+	}
+}
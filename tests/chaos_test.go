@@ -0,0 +1,121 @@
+package httpc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/chaos"
+)
+
+func TestChaosDisabledPassesThrough(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(
+		httpc.WithBaseURL(server.URL),
+		httpc.WithRetry(false, 0),
+		httpc.WithChaos(chaos.Config{ErrorProbability: 1}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	resp, err := client.Get(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if resp.StatusCode() != http.StatusOK || atomic.LoadInt32(&hits) != 1 {
+		t.Fatalf("expected untouched request, got status %d hits %d", resp.StatusCode(), hits)
+	}
+}
+
+func TestChaosInjectedErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var attempts int32
+	counter := func(next http.RoundTripper) http.RoundTripper {
+		return roundTripper(func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&attempts, 1)
+			return next.RoundTrip(req)
+		})
+	}
+
+	client, err := httpc.New(
+		httpc.WithBaseURL(server.URL),
+		httpc.WithRetry(true, 3),
+		httpc.WithRetryPolicy(noDelayPolicy{max: 3}),
+		httpc.WithTransport(counter(http.DefaultTransport)),
+		httpc.WithChaos(chaos.Config{Enabled: true, ErrorProbability: 1, ErrorStatus: http.StatusBadGateway}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	resp, err := client.Get(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if resp.StatusCode() != http.StatusBadGateway {
+		t.Fatalf("expected injected 502, got %d", resp.StatusCode())
+	}
+	if resp.Header("X-Chaos-Injected") != "true" {
+		t.Fatalf("expected chaos marker header")
+	}
+	if got := atomic.LoadInt32(&attempts); got != 0 {
+		t.Fatalf("expected upstream never reached, got %d calls", got)
+	}
+}
+
+func TestChaosConnectionReset(t *testing.T) {
+	mw := chaos.NewMiddleware(chaos.Config{Enabled: true, ResetProbability: 1, Seed: 1})
+	rt := mw(roundTripper(func(req *http.Request) (*http.Response, error) {
+		t.Fatalf("upstream should not be called")
+		return nil, nil
+	}))
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	_, err := rt.RoundTrip(req)
+	if err == nil {
+		t.Fatalf("expected reset error")
+	}
+	if !errors.Is(err, syscall.ECONNRESET) {
+		t.Fatalf("expected connection reset, got %v", err)
+	}
+}
+
+func TestChaosTruncatedBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"items":[1,2,3,4,5,6,7,8]}`))
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(
+		httpc.WithBaseURL(server.URL),
+		httpc.WithRetry(false, 0),
+		httpc.WithChaos(chaos.Config{Enabled: true, TruncateProbability: 1}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	resp, err := client.Get(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if _, err := resp.Bytes(); !errors.Is(err, chaos.ErrTruncatedBody) {
+		t.Fatalf("expected truncated body error, got %v", err)
+	}
+}
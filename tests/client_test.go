@@ -107,3 +107,41 @@ func TestClientMultipart(t *testing.T) {
 		t.Fatalf("multipart body missing field: %s", capture.body)
 	}
 }
+
+func TestClientBodyReaderAtReplayedOnRetry(t *testing.T) {
+	payload := bytes.Repeat([]byte("0123456789"), 1000)
+	var bodies [][]byte
+	var lengths []int64
+	client, err := httpc.New(
+		httpc.WithTransport(roundTripper(func(req *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(req.Body)
+			_ = req.Body.Close()
+			bodies = append(bodies, body)
+			lengths = append(lengths, req.ContentLength)
+			status := http.StatusOK
+			if len(bodies) == 1 {
+				status = http.StatusServiceUnavailable
+			}
+			return &http.Response{StatusCode: status, Header: make(http.Header), Body: http.NoBody}, nil
+		})),
+		httpc.WithRetry(true, 2),
+		httpc.WithRetryPolicy(noDelayPolicy{max: 2}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	resp, err := client.Put(context.Background(), "https://upload.example.com/blob", nil,
+		httpc.WithBodyReaderAt(bytes.NewReader(payload), int64(len(payload)), "application/octet-stream"))
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if resp.StatusCode() != http.StatusOK || len(bodies) != 2 {
+		t.Fatalf("status %d after %d attempts", resp.StatusCode(), len(bodies))
+	}
+	for i, body := range bodies {
+		if !bytes.Equal(body, payload) || lengths[i] != int64(len(payload)) {
+			t.Fatalf("attempt %d sent %d bytes with Content-Length %d", i+1, len(body), lengths[i])
+		}
+	}
+}
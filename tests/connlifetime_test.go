@@ -0,0 +1,68 @@
+package httpc_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc"
+)
+
+func TestMaxConnLifetime(t *testing.T) {
+	var conns atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(150 * time.Millisecond)
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	client, err := httpc.New(
+		httpc.WithBaseURL(srv.URL),
+		httpc.WithMaxConnLifetime(100*time.Millisecond),
+		httpc.WithRetry(false, 0),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	get := func(path string) {
+		t.Helper()
+		resp, err := client.Get(context.Background(), path)
+		if err != nil {
+			t.Fatalf("get %s: %v", path, err)
+		}
+		if _, err := resp.String(); err != nil {
+			t.Fatalf("read %s: %v", path, err)
+		}
+	}
+
+	get("/")
+	get("/")
+	if got := conns.Load(); got != 1 {
+		t.Fatalf("expected a reused connection, got %d", got)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	get("/")
+	if got := conns.Load(); got != 2 {
+		t.Fatalf("expected the expired connection to be recycled, got %d connections", got)
+	}
+
+	// A connection expiring mid-request finishes the request, then retires.
+	get("/slow")
+	get("/")
+	if got := conns.Load(); got != 3 {
+		t.Fatalf("expected a fresh connection after the busy one expired, got %d", got)
+	}
+}
@@ -0,0 +1,124 @@
+package httpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/contract"
+)
+
+func TestContractRecordsAndExportsPact(t *testing.T) {
+	pact := contract.New("billing", "users").
+		AddInteraction(contract.Interaction{
+			Description:   "fetch user 42",
+			ProviderState: "user 42 exists",
+			Request: contract.Request{
+				Method: http.MethodGet,
+				Path:   "/users/42",
+				Query:  url.Values{"expand": {"plan"}},
+			},
+			Response: contract.Response{
+				Status: http.StatusOK,
+				Body:   map[string]any{"id": 42, "plan": "pro"},
+			},
+		}).
+		AddInteraction(contract.Interaction{
+			Description: "create user",
+			Request: contract.Request{
+				Method:  http.MethodPost,
+				Path:    "/users",
+				Headers: map[string]string{"Content-Type": "application/json"},
+				Body:    map[string]string{"name": "demo"},
+			},
+			Response: contract.Response{Status: http.StatusCreated},
+		})
+
+	client, err := httpc.New(
+		httpc.WithBaseURL("https://users.internal"),
+		httpc.WithTransport(pact.Transport()),
+		httpc.WithRetry(false, 0),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	resp, err := client.Get(context.Background(), "/users/42", httpc.WithQuery("expand", "plan"))
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	var user struct {
+		Plan string `json:"plan"`
+	}
+	if err := resp.DecodeJSON(&user); err != nil || user.Plan != "pro" {
+		t.Fatalf("unexpected user %+v (%v)", user, err)
+	}
+
+	resp, err = client.Post(context.Background(), "/users", map[string]string{"name": "demo"})
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	if resp.StatusCode() != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode())
+	}
+
+	if err := pact.Verify(); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	path, err := pact.WriteFile(t.TempDir())
+	if err != nil {
+		t.Fatalf("write pact: %v", err)
+	}
+	if !strings.HasSuffix(path, "billing-users.json") {
+		t.Fatalf("unexpected pact file name %s", path)
+	}
+	data, _ := os.ReadFile(path)
+	var doc struct {
+		Interactions []struct {
+			ProviderState string `json:"providerState"`
+			Request       struct {
+				Query string `json:"query"`
+			} `json:"request"`
+		} `json:"interactions"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("decode pact: %v", err)
+	}
+	if len(doc.Interactions) != 2 || doc.Interactions[0].Request.Query != "expand=plan" || doc.Interactions[0].ProviderState != "user 42 exists" {
+		t.Fatalf("unexpected pact document: %s", data)
+	}
+}
+
+func TestContractReportsUnexpectedAndMissingInteractions(t *testing.T) {
+	pact := contract.New("billing", "users").AddInteraction(contract.Interaction{
+		Description: "delete user",
+		Request:     contract.Request{Method: http.MethodDelete, Path: "/users/1"},
+	})
+
+	client, err := httpc.New(httpc.WithTransport(pact.Transport()), httpc.WithRetry(false, 0))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	resp, err := client.Get(context.Background(), "https://users.internal/users/1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if resp.StatusCode() != http.StatusInternalServerError {
+		t.Fatalf("expected 500 for unmatched request, got %d", resp.StatusCode())
+	}
+
+	err = pact.Verify()
+	if err == nil {
+		t.Fatalf("expected verification failure")
+	}
+	if !strings.Contains(err.Error(), "unexpected request GET /users/1") || !strings.Contains(err.Error(), "never exercised") {
+		t.Fatalf("unexpected verification error: %v", err)
+	}
+}
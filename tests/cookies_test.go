@@ -0,0 +1,100 @@
+package httpc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gostratum/httpc"
+)
+
+func TestCookieJarKeepsSession(t *testing.T) {
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "s-1", Path: "/"})
+			http.SetCookie(w, &http.Cookie{Name: "csrf", Value: "t-1", Path: "/"})
+			return
+		}
+		var sid, csrf, locale string
+		if c, err := r.Cookie("sid"); err == nil {
+			sid = c.Value
+		}
+		if c, err := r.Cookie("csrf"); err == nil {
+			csrf = c.Value
+		}
+		if c, err := r.Cookie("locale"); err == nil {
+			locale = c.Value
+		}
+		seen = append(seen, sid+"|"+csrf+"|"+locale)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := httpc.New(httpc.WithBaseURL(server.URL), httpc.WithCookies(true))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	resp, err := client.Post(ctx, "/login", nil)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	cookies := resp.Cookies()
+	if len(cookies) != 2 || cookies[1].Name != "csrf" || cookies[1].Value != "t-1" {
+		t.Fatalf("unexpected response cookies %v", cookies)
+	}
+	if _, err := client.Get(ctx, "/me", httpc.WithCookie("locale", "de"), httpc.WithCookie("locale", "fr")); err != nil {
+		t.Fatalf("me: %v", err)
+	}
+
+	plain, err := httpc.New(httpc.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	if _, err := plain.Post(ctx, "/login", nil); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if _, err := plain.Get(ctx, "/me"); err != nil {
+		t.Fatalf("me: %v", err)
+	}
+
+	want := []string{"s-1|t-1|fr", "||"}
+	if len(seen) != len(want) || seen[0] != want[0] || seen[1] != want[1] {
+		t.Fatalf("cookies seen %v, want %v", seen, want)
+	}
+}
+
+type recordingJar struct {
+	http.CookieJar
+	saved []string
+}
+
+func (j *recordingJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	for _, c := range cookies {
+		j.saved = append(j.saved, c.Name)
+	}
+	j.CookieJar.SetCookies(u, cookies)
+}
+
+func TestCookieJarCustom(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "s-1"})
+	}))
+	defer server.Close()
+
+	inner, _ := cookiejar.New(nil)
+	jar := &recordingJar{CookieJar: inner}
+	client, err := httpc.New(httpc.WithBaseURL(server.URL), httpc.WithCookieJar(jar))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	if _, err := client.Get(context.Background(), "/"); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if len(jar.saved) != 1 || jar.saved[0] != "sid" {
+		t.Fatalf("expected the jar to receive sid, got %v", jar.saved)
+	}
+}
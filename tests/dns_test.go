@@ -0,0 +1,82 @@
+package httpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/clock"
+	"github.com/gostratum/httpc/dns"
+)
+
+func TestDoHResolver(t *testing.T) {
+	var queries atomic.Int32
+	doh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries.Add(1)
+		if r.Header.Get("Accept") != "application/dns-json" {
+			t.Errorf("unexpected accept header %q", r.Header.Get("Accept"))
+		}
+		resp := map[string]any{"Status": 0}
+		if r.URL.Query().Get("name") == "api.internal.test" && r.URL.Query().Get("type") == "A" {
+			resp["Answer"] = []map[string]any{{"type": 1, "TTL": 30, "data": "127.0.0.1"}}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer doh.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Host))
+	}))
+	defer upstream.Close()
+	u, _ := url.Parse(upstream.URL)
+
+	fake := clock.NewFake(time.Unix(0, 0))
+	resolver, err := dns.NewDoH(dns.DoHConfig{Endpoint: doh.URL, Clock: fake})
+	if err != nil {
+		t.Fatalf("new resolver: %v", err)
+	}
+	client, err := httpc.New(
+		httpc.WithBaseURL("http://api.internal.test:"+u.Port()),
+		httpc.WithResolver(resolver),
+		httpc.WithRetry(false, 0),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	resp, err := client.Get(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if body, _ := resp.String(); body != "api.internal.test:"+u.Port() {
+		t.Fatalf("expected original host header, got %q", body)
+	}
+	if got := queries.Load(); got != 2 {
+		t.Fatalf("expected A and AAAA queries, got %d", got)
+	}
+
+	if _, err := resolver.LookupHost(context.Background(), "api.internal.test"); err != nil {
+		t.Fatalf("cached lookup: %v", err)
+	}
+	if got := queries.Load(); got != 2 {
+		t.Fatalf("expected cached answer, got %d queries", got)
+	}
+
+	fake.Advance(31 * time.Second)
+	if _, err := resolver.LookupHost(context.Background(), "api.internal.test"); err != nil {
+		t.Fatalf("expired lookup: %v", err)
+	}
+	if got := queries.Load(); got != 4 {
+		t.Fatalf("expected requery after TTL, got %d queries", got)
+	}
+
+	if _, err := resolver.LookupHost(context.Background(), "missing.test"); err == nil {
+		t.Fatal("expected error for host without addresses")
+	}
+}
@@ -0,0 +1,64 @@
+package httpc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gostratum/httpc"
+)
+
+func TestGetIfChanged(t *testing.T) {
+	var version atomic.Value
+	version.Store("v1")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := version.Load().(string)
+		etag := `"` + current + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(current))
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(httpc.WithBaseURL(server.URL), httpc.WithRetry(false, 0))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	store := httpc.NewMemoryETagStore()
+
+	resp, err := client.GetIfChanged(context.Background(), "/feed", store)
+	if err != nil {
+		t.Fatalf("first get: %v", err)
+	}
+	if body, _ := resp.String(); resp.NotModified() || body != "v1" {
+		t.Fatalf("expected fresh v1 body, got %q", body)
+	}
+	if etag, _ := store.Get("/feed"); etag != `"v1"` {
+		t.Fatalf("store not updated, got %q", etag)
+	}
+
+	resp, err = client.GetIfChanged(context.Background(), "/feed", store)
+	if err != nil {
+		t.Fatalf("second get: %v", err)
+	}
+	if !resp.NotModified() {
+		t.Fatalf("expected not modified, got %d", resp.StatusCode())
+	}
+
+	version.Store("v2")
+	resp, err = client.GetIfChanged(context.Background(), "/feed", store)
+	if err != nil {
+		t.Fatalf("third get: %v", err)
+	}
+	if body, _ := resp.String(); resp.NotModified() || body != "v2" {
+		t.Fatalf("expected changed v2 body, got %q", body)
+	}
+	if etag, _ := store.Get("/feed"); etag != `"v2"` {
+		t.Fatalf("store not refreshed, got %q", etag)
+	}
+}
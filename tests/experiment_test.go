@@ -0,0 +1,80 @@
+package httpc_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/experiment"
+)
+
+func TestExperimentAssignment(t *testing.T) {
+	checkout := experiment.Experiment{
+		Name:     "checkout",
+		Variants: []experiment.Variant{{Name: "control", Weight: 1}, {Name: "new", Weight: 1}},
+	}
+	counts := map[string]int{}
+	for i := range 1000 {
+		key := fmt.Sprintf("user-%d", i)
+		variant := checkout.Assign(key)
+		if variant != checkout.Assign(key) {
+			t.Fatalf("assignment of %s is not deterministic", key)
+		}
+		counts[variant]++
+	}
+	if counts["control"] < 400 || counts["new"] < 400 {
+		t.Fatalf("expected a roughly even split, got %v", counts)
+	}
+	if got := (experiment.Experiment{Name: "off"}).Assign("user-1"); got != "" {
+		t.Fatalf("expected no variant without weights, got %q", got)
+	}
+}
+
+func TestExperimentMiddleware(t *testing.T) {
+	var headers []http.Header
+	transport := roundTripper(func(req *http.Request) (*http.Response, error) {
+		headers = append(headers, req.Header.Clone())
+		return &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: http.NoBody, Request: req}, nil
+	})
+	checkout := experiment.Experiment{
+		Name:     "checkout",
+		Variants: []experiment.Variant{{Name: "control", Weight: 1}, {Name: "new", Weight: 1}},
+	}
+	search := experiment.Experiment{
+		Name:     "search",
+		Header:   "X-Search-Variant",
+		Variants: []experiment.Variant{{Name: "b", Weight: 1}},
+	}
+	client, err := httpc.New(
+		httpc.WithTransport(transport),
+		httpc.WithMiddleware(experiment.NewMiddleware(experiment.Config{Experiments: []experiment.Experiment{checkout, search}})),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	ctx := experiment.WithKey(context.Background(), "user-42")
+	for _, opts := range [][]httpc.ReqOption{nil, {httpc.WithHeader("X-Search-Variant", "forced")}} {
+		if _, err := client.Get(ctx, "https://api.example.com/", opts...); err != nil {
+			t.Fatalf("get: %v", err)
+		}
+	}
+	if _, err := client.Get(context.Background(), "https://api.example.com/"); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+
+	if got := headers[0].Get("X-Experiment-checkout"); got != checkout.Assign("user-42") {
+		t.Fatalf("expected the assigned checkout variant, got %q", got)
+	}
+	if got := headers[0].Get("X-Search-Variant"); got != "b" {
+		t.Fatalf("expected the search variant, got %q", got)
+	}
+	if got := headers[1].Get("X-Search-Variant"); got != "forced" {
+		t.Fatalf("explicit header must win, got %q", got)
+	}
+	if got := headers[2].Get("X-Experiment-checkout"); got != "" {
+		t.Fatalf("requests without a key must not be assigned, got %q", got)
+	}
+}
@@ -0,0 +1,91 @@
+package httpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gostratum/httpc"
+)
+
+func TestExpvarPublishesClientInternals(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client, err := httpc.New(
+		httpc.WithBaseURL(server.URL),
+		httpc.WithBreaker(true),
+		httpc.WithExpvar("httpc.expvar_test"),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get(context.Background(), "/"); err != nil {
+			t.Fatalf("get: %v", err)
+		}
+	}
+
+	v := expvar.Get("httpc.expvar_test")
+	if v == nil {
+		t.Fatalf("expvar not published")
+	}
+	var doc struct {
+		InFlight int64             `json:"in_flight"`
+		Breakers map[string]string `json:"breakers"`
+		Pool     struct {
+			ConnsNew    int64 `json:"conns_new"`
+			ConnsReused int64 `json:"conns_reused"`
+		} `json:"pool"`
+		Hosts map[string]struct {
+			Requests int64 `json:"requests"`
+		} `json:"hosts"`
+	}
+	if err := json.Unmarshal([]byte(v.String()), &doc); err != nil {
+		t.Fatalf("decode expvar: %v", err)
+	}
+	host := server.Listener.Addr().String()
+	if doc.Hosts[host].Requests != 2 || doc.InFlight != 0 {
+		t.Fatalf("unexpected expvar document %s", v.String())
+	}
+	if doc.Breakers[host] != "closed" {
+		t.Fatalf("expected closed breaker for %s, got %s", host, v.String())
+	}
+	if doc.Pool.ConnsNew+doc.Pool.ConnsReused != 2 {
+		t.Fatalf("unexpected pool stats %s", v.String())
+	}
+
+	if _, err := httpc.New(httpc.WithExpvar("httpc.expvar_test")); err == nil {
+		t.Fatalf("expected duplicate expvar name to fail")
+	}
+}
+
+func TestExpvarConcurrentDuplicateFails(t *testing.T) {
+	const clients = 8
+	errs := make(chan error, clients)
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := httpc.New(httpc.WithExpvar("httpc.expvar_concurrent_test"))
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	published := 0
+	for err := range errs {
+		if err == nil {
+			published++
+		}
+	}
+	if published != 1 {
+		t.Fatalf("expected exactly one client to publish, got %d", published)
+	}
+}
@@ -0,0 +1,167 @@
+package httpc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/breaker"
+	httpcfx "github.com/gostratum/httpc/fx"
+	"github.com/gostratum/httpc/ratelimit"
+	"github.com/gostratum/httpc/retry"
+	"github.com/gostratum/httpc/tracing"
+	"github.com/sony/gobreaker"
+	"go.uber.org/fx"
+)
+
+func TestFxMiddlewareGroupAndRetryPolicy(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var stamped int32
+	var client httpc.Client
+	app := fx.New(
+		fx.NopLogger,
+		fx.Provide(
+			func() httpc.Config {
+				return httpc.Config{BaseURL: server.URL, RetryEnabled: true}
+			},
+			httpc.NewFx,
+			httpcfx.AsMiddleware(func() httpc.Middleware {
+				return func(next http.RoundTripper) http.RoundTripper {
+					return roundTripper(func(req *http.Request) (*http.Response, error) {
+						atomic.AddInt32(&stamped, 1)
+						req.Header.Set("X-Stamped", "yes")
+						return next.RoundTrip(req)
+					})
+				}
+			}),
+			httpcfx.AsRetryPolicy(func() retry.Policy { return noDelayPolicy{max: 2} }),
+		),
+		fx.Populate(&client),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatalf("fx app: %v", err)
+	}
+
+	resp, err := client.Get(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		t.Fatalf("expected container-provided policy to retry 500, got %d", resp.StatusCode())
+	}
+	if atomic.LoadInt32(&stamped) != 1 {
+		t.Fatalf("expected group middleware to run once around the retry chain, got %d", stamped)
+	}
+}
+
+func TestFxSharedResilience(t *testing.T) {
+	newClients := func(t *testing.T, baseURL string, shared fx.Option) (httpc.Client, httpc.Client) {
+		t.Helper()
+		var first, second httpc.Client
+		app := fx.New(
+			fx.NopLogger,
+			shared,
+			fx.Provide(func() httpc.Config {
+				return httpc.Config{BaseURL: baseURL, BreakerEnabled: true}
+			}),
+			fx.Invoke(func(params httpc.FxParams) error {
+				var err error
+				if first, err = httpc.NewFx(params); err != nil {
+					return err
+				}
+				second, err = httpc.NewFx(params)
+				return err
+			}),
+		)
+		if err := app.Err(); err != nil {
+			t.Fatalf("fx app: %v", err)
+		}
+		return first, second
+	}
+
+	t.Run("breaker", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+		server.Close()
+
+		first, second := newClients(t, server.URL, httpcfx.SharedResilience(breaker.Config{
+			ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+		}, nil))
+
+		if _, err := first.Get(context.Background(), "/"); err == nil {
+			t.Fatal("expected dial error against closed server")
+		}
+		if _, err := second.Get(context.Background(), "/"); !errors.Is(err, gobreaker.ErrOpenState) {
+			t.Fatalf("expected breaker opened by the other client, got %v", err)
+		}
+	})
+
+	t.Run("rate limiter", func(t *testing.T) {
+		var hits int32
+		server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			atomic.AddInt32(&hits, 1)
+		}))
+		defer server.Close()
+
+		first, second := newClients(t, server.URL, httpcfx.SharedResilience(breaker.Config{}, &ratelimit.Config{
+			Rate:    0.001,
+			Burst:   1,
+			MaxWait: time.Millisecond,
+		}))
+
+		if _, err := first.Get(context.Background(), "/"); err != nil {
+			t.Fatalf("first get: %v", err)
+		}
+		if _, err := second.Get(context.Background(), "/"); !errors.Is(err, ratelimit.ErrRateLimited) {
+			t.Fatalf("expected shared budget to be spent, got %v", err)
+		}
+		if got := atomic.LoadInt32(&hits); got != 1 {
+			t.Fatalf("expected one request upstream, got %d", got)
+		}
+	})
+}
+
+func TestFxTracer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := tracing.Parse(r.Header.Get(tracing.Header)); err != nil {
+			t.Errorf("expected a traceparent from the provided tracer, got %v", err)
+		}
+	}))
+	defer server.Close()
+
+	tracer := &recordingTracer{}
+	var client httpc.Client
+	app := fx.New(
+		fx.NopLogger,
+		fx.Provide(
+			func() httpc.Config { return httpc.Config{BaseURL: server.URL} },
+			httpc.NewFx,
+			httpcfx.AsTracer(func() tracing.Tracer { return tracer }),
+		),
+		fx.Populate(&client),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatalf("fx app: %v", err)
+	}
+
+	ctx := tracing.WithSpanContext(context.Background(), tracing.SpanContext{TraceID: [16]byte{1}, SpanID: [8]byte{2}})
+	if _, err := client.Get(ctx, "/"); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if len(tracer.spans) != 1 || tracer.spans[0].result == nil || tracer.spans[0].result.StatusCode != http.StatusOK {
+		t.Fatalf("expected one finished span, got %d", len(tracer.spans))
+	}
+}
@@ -0,0 +1,96 @@
+package httpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc"
+)
+
+func TestGroupFanOut(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		id := strings.TrimPrefix(r.URL.Path, "/widgets/")
+		_ = json.NewEncoder(w).Encode(widget{Name: "w" + id})
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(httpc.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	g := httpc.Group(context.Background(), client)
+	g.SetLimit(2)
+	got := make([]widget, 5)
+	for i := range got {
+		g.Get("/widgets/"+strconv.Itoa(i), &got[i])
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	for i, w := range got {
+		if w.Name != "w"+strconv.Itoa(i) {
+			t.Fatalf("widget %d decoded as %+v", i, w)
+		}
+	}
+	if p := peak.Load(); p > 2 {
+		t.Fatalf("expected at most 2 calls in flight, saw %d", p)
+	}
+}
+
+func TestGroupCancelsOnFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/broken":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		case "/slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		}
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(httpc.WithBaseURL(server.URL), httpc.WithRetry(false, 0))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	start := time.Now()
+	g := httpc.Group(context.Background(), client)
+	g.Get("/slow", nil)
+	g.Get("/broken", nil)
+	g.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	err = g.Wait()
+	if time.Since(start) > 4*time.Second {
+		t.Fatal("expected the failure to cancel the slow call")
+	}
+
+	var httpErr *httpc.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected the 500 as *HTTPError, got %v", err)
+	}
+	if errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled calls must not be reported, got %v", err)
+	}
+}
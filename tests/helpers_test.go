@@ -0,0 +1,27 @@
+package httpc_test
+
+import (
+	"net/http"
+	"time"
+)
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// noDelayPolicy retries any 5xx response or transport error immediately.
+type noDelayPolicy struct {
+	max int
+}
+
+func (p noDelayPolicy) ShouldRetry(req *http.Request, resp *http.Response, err error, attempt int, force bool) (time.Duration, bool) {
+	if attempt >= p.max {
+		return 0, false
+	}
+	if err != nil {
+		return 0, true
+	}
+	return 0, resp != nil && resp.StatusCode >= 500
+}
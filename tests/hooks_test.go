@@ -0,0 +1,85 @@
+package httpc_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/clock"
+)
+
+func TestAttemptHooks(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	var stamps []string
+	transport := roundTripper(func(req *http.Request) (*http.Response, error) {
+		stamps = append(stamps, req.Header.Get("X-Audit"))
+		fake.Advance(time.Second)
+		switch len(stamps) {
+		case 1:
+			return nil, errors.New("connection reset")
+		case 2:
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Header: make(http.Header), Body: http.NoBody, Request: req}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: http.NoBody, Request: req}, nil
+	})
+
+	var events []string
+	client, err := httpc.New(
+		httpc.WithTransport(transport),
+		httpc.WithClock(fake),
+		httpc.WithRetry(true, 3),
+		httpc.WithRetryPolicy(noDelayPolicy{max: 3}),
+		httpc.WithRequestHook(func(info *httpc.AttemptInfo) error {
+			info.Request.Header.Set("X-Audit", fmt.Sprintf("attempt-%d", info.Attempt))
+			return nil
+		}),
+		httpc.WithResponseHook(func(info *httpc.AttemptInfo) {
+			events = append(events, fmt.Sprintf("response %d %d %s", info.Attempt, info.Response.StatusCode, info.Duration))
+		}),
+		httpc.WithErrorHook(func(info *httpc.AttemptInfo) {
+			events = append(events, fmt.Sprintf("error %d %v %s", info.Attempt, info.Err != nil, info.Duration))
+		}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	resp, err := client.Get(context.Background(), "https://api.example.com/report")
+	if err != nil || resp.StatusCode() != http.StatusOK {
+		t.Fatalf("get: %v", err)
+	}
+	if fmt.Sprint(stamps) != "[attempt-1 attempt-2 attempt-3]" {
+		t.Fatalf("unexpected stamped headers %q", stamps)
+	}
+	want := "[error 1 true 1s response 2 503 1s response 3 200 1s]"
+	if fmt.Sprint(events) != want {
+		t.Fatalf("hooks saw %v, want %s", events, want)
+	}
+}
+
+func TestRequestHookAbortsAttempt(t *testing.T) {
+	var sent int
+	transport := roundTripper(func(req *http.Request) (*http.Response, error) {
+		sent++
+		return &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: http.NoBody, Request: req}, nil
+	})
+	denied := errors.New("audit log unavailable")
+	client, err := httpc.New(
+		httpc.WithTransport(transport),
+		httpc.WithRetry(false, 0),
+		httpc.WithRequestHook(func(*httpc.AttemptInfo) error { return denied }),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	if _, err := client.Get(context.Background(), "https://api.example.com/"); !errors.Is(err, denied) {
+		t.Fatalf("expected the hook error, got %v", err)
+	}
+	if sent != 0 {
+		t.Fatalf("expected no request to be sent, got %d", sent)
+	}
+}
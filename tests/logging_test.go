@@ -0,0 +1,109 @@
+package httpc_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gostratum/core/logx"
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/logging"
+)
+
+func TestLogLevelSelection(t *testing.T) {
+	var got string
+	pick := func(lvl logging.Level) {
+		got = ""
+		emit := logging.Select(lvl,
+			func(string) { got = "debug" },
+			func(string) { got = "info" },
+			func(string) { got = "warn" },
+			func(string) { got = "error" },
+		)
+		if emit != nil {
+			emit("msg")
+		}
+	}
+
+	ctx := logging.WithLevel(context.Background(), logging.LevelInfo)
+	pick(logging.LevelFromContext(ctx).Or(logging.LevelDebug))
+	if got != "info" {
+		t.Fatalf("expected per-request info level, got %q", got)
+	}
+
+	pick(logging.LevelFromContext(context.Background()).Or(logging.LevelDebug))
+	if got != "debug" {
+		t.Fatalf("expected middleware default, got %q", got)
+	}
+
+	pick(logging.LevelOff)
+	if got != "" {
+		t.Fatalf("LevelOff must not emit, got %q", got)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for in, want := range map[string]logging.Level{
+		"":      logging.LevelDefault,
+		"DEBUG": logging.LevelDebug,
+		"warn":  logging.LevelWarn,
+		"off":   logging.LevelOff,
+	} {
+		got, err := logging.ParseLevel(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseLevel(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := logging.ParseLevel("loud"); err == nil {
+		t.Fatalf("expected error for unknown level")
+	}
+}
+
+func TestLoggingBodiesPassThrough(t *testing.T) {
+	payload := `{"user":"ann","password":"hunter2","note":"` + strings.Repeat("x", 64) + `"}`
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != payload {
+			t.Errorf("attempt %d: server got %q", hits, body)
+		}
+		if hits == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(
+		httpc.WithBaseURL(server.URL),
+		httpc.WithLogger(logx.NewNoopLogger()),
+		httpc.WithLogging(true),
+		httpc.WithLogHeaders(true),
+		httpc.WithLogBodies(16),
+		httpc.WithRetry(true, 2),
+		httpc.WithRetryPolicy(noDelayPolicy{max: 2}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	resp, err := client.Do(context.Background(), httpc.NewRequest(http.MethodPut, "/echo",
+		httpc.WithRaw([]byte(payload), "application/json"),
+		httpc.WithHeader("Authorization", "Bearer secret"),
+	))
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	got, err := resp.String()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if got != payload || hits != 2 {
+		t.Fatalf("expected the echoed body after 2 attempts, got %q after %d", got, hits)
+	}
+}
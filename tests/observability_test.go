@@ -0,0 +1,268 @@
+package httpc_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"expvar"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/metrics"
+	"github.com/gostratum/httpc/tracing"
+)
+
+func TestConfigTogglesBuiltinMiddlewares(t *testing.T) {
+	var calls int
+	var traceparents []string
+	transport := roundTripper(func(req *http.Request) (*http.Response, error) {
+		calls++
+		traceparents = append(traceparents, req.Header.Get(tracing.Header))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Cache-Control": {"max-age=60"}},
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	})
+
+	cfg := httpc.Config{
+		BaseURL:        "https://api.example.com",
+		ExpvarName:     "httpc.observability_test",
+		LoggingEnabled: true,
+		MetricsEnabled: true,
+		TracingEnabled: true,
+		CacheEnabled:   true,
+		Transport:      transport,
+	}
+	client, err := httpc.New(httpc.WithConfig(cfg))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	parent := tracing.SpanContext{TraceID: [16]byte{1}, SpanID: [8]byte{2}, Sampled: true}
+	ctx := tracing.WithSpanContext(context.Background(), parent)
+	for range 2 {
+		resp, err := client.Get(ctx, "/items")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		_, _ = resp.String()
+	}
+
+	if calls != 1 {
+		t.Fatalf("expected the second GET to be served from cache, got %d transport calls", calls)
+	}
+	sc, err := tracing.Parse(traceparents[0])
+	if err != nil {
+		t.Fatalf("parse traceparent %q: %v", traceparents[0], err)
+	}
+	if sc.TraceID != parent.TraceID || sc.SpanID == parent.SpanID || !sc.Sampled {
+		t.Fatalf("traceparent %q is not a child of %s", traceparents[0], parent)
+	}
+
+	var doc struct {
+		Metrics []struct {
+			Host     string `json:"host"`
+			Requests int64  `json:"requests"`
+			Duration struct {
+				Count int64 `json:"count"`
+			} `json:"duration_seconds"`
+		} `json:"metrics"`
+	}
+	raw := expvar.Get("httpc.observability_test").String()
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		t.Fatalf("decode expvar: %v", err)
+	}
+	if len(doc.Metrics) != 1 || doc.Metrics[0].Host != "api.example.com" || doc.Metrics[0].Requests != 2 || doc.Metrics[0].Duration.Count != 2 {
+		t.Fatalf("unexpected metrics %s", raw)
+	}
+}
+
+func TestMetricsRecordBodySizes(t *testing.T) {
+	payload := strings.Repeat("a", 10000)
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	_, _ = zw.Write([]byte(payload))
+	_ = zw.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Length", strconv.Itoa(compressed.Len()))
+		_, _ = w.Write(compressed.Bytes())
+	}))
+	defer server.Close()
+
+	reg := metrics.NewRegistry()
+	client, err := httpc.New(httpc.WithBaseURL(server.URL), httpc.WithMetrics(reg))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	resp, err := client.Post(context.Background(), "/upload", httpc.WithBodyStream(func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("hello world")), nil
+	}))
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	if body, _ := resp.String(); body != payload {
+		t.Fatalf("unexpected body of %d bytes", len(body))
+	}
+
+	series := reg.Snapshot()
+	if len(series) != 1 {
+		t.Fatalf("expected one series, got %+v", series)
+	}
+	s := series[0]
+	if s.RequestSize.Count != 1 || s.RequestSize.Sum != 11 {
+		t.Fatalf("unexpected request size %+v", s.RequestSize)
+	}
+	if s.ResponseSize.Count != 1 || s.ResponseSize.Sum != float64(len(payload)) {
+		t.Fatalf("unexpected response size %+v", s.ResponseSize)
+	}
+	if s.ResponseWireBytes != int64(compressed.Len()) {
+		t.Fatalf("expected %d wire bytes, got %d", compressed.Len(), s.ResponseWireBytes)
+	}
+}
+
+// inFlightRecorder counts requests between Begin and End.
+type inFlightRecorder struct {
+	mu       sync.Mutex
+	inFlight int
+	observed int
+}
+
+func (r *inFlightRecorder) Begin(string, string) { r.mu.Lock(); r.inFlight++; r.mu.Unlock() }
+func (r *inFlightRecorder) End(string, string)   { r.mu.Lock(); r.inFlight--; r.mu.Unlock() }
+func (r *inFlightRecorder) Observe(metrics.Observation) {
+	r.mu.Lock()
+	r.observed++
+	r.mu.Unlock()
+}
+
+func TestInFlightEndsWithRoundTrip(t *testing.T) {
+	transport := roundTripper(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: io.NopCloser(strings.NewReader("payload")), Request: req}, nil
+	})
+	rec := &inFlightRecorder{}
+	client, err := httpc.New(httpc.WithTransport(transport), httpc.WithMetrics(rec))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	resp, err := client.Get(context.Background(), "https://api.example.com/export", httpc.WithStreamResponse())
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	rec.mu.Lock()
+	inFlight, observed := rec.inFlight, rec.observed
+	rec.mu.Unlock()
+	if inFlight != 0 || observed != 0 {
+		t.Fatalf("expected the request to leave flight before its body is read, in flight %d, observed %d", inFlight, observed)
+	}
+	body := resp.Body()
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	body.Close()
+	if rec.observed != 1 {
+		t.Fatalf("expected one observation once the body was read, got %d", rec.observed)
+	}
+}
+
+func TestTraceParentParse(t *testing.T) {
+	valid := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := tracing.Parse(valid)
+	if err != nil || sc.String() != valid {
+		t.Fatalf("Parse(%q) = %v, %v", valid, sc, err)
+	}
+	for _, bad := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		strings.Replace(valid, "4bf9", "zzzz", 1),
+	} {
+		if _, err := tracing.Parse(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+type recordingTracer struct {
+	spans []*recordingSpan
+}
+
+type recordingSpan struct {
+	parent tracing.SpanContext
+	sc     tracing.SpanContext
+	result *tracing.Result
+}
+
+func (t *recordingTracer) Start(ctx context.Context, req *http.Request) (context.Context, tracing.Span) {
+	span := &recordingSpan{}
+	span.parent, _ = tracing.SpanContextFromContext(ctx)
+	span.sc = tracing.SpanContext{TraceID: span.parent.TraceID, Sampled: true}
+	span.sc.SpanID[7] = byte(len(t.spans) + 1)
+	t.spans = append(t.spans, span)
+	return tracing.WithSpanContext(ctx, span.sc), span
+}
+
+func (s *recordingSpan) SpanContext() tracing.SpanContext { return s.sc }
+func (s *recordingSpan) End(r tracing.Result)             { s.result = &r }
+
+func TestTracerSpansPerAttempt(t *testing.T) {
+	var traceparents []string
+	transport := roundTripper(func(req *http.Request) (*http.Response, error) {
+		traceparents = append(traceparents, req.Header.Get(tracing.Header))
+		status := http.StatusOK
+		if len(traceparents) == 1 {
+			status = http.StatusServiceUnavailable
+		}
+		return &http.Response{StatusCode: status, Header: make(http.Header), Body: http.NoBody, Request: req}, nil
+	})
+
+	tracer := &recordingTracer{}
+	client, err := httpc.New(
+		httpc.WithTransport(transport),
+		httpc.WithTracer(tracer),
+		httpc.WithRetry(true, 2),
+		httpc.WithRetryPolicy(noDelayPolicy{max: 2}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	parent := tracing.SpanContext{TraceID: [16]byte{1}, SpanID: [8]byte{2}, Sampled: true}
+	ctx := tracing.WithSpanContext(context.Background(), parent)
+	resp, err := client.Get(ctx, "https://api.example.com/items", httpc.WithHeader(tracing.Header, "stale"))
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		t.Fatalf("expected the retry to succeed, got %d", resp.StatusCode())
+	}
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("expected a span per attempt, got %d", len(tracer.spans))
+	}
+	for i, span := range tracer.spans {
+		if span.parent != parent {
+			t.Fatalf("span %d: parent %s, want %s", i, span.parent, parent)
+		}
+		if traceparents[i] != span.sc.String() {
+			t.Fatalf("attempt %d sent traceparent %q, want %q", i+1, traceparents[i], span.sc)
+		}
+		want := []int{http.StatusServiceUnavailable, http.StatusOK}[i]
+		if span.result == nil || span.result.Attempt != i+1 || span.result.StatusCode != want || span.result.Err != nil {
+			t.Fatalf("span %d ended with %+v", i, span.result)
+		}
+	}
+}
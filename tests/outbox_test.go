@@ -0,0 +1,221 @@
+package httpc_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/clock"
+	"github.com/gostratum/httpc/outbox"
+)
+
+func TestOutboxRetriesUntilDelivered(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"order":1}` {
+			t.Errorf("unexpected body %q", body)
+		}
+		keys = append(keys, r.Header.Get(outbox.DefaultIdempotencyHeader))
+		switch {
+		case r.URL.Path == "/rejected":
+			w.WriteHeader(http.StatusBadRequest)
+		case len(keys) < 3:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(httpc.WithBaseURL(server.URL), httpc.WithRetry(false, 0))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	fake := clock.NewFake(time.Unix(0, 0))
+	journal := outbox.NewMemoryJournal()
+	var events []outbox.Event
+	deliverer, err := outbox.New(client, outbox.Config{
+		Journal:     journal,
+		BaseBackoff: time.Second,
+		MaxBackoff:  time.Minute,
+		OnStatus:    func(ev outbox.Event) { events = append(events, ev) },
+		Clock:       fake,
+	})
+	if err != nil {
+		t.Fatalf("new deliverer: %v", err)
+	}
+
+	ctx := context.Background()
+	id, err := deliverer.Enqueue(ctx, httpc.NewRequest(http.MethodPost, "/orders", httpc.WithRaw([]byte(`{"order":1}`), "application/json")))
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	for range 3 {
+		if err := deliverer.Flush(ctx); err != nil {
+			t.Fatalf("flush: %v", err)
+		}
+		// Not due yet: the backoff holds the entry back.
+		if err := deliverer.Flush(ctx); err != nil {
+			t.Fatalf("flush: %v", err)
+		}
+		fake.Advance(time.Minute)
+	}
+
+	if len(keys) != 3 || keys[0] != id || keys[1] != id || keys[2] != id {
+		t.Fatalf("expected 3 attempts keyed by the entry ID %s, got %v", id, keys)
+	}
+	want := []outbox.Status{outbox.StatusRetrying, outbox.StatusRetrying, outbox.StatusDelivered}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), events)
+	}
+	for i, ev := range events {
+		if ev.Status != want[i] || ev.Attempt != i+1 || ev.ID != id {
+			t.Fatalf("event %d: %+v", i, ev)
+		}
+	}
+	if pending, _ := journal.Pending(ctx); len(pending) != 0 {
+		t.Fatalf("expected the delivered entry to be removed, got %d", len(pending))
+	}
+
+	events = nil
+	if _, err := deliverer.Enqueue(ctx, httpc.NewRequest(http.MethodPost, "/rejected", httpc.WithRaw([]byte(`{"order":1}`), "application/json"))); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if err := deliverer.Flush(ctx); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if len(events) != 1 || events[0].Status != outbox.StatusFailed || events[0].StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a permanent failure on 400, got %+v", events)
+	}
+	if pending, _ := journal.Pending(ctx); len(pending) != 0 {
+		t.Fatalf("expected the failed entry to be removed, got %d", len(pending))
+	}
+}
+
+func TestOutboxWorkerDeliversInBackground(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client, err := httpc.New(httpc.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	delivered := make(chan string, 1)
+	deliverer, err := outbox.New(client, outbox.Config{
+		PollInterval: time.Hour,
+		OnStatus: func(ev outbox.Event) {
+			if ev.Status == outbox.StatusDelivered {
+				delivered <- ev.ID
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("new deliverer: %v", err)
+	}
+	if err := deliverer.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer deliverer.Stop(context.Background())
+
+	id, err := deliverer.Enqueue(context.Background(), httpc.NewRequest(http.MethodPut, "/items/1", httpc.WithRaw([]byte("x"), "text/plain")))
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	select {
+	case got := <-delivered:
+		if got != id {
+			t.Fatalf("delivered %s, want %s", got, id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("entry was not delivered after Enqueue woke the worker")
+	}
+}
+
+func TestOutboxRetriesAttemptTimeouts(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(httpc.WithBaseURL(server.URL), httpc.WithRetry(false, 0), httpc.WithTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	fake := clock.NewFake(time.Unix(0, 0))
+	journal := outbox.NewMemoryJournal()
+	var events []outbox.Event
+	deliverer, err := outbox.New(client, outbox.Config{
+		Journal:  journal,
+		OnStatus: func(ev outbox.Event) { events = append(events, ev) },
+		Clock:    fake,
+	})
+	if err != nil {
+		t.Fatalf("new deliverer: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := deliverer.Enqueue(ctx, httpc.NewRequest(http.MethodPost, "/slow", httpc.WithRaw([]byte("x"), "text/plain"))); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if err := deliverer.Flush(ctx); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if len(events) != 1 || events[0].Status != outbox.StatusRetrying {
+		t.Fatalf("expected the timed out attempt to be retried, got %+v", events)
+	}
+	if pending, _ := journal.Pending(ctx); len(pending) != 1 {
+		t.Fatalf("expected the entry to stay pending, got %d", len(pending))
+	}
+	fake.Advance(time.Hour)
+	if err := deliverer.Flush(ctx); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if len(events) != 2 || events[1].Status != outbox.StatusDelivered {
+		t.Fatalf("expected delivery on the second attempt, got %+v", events)
+	}
+}
+
+func TestOutboxRejectsWithErrorOnStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(httpc.WithBaseURL(server.URL), httpc.WithRetry(false, 0), httpc.WithErrorOnStatus())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	journal := outbox.NewMemoryJournal()
+	var events []outbox.Event
+	deliverer, err := outbox.New(client, outbox.Config{
+		Journal:  journal,
+		OnStatus: func(ev outbox.Event) { events = append(events, ev) },
+	})
+	if err != nil {
+		t.Fatalf("new deliverer: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := deliverer.Enqueue(ctx, httpc.NewRequest(http.MethodPost, "/orders", httpc.WithRaw([]byte("x"), "text/plain"))); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if err := deliverer.Flush(ctx); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if len(events) != 1 || events[0].Status != outbox.StatusFailed || events[0].StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a permanent failure on 400, got %+v", events)
+	}
+	if pending, _ := journal.Pending(ctx); len(pending) != 0 {
+		t.Fatalf("expected the failed entry to be removed, got %d", len(pending))
+	}
+}
@@ -0,0 +1,115 @@
+package httpc_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/clock"
+)
+
+func TestPagesFollowsLinkHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" {
+			t.Errorf("page %s lost the X-Token header", r.URL.RawQuery)
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 3 {
+			w.Header().Set("Link", fmt.Sprintf(`</items?page=%d>; rel="next", </items?page=3>; rel="last"`, page+1))
+		}
+		fmt.Fprintf(w, "page %d", page)
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(httpc.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	req := httpc.NewRequest(http.MethodGet, "/items",
+		httpc.WithQuery("page", "1"),
+		httpc.WithHeader("X-Token", "secret"),
+	)
+	var got []string
+	for resp, err := range httpc.Pages(context.Background(), client, req, httpc.NextLink) {
+		if err != nil {
+			t.Fatalf("page %d: %v", len(got)+1, err)
+		}
+		body, err := resp.String()
+		if err != nil {
+			t.Fatalf("read page: %v", err)
+		}
+		got = append(got, body)
+	}
+	if fmt.Sprint(got) != "[page 1 page 2 page 3]" {
+		t.Fatalf("unexpected pages %q", got)
+	}
+}
+
+func TestPagesAdaptivePacing(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch hits.Add(1) {
+		case 1:
+			// Two requests left for the next ten seconds: one every five.
+			w.Header().Set("X-RateLimit-Remaining", "2")
+			w.Header().Set("X-RateLimit-Reset", "10")
+			w.Header().Set("Link", `</items?page=2>; rel="next"`)
+		case 2:
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", "30")
+			w.Header().Set("Link", `</items?page=3>; rel="next"`)
+		}
+	}))
+	defer server.Close()
+
+	fake := clock.NewFake(time.Unix(1_000, 0))
+	client, err := httpc.New(
+		httpc.WithBaseURL(server.URL),
+		httpc.WithClock(fake),
+		httpc.WithRetry(false, 0),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		pages := httpc.Pages(context.Background(), client, httpc.NewRequest(http.MethodGet, "/items"),
+			httpc.NextLink, httpc.WithAdaptivePacing(), httpc.WithPageDelay(time.Second))
+		for _, err := range pages {
+			if err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	for _, step := range []struct {
+		hits int32
+		wait time.Duration
+	}{{1, 5 * time.Second}, {2, 30 * time.Second}} {
+		fake.BlockUntil(1)
+		if got := hits.Load(); got != step.hits {
+			t.Fatalf("expected %d pages before pausing, got %d", step.hits, got)
+		}
+		fake.Advance(step.wait - time.Millisecond)
+		if got := hits.Load(); got != step.hits {
+			t.Fatalf("next page sent %s early", time.Millisecond)
+		}
+		fake.Advance(time.Millisecond)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("pages: %v", err)
+	}
+	if got := hits.Load(); got != 3 {
+		t.Fatalf("expected 3 pages, got %d", got)
+	}
+}
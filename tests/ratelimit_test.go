@@ -0,0 +1,68 @@
+package httpc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/clock"
+	"github.com/gostratum/httpc/ratelimit"
+)
+
+func TestRateLimiterLearnsFrom429(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	fake := clock.NewFake(time.Unix(0, 0))
+	limiter := ratelimit.New(ratelimit.Config{
+		Rate:    100,
+		MaxWait: time.Second,
+		Clock:   fake,
+		Learn:   true,
+	})
+	client, err := httpc.New(
+		httpc.WithBaseURL(server.URL),
+		httpc.WithRateLimiter(limiter),
+		httpc.WithRetry(false, 0),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	resp, err := client.Get(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("first get: %v", err)
+	}
+	if resp.StatusCode() != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", resp.StatusCode())
+	}
+
+	if _, err := client.Get(context.Background(), "/"); !errors.Is(err, ratelimit.ErrRateLimited) {
+		t.Fatalf("expected host to be paused for Retry-After, got %v", err)
+	}
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Fatalf("paused request reached the server: %d hits", got)
+	}
+
+	fake.Advance(30 * time.Second)
+	resp, err = client.Get(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("get after pause: %v", err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		t.Fatalf("expected 200 after pause, got %d", resp.StatusCode())
+	}
+}
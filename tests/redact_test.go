@@ -0,0 +1,58 @@
+package httpc_test
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gostratum/httpc/redact"
+)
+
+func TestRedactHeaders(t *testing.T) {
+	r := redact.New(redact.DefaultRules().Merge(redact.Rules{Headers: []string{"X-Tenant-*"}}))
+	h := http.Header{}
+	h.Set("Authorization", "Bearer abc")
+	h.Set("X-Session-Token", "t0k3n")
+	h.Set("X-Tenant-Key", "tenant")
+	h.Set("Content-Type", "application/json")
+
+	got := r.Header(h)
+	for _, k := range []string{"Authorization", "X-Session-Token", "X-Tenant-Key"} {
+		if got.Get(k) != redact.DefaultMask {
+			t.Fatalf("expected %s masked, got %q", k, got.Get(k))
+		}
+	}
+	if got.Get("Content-Type") != "application/json" {
+		t.Fatalf("expected content type kept, got %q", got.Get("Content-Type"))
+	}
+	if h.Get("Authorization") != "Bearer abc" {
+		t.Fatalf("original header must not be modified")
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	r := redact.Default()
+	u, _ := url.Parse("https://user:pw@store.example.com/container?sig=abc123&sv=2024&api_key=k")
+	got := r.URL(u)
+	if strings.Contains(got, "abc123") || strings.Contains(got, "pw@") || strings.Contains(got, "=k") {
+		t.Fatalf("secrets leaked: %s", got)
+	}
+	if !strings.Contains(got, "sv=2024") || !strings.Contains(got, "/container") {
+		t.Fatalf("non-sensitive parts lost: %s", got)
+	}
+}
+
+func TestRedactJSON(t *testing.T) {
+	r := redact.New(redact.Rules{JSONFields: []string{"password", "card_*"}, Mask: "***"})
+	got := string(r.JSON([]byte(`{"user":"bob","password":"hunter2","payment":{"card_number":"4111","amount":12.50},"items":[{"password":"x"}]}`)))
+	if strings.Contains(got, "hunter2") || strings.Contains(got, "4111") || strings.Contains(got, `"x"`) {
+		t.Fatalf("secrets leaked: %s", got)
+	}
+	if !strings.Contains(got, `"amount":12.50`) || !strings.Contains(got, `"user":"bob"`) {
+		t.Fatalf("unexpected redaction result: %s", got)
+	}
+	if raw := r.JSON([]byte("not json")); string(raw) != "not json" {
+		t.Fatalf("non-JSON bodies must pass through, got %s", raw)
+	}
+}
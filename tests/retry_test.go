@@ -2,14 +2,22 @@ package httpc_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gostratum/core/logx"
 	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/clock"
+	"github.com/gostratum/httpc/retry"
 )
 
 func TestRetriesOn503(t *testing.T) {
@@ -45,4 +53,341 @@ func TestRetriesOn503(t *testing.T) {
 	if atomic.LoadInt32(&attempts) < 2 {
 		t.Fatalf("expected retry attempts, got %d", attempts)
 	}
+	if resp.Attempts() != 2 {
+		t.Fatalf("expected response to report 2 attempts, got %d", resp.Attempts())
+	}
+	if resp.RetryDelay() <= 0 {
+		t.Fatalf("expected non-zero retry delay, got %v", resp.RetryDelay())
+	}
+}
+
+func TestResponseAttemptsWithoutRetry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(httpc.WithBaseURL(server.URL), httpc.WithRetry(false, 0))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	resp, err := client.Get(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if resp.Attempts() != 1 || resp.RetryDelay() != 0 {
+		t.Fatalf("expected first-try response, got attempts=%d delay=%v", resp.Attempts(), resp.RetryDelay())
+	}
+}
+
+func TestRetryPolicyDeterministicJitter(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	resp := &http.Response{StatusCode: http.StatusServiceUnavailable}
+
+	newPolicy := func() retry.Policy {
+		return retry.NewPolicy(retry.PolicyConfig{
+			MaxAttempts: 5,
+			BaseBackoff: 100 * time.Millisecond,
+			MaxBackoff:  time.Second,
+			StatusCodes: []int{http.StatusServiceUnavailable},
+			Rand:        rand.New(rand.NewSource(42)),
+		})
+	}
+
+	a, b := newPolicy(), newPolicy()
+	for attempt := 1; attempt < 5; attempt++ {
+		da, _ := a.ShouldRetry(req, resp, nil, attempt, false)
+		db, _ := b.ShouldRetry(req, resp, nil, attempt, false)
+		if da != db {
+			t.Fatalf("attempt %d: expected identical delays, got %v and %v", attempt, da, db)
+		}
+	}
+
+	noJitter := retry.NewPolicy(retry.PolicyConfig{
+		MaxAttempts: 5,
+		BaseBackoff: 100 * time.Millisecond,
+		MaxBackoff:  time.Second,
+		StatusCodes: []int{http.StatusServiceUnavailable},
+		JitterFunc:  retry.NoJitter,
+	})
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 4: 800 * time.Millisecond} {
+		if got, _ := noJitter.ShouldRetry(req, resp, nil, attempt, false); got != want {
+			t.Fatalf("attempt %d: expected %v, got %v", attempt, want, got)
+		}
+	}
+}
+
+func TestRetryWaitsOnInjectedClock(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	fake := clock.NewFake(time.Unix(0, 0))
+	client, err := httpc.New(
+		httpc.WithBaseURL(server.URL),
+		httpc.WithClock(fake),
+		httpc.WithRetry(true, 2),
+		httpc.WithRetryPolicy(retry.NewPolicy(retry.PolicyConfig{
+			MaxAttempts: 2,
+			BaseBackoff: time.Hour,
+			MaxBackoff:  time.Hour,
+			StatusCodes: []int{http.StatusServiceUnavailable},
+			JitterFunc:  retry.NoJitter,
+		})),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	done := make(chan int, 1)
+	go func() {
+		resp, err := client.Get(context.Background(), "/")
+		if err != nil {
+			t.Errorf("get: %v", err)
+			done <- 0
+			return
+		}
+		done <- resp.StatusCode()
+	}()
+
+	fake.BlockUntil(1)
+	fake.Advance(time.Hour)
+
+	select {
+	case status := <-done:
+		if status != http.StatusOK {
+			t.Fatalf("expected 200 after retry, got %d", status)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("retry did not resume after advancing the fake clock")
+	}
+}
+
+// refusedError is not comparable, so ExhaustedError must not compare it.
+type refusedError struct{ addrs []string }
+
+func (refusedError) Error() string { return "connection refused" }
+
+func TestRetryExhaustedErrorHistory(t *testing.T) {
+	var calls int32
+	failing := roundTripper(func(req *http.Request) (*http.Response, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return &http.Response{StatusCode: http.StatusBadGateway, Body: http.NoBody, Header: http.Header{}}, nil
+		}
+		return nil, refusedError{addrs: []string{req.URL.Host}}
+	})
+
+	client, err := httpc.New(
+		httpc.WithTransport(failing),
+		httpc.WithRetry(true, 3),
+		httpc.WithRetryPolicy(noDelayPolicy{max: 3}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	_, err = client.Get(context.Background(), "http://example.invalid/")
+	var exhausted *retry.ExhaustedError
+	if !errors.As(err, &exhausted) {
+		t.Fatalf("expected ExhaustedError, got %v", err)
+	}
+	if len(exhausted.Attempts) != 3 {
+		t.Fatalf("expected 3 attempts, got %+v", exhausted.Attempts)
+	}
+	if exhausted.Attempts[0].StatusCode != http.StatusBadGateway || exhausted.Attempts[2].Err == nil || exhausted.Interrupted {
+		t.Fatalf("unexpected history %+v", exhausted.Attempts)
+	}
+	if !strings.Contains(err.Error(), "attempt 1: status 502") || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("error message lacks history: %v", err)
+	}
+}
+
+// fixedDelayPolicy retries 5xx responses after delay.
+type fixedDelayPolicy struct {
+	max   int
+	delay time.Duration
+}
+
+func (p fixedDelayPolicy) ShouldRetry(req *http.Request, resp *http.Response, err error, attempt int, force bool) (time.Duration, bool) {
+	return p.delay, attempt < p.max && err == nil && resp.StatusCode >= 500
+}
+
+func TestRetryHistoryOnStatusAndInterruption(t *testing.T) {
+	unavailable := roundTripper(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody, Header: http.Header{}}, nil
+	})
+	client, err := httpc.New(httpc.WithTransport(unavailable), httpc.WithRetry(true, 2))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	resp, err := client.Get(context.Background(), "http://example.invalid/", httpc.WithRequestRetry(noDelayPolicy{max: 2}))
+	if err != nil {
+		t.Fatalf("status exhaustion returns the last response, got %v", err)
+	}
+	history := resp.RetryHistory()
+	if len(history) != 2 || history[0].StatusCode != http.StatusServiceUnavailable || history[1].StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected history %+v", history)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = client.Get(ctx, "http://example.invalid/", httpc.WithRequestRetry(fixedDelayPolicy{max: 3, delay: time.Hour}))
+	var exhausted *retry.ExhaustedError
+	if !errors.As(err, &exhausted) || !exhausted.Interrupted || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected an interrupted ExhaustedError, got %v", err)
+	}
+	if len(exhausted.Attempts) != 1 || exhausted.Attempts[0].StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected the attempt before the interruption, got %+v", exhausted.Attempts)
+	}
+	if !strings.Contains(err.Error(), "retry interrupted") {
+		t.Fatalf("error message lacks the interruption: %v", err)
+	}
+}
+
+func TestWithIdempotentAllowsPostRetries(t *testing.T) {
+	newClient := func(t *testing.T, calls *int) httpc.Client {
+		t.Helper()
+		transport := roundTripper(func(req *http.Request) (*http.Response, error) {
+			*calls++
+			status := http.StatusOK
+			if *calls == 1 {
+				status = http.StatusServiceUnavailable
+			}
+			return &http.Response{StatusCode: status, Body: http.NoBody, Request: req}, nil
+		})
+		client, err := httpc.New(
+			httpc.WithTransport(transport),
+			httpc.WithRetry(true, 3),
+			httpc.WithRetryPolicy(retry.NewPolicy(retry.PolicyConfig{
+				MaxAttempts:    3,
+				BaseBackoff:    time.Millisecond,
+				MaxBackoff:     time.Millisecond,
+				StatusCodes:    []int{http.StatusServiceUnavailable},
+				IdempotentOnly: true,
+			})),
+		)
+		if err != nil {
+			t.Fatalf("new client: %v", err)
+		}
+		return client
+	}
+
+	var plain int
+	resp, err := newClient(t, &plain).Post(context.Background(), "http://api.test/orders", map[string]string{"sku": "a"})
+	if err != nil || resp.StatusCode() != http.StatusServiceUnavailable || plain != 1 {
+		t.Fatalf("plain POST must not be retried: calls=%d err=%v", plain, err)
+	}
+
+	var declared int
+	resp, err = newClient(t, &declared).Post(context.Background(), "http://api.test/orders", map[string]string{"sku": "a"},
+		httpc.WithIdempotencyKey("order-1"),
+		httpc.WithIdempotent(),
+	)
+	if err != nil || resp.StatusCode() != http.StatusOK || declared != 2 {
+		t.Fatalf("idempotent POST should be retried: calls=%d err=%v", declared, err)
+	}
+}
+
+func TestStrategyStandalone(t *testing.T) {
+	errBusy := errors.New("database busy")
+	strategy := retry.NewStrategy(retry.PolicyConfig{
+		MaxAttempts: 3,
+		BaseBackoff: time.Millisecond,
+		JitterFunc:  retry.NoJitter,
+	}, func(err error) bool { return errors.Is(err, errBusy) })
+
+	if delay, ok := strategy.Next(errBusy, 2); !ok || delay != 2*time.Millisecond {
+		t.Fatalf("expected retry after 2ms, got %s %v", delay, ok)
+	}
+	if _, ok := strategy.Next(context.Canceled, 1); ok {
+		t.Fatal("cancellation must never be retried")
+	}
+	timeout := fmt.Errorf("%w: %w", errBusy, context.DeadlineExceeded)
+	if _, ok := strategy.NextContext(context.Background(), timeout, 1); !ok {
+		t.Fatal("an attempt timeout under a live context should be retried")
+	}
+	expired, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, ok := strategy.NextContext(expired, timeout, 1); ok {
+		t.Fatal("nothing is retried once the caller's context is done")
+	}
+
+	calls := 0
+	err := strategy.Do(context.Background(), func(ctx context.Context, attempt int) error {
+		calls++
+		if attempt < 2 {
+			return errBusy
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Fatalf("expected success on second call, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = strategy.Do(context.Background(), func(ctx context.Context, attempt int) error {
+		calls++
+		return errBusy
+	})
+	var exhausted *retry.ExhaustedError
+	if !errors.As(err, &exhausted) || !errors.Is(err, errBusy) || calls != 3 || len(exhausted.Attempts) != 3 {
+		t.Fatalf("expected exhaustion after 3 calls, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	permanent := errors.New("constraint violation")
+	err = strategy.Do(context.Background(), func(ctx context.Context, attempt int) error {
+		calls++
+		return permanent
+	})
+	if err != permanent || calls != 1 {
+		t.Fatalf("expected permanent error without retry, got %v after %d calls", err, calls)
+	}
+}
+
+func TestStaleConnRetriedOnceForPost(t *testing.T) {
+	errStale := errors.New("http: server closed idle connection")
+	newClient := func(t *testing.T, calls *int, readBody bool) httpc.Client {
+		t.Helper()
+		transport := roundTripper(func(req *http.Request) (*http.Response, error) {
+			*calls++
+			if trace := httptrace.ContextClientTrace(req.Context()); trace != nil && trace.GotConn != nil {
+				trace.GotConn(httptrace.GotConnInfo{Reused: true})
+			}
+			if readBody {
+				_, _ = io.ReadAll(req.Body)
+				if trace := httptrace.ContextClientTrace(req.Context()); trace != nil && trace.WroteRequest != nil {
+					trace.WroteRequest(httptrace.WroteRequestInfo{})
+				}
+			}
+			if *calls == 1 {
+				return nil, errStale
+			}
+			return &http.Response{StatusCode: http.StatusCreated, Body: http.NoBody, Request: req}, nil
+		})
+		client, err := httpc.New(httpc.WithTransport(transport), httpc.WithRetry(false, 0))
+		if err != nil {
+			t.Fatalf("new client: %v", err)
+		}
+		return client
+	}
+
+	var unsent int
+	resp, err := newClient(t, &unsent, false).Post(context.Background(), "http://api.test/orders", map[string]string{"sku": "a"})
+	if err != nil || resp.StatusCode() != http.StatusCreated || unsent != 2 {
+		t.Fatalf("unsent POST on a stale connection should be retried once: calls=%d err=%v", unsent, err)
+	}
+
+	var sent int
+	_, err = newClient(t, &sent, true).Post(context.Background(), "http://api.test/orders", map[string]string{"sku": "a"})
+	if err == nil || sent != 1 {
+		t.Fatalf("POST whose body reached the server must not be retried: calls=%d err=%v", sent, err)
+	}
 }
@@ -0,0 +1,66 @@
+package httpc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gostratum/httpc"
+)
+
+func TestSessionReadYourWrites(t *testing.T) {
+	var seenToken, seenCookie []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenToken = append(seenToken, r.Header.Get("X-Session-LSN"))
+		c, _ := r.Cookie("affinity")
+		if c != nil {
+			seenCookie = append(seenCookie, c.Value)
+		} else {
+			seenCookie = append(seenCookie, "")
+		}
+		// Every response carries a position; only writes may advance it.
+		w.Header().Set("X-Session-LSN", r.Method+"-lsn")
+		http.SetCookie(w, &http.Cookie{Name: "affinity", Value: "replica-" + r.Method})
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(httpc.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	session := httpc.NewSession(httpc.SessionOptions{Header: "X-Session-LSN", Cookie: "affinity"})
+	ctx := context.Background()
+
+	if _, err := client.Get(ctx, "/orders/1", httpc.WithSession(session)); err != nil {
+		t.Fatalf("first read: %v", err)
+	}
+	if session.Token() != "" {
+		t.Fatalf("reads must not capture a token, got %q", session.Token())
+	}
+	if _, err := client.Post(ctx, "/orders", "payload", httpc.WithSession(session)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := client.Get(ctx, "/orders/1", httpc.WithSession(session)); err != nil {
+		t.Fatalf("read after write: %v", err)
+	}
+	if _, err := client.Get(ctx, "/orders/1", httpc.WithSession(session), httpc.WithHeader("X-Session-LSN", "explicit")); err != nil {
+		t.Fatalf("read with explicit token: %v", err)
+	}
+
+	wantToken := []string{"", "", "POST-lsn", "explicit"}
+	wantCookie := []string{"", "", "replica-POST", "replica-POST"}
+	for i := range wantToken {
+		if seenToken[i] != wantToken[i] || seenCookie[i] != wantCookie[i] {
+			t.Fatalf("request %d: token=%q cookie=%q, want %q and %q", i, seenToken[i], seenCookie[i], wantToken[i], wantCookie[i])
+		}
+	}
+
+	session.Reset()
+	if _, err := client.Get(ctx, "/orders/1", httpc.WithSession(session)); err != nil {
+		t.Fatalf("read after reset: %v", err)
+	}
+	if last := len(seenToken) - 1; seenToken[last] != "" || seenCookie[last] != "" {
+		t.Fatalf("expected no token after Reset, got %q and %q", seenToken[last], seenCookie[last])
+	}
+}
@@ -0,0 +1,121 @@
+package httpc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/clock"
+)
+
+func TestClientStats(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1000, 0))
+	calls := map[string]int{}
+	transport := roundTripper(func(req *http.Request) (*http.Response, error) {
+		calls[req.URL.Path]++
+		switch req.URL.Path {
+		case "/flaky":
+			fake.Advance(30 * time.Millisecond)
+			if calls["/flaky"] == 1 {
+				return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody, Request: req}, nil
+			}
+		case "/down":
+			return nil, errors.New("connection refused")
+		default:
+			fake.Advance(10 * time.Millisecond)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+
+	client, err := httpc.New(
+		httpc.WithTransport(transport),
+		httpc.WithClock(fake),
+		httpc.WithRetry(true, 2),
+		httpc.WithRetryPolicy(noDelayPolicy{max: 2}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 8; i++ {
+		if _, err := client.Get(ctx, "http://api.test/ok"); err != nil {
+			t.Fatalf("get ok: %v", err)
+		}
+	}
+	if _, err := client.Get(ctx, "http://api.test/flaky"); err != nil {
+		t.Fatalf("get flaky: %v", err)
+	}
+	if _, err := client.Get(ctx, "http://api.test/down"); err == nil {
+		t.Fatalf("expected transport error")
+	}
+
+	hs, ok := client.Stats().Hosts["api.test"]
+	if !ok {
+		t.Fatalf("no stats for host")
+	}
+	if hs.Requests != 10 || hs.Errors != 1 || hs.Retried != 2 {
+		t.Fatalf("unexpected counters %+v", hs)
+	}
+	if hs.ErrorRate != 0.1 || hs.RetryRate != 0.2 {
+		t.Fatalf("unexpected rates %+v", hs)
+	}
+	if hs.P50 != 10*time.Millisecond || hs.Max != 60*time.Millisecond {
+		t.Fatalf("unexpected latencies p50=%v max=%v", hs.P50, hs.Max)
+	}
+}
+
+func TestClientHealth(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1000, 0))
+	var slowFails bool
+	transport := roundTripper(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Host {
+		case "fast.test":
+			fake.Advance(20 * time.Millisecond)
+		case "slow.test":
+			fake.Advance(400 * time.Millisecond)
+			if slowFails {
+				return &http.Response{StatusCode: http.StatusBadGateway, Body: http.NoBody, Request: req}, nil
+			}
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+	client, err := httpc.New(httpc.WithTransport(transport), httpc.WithClock(fake), httpc.WithRetry(false, 0))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	if h := client.Health("unknown.test"); h.Score != 1 || h.Samples != 0 {
+		t.Fatalf("unseen hosts should score 1, got %+v", h)
+	}
+
+	ctx := context.Background()
+	for range 5 {
+		_, _ = client.Get(ctx, "http://fast.test/")
+		_, _ = client.Get(ctx, "http://slow.test/")
+	}
+	fast, slow := client.Health("fast.test"), client.Health("slow.test")
+	if fast.Samples != 5 || fast.Latency != 20*time.Millisecond || fast.ErrorRate != 0 {
+		t.Fatalf("unexpected fast health %+v", fast)
+	}
+	if !(fast.Score > slow.Score) {
+		t.Fatalf("expected the fast host to score higher: fast=%+v slow=%+v", fast, slow)
+	}
+
+	// Errors pull the score down progressively rather than all at once.
+	slowFails = true
+	_, _ = client.Get(ctx, "http://slow.test/")
+	once := client.Health("slow.test")
+	if once.ErrorRate < 0.19 || once.ErrorRate > 0.21 || once.Score >= slow.Score {
+		t.Fatalf("expected one failure to weigh 0.2: %+v", once)
+	}
+	for range 20 {
+		_, _ = client.Get(ctx, "http://slow.test/")
+	}
+	if h := client.Health("slow.test"); h.ErrorRate < 0.99 || h.Score > 0.01 {
+		t.Fatalf("expected a failing host to approach zero: %+v", h)
+	}
+}
@@ -0,0 +1,79 @@
+package httpc_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/auth"
+)
+
+func TestTenantClientFactory(t *testing.T) {
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "%s %s %s", r.URL.Path, r.Header.Get("Authorization"), r.Header.Get("X-Region"))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	var resolved atomic.Int32
+	factory, err := httpc.NewTenantClientFactory(httpc.TenantFactoryConfig{
+		MaxClients: 2,
+		Resolve: func(ctx context.Context, id string) (httpc.Tenant, error) {
+			resolved.Add(1)
+			return httpc.Tenant{
+				Auth:          auth.NewBasic(auth.BasicOptions{Username: id, Password: "pw"}),
+				BaseURLSuffix: "/tenants/" + id,
+				Headers:       map[string]string{"X-Region": "eu"},
+			}, nil
+		},
+	}, httpc.WithBaseURL(server.URL+"/api"), httpc.WithRetry(false, 0))
+	if err != nil {
+		t.Fatalf("new factory: %v", err)
+	}
+
+	get := func(tenant string) string {
+		t.Helper()
+		client, err := factory.Client(context.Background(), tenant)
+		if err != nil {
+			t.Fatalf("client %s: %v", tenant, err)
+		}
+		resp, err := client.Get(context.Background(), "/orders")
+		if err != nil {
+			t.Fatalf("get %s: %v", tenant, err)
+		}
+		body, _ := resp.String()
+		return body
+	}
+
+	if body := get("acme"); body != "/api/tenants/acme/orders Basic YWNtZTpwdw== eu" {
+		t.Fatalf("unexpected acme response %q", body)
+	}
+	if body := get("globex"); body != "/api/tenants/globex/orders Basic Z2xvYmV4OnB3 eu" {
+		t.Fatalf("unexpected globex response %q", body)
+	}
+	get("acme")
+	if got := resolved.Load(); got != 2 {
+		t.Fatalf("expected cached tenant clients, got %d resolutions", got)
+	}
+	if got := conns.Load(); got != 1 {
+		t.Fatalf("expected tenants to share one pooled connection, got %d", got)
+	}
+
+	// A third tenant evicts the least recently used (globex).
+	get("initech")
+	get("globex")
+	if got := resolved.Load(); got != 4 {
+		t.Fatalf("expected globex to be rebuilt after eviction, got %d resolutions", got)
+	}
+}
@@ -0,0 +1,157 @@
+package httpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gostratum/httpc"
+)
+
+type widget struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestTypedHelpers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/widgets/1":
+			_ = json.NewEncoder(w).Encode(widget{ID: 1, Name: "sprocket"})
+		case "/widgets":
+			var in widget
+			_ = json.NewDecoder(r.Body).Decode(&in)
+			in.ID = 2
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(in)
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(httpc.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	ctx := context.Background()
+
+	got, resp, err := httpc.GetAs[widget](ctx, client, "/widgets/1")
+	if err != nil || got != (widget{ID: 1, Name: "sprocket"}) || resp.StatusCode() != http.StatusOK {
+		t.Fatalf("GetAs = %+v, %v", got, err)
+	}
+
+	created, _, err := httpc.PostAs[widget](ctx, client, "/widgets", httpc.WithJSON(widget{Name: "gear"}))
+	if err != nil || created != (widget{ID: 2, Name: "gear"}) {
+		t.Fatalf("PostAs = %+v, %v", created, err)
+	}
+
+	empty, _, err := httpc.DoAs[*widget](ctx, client, httpc.NewRequest(http.MethodDelete, "/empty"))
+	if err != nil || empty != nil {
+		t.Fatalf("DoAs on 204 = %+v, %v", empty, err)
+	}
+
+	_, resp, err = httpc.GetAs[widget](ctx, client, "/missing?api_key=secret")
+	var httpErr *httpc.HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("expected *HTTPError, got %v", err)
+	}
+	if httpErr.StatusCode != http.StatusNotFound || httpErr.Method != http.MethodGet || httpErr.Response != resp {
+		t.Fatalf("unexpected error %+v", httpErr)
+	}
+	if !strings.Contains(string(httpErr.Body), "not found") || strings.Contains(httpErr.Error(), "secret") {
+		t.Fatalf("expected the body and a redacted URL in %q", httpErr.Error())
+	}
+}
+
+func TestErrorOnStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte("slow down"))
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(httpc.WithBaseURL(server.URL), httpc.WithRetry(false, 0), httpc.WithErrorOnStatus())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	_, err = client.Get(context.Background(), "/limited")
+	var httpErr *httpc.HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("expected *HTTPError, got %v", err)
+	}
+	if httpErr.StatusCode != http.StatusTooManyRequests || httpErr.Header.Get("Retry-After") != "3" || string(httpErr.Body) != "slow down" {
+		t.Fatalf("unexpected error %+v", httpErr)
+	}
+	if httpErr.Header.Get("Set-Cookie") == "session=secret" {
+		t.Fatal("expected sensitive response headers to be redacted")
+	}
+
+	resp, err := client.Get(context.Background(), "/limited", httpc.WithRequestErrorOnStatus(false))
+	if err != nil || resp.StatusCode() != http.StatusTooManyRequests {
+		t.Fatalf("expected the per-request override to return the response, got %v", err)
+	}
+}
+
+func TestErrorOnStatusKeepsBodyPrefix(t *testing.T) {
+	payload := strings.Repeat("x", 64<<10)
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/short" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("bad input"))
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(payload))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client, err := httpc.New(httpc.WithBaseURL(server.URL), httpc.WithRetry(false, 0), httpc.WithErrorOnStatus())
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		_, err = client.Get(context.Background(), "/long")
+		var httpErr *httpc.HTTPError
+		if !errors.As(err, &httpErr) {
+			t.Fatalf("expected *HTTPError, got %v", err)
+		}
+		if string(httpErr.Body) != payload[:4<<10] {
+			t.Fatalf("expected a 4 KiB body prefix, got %d bytes", len(httpErr.Body))
+		}
+		if _, err := httpErr.Response.Bytes(); !errors.Is(err, httpc.ErrBodyConsumed) {
+			t.Fatalf("expected ErrBodyConsumed for a truncated body, got %v", err)
+		}
+	}
+	if got := conns.Load(); got != 1 {
+		t.Fatalf("expected error responses to release their connection, opened %d", got)
+	}
+
+	_, err = client.Get(context.Background(), "/short")
+	var httpErr *httpc.HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("expected *HTTPError, got %v", err)
+	}
+	if body, err := httpErr.Response.String(); err != nil || body != "bad input" {
+		t.Fatalf("expected a short body to stay readable, got %q, %v", body, err)
+	}
+}
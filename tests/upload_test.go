@@ -0,0 +1,149 @@
+package httpc_test
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/upload"
+)
+
+type fakeS3 struct {
+	mu        sync.Mutex
+	parts     map[int]string
+	failOnce  map[int]bool
+	rejectAll bool
+	completed string
+	aborted   int32
+	puts      int32
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		_, _ = w.Write([]byte(`<InitiateMultipartUploadResult><UploadId>up-1</UploadId></InitiateMultipartUploadResult>`))
+	case r.Method == http.MethodPut && q.Get("uploadId") == "up-1":
+		number, _ := strconv.Atoi(q.Get("partNumber"))
+		body, _ := io.ReadAll(r.Body)
+		atomic.AddInt32(&f.puts, 1)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.rejectAll {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if f.failOnce[number] {
+			delete(f.failOnce, number)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		f.parts[number] = string(body)
+		w.Header().Set("ETag", `"etag-`+strconv.Itoa(number)+`"`)
+	case r.Method == http.MethodPost && q.Get("uploadId") == "up-1":
+		var doc struct {
+			Parts []struct {
+				PartNumber int
+				ETag       string
+			} `xml:"Part"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := xml.Unmarshal(body, &doc); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		var assembled strings.Builder
+		for i, p := range doc.Parts {
+			if p.PartNumber != i+1 || p.ETag != `"etag-`+strconv.Itoa(p.PartNumber)+`"` {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			assembled.WriteString(f.parts[p.PartNumber])
+		}
+		f.completed = assembled.String()
+		_, _ = w.Write([]byte(`<CompleteMultipartUploadResult/>`))
+	case r.Method == http.MethodDelete && q.Get("uploadId") == "up-1":
+		atomic.AddInt32(&f.aborted, 1)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestMultipartUploadRetriesFailedParts(t *testing.T) {
+	store := &fakeS3{parts: map[int]string{}, failOnce: map[int]bool{2: true}}
+	server := httptest.NewServer(store)
+	defer server.Close()
+
+	client, err := httpc.New(httpc.WithBaseURL(server.URL+"/bucket"), httpc.WithRetry(false, 0))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	uploader := upload.NewUploader(client, upload.Config{
+		PartSize:    4,
+		Concurrency: 2,
+		BaseBackoff: time.Millisecond,
+		MaxBackoff:  time.Millisecond,
+	})
+
+	res, err := uploader.Upload(context.Background(), "/big.bin", strings.NewReader("0123456789"))
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if len(res.Parts) != 3 || res.Size != 10 || res.UploadID != "up-1" {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if store.completed != "0123456789" {
+		t.Fatalf("parts assembled out of order: %q", store.completed)
+	}
+	if atomic.LoadInt32(&store.aborted) != 0 {
+		t.Fatal("successful upload must not be aborted")
+	}
+}
+
+func TestMultipartUploadAbortsOnPermanentFailure(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []httpc.Option
+	}{
+		{"default", nil},
+		{"error_on_status", []httpc.Option{httpc.WithErrorOnStatus()}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeS3{parts: map[int]string{}, rejectAll: true}
+			server := httptest.NewServer(store)
+			defer server.Close()
+
+			opts := append([]httpc.Option{httpc.WithBaseURL(server.URL + "/bucket"), httpc.WithRetry(false, 0)}, tc.opts...)
+			client, err := httpc.New(opts...)
+			if err != nil {
+				t.Fatalf("new client: %v", err)
+			}
+			uploader := upload.NewUploader(client, upload.Config{PartSize: 4, Concurrency: 1})
+
+			_, err = uploader.Upload(context.Background(), "/big.bin", strings.NewReader("0123456789"))
+			var status *upload.StatusError
+			if !errors.As(err, &status) || status.StatusCode != http.StatusForbidden {
+				t.Fatalf("expected 403 status error, got %v", err)
+			}
+			if got := atomic.LoadInt32(&store.puts); got != 1 {
+				t.Fatalf("expected the rejected part not to be retried, got %d attempts", got)
+			}
+			if atomic.LoadInt32(&store.aborted) != 1 {
+				t.Fatalf("expected upload to be aborted once, got %d", store.aborted)
+			}
+		})
+	}
+}
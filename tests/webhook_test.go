@@ -0,0 +1,152 @@
+package httpc_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/webhook"
+)
+
+func TestWebhookSignedDeliveryWithRetries(t *testing.T) {
+	secret := []byte("s3cret")
+	var calls int32
+	ids := make(chan string, 3)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		err := webhook.Verify(secret, r.Header.Get(webhook.DefaultSignatureHeader),
+			r.Header.Get(webhook.DefaultTimestampHeader), body, time.Minute, time.Now())
+		if err != nil {
+			t.Errorf("verify: %v", err)
+		}
+		ids <- r.Header.Get(webhook.DefaultIDHeader)
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(httpc.WithRetry(false, 0))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	sender, err := webhook.NewSender(client, webhook.Config{
+		Secret:      secret,
+		BaseBackoff: time.Millisecond,
+		MaxBackoff:  time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("new sender: %v", err)
+	}
+
+	d, err := sender.Send(context.Background(), server.URL, map[string]string{"event": "invoice.paid"})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if !d.Delivered || len(d.Attempts) != 3 || string(d.Payload) != `{"event":"invoice.paid"}` {
+		t.Fatalf("unexpected delivery %+v", d)
+	}
+	if d.Attempts[0].StatusCode != http.StatusServiceUnavailable || d.Attempts[2].StatusCode != http.StatusNoContent {
+		t.Fatalf("unexpected attempt history %v", d.Attempts)
+	}
+	close(ids)
+	for id := range ids {
+		if id != d.ID {
+			t.Fatalf("delivery id changed across attempts: %q vs %q", id, d.ID)
+		}
+	}
+}
+
+func TestWebhookDeadLetterOnPermanentRejection(t *testing.T) {
+	for name, opts := range map[string][]httpc.Option{
+		"plain":           {httpc.WithRetry(false, 0)},
+		"error_on_status": {httpc.WithRetry(false, 0), httpc.WithErrorOnStatus()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var calls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				w.WriteHeader(http.StatusBadRequest)
+			}))
+			defer server.Close()
+
+			client, err := httpc.New(opts...)
+			if err != nil {
+				t.Fatalf("new client: %v", err)
+			}
+			var dead *webhook.Delivery
+			sender, err := webhook.NewSender(client, webhook.Config{
+				Secret:       []byte("s3cret"),
+				OnDeadLetter: func(_ context.Context, d *webhook.Delivery) { dead = d },
+			})
+			if err != nil {
+				t.Fatalf("new sender: %v", err)
+			}
+
+			_, err = sender.Send(context.Background(), server.URL, []byte(`{}`))
+			var status *webhook.StatusError
+			if !errors.As(err, &status) || status.StatusCode != http.StatusBadRequest {
+				t.Fatalf("expected 400 status error, got %v", err)
+			}
+			if atomic.LoadInt32(&calls) != 1 {
+				t.Fatalf("permanent rejection must not be retried, got %d calls", calls)
+			}
+			if dead == nil || dead.Delivered || len(dead.Attempts) != 1 || dead.Attempts[0].StatusCode != http.StatusBadRequest {
+				t.Fatalf("expected dead-lettered delivery, got %+v", dead)
+			}
+		})
+	}
+}
+
+func TestWebhookRetriesRequestTimeout(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(httpc.WithRetry(false, 0), httpc.WithTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	sender, err := webhook.NewSender(client, webhook.Config{
+		Secret:      []byte("s3cret"),
+		BaseBackoff: time.Millisecond,
+		MaxBackoff:  time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("new sender: %v", err)
+	}
+
+	d, err := sender.Send(context.Background(), server.URL, []byte(`{}`))
+	if err != nil {
+		t.Fatalf("expected the slow first attempt to be retried, got %v", err)
+	}
+	if !d.Delivered || len(d.Attempts) != 2 || !errors.Is(d.Attempts[0].Err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected delivery %+v", d)
+	}
+}
+
+func TestWebhookVerifyRejectsTampering(t *testing.T) {
+	secret := []byte("s3cret")
+	sig := webhook.Sign(secret, "1700000000", []byte(`{"a":1}`))
+	if err := webhook.Verify(secret, sig, "1700000000", []byte(`{"a":2}`), 0, time.Time{}); !errors.Is(err, webhook.ErrInvalidSignature) {
+		t.Fatalf("expected invalid signature, got %v", err)
+	}
+	if err := webhook.Verify(secret, sig, "1700000000", []byte(`{"a":1}`), time.Minute, time.Unix(1700000000, 0).Add(2*time.Minute)); !errors.Is(err, webhook.ErrInvalidSignature) {
+		t.Fatalf("expected stale timestamp rejection, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package httpc_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/yaml"
+)
+
+func TestYAMLRoundTrip(t *testing.T) {
+	type manifest struct {
+		Name     string `yaml:"name"`
+		Replicas int    `yaml:"replicas"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != yaml.MediaType {
+			t.Errorf("content type = %q", ct)
+		}
+		if accept := r.Header.Get("Accept"); accept != yaml.MediaType {
+			t.Errorf("accept = %q", accept)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "name: api") {
+			t.Errorf("unexpected body %q", body)
+		}
+		w.Header().Set("Content-Type", yaml.MediaType)
+		_, _ = io.WriteString(w, "name: api\nreplicas: 3\n")
+	}))
+	defer server.Close()
+
+	client, err := httpc.New(httpc.WithRetry(false, 0))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	resp, err := client.Post(context.Background(), server.URL, nil, yaml.WithYAML(manifest{Name: "api", Replicas: 2}))
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	var out manifest
+	if err := yaml.DecodeYAML(resp, &out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out.Name != "api" || out.Replicas != 3 {
+		t.Fatalf("decoded %+v", out)
+	}
+
+	// An explicit Accept is kept.
+	req := httpc.NewRequest(http.MethodPut, "/x", httpc.WithAccept("application/json"), yaml.WithYAML(manifest{}))
+	if got := req.Header("Accept"); got != "application/json" {
+		t.Fatalf("accept = %q", got)
+	}
+}
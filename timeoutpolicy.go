@@ -0,0 +1,144 @@
+package httpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gostratum/httpc/clock"
+)
+
+// Policies for combining Config.Timeout with WithRequestTimeout, for
+// Config.TimeoutPolicy. Deadlines on the caller's context always apply.
+const (
+	// TimeoutMinOf enforces both the client timeout and the request
+	// timeout, so the shorter wins. It is the default.
+	TimeoutMinOf = "min"
+	// TimeoutOverride lets a request timeout replace the client timeout,
+	// so a single slow call can be given longer than the client default.
+	// Requests without one use the client timeout.
+	TimeoutOverride = "override"
+	// TimeoutIgnoreClient ignores the client timeout: only request
+	// timeouts and context deadlines bound a request.
+	TimeoutIgnoreClient = "ignore_client"
+	// TimeoutHeaders bounds only the wait for the response headers,
+	// retries included, by the client timeout. The body is then bounded by
+	// request timeouts and context deadlines alone, so SSE streams and long
+	// downloads are not cut off by a client timeout meant for API calls.
+	TimeoutHeaders = "headers"
+)
+
+func validateTimeoutPolicy(policy string) error {
+	switch policy {
+	case TimeoutMinOf, TimeoutOverride, TimeoutIgnoreClient, TimeoutHeaders:
+		return nil
+	}
+	return fmt.Errorf("unsupported timeout_policy %q", policy)
+}
+
+// httpClientTimeout is the http.Client.Timeout for cfg. Only TimeoutMinOf
+// leaves the client timeout to net/http; the other policies apply it, if at
+// all, through the request context.
+func (c Config) httpClientTimeout() time.Duration {
+	if c.TimeoutPolicy == TimeoutMinOf {
+		return c.Timeout
+	}
+	return 0
+}
+
+// requestTimeout is the timeout Do puts on the request context, given the
+// request's own timeout.
+func (c Config) requestTimeout(d time.Duration) time.Duration {
+	switch c.TimeoutPolicy {
+	case TimeoutOverride:
+		if d > 0 {
+			return d
+		}
+		return c.Timeout
+	default:
+		return d
+	}
+}
+
+// headerTimeout is how long Do waits for response headers under
+// TimeoutHeaders, or zero.
+func (c Config) headerTimeout() time.Duration {
+	if c.TimeoutPolicy == TimeoutHeaders {
+		return c.Timeout
+	}
+	return 0
+}
+
+// headerDeadline cancels a request context unless the response headers
+// arrive, and stop is called, within its timeout.
+type headerDeadline struct {
+	timer   clock.Timer
+	stopped chan struct{}
+	cancel  context.CancelCauseFunc
+	cause   error
+}
+
+func newHeaderDeadline(ctx context.Context, clk clock.Clock, d time.Duration) (*headerDeadline, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	h := &headerDeadline{
+		timer:   clk.NewTimer(d),
+		stopped: make(chan struct{}),
+		cancel:  cancel,
+		cause:   fmt.Errorf("no response headers within %s: %w", d, context.DeadlineExceeded),
+	}
+	go func() {
+		select {
+		case <-h.timer.C():
+			cancel(h.cause)
+		case <-h.stopped:
+		case <-ctx.Done():
+		}
+	}()
+	return h, ctx
+}
+
+// stop lifts the deadline once the headers have arrived, failing if it
+// expired first.
+func (h *headerDeadline) stop() error {
+	if h == nil {
+		return nil
+	}
+	if h.timer.Stop() {
+		close(h.stopped)
+		return nil
+	}
+	return &TransportError{Code: CodeTimeout, Err: h.cause}
+}
+
+// wrap reports err as a timeout if the deadline caused it.
+func (h *headerDeadline) wrap(ctx context.Context, err error) error {
+	if h == nil || context.Cause(ctx) != h.cause {
+		return err
+	}
+	return &TransportError{Code: CodeTimeout, Err: h.cause}
+}
+
+// cancelBody releases the request context once the response body has been
+// read to the end or closed, so a request timeout keeps bounding the body
+// without cutting it off when Do returns.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+func (b *cancelBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.cancel)
+	}
+	return n, err
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.cancel)
+	return err
+}
@@ -0,0 +1,109 @@
+// Package httpcotel traces httpc clients with OpenTelemetry. It is a
+// separate module so clients that do not use OpenTelemetry do not depend on
+// it.
+package httpcotel
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/redact"
+	"github.com/gostratum/httpc/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/fx"
+)
+
+// ScopeName is the instrumentation scope of the spans.
+const ScopeName = "github.com/gostratum/httpc"
+
+// NewTracer adapts tp to tracing.Tracer, starting a client span per attempt
+// as a child of the span in the request context. The spans follow the HTTP
+// client semantic conventions, with the URL redacted like httpc's logs. A
+// nil tp uses the global tracer provider.
+func NewTracer(tp trace.TracerProvider) tracing.Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &tracer{tracer: tp.Tracer(ScopeName), redactor: redact.Default()}
+}
+
+// WithTracing traces the client's attempts with tp, as with NewTracer.
+func WithTracing(tp trace.TracerProvider) httpc.Option {
+	return httpc.WithTracer(NewTracer(tp))
+}
+
+// Params are the dependencies of Module.
+type Params struct {
+	fx.In
+
+	// Provider is the app's tracer provider; without one the global tracer
+	// provider is used.
+	Provider trace.TracerProvider `optional:"true"`
+}
+
+// Module provides the tracer every client built by httpc.NewFx traces its
+// attempts with, backed by the app's trace.TracerProvider when it provides
+// one.
+func Module() fx.Option {
+	return fx.Module("httpc-otel",
+		fx.Provide(fx.Annotate(
+			func(p Params) tracing.Tracer { return NewTracer(p.Provider) },
+			fx.ResultTags(`name:"httpc_tracer"`),
+		)),
+	)
+}
+
+type tracer struct {
+	tracer   trace.Tracer
+	redactor *redact.Redactor
+}
+
+func (t *tracer) Start(ctx context.Context, req *http.Request) (context.Context, tracing.Span) {
+	attrs := []attribute.KeyValue{
+		attribute.String("http.request.method", req.Method),
+		attribute.String("url.full", t.redactor.Request(req)),
+		attribute.String("server.address", req.URL.Hostname()),
+	}
+	if port, err := strconv.Atoi(req.URL.Port()); err == nil {
+		attrs = append(attrs, attribute.Int("server.port", port))
+	}
+	ctx, span := t.tracer.Start(ctx, req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx, otelSpan{span}
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s otelSpan) SpanContext() tracing.SpanContext {
+	sc := s.span.SpanContext()
+	return tracing.SpanContext{TraceID: sc.TraceID(), SpanID: sc.SpanID(), Sampled: sc.IsSampled()}
+}
+
+func (s otelSpan) End(r tracing.Result) {
+	if r.Attempt > 1 {
+		s.span.SetAttributes(attribute.Int("http.request.resend_count", r.Attempt-1))
+	}
+	switch {
+	case r.Err != nil:
+		s.span.RecordError(r.Err)
+		s.span.SetAttributes(attribute.String("error.type", "transport"))
+		s.span.SetStatus(codes.Error, r.Err.Error())
+	case r.StatusCode >= 400:
+		// Client spans treat 4xx answers as errors too.
+		code := strconv.Itoa(r.StatusCode)
+		s.span.SetAttributes(attribute.Int("http.response.status_code", r.StatusCode), attribute.String("error.type", code))
+		s.span.SetStatus(codes.Error, "")
+	default:
+		s.span.SetAttributes(attribute.Int("http.response.status_code", r.StatusCode))
+	}
+	s.span.End()
+}
@@ -0,0 +1,122 @@
+package httpcotel
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/fx"
+)
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestTracerSpansPerAttempt(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+
+	var traceparents []string
+	var calls int
+	transport := roundTripper(func(req *http.Request) (*http.Response, error) {
+		traceparents = append(traceparents, req.Header.Get(tracing.Header))
+		calls++
+		status := http.StatusOK
+		if calls == 1 {
+			status = http.StatusServiceUnavailable
+		}
+		return &http.Response{StatusCode: status, Header: make(http.Header), Body: http.NoBody, Request: req}, nil
+	})
+	client, err := httpc.New(
+		httpc.WithTransport(transport),
+		WithTracing(tp),
+		httpc.WithRetry(true, 2),
+		httpc.WithRetryPolicy(retryOn503{}),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	if _, err := client.Get(ctx, "https://api.example.com:8443/items?token=secret"); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	parent.End()
+
+	spans := rec.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expected two attempt spans and the parent, got %d", len(spans))
+	}
+	first, second := spans[0], spans[1]
+	for i, s := range []sdktrace.ReadOnlySpan{first, second} {
+		if s.SpanKind() != trace.SpanKindClient || s.Name() != http.MethodGet {
+			t.Fatalf("span %d: kind %v name %q", i, s.SpanKind(), s.Name())
+		}
+		if s.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Fatalf("span %d is not a child of the request context's span", i)
+		}
+		if want := "00-" + s.SpanContext().TraceID().String() + "-" + s.SpanContext().SpanID().String() + "-01"; traceparents[i] != want {
+			t.Fatalf("attempt %d sent traceparent %q, want %q", i, traceparents[i], want)
+		}
+	}
+	if first.Status().Code != codes.Error || second.Status().Code == codes.Error {
+		t.Fatalf("unexpected statuses %v and %v", first.Status(), second.Status())
+	}
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range second.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["http.response.status_code"].AsInt64() != http.StatusOK ||
+		attrs["http.request.resend_count"].AsInt64() != 1 ||
+		attrs["server.port"].AsInt64() != 8443 ||
+		attrs["server.address"].AsString() != "api.example.com" {
+		t.Fatalf("unexpected attributes %v", second.Attributes())
+	}
+	if url := attrs["url.full"].AsString(); url == "" || strings.Contains(url, "secret") {
+		t.Fatalf("expected a redacted url.full, got %q", url)
+	}
+}
+
+type retryOn503 struct{}
+
+func (retryOn503) ShouldRetry(_ *http.Request, resp *http.Response, err error, attempt int, _ bool) (time.Duration, bool) {
+	return 0, attempt < 2 && err == nil && resp.StatusCode == http.StatusServiceUnavailable
+}
+
+func TestModuleUsesAppTracerProvider(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	transport := roundTripper(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: http.NoBody, Request: req}, nil
+	})
+
+	var client httpc.Client
+	app := fx.New(
+		fx.NopLogger,
+		Module(),
+		fx.Provide(
+			func() httpc.Config { return httpc.Config{Transport: transport} },
+			func() trace.TracerProvider { return tp },
+			httpc.NewFx,
+		),
+		fx.Populate(&client),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatalf("fx app: %v", err)
+	}
+	if _, err := client.Get(context.Background(), "https://api.example.com/"); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if spans := rec.Ended(); len(spans) != 1 || spans[0].SpanKind() != trace.SpanKindClient {
+		t.Fatalf("expected one client span from the app's provider, got %d", len(spans))
+	}
+}
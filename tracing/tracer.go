@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+)
+
+// Tracer starts client spans, one per attempt, when set with WithTracer. It
+// is the extension point for tracing backends such as OpenTelemetry: an
+// adapter starts a client span from the tracer provider and returns its IDs,
+// which the middleware propagates in the traceparent header.
+type Tracer interface {
+	// Start opens a span for req as a child of the span in ctx, if any, and
+	// returns ctx carrying the new span.
+	Start(ctx context.Context, req *http.Request) (context.Context, Span)
+}
+
+// Span is an attempt's span started by a Tracer.
+type Span interface {
+	// SpanContext identifies the span for the traceparent header; an invalid
+	// one skips propagation.
+	SpanContext() SpanContext
+	// End finishes the span with the outcome of its attempt.
+	End(Result)
+}
+
+// Result is the outcome of an attempt, for span attributes.
+type Result struct {
+	// StatusCode is the response status, zero when Err is set.
+	StatusCode int
+	// Err is the transport error, if any.
+	Err error
+	// Attempt is the 1-based attempt number, so Attempt-1 is the retry count
+	// (http.request.resend_count in the OpenTelemetry conventions).
+	Attempt int
+}
+
+// MiddlewareOption customises the tracing middleware.
+type MiddlewareOption func(*middlewareOptions)
+
+type middlewareOptions struct {
+	tracer Tracer
+}
+
+// WithTracer starts a span per attempt with t and propagates it instead of
+// generating span IDs. Requests that already carry a traceparent header are
+// then traced too, their header replaced by the new span's.
+func WithTracer(t Tracer) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.tracer = t
+	}
+}
@@ -0,0 +1,147 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gostratum/httpc/retry"
+)
+
+// Header is the W3C Trace Context request header.
+const Header = "traceparent"
+
+// SpanContext identifies a span as carried by the traceparent header.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// IsValid reports whether both IDs are set, as the specification requires.
+func (s SpanContext) IsValid() bool {
+	return s.TraceID != [16]byte{} && s.SpanID != [8]byte{}
+}
+
+// String formats s as a version 00 traceparent value.
+func (s SpanContext) String() string {
+	flags := "00"
+	if s.Sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(s.TraceID[:]) + "-" + hex.EncodeToString(s.SpanID[:]) + "-" + flags
+}
+
+// ErrInvalidTraceParent is returned by Parse for malformed values.
+var ErrInvalidTraceParent = errors.New("invalid traceparent")
+
+// Parse decodes a traceparent header value, e.g. from an incoming request.
+func Parse(value string) (SpanContext, error) {
+	// Later versions may append fields; version 00 has exactly four.
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || (parts[0] == "00" && len(parts) != 4) || parts[0] == "ff" {
+		return SpanContext{}, ErrInvalidTraceParent
+	}
+	var (
+		sc      SpanContext
+		version [1]byte
+		flags   [1]byte
+	)
+	for _, f := range []struct {
+		dst []byte
+		src string
+	}{{version[:], parts[0]}, {sc.TraceID[:], parts[1]}, {sc.SpanID[:], parts[2]}, {flags[:], parts[3]}} {
+		if len(f.src) != 2*len(f.dst) {
+			return SpanContext{}, ErrInvalidTraceParent
+		}
+		if _, err := hex.Decode(f.dst, []byte(f.src)); err != nil {
+			return SpanContext{}, ErrInvalidTraceParent
+		}
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, ErrInvalidTraceParent
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, nil
+}
+
+type spanKey struct{}
+
+// WithSpanContext makes sc the parent of the requests sent with ctx, e.g.
+// the span of the incoming request being served.
+func WithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, spanKey{}, sc)
+}
+
+// SpanContextFromContext returns the span set with WithSpanContext.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	if ctx == nil {
+		return SpanContext{}, false
+	}
+	sc, ok := ctx.Value(spanKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+// NewMiddleware propagates W3C Trace Context: each request without a
+// traceparent header gets one naming a new span, child of the span set with
+// WithSpanContext or the root of a new sampled trace otherwise. Requests that
+// already carry the header, e.g. set by an OpenTelemetry transport, are left
+// untouched. With WithTracer, spans come from the tracer instead and end when
+// the response headers arrive or the attempt fails.
+func NewMiddleware(opts ...MiddlewareOption) func(http.RoundTripper) http.RoundTripper {
+	var mo middlewareOptions
+	for _, opt := range opts {
+		opt(&mo)
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if mo.tracer != nil {
+				return traceAttempt(mo.tracer, next, req)
+			}
+			if req.Header.Get(Header) != "" {
+				return next.RoundTrip(req)
+			}
+			span, ok := SpanContextFromContext(req.Context())
+			if !ok {
+				span = SpanContext{Sampled: true}
+				_, _ = rand.Read(span.TraceID[:])
+			}
+			_, _ = rand.Read(span.SpanID[:])
+
+			req = req.Clone(req.Context())
+			req.Header.Set(Header, span.String())
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+func traceAttempt(tracer Tracer, next http.RoundTripper, req *http.Request) (*http.Response, error) {
+	attempt := 1
+	if st := retry.StatsFromContext(req.Context()); st != nil && st.Attempts > 0 {
+		attempt = st.Attempts
+	}
+	ctx, span := tracer.Start(req.Context(), req)
+	req = req.Clone(ctx)
+	if sc := span.SpanContext(); sc.IsValid() {
+		req.Header.Set(Header, sc.String())
+	}
+	resp, err := next.RoundTrip(req)
+	result := Result{Err: err, Attempt: attempt}
+	if err == nil {
+		result.StatusCode = resp.StatusCode
+	}
+	span.End(result)
+	return resp, err
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,118 @@
+package httpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// ErrorCode is a stable classification of transport failures, meant for
+// alerting rules and retry policies in place of matching error strings.
+type ErrorCode string
+
+const (
+	// CodeDNSFailure: the host name could not be resolved.
+	CodeDNSFailure ErrorCode = "DNS_FAILURE"
+	// CodeConnRefused: nothing accepted the connection on the target port.
+	CodeConnRefused ErrorCode = "CONN_REFUSED"
+	// CodeTLSHandshake: the TLS handshake failed or the server certificate
+	// was rejected.
+	CodeTLSHandshake ErrorCode = "TLS_HANDSHAKE"
+	// CodeTimeout: a dial, handshake, response or context deadline expired.
+	CodeTimeout ErrorCode = "TIMEOUT"
+	// CodeReset: the peer reset or closed the connection mid-request.
+	CodeReset ErrorCode = "RESET"
+)
+
+// ErrTransport is matched (via errors.Is) by *TransportError.
+var ErrTransport = errors.New("transport error")
+
+// TransportError wraps a request failure that never produced a response
+// with its ErrorCode. Client methods return it for every failure
+// ClassifyTransportError recognises; the original error stays reachable
+// through errors.Is and errors.As.
+type TransportError struct {
+	Code ErrorCode
+	Err  error
+}
+
+func (e *TransportError) Error() string {
+	return string(e.Code) + ": " + e.Err.Error()
+}
+
+func (e *TransportError) Unwrap() error { return e.Err }
+
+// Is reports ErrTransport.
+func (e *TransportError) Is(target error) bool {
+	return target == ErrTransport
+}
+
+// ClassifyTransportError returns the ErrorCode of err, or "" when it is not a
+// recognised transport failure (including context cancellation). It accepts
+// both errors returned by Client methods and the raw errors seen by
+// middleware and retry policies.
+func ClassifyTransportError(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+	var te *TransportError
+	if errors.As(err, &te) {
+		return te.Code
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return CodeDNSFailure
+	}
+	if isTLSError(err) {
+		return CodeTLSHandshake
+	}
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return CodeConnRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return CodeReset
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return CodeTimeout
+	}
+	return ""
+}
+
+func isTLSError(err error) bool {
+	var (
+		recordErr tls.RecordHeaderError
+		alertErr  tls.AlertError
+		verifyErr *tls.CertificateVerificationError
+		unknownCA x509.UnknownAuthorityError
+		hostErr   x509.HostnameError
+		invalid   x509.CertificateInvalidError
+	)
+	if errors.As(err, &recordErr) || errors.As(err, &alertErr) || errors.As(err, &verifyErr) ||
+		errors.As(err, &unknownCA) || errors.As(err, &hostErr) || errors.As(err, &invalid) {
+		return true
+	}
+	// http.Transport reports handshake timeouts with an unexported type.
+	return strings.Contains(err.Error(), "TLS handshake")
+}
+
+// classifyError wraps err in a *TransportError when it is recognised.
+func classifyError(err error) error {
+	var te *TransportError
+	if errors.As(err, &te) {
+		return err
+	}
+	if code := ClassifyTransportError(err); code != "" {
+		return &TransportError{Code: code, Err: err}
+	}
+	return err
+}
@@ -0,0 +1,79 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gostratum/core/logx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransportErrorCodes(t *testing.T) {
+	get := func(t *testing.T, url string, opts ...Option) error {
+		t.Helper()
+		client, err := New(append([]Option{WithRetry(false, 0), WithLogger(logx.NewNoopLogger())}, opts...)...)
+		require.NoError(t, err)
+		_, err = client.Get(context.Background(), url)
+		require.Error(t, err)
+		return err
+	}
+
+	t.Run("conn_refused", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := ln.Addr().String()
+		require.NoError(t, ln.Close())
+
+		err = get(t, "http://"+addr)
+		var te *TransportError
+		require.ErrorAs(t, err, &te)
+		assert.Equal(t, CodeConnRefused, te.Code)
+		assert.ErrorIs(t, err, ErrTransport)
+		assert.ErrorIs(t, err, syscall.ECONNREFUSED)
+	})
+
+	t.Run("tls_handshake", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+		defer server.Close()
+
+		assert.Equal(t, CodeTLSHandshake, ClassifyTransportError(get(t, server.URL)))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer server.Close()
+
+		assert.Equal(t, CodeTimeout, ClassifyTransportError(get(t, server.URL, WithTimeout(20*time.Millisecond))))
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			_ = conn.Close()
+		}))
+		defer server.Close()
+
+		err := get(t, server.URL)
+		assert.Equal(t, CodeReset, ClassifyTransportError(err))
+		assert.Contains(t, err.Error(), "RESET: ")
+	})
+
+	t.Run("raw_errors", func(t *testing.T) {
+		assert.Equal(t, CodeDNSFailure, ClassifyTransportError(fmt.Errorf("dial: %w", &net.DNSError{Err: "no such host", Name: "api.invalid"})))
+		assert.Equal(t, CodeReset, ClassifyTransportError(syscall.ECONNRESET))
+		assert.Equal(t, CodeTimeout, ClassifyTransportError(context.DeadlineExceeded))
+		assert.Equal(t, ErrorCode(""), ClassifyTransportError(context.Canceled))
+		assert.Equal(t, ErrorCode(""), ClassifyTransportError(errors.New("boom")))
+	})
+}
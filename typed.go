@@ -0,0 +1,111 @@
+package httpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gostratum/httpc/redact"
+)
+
+// maxErrorBody bounds the body kept by an HTTPError.
+const maxErrorBody = 4 << 10
+
+// HTTPError reports an unsuccessful response: any non-2xx status from the
+// typed helpers, and 4xx and 5xx statuses from clients and requests with
+// WithErrorOnStatus. Use errors.As to inspect it; Response stays available,
+// e.g. for Retry-After.
+type HTTPError struct {
+	StatusCode int
+	Method     string
+	// URL is the request URL with sensitive query parameters redacted.
+	URL string
+	// Header is the response header, sensitive values redacted.
+	Header http.Header
+	// Body holds up to 4 KiB of the response body, which is then closed so
+	// the connection need not be released by the caller. Response helpers
+	// can still read bodies of up to 4 KiB. Body is empty for requests sent
+	// WithRawBody, whose body is left unread in Response for the caller to
+	// close.
+	Body     []byte
+	Response *Response
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("%s %s: status %d", e.Method, e.URL, e.StatusCode)
+	if len(e.Body) > 0 {
+		body := e.Body
+		if len(body) > 256 {
+			body = body[:256]
+		}
+		msg += ": " + string(body)
+	}
+	return msg
+}
+
+func newHTTPError(resp *Response, redactor *redact.Redactor) *HTTPError {
+	e := &HTTPError{StatusCode: resp.StatusCode(), Header: redactor.Header(resp.Headers()), Response: resp}
+	if raw := resp.Raw(); raw != nil && raw.Request != nil {
+		e.Method = raw.Request.Method
+		e.URL = redactor.Request(raw.Request)
+	}
+	if body, _ := resp.keepPrefix(maxErrorBody); len(body) > 0 {
+		e.Body = append([]byte(nil), body...)
+	}
+	return e
+}
+
+// GetAs sends a GET request and decodes the JSON response into a T. A
+// non-2xx status is returned as an *HTTPError; 204 and 205 responses, and
+// empty bodies, yield the zero T.
+func GetAs[T any](ctx context.Context, c Client, url string, opts ...ReqOption) (T, *Response, error) {
+	resp, err := c.Get(ctx, url, opts...)
+	return decodeAs[T](c, resp, err)
+}
+
+// PostAs sends a POST request with body, as accepted by Client.Post, and
+// decodes the JSON response into a T like GetAs.
+func PostAs[T any](ctx context.Context, c Client, url string, body any, opts ...ReqOption) (T, *Response, error) {
+	resp, err := c.Post(ctx, url, body, opts...)
+	return decodeAs[T](c, resp, err)
+}
+
+// DoAs sends req and decodes the JSON response into a T like GetAs.
+func DoAs[T any](ctx context.Context, c Client, req *Request) (T, *Response, error) {
+	resp, err := c.Do(ctx, req)
+	return decodeAs[T](c, resp, err)
+}
+
+func decodeAs[T any](c Client, resp *Response, err error) (T, *Response, error) {
+	var out T
+	return out, resp, decodeInto(c, resp, err, &out)
+}
+
+// decodeInto applies the rules of GetAs to resp, decoding into dest unless
+// it is nil.
+func decodeInto(c Client, resp *Response, err error, dest any) error {
+	if err != nil {
+		return err
+	}
+	if code := resp.StatusCode(); code < 200 || code > 299 {
+		redactor := redact.Default()
+		if cc, ok := c.(*client); ok {
+			redactor = cc.redactor
+		}
+		return newHTTPError(resp, redactor)
+	}
+	if code := resp.StatusCode(); code == http.StatusNoContent || code == http.StatusResetContent {
+		return nil
+	}
+	body, err := resp.Bytes()
+	if err != nil {
+		return err
+	}
+	if len(body) == 0 || dest == nil {
+		return nil
+	}
+	if err := resp.DecodeJSON(dest); err != nil {
+		return fmt.Errorf("decode %T: %w", dest, err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gostratum/httpc"
+)
+
+// S3 returns the Protocol of the S3 multipart upload API. Keys are request
+// paths relative to the client's base URL (the bucket endpoint); requests are
+// authorised by the client, typically with SigV4.
+func S3() Protocol { return s3Protocol{} }
+
+type s3Protocol struct{}
+
+type s3Initiate struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type s3Complete struct {
+	XMLName xml.Name `xml:"CompleteMultipartUpload"`
+	Parts   []s3Part `xml:"Part"`
+}
+
+type s3Part struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type s3Error struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+func (s3Protocol) Initiate(ctx context.Context, c httpc.Client, key string) (string, error) {
+	resp, err := c.Do(ctx, httpc.NewRequest(http.MethodPost, key, httpc.WithQuery("uploads", ""), statusChecked))
+	if err != nil {
+		return "", err
+	}
+	if err := checkStatus("initiate", resp); err != nil {
+		return "", err
+	}
+	body, err := resp.Bytes()
+	if err != nil {
+		return "", err
+	}
+	var out s3Initiate
+	if err := xml.Unmarshal(body, &out); err != nil || out.UploadID == "" {
+		return "", fmt.Errorf("upload: initiate: no upload id in response")
+	}
+	return out.UploadID, nil
+}
+
+func (s3Protocol) UploadPart(ctx context.Context, c httpc.Client, key, uploadID string, number int, body []byte) (string, error) {
+	resp, err := c.Do(ctx, httpc.NewRequest(http.MethodPut, key,
+		httpc.WithQuery("partNumber", strconv.Itoa(number)),
+		httpc.WithQuery("uploadId", uploadID),
+		httpc.WithRaw(body, "application/octet-stream"),
+		statusChecked,
+	))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Release()
+	if err := checkStatus("upload part", resp); err != nil {
+		return "", err
+	}
+	etag := resp.Header("ETag")
+	if etag == "" {
+		return "", fmt.Errorf("upload: part %d: response has no ETag", number)
+	}
+	return etag, nil
+}
+
+func (s3Protocol) Complete(ctx context.Context, c httpc.Client, key, uploadID string, parts []Part) error {
+	doc := s3Complete{Parts: make([]s3Part, len(parts))}
+	for i, p := range parts {
+		doc.Parts[i] = s3Part{PartNumber: p.Number, ETag: p.ETag}
+	}
+	payload, err := xml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	resp, err := c.Do(ctx, httpc.NewRequest(http.MethodPost, key,
+		httpc.WithQuery("uploadId", uploadID),
+		httpc.WithRaw(payload, "application/xml"),
+		statusChecked,
+	))
+	if err != nil {
+		return err
+	}
+	if err := checkStatus("complete", resp); err != nil {
+		return err
+	}
+	// S3 can answer 200 and still report a failure in the body.
+	body, err := resp.Bytes()
+	if err != nil {
+		return err
+	}
+	var failure s3Error
+	if bytes.Contains(body, []byte("<Error>")) && xml.Unmarshal(body, &failure) == nil {
+		return fmt.Errorf("upload: complete: %s: %s", failure.Code, failure.Message)
+	}
+	return nil
+}
+
+func (s3Protocol) Abort(ctx context.Context, c httpc.Client, key, uploadID string) error {
+	resp, err := c.Do(ctx, httpc.NewRequest(http.MethodDelete, key, httpc.WithQuery("uploadId", uploadID), statusChecked))
+	if err != nil {
+		return err
+	}
+	defer resp.Release()
+	return checkStatus("abort", resp)
+}
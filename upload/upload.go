@@ -0,0 +1,239 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/retry"
+)
+
+// Part is an uploaded part.
+type Part struct {
+	Number int
+	ETag   string
+	Size   int64
+}
+
+// Protocol speaks a multipart upload API. S3 returns the S3 (and MinIO, R2,
+// GCS XML API) implementation; other stores can plug in their own.
+type Protocol interface {
+	Initiate(ctx context.Context, c httpc.Client, key string) (uploadID string, err error)
+	UploadPart(ctx context.Context, c httpc.Client, key, uploadID string, number int, body []byte) (etag string, err error)
+	Complete(ctx context.Context, c httpc.Client, key, uploadID string, parts []Part) error
+	Abort(ctx context.Context, c httpc.Client, key, uploadID string) error
+}
+
+// Config controls an Uploader.
+type Config struct {
+	// Protocol defaults to S3().
+	Protocol Protocol
+	// PartSize is the size of every part but the last. Defaults to 8 MiB;
+	// S3 requires at least 5 MiB.
+	PartSize int64
+	// Concurrency bounds parts in flight, and so memory held, to
+	// Concurrency*PartSize. Defaults to 4.
+	Concurrency int
+	// PartAttempts, BaseBackoff and MaxBackoff configure the retries of each
+	// part. They default to 3 attempts between 500ms and 5s.
+	PartAttempts int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+}
+
+// Result describes a completed upload.
+type Result struct {
+	Key      string
+	UploadID string
+	Parts    []Part
+	Size     int64
+}
+
+// StatusError reports an unexpected status from the storage API.
+type StatusError struct {
+	Op         string
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("upload: %s: status %d: %s", e.Op, e.StatusCode, e.Body)
+	}
+	return fmt.Sprintf("upload: %s: status %d", e.Op, e.StatusCode)
+}
+
+// Uploader drives multipart uploads through an httpc.Client, which carries
+// the base URL (e.g. the bucket endpoint) and credentials.
+type Uploader struct {
+	client   httpc.Client
+	cfg      Config
+	strategy *retry.Strategy
+}
+
+// NewUploader returns an Uploader for client.
+func NewUploader(client httpc.Client, cfg Config) *Uploader {
+	if cfg.Protocol == nil {
+		cfg.Protocol = S3()
+	}
+	if cfg.PartSize <= 0 {
+		cfg.PartSize = 8 << 20
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.PartAttempts <= 0 {
+		cfg.PartAttempts = 3
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = 500 * time.Millisecond
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 5 * time.Second
+	}
+	return &Uploader{
+		client: client,
+		cfg:    cfg,
+		strategy: retry.NewStrategy(retry.PolicyConfig{
+			MaxAttempts: cfg.PartAttempts,
+			BaseBackoff: cfg.BaseBackoff,
+			MaxBackoff:  cfg.MaxBackoff,
+		}, retryable),
+	}
+}
+
+// Upload reads r to the end and stores it under key, uploading parts in
+// parallel. Each part is retried on its own; if any part still fails, or ctx
+// is cancelled, the upload is aborted and the first error returned.
+func (u *Uploader) Upload(ctx context.Context, key string, r io.Reader) (*Result, error) {
+	uploadID, err := u.cfg.Protocol.Initiate(ctx, u.client, key)
+	if err != nil {
+		return nil, err
+	}
+
+	parts, size, err := u.uploadParts(ctx, key, uploadID, r)
+	if err == nil {
+		err = u.cfg.Protocol.Complete(ctx, u.client, key, uploadID, parts)
+	}
+	if err != nil {
+		if abortErr := u.cfg.Protocol.Abort(context.WithoutCancel(ctx), u.client, key, uploadID); abortErr != nil {
+			err = errors.Join(err, fmt.Errorf("abort: %w", abortErr))
+		}
+		return nil, err
+	}
+	return &Result{Key: key, UploadID: uploadID, Parts: parts, Size: size}, nil
+}
+
+func (u *Uploader) uploadParts(ctx context.Context, key, uploadID string, r io.Reader) ([]Part, int64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		parts    []Part
+		firstErr error
+		size     int64
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+	slots := make(chan struct{}, u.cfg.Concurrency)
+
+	for number := 1; ; number++ {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		buf := make([]byte, u.cfg.PartSize)
+		n, readErr := io.ReadFull(r, buf)
+		if readErr != nil && !errors.Is(readErr, io.EOF) && !errors.Is(readErr, io.ErrUnexpectedEOF) {
+			<-slots
+			fail(fmt.Errorf("upload: read part %d: %w", number, readErr))
+			break
+		}
+		// Stores require at least one part, so an empty input becomes a
+		// single empty part.
+		if n == 0 && number > 1 {
+			<-slots
+			break
+		}
+		size += int64(n)
+
+		wg.Add(1)
+		go func(number int, body []byte) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			var etag string
+			err := u.strategy.Do(ctx, func(ctx context.Context, _ int) error {
+				var err error
+				etag, err = u.cfg.Protocol.UploadPart(ctx, u.client, key, uploadID, number, body)
+				return err
+			})
+			if err != nil {
+				fail(fmt.Errorf("upload: part %d: %w", number, err))
+				return
+			}
+			mu.Lock()
+			parts = append(parts, Part{Number: number, ETag: etag, Size: int64(len(body))})
+			mu.Unlock()
+		}(number, buf[:n])
+
+		if n < len(buf) {
+			break
+		}
+	}
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		return nil, 0, firstErr
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Number < parts[j].Number })
+	return parts, size, nil
+}
+
+// retryable retries transport failures, timeouts, 429 and 5xx answers.
+func retryable(err error) bool {
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.StatusCode == http.StatusRequestTimeout ||
+			status.StatusCode == http.StatusTooManyRequests ||
+			status.StatusCode >= 500
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// statusChecked keeps error statuses as responses under the client's
+// WithErrorOnStatus, so checkStatus can report them as *StatusError.
+var statusChecked = httpc.WithRequestErrorOnStatus(false)
+
+// checkStatus returns a *StatusError for non-2xx responses.
+func checkStatus(op string, resp *httpc.Response) error {
+	if code := resp.StatusCode(); code >= 200 && code < 300 {
+		return nil
+	}
+	body, _ := resp.Bytes()
+	if len(body) > 512 {
+		body = body[:512]
+	}
+	return &StatusError{Op: op, StatusCode: resp.StatusCode(), Body: string(bytes.TrimSpace(body))}
+}
@@ -0,0 +1,101 @@
+package httpc
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Strategies for joining request targets to the base URL, for
+// Config.URLJoin.
+const (
+	// URLJoinPath appends the target to the base URL path, whatever its
+	// form: "../x" and "/x" both stay below the base path. It is the default,
+	// kept for compatibility.
+	URLJoinPath = "path"
+	// URLJoinRFC3986 resolves the target as a reference against the base URL
+	// (RFC 3986 section 5, url.URL.ResolveReference): "x" replaces the last
+	// base segment unless the base path ends in "/", "/x" replaces the path,
+	// "../" climbs and fragments are kept. A target with a path drops any
+	// base query, as the RFC specifies.
+	URLJoinRFC3986 = "rfc3986"
+)
+
+func validateURLJoin(strategy string) error {
+	switch strategy {
+	case URLJoinPath, URLJoinRFC3986:
+		return nil
+	}
+	return fmt.Errorf("unsupported url_join %q", strategy)
+}
+
+// joinURL joins target to cfg.BaseURL using cfg.URLJoin.
+func joinURL(cfg Config, target string) (string, error) {
+	if cfg.PreserveEncodedPath {
+		target = escapePathPreserving(target)
+	}
+	if cfg.URLJoin != URLJoinRFC3986 {
+		return joinBaseURL(cfg.BaseURL, target)
+	}
+	return resolveBaseURL(cfg.BaseURL, target)
+}
+
+// resolveBaseURL resolves target as a URI reference against baseURL.
+func resolveBaseURL(baseURL, target string) (string, error) {
+	if baseURL == "" {
+		return target, nil
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL: %w", err)
+	}
+	ref, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid request URL: %w", err)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// escapePathPreserving escapes the characters of target's path that may not
+// appear in a URL path, leaving valid percent-encoded sequences and the
+// query and fragment as they are. net/url re-encodes a path from its decoded
+// form as soon as one character needs escaping, which would turn a
+// caller's %2F into a path separator.
+func escapePathPreserving(target string) string {
+	end := strings.IndexAny(target, "?#")
+	if end < 0 {
+		end = len(target)
+	}
+	return escapePreserving(target[:end], "/:[]") + target[end:]
+}
+
+// escapeSegmentPreserving is url.PathEscape for values that may already be
+// percent-encoded: valid %XX sequences are kept, so "group%2Fproject" is sent
+// as is while "group/project" still becomes one segment.
+func escapeSegmentPreserving(s string) string {
+	return escapePreserving(s, ":")
+}
+
+// escapePreserving percent-encodes every byte of s other than unreserved
+// characters, sub-delimiters, '@', the bytes in keep and the '%' of valid
+// escape sequences.
+func escapePreserving(s, keep string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			b.WriteByte(c)
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			strings.IndexByte("-._~!$&'()*+,;=@", c) >= 0, strings.IndexByte(keep, c) >= 0:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
@@ -0,0 +1,78 @@
+package httpc
+
+import (
+	"runtime"
+	"strings"
+)
+
+// UserAgentInfo identifies the calling application for BuildUserAgent.
+type UserAgentInfo struct {
+	// App and Version name the application, e.g. "billing" and "1.4.2",
+	// and form the leading product token. Without App only the library and
+	// runtime are described.
+	App     string
+	Version string
+	// Comments are extra details about the application, such as a contact
+	// URL or deployment name.
+	Comments []string
+}
+
+// BuildUserAgent returns a User-Agent in the product and comment form of
+// RFC 9110 section 10.1.5 naming the application, this library and the Go
+// runtime, so vendors can tell client populations apart when debugging:
+//
+//	billing/1.4.2 (+https://example.com/ops) httpc/0 (linux; amd64; go1.25.1)
+//
+// Characters not allowed in product tokens become "-", and comment text is
+// escaped as needed.
+func BuildUserAgent(info UserAgentInfo) string {
+	var b strings.Builder
+	if info.App != "" {
+		b.WriteString(uaToken(info.App))
+		if info.Version != "" {
+			b.WriteString("/" + uaToken(info.Version))
+		}
+		if len(info.Comments) > 0 {
+			b.WriteString(" " + uaComment(info.Comments...))
+		}
+		b.WriteString(" ")
+	}
+	b.WriteString(defaultUserAgent + " ")
+	b.WriteString(uaComment(runtime.GOOS, runtime.GOARCH, runtime.Version()))
+	return b.String()
+}
+
+// uaToken replaces everything but RFC 9110 tchar with "-".
+func uaToken(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x80 && (r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' ||
+			strings.ContainsRune("!#$%&'*+-.^_`|~", r)) {
+			return r
+		}
+		return '-'
+	}, s)
+}
+
+// uaComment joins parts into one comment, escaping parentheses and
+// backslashes and dropping control characters.
+func uaComment(parts ...string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for i, part := range parts {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		for _, r := range part {
+			switch {
+			case r == '(' || r == ')' || r == '\\':
+				b.WriteByte('\\')
+				b.WriteRune(r)
+			case r < 0x20 || r == 0x7f:
+			default:
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
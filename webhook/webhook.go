@@ -0,0 +1,245 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gostratum/httpc"
+	"github.com/gostratum/httpc/clock"
+	"github.com/gostratum/httpc/retry"
+)
+
+const (
+	// DefaultSignatureHeader carries "sha256=<hex HMAC>".
+	DefaultSignatureHeader = "X-Webhook-Signature"
+	// DefaultTimestampHeader carries the Unix time the payload was signed.
+	DefaultTimestampHeader = "X-Webhook-Timestamp"
+	// DefaultIDHeader carries the delivery ID, stable across attempts, so
+	// receivers can drop duplicates.
+	DefaultIDHeader = "X-Webhook-ID"
+)
+
+// ErrInvalidSignature is returned by Verify.
+var ErrInvalidSignature = errors.New("webhook: invalid signature")
+
+// Config controls signing and delivery.
+type Config struct {
+	// Secret keys the HMAC-SHA256 signature. Required.
+	Secret []byte
+	// SignatureHeader, TimestampHeader and IDHeader default to the
+	// Default*Header constants.
+	SignatureHeader string
+	TimestampHeader string
+	IDHeader        string
+	// MaxAttempts, BaseBackoff and MaxBackoff shape the exponential retries;
+	// they default to 5 attempts between 1s and 1m.
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// OnDeadLetter is called with the delivery once every attempt failed or
+	// the receiver rejected it permanently.
+	OnDeadLetter func(ctx context.Context, d *Delivery)
+	// Clock drives timestamps and backoff waits; defaults to the real clock.
+	Clock clock.Clock
+}
+
+// Delivery records one payload's delivery.
+type Delivery struct {
+	ID       string
+	URL      string
+	Payload  []byte
+	Attempts []retry.Attempt
+	// Delivered is true once the receiver answered 2xx.
+	Delivered bool
+	// Err is the final error when Delivered is false.
+	Err error
+}
+
+// Sender signs and delivers webhooks through an httpc.Client. The client
+// should have its own retries disabled; the Sender retries deliveries itself
+// so attempts are recorded and dead-lettered.
+type Sender struct {
+	client   httpc.Client
+	cfg      Config
+	clk      clock.Clock
+	strategy *retry.Strategy
+}
+
+// NewSender validates cfg and returns a Sender.
+func NewSender(client httpc.Client, cfg Config) (*Sender, error) {
+	if client == nil {
+		return nil, errors.New("webhook: nil client")
+	}
+	if len(cfg.Secret) == 0 {
+		return nil, errors.New("webhook: secret is required")
+	}
+	if cfg.SignatureHeader == "" {
+		cfg.SignatureHeader = DefaultSignatureHeader
+	}
+	if cfg.TimestampHeader == "" {
+		cfg.TimestampHeader = DefaultTimestampHeader
+	}
+	if cfg.IDHeader == "" {
+		cfg.IDHeader = DefaultIDHeader
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = time.Minute
+	}
+	return &Sender{
+		client: client,
+		cfg:    cfg,
+		clk:    clock.OrReal(cfg.Clock),
+		strategy: retry.NewStrategy(retry.PolicyConfig{
+			MaxAttempts: cfg.MaxAttempts,
+			BaseBackoff: cfg.BaseBackoff,
+			MaxBackoff:  cfg.MaxBackoff,
+			Clock:       cfg.Clock,
+		}, retryable),
+	}, nil
+}
+
+// Send delivers event to url as JSON; []byte and json.RawMessage payloads
+// are sent as is. Each attempt is re-signed with a fresh timestamp. The
+// returned Delivery is populated even when err is non-nil.
+func (s *Sender) Send(ctx context.Context, url string, event any) (*Delivery, error) {
+	payload, err := encode(event)
+	if err != nil {
+		return nil, err
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	d := &Delivery{ID: id, URL: url, Payload: payload}
+
+	var finished time.Time
+
+	err = s.strategy.Do(ctx, func(ctx context.Context, attempt int) error {
+		started := s.clk.Now()
+		record := retry.Attempt{Number: attempt}
+		if n := len(d.Attempts); n > 0 {
+			d.Attempts[n-1].Delay = started.Sub(finished)
+		}
+
+		timestamp := strconv.FormatInt(started.Unix(), 10)
+		resp, err := s.client.Do(ctx, httpc.NewRequest(http.MethodPost, url,
+			httpc.WithRaw(payload, "application/json"),
+			httpc.WithHeader(s.cfg.IDHeader, id),
+			httpc.WithHeader(s.cfg.TimestampHeader, timestamp),
+			httpc.WithHeader(s.cfg.SignatureHeader, Sign(s.cfg.Secret, timestamp, payload)),
+			// Classified by status below, also on clients with WithErrorOnStatus.
+			httpc.WithRequestErrorOnStatus(false),
+		))
+		if err == nil {
+			record.StatusCode = resp.StatusCode()
+			resp.Release()
+			if record.StatusCode < 200 || record.StatusCode >= 300 {
+				err = &StatusError{StatusCode: record.StatusCode}
+			}
+		}
+		record.Err = err
+		finished = s.clk.Now()
+		record.Duration = finished.Sub(started)
+		d.Attempts = append(d.Attempts, record)
+		return err
+	})
+	if err != nil {
+		d.Err = err
+		if s.cfg.OnDeadLetter != nil {
+			s.cfg.OnDeadLetter(ctx, d)
+		}
+		return d, err
+	}
+	d.Delivered = true
+	return d, nil
+}
+
+// StatusError reports a non-2xx answer from the receiver.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webhook: receiver answered %d", e.StatusCode)
+}
+
+// retryable retries transport failures, timeouts, 429 and 5xx answers; other
+// 4xx answers are permanent rejections.
+func retryable(err error) bool {
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.StatusCode == http.StatusRequestTimeout ||
+			status.StatusCode == http.StatusTooManyRequests ||
+			status.StatusCode >= 500
+	}
+	return true
+}
+
+// Sign returns the signature header value for payload signed at timestamp:
+// "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<payload>".
+func Sign(secret []byte, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a received signature and rejects timestamps further than
+// tolerance from now (no check when tolerance is zero). Receivers use it to
+// authenticate deliveries.
+func Verify(secret []byte, signature, timestamp string, payload []byte, tolerance time.Duration, now time.Time) error {
+	if tolerance > 0 {
+		secs, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return ErrInvalidSignature
+		}
+		if skew := now.Sub(time.Unix(secs, 0)); skew > tolerance || skew < -tolerance {
+			return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+		}
+	}
+	expected := Sign(secret, timestamp, payload)
+	if !hmac.Equal([]byte(expected), []byte(strings.TrimSpace(signature))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func encode(event any) ([]byte, error) {
+	switch v := event.(type) {
+	case []byte:
+		return v, nil
+	case json.RawMessage:
+		return v, nil
+	default:
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return nil, fmt.Errorf("webhook: encode payload: %w", err)
+		}
+		return payload, nil
+	}
+}
+
+func newID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("webhook: generate id: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
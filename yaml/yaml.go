@@ -0,0 +1,28 @@
+package yaml
+
+import (
+	"github.com/gostratum/httpc"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// MediaType is sent as the Content-Type of YAML bodies.
+const MediaType = "application/yaml"
+
+// WithYAML serialises v as YAML, for the infrastructure APIs (Kubernetes
+// adjacent tooling, CI systems) that accept application/yaml. Accept is set
+// to the same media type unless the request already chose one. Importing
+// this package is what pulls in gopkg.in/yaml.v3; the core client does not
+// depend on it.
+func WithYAML(v any) httpc.ReqOption {
+	return func(r *httpc.Request) {
+		httpc.WithEncodedBody(v, MediaType, yamlv3.Marshal)(r)
+		if r.Header("Accept") == "" {
+			httpc.WithAccept(MediaType)(r)
+		}
+	}
+}
+
+// DecodeYAML decodes the YAML body of resp into dest.
+func DecodeYAML(resp *httpc.Response, dest any) error {
+	return resp.Decode(yamlv3.Unmarshal, dest)
+}